@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/router"
+)
+
+// Log keeps a persistent record of the actions users take
+type Log interface {
+	// Record stores the entry. Failing to store an entry must not fail the action
+	// itself, so errors are only logged.
+	Record(*model.AuditEntry)
+}
+
+// NewLog returns a Log that stores the entries in the DB
+func NewLog(entries db.AuditEntries) Log {
+	return dbLog{entries}
+}
+
+type dbLog struct {
+	entries db.AuditEntries
+}
+
+func (l dbLog) Record(entry *model.AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if err := l.entries.Insert(entry); err != nil {
+		log.Printf("Failed to record %s by %s in the audit log: %v\n", entry.Action, entry.ActorID.Hex(), err)
+	}
+}
+
+// NewEntry returns an entry for the action the actor took on the target in the
+// restaurant. The action failed if handlerErr is set.
+func NewEntry(actorID, restaurantID bson.ObjectId, action, target string, handlerErr *router.HandlerError) *model.AuditEntry {
+	entry := &model.AuditEntry{
+		Time:         time.Now(),
+		ActorID:      actorID,
+		RestaurantID: restaurantID,
+		Action:       action,
+		Target:       target,
+		Outcome:      model.AuditOutcomeSuccess,
+	}
+	if handlerErr != nil {
+		entry.Outcome = model.AuditOutcomeFailure
+		entry.Error = handlerErr.Message
+		if entry.Error == "" {
+			entry.Error = http.StatusText(handlerErr.Code)
+		}
+	}
+	return entry
+}
@@ -0,0 +1,91 @@
+package audit_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	. "github.com/Lunchr/luncher-api/audit"
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/router"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Log", func() {
+	var (
+		entries  *fakeAuditEntries
+		auditLog Log
+	)
+
+	BeforeEach(func() {
+		entries = &fakeAuditEntries{}
+		auditLog = NewLog(entries)
+	})
+
+	Describe("Record", func() {
+		It("should store the entry", func() {
+			entry := &model.AuditEntry{Action: "offer.create"}
+			auditLog.Record(entry)
+			Expect(entries.entries).To(ConsistOf(entry))
+			Expect(entry.Time).To(BeTemporally("~", time.Now(), time.Second))
+		})
+
+		It("should not panic if storing fails", func() {
+			entries.err = errors.New("something went wrong")
+			auditLog.Record(&model.AuditEntry{Action: "offer.create"})
+		})
+	})
+
+	Describe("NewEntry", func() {
+		var (
+			actorID      bson.ObjectId
+			restaurantID bson.ObjectId
+		)
+
+		BeforeEach(func() {
+			actorID = bson.NewObjectId()
+			restaurantID = bson.NewObjectId()
+		})
+
+		It("should describe a successful action", func() {
+			entry := NewEntry(actorID, restaurantID, "offer.delete", "an offer", nil)
+			Expect(entry.ActorID).To(Equal(actorID))
+			Expect(entry.RestaurantID).To(Equal(restaurantID))
+			Expect(entry.Action).To(Equal("offer.delete"))
+			Expect(entry.Target).To(Equal("an offer"))
+			Expect(entry.Outcome).To(Equal(model.AuditOutcomeSuccess))
+			Expect(entry.Error).To(BeEmpty())
+		})
+
+		It("should describe a failed action with the message shown to the user", func() {
+			handlerErr := router.NewHandlerError(errors.New("internal details"), "Failed to find the offer", http.StatusNotFound)
+			entry := NewEntry(actorID, restaurantID, "offer.delete", "an offer", handlerErr)
+			Expect(entry.Outcome).To(Equal(model.AuditOutcomeFailure))
+			Expect(entry.Error).To(Equal("Failed to find the offer"))
+		})
+
+		It("should fall back to the status text for errors without a message", func() {
+			handlerErr := router.NewHandlerError(errors.New("internal details"), "", http.StatusInternalServerError)
+			entry := NewEntry(actorID, restaurantID, "offer.delete", "an offer", handlerErr)
+			Expect(entry.Error).To(Equal("Internal Server Error"))
+		})
+	})
+})
+
+type fakeAuditEntries struct {
+	db.AuditEntries
+	entries []*model.AuditEntry
+	err     error
+}
+
+func (f *fakeAuditEntries) Insert(entry *model.AuditEntry) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.entries = append(f.entries, entry)
+	return nil
+}
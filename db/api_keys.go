@@ -0,0 +1,110 @@
+package db
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type APIKeys interface {
+	Insert(*model.APIKey) (*model.APIKey, error)
+	// GetKey returns the API key with the hash of the key or mgo.ErrNotFound
+	GetKey(key string) (*model.APIKey, error)
+	GetForRestaurant(restaurantID bson.ObjectId) ([]*model.APIKey, error)
+	GetCreatedBy(userID bson.ObjectId) ([]*model.APIKey, error)
+	SetLastUsed(id bson.ObjectId, lastUsedAt time.Time) error
+	// Remove removes the restaurant's API key with the specified ID. Returns
+	// mgo.ErrNotFound if the restaurant has no such key.
+	Remove(id, restaurantID bson.ObjectId) error
+	RemoveForRestaurant(restaurantID bson.ObjectId) error
+	RemoveCreatedBy(userID bson.ObjectId) error
+}
+
+type apiKeysCollection struct {
+	*mgo.Collection
+}
+
+func NewAPIKeys(c *Client) (APIKeys, error) {
+	collection := c.database.C(model.APIKeyCollectionName)
+	apiKeys := &apiKeysCollection{collection}
+	if err := apiKeys.ensureHashIndex(); err != nil {
+		return nil, err
+	}
+	if err := apiKeys.ensureRestaurantIndex(); err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+func (c apiKeysCollection) Insert(apiKey *model.APIKey) (*model.APIKey, error) {
+	if apiKey.ID == "" {
+		apiKey.ID = bson.NewObjectId()
+	}
+	return apiKey, c.Collection.Insert(apiKey)
+}
+
+func (c apiKeysCollection) GetKey(key string) (*model.APIKey, error) {
+	var apiKey model.APIKey
+	err := c.Find(bson.M{
+		"hash": model.HashAPIKey(key),
+	}).One(&apiKey)
+	return &apiKey, err
+}
+
+func (c apiKeysCollection) GetForRestaurant(restaurantID bson.ObjectId) ([]*model.APIKey, error) {
+	var apiKeys []*model.APIKey
+	err := c.Find(bson.M{
+		"restaurant_id": restaurantID,
+	}).Sort("created_at").All(&apiKeys)
+	return apiKeys, err
+}
+
+func (c apiKeysCollection) GetCreatedBy(userID bson.ObjectId) ([]*model.APIKey, error) {
+	var apiKeys []*model.APIKey
+	err := c.Find(bson.M{
+		"created_by": userID,
+	}).Sort("created_at").All(&apiKeys)
+	return apiKeys, err
+}
+
+func (c apiKeysCollection) SetLastUsed(id bson.ObjectId, lastUsedAt time.Time) error {
+	return c.UpdateId(id, bson.M{
+		"$set": bson.M{"last_used_at": lastUsedAt},
+	})
+}
+
+func (c apiKeysCollection) Remove(id, restaurantID bson.ObjectId) error {
+	return c.Collection.Remove(bson.M{
+		"_id":           id,
+		"restaurant_id": restaurantID,
+	})
+}
+
+func (c apiKeysCollection) RemoveForRestaurant(restaurantID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"restaurant_id": restaurantID,
+	})
+	return err
+}
+
+func (c apiKeysCollection) RemoveCreatedBy(userID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"created_by": userID,
+	})
+	return err
+}
+
+func (c apiKeysCollection) ensureHashIndex() error {
+	return c.EnsureIndex(mgo.Index{
+		Key:    []string{"hash"},
+		Unique: true,
+	})
+}
+
+func (c apiKeysCollection) ensureRestaurantIndex() error {
+	return c.EnsureIndex(mgo.Index{
+		Key: []string{"restaurant_id"},
+	})
+}
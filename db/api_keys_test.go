@@ -0,0 +1,120 @@
+package db_test
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var _ = Describe("APIKeys", func() {
+	RebuildDBAfterEach()
+	var (
+		restaurantID bson.ObjectId
+		apiKey       *model.APIKey
+		key          string
+	)
+
+	BeforeEach(func() {
+		var err error
+		restaurantID = bson.NewObjectId()
+		apiKey, key, err = model.NewAPIKey(restaurantID, bson.NewObjectId(), "POS", model.RoleEditor)
+		Expect(err).NotTo(HaveOccurred())
+		apiKey, err = apiKeysCollection.Insert(apiKey)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("GetKey", func() {
+		It("returns the API key", func() {
+			foundAPIKey, err := apiKeysCollection.GetKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(foundAPIKey.ID).To(Equal(apiKey.ID))
+			Expect(foundAPIKey.RestaurantID).To(Equal(restaurantID))
+			Expect(foundAPIKey.Role).To(Equal(model.RoleEditor))
+		})
+
+		It("returns mgo.ErrNotFound for unknown keys", func() {
+			_, err := apiKeysCollection.GetKey(key + "x")
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+	})
+
+	Describe("GetForRestaurant", func() {
+		BeforeEach(func() {
+			otherAPIKey, _, err := model.NewAPIKey(bson.NewObjectId(), bson.NewObjectId(), "POS", model.RoleViewer)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = apiKeysCollection.Insert(otherAPIKey)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns only the restaurant's keys", func() {
+			apiKeys, err := apiKeysCollection.GetForRestaurant(restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apiKeys).To(HaveLen(1))
+			Expect(apiKeys[0].ID).To(Equal(apiKey.ID))
+		})
+	})
+
+	Describe("SetLastUsed", func() {
+		It("stores the time the key was last used", func() {
+			lastUsedAt := time.Now()
+			err := apiKeysCollection.SetLastUsed(apiKey.ID, lastUsedAt)
+			Expect(err).NotTo(HaveOccurred())
+			foundAPIKey, err := apiKeysCollection.GetKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(foundAPIKey.LastUsedAt).To(BeTemporally("~", lastUsedAt, time.Second))
+		})
+	})
+
+	Describe("Remove", func() {
+		It("removes the key", func() {
+			err := apiKeysCollection.Remove(apiKey.ID, restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = apiKeysCollection.GetKey(key)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+
+		It("doesn't remove keys of other restaurants", func() {
+			err := apiKeysCollection.Remove(apiKey.ID, bson.NewObjectId())
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+	})
+
+	Describe("RemoveForRestaurant", func() {
+		It("removes all of the restaurant's keys", func() {
+			err := apiKeysCollection.RemoveForRestaurant(restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			apiKeys, err := apiKeysCollection.GetForRestaurant(restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apiKeys).To(BeEmpty())
+		})
+	})
+
+	Describe("GetCreatedBy and RemoveCreatedBy", func() {
+		BeforeEach(func() {
+			otherAPIKey, _, err := model.NewAPIKey(restaurantID, bson.NewObjectId(), "Website", model.RoleViewer)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = apiKeysCollection.Insert(otherAPIKey)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns only the keys the user has created", func() {
+			apiKeys, err := apiKeysCollection.GetCreatedBy(apiKey.CreatedBy)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apiKeys).To(HaveLen(1))
+			Expect(apiKeys[0].ID).To(Equal(apiKey.ID))
+		})
+
+		It("removes only the keys the user has created", func() {
+			err := apiKeysCollection.RemoveCreatedBy(apiKey.CreatedBy)
+			Expect(err).NotTo(HaveOccurred())
+			apiKeys, err := apiKeysCollection.GetForRestaurant(restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apiKeys).To(HaveLen(1))
+			Expect(apiKeys[0].Name).To(Equal("Website"))
+		})
+	})
+})
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type AuditEntries interface {
+	Insert(*model.AuditEntry) error
+	// GetForRestaurant returns the restaurant's entries recorded in the [from, to)
+	// time range, most recent first, up to the limit
+	GetForRestaurant(restaurantID bson.ObjectId, from, to time.Time, limit int) ([]*model.AuditEntry, error)
+	// GetForActor returns all the entries of the actions the user has taken, most
+	// recent first
+	GetForActor(actorID bson.ObjectId) ([]*model.AuditEntry, error)
+}
+
+type auditEntriesCollection struct {
+	*mgo.Collection
+}
+
+func NewAuditEntries(client *Client) (AuditEntries, error) {
+	collection := client.database.C(model.AuditEntryCollectionName)
+	auditEntries := &auditEntriesCollection{collection}
+	if err := auditEntries.ensureRestaurantIndex(); err != nil {
+		return nil, err
+	}
+	if err := auditEntries.ensureActorIndex(); err != nil {
+		return nil, err
+	}
+	return auditEntries, nil
+}
+
+func (c auditEntriesCollection) Insert(entry *model.AuditEntry) error {
+	if entry.ID == "" {
+		entry.ID = bson.NewObjectId()
+	}
+	return c.Collection.Insert(entry)
+}
+
+func (c auditEntriesCollection) GetForRestaurant(restaurantID bson.ObjectId, from, to time.Time,
+	limit int) ([]*model.AuditEntry, error) {
+	var entries []*model.AuditEntry
+	err := c.Find(bson.M{
+		"restaurant_id": restaurantID,
+		"time": bson.M{
+			"$gte": from,
+			"$lt":  to,
+		},
+	}).Sort("-time").Limit(limit).All(&entries)
+	return entries, err
+}
+
+func (c auditEntriesCollection) GetForActor(actorID bson.ObjectId) ([]*model.AuditEntry, error) {
+	var entries []*model.AuditEntry
+	err := c.Find(bson.M{
+		"actor_id": actorID,
+	}).Sort("-time").All(&entries)
+	return entries, err
+}
+
+func (c auditEntriesCollection) ensureRestaurantIndex() error {
+	return c.EnsureIndex(mgo.Index{
+		Key: []string{"restaurant_id", "-time"},
+	})
+}
+
+func (c auditEntriesCollection) ensureActorIndex() error {
+	return c.EnsureIndex(mgo.Index{
+		Key: []string{"actor_id", "-time"},
+	})
+}
@@ -0,0 +1,78 @@
+package db_test
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuditEntries", func() {
+	RebuildDBAfterEach()
+	var (
+		restaurantID bson.ObjectId
+		now          time.Time
+	)
+
+	BeforeEach(func() {
+		restaurantID = bson.NewObjectId()
+		now = time.Now().Truncate(time.Millisecond)
+		for _, entry := range []*model.AuditEntry{
+			{Time: now.Add(-2 * time.Hour), RestaurantID: restaurantID, Action: "offer.create", Outcome: model.AuditOutcomeSuccess},
+			{Time: now.Add(-time.Hour), RestaurantID: restaurantID, Action: "offer.delete", Outcome: model.AuditOutcomeFailure},
+			{Time: now, RestaurantID: restaurantID, Action: "restaurant.update", Outcome: model.AuditOutcomeSuccess},
+			{Time: now, RestaurantID: bson.NewObjectId(), Action: "restaurant.update", Outcome: model.AuditOutcomeSuccess},
+		} {
+			err := auditEntriesCollection.Insert(entry)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	Describe("GetForRestaurant", func() {
+		It("should return the restaurant's entries in the time range, most recent first", func() {
+			entries, err := auditEntriesCollection.GetForRestaurant(restaurantID, now.Add(-90*time.Minute), now.Add(time.Minute), 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Action).To(Equal("restaurant.update"))
+			Expect(entries[1].Action).To(Equal("offer.delete"))
+		})
+
+		It("should exclude the end of the range", func() {
+			entries, err := auditEntriesCollection.GetForRestaurant(restaurantID, now.Add(-3*time.Hour), now, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(2))
+		})
+
+		It("should respect the limit", func() {
+			entries, err := auditEntriesCollection.GetForRestaurant(restaurantID, now.Add(-3*time.Hour), now.Add(time.Minute), 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+	})
+
+	Describe("GetForActor", func() {
+		var actorID bson.ObjectId
+
+		BeforeEach(func() {
+			actorID = bson.NewObjectId()
+			for _, entry := range []*model.AuditEntry{
+				{Time: now.Add(-time.Hour), ActorID: actorID, RestaurantID: restaurantID, Action: "offer.create", Outcome: model.AuditOutcomeSuccess},
+				{Time: now, ActorID: actorID, Action: "restaurant.create", Outcome: model.AuditOutcomeSuccess},
+			} {
+				err := auditEntriesCollection.Insert(entry)
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("should return all of the actor's entries, most recent first", func() {
+			entries, err := auditEntriesCollection.GetForActor(actorID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Action).To(Equal("restaurant.create"))
+			Expect(entries[1].Action).To(Equal("offer.create"))
+		})
+	})
+})
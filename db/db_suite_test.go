@@ -18,6 +18,15 @@ var (
 	restaurantsCollection              db.Restaurants
 	usersCollection                    db.Users
 	registrationAccessTokensCollection db.RegistrationAccessTokens
+	geocodesCollection                 db.Geocodes
+	emailTokensCollection              db.EmailTokens
+	membershipsCollection              db.Memberships
+	invitesCollection                  db.Invites
+	sessionsCollection                 db.Sessions
+	apiKeysCollection                  db.APIKeys
+	impersonationsCollection           db.Impersonations
+	auditEntriesCollection             db.AuditEntries
+	publishJobsCollection              db.PublishJobs
 	mocks                              *Mocks
 )
 
@@ -63,6 +72,15 @@ func initCollections() {
 	initRestaurantsCollection()
 	initUsersCollection()
 	initRegistrationAccessTokensCollection()
+	initGeocodesCollection()
+	initEmailTokensCollection()
+	initMembershipsCollection()
+	initInvitesCollection()
+	initSessionsCollection()
+	initAPIKeysCollection()
+	initImpersonationsCollection()
+	initAuditEntriesCollection()
+	initPublishJobsCollection()
 }
 
 func initOffersCollection() {
@@ -96,8 +114,10 @@ func initRestaurantsCollection() {
 }
 
 func initUsersCollection() {
-	usersCollection = db.NewUsers(dbClient)
-	err := insertUsers()
+	var err error
+	usersCollection, err = db.NewUsers(dbClient)
+	Expect(err).NotTo(HaveOccurred())
+	err = insertUsers()
 	Expect(err).NotTo(HaveOccurred())
 }
 
@@ -107,6 +127,60 @@ func initRegistrationAccessTokensCollection() {
 	Expect(err).NotTo(HaveOccurred())
 }
 
+func initGeocodesCollection() {
+	var err error
+	geocodesCollection, err = db.NewGeocodes(dbClient)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func initEmailTokensCollection() {
+	var err error
+	emailTokensCollection, err = db.NewEmailTokens(dbClient)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func initMembershipsCollection() {
+	var err error
+	membershipsCollection, err = db.NewMemberships(dbClient)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func initInvitesCollection() {
+	var err error
+	invitesCollection, err = db.NewInvites(dbClient)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func initSessionsCollection() {
+	var err error
+	sessionsCollection, err = db.NewSessions(dbClient)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func initAPIKeysCollection() {
+	var err error
+	apiKeysCollection, err = db.NewAPIKeys(dbClient)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func initImpersonationsCollection() {
+	var err error
+	impersonationsCollection, err = db.NewImpersonations(dbClient)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func initAuditEntriesCollection() {
+	var err error
+	auditEntriesCollection, err = db.NewAuditEntries(dbClient)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func initPublishJobsCollection() {
+	var err error
+	publishJobsCollection, err = db.NewPublishJobs(dbClient)
+	Expect(err).NotTo(HaveOccurred())
+}
+
 func createTestDbConf() (dbConfig *db.Config) {
 	dbConfig = &db.Config{
 		DbURL:  "127.0.0.1",
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type EmailTokens interface {
+	Insert(*model.EmailToken) error
+	// Claim removes and returns the unexpired token, so that it could only be used
+	// once. Returns mgo.ErrNotFound if there's no such token.
+	Claim(model.Token, model.EmailTokenPurpose) (*model.EmailToken, error)
+	RemoveForUser(userID bson.ObjectId) error
+}
+
+type emailTokensCollection struct {
+	*mgo.Collection
+}
+
+func NewEmailTokens(c *Client) (EmailTokens, error) {
+	collection := c.database.C(model.EmailTokenCollectionName)
+	tokens := &emailTokensCollection{collection}
+	if err := ensureTTLIndex(tokens.Collection, "expires_at"); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (c emailTokensCollection) Insert(t *model.EmailToken) error {
+	return c.Collection.Insert(t)
+}
+
+func (c emailTokensCollection) Claim(token model.Token, purpose model.EmailTokenPurpose) (*model.EmailToken, error) {
+	var emailToken model.EmailToken
+	_, err := c.Find(bson.M{
+		"token":   token,
+		"purpose": purpose,
+		"expires_at": bson.M{
+			"$gt": time.Now(),
+		},
+	}).Apply(mgo.Change{Remove: true}, &emailToken)
+	if err != nil {
+		return nil, err
+	}
+	return &emailToken, nil
+}
+
+func (c emailTokensCollection) RemoveForUser(userID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"user_id": userID,
+	})
+	return err
+}
@@ -0,0 +1,63 @@
+package db_test
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var _ = Describe("EmailTokens", func() {
+	Describe("Claim", func() {
+		RebuildDBAfterEach()
+		var (
+			userID     bson.ObjectId
+			emailToken *model.EmailToken
+		)
+
+		BeforeEach(func() {
+			var err error
+			userID = bson.NewObjectId()
+			emailToken, err = model.NewEmailToken(userID, model.EmailTokenVerifyEmail, time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			err = emailTokensCollection.Insert(emailToken)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the token", func() {
+			claimedToken, err := emailTokensCollection.Claim(emailToken.Token, model.EmailTokenVerifyEmail)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimedToken.UserID).To(Equal(userID))
+		})
+
+		It("can only be claimed once", func() {
+			_, err := emailTokensCollection.Claim(emailToken.Token, model.EmailTokenVerifyEmail)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = emailTokensCollection.Claim(emailToken.Token, model.EmailTokenVerifyEmail)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+
+		It("can't be claimed for another purpose", func() {
+			_, err := emailTokensCollection.Claim(emailToken.Token, model.EmailTokenResetPassword)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+
+		Context("with an expired token", func() {
+			BeforeEach(func() {
+				var err error
+				emailToken, err = model.NewEmailToken(userID, model.EmailTokenResetPassword, -time.Minute)
+				Expect(err).NotTo(HaveOccurred())
+				err = emailTokensCollection.Insert(emailToken)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("can't be claimed", func() {
+				_, err := emailTokensCollection.Claim(emailToken.Token, model.EmailTokenResetPassword)
+				Expect(err).To(Equal(mgo.ErrNotFound))
+			})
+		})
+	})
+})
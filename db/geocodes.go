@@ -0,0 +1,78 @@
+package db
+
+import (
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/geo"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Geocodes is a geo.Cache that stores the geocoding results in the DB
+type Geocodes interface {
+	geo.Cache
+}
+
+type geocodesCollection struct {
+	*mgo.Collection
+}
+
+func NewGeocodes(c *Client) (Geocodes, error) {
+	collection := c.database.C(model.GeocodeCollectionName)
+	geocodes := &geocodesCollection{collection}
+	if err := geocodes.ensureKeyIndex(); err != nil {
+		return nil, err
+	}
+	if err := ensureTTLIndex(geocodes.Collection, "expires_at"); err != nil {
+		return nil, err
+	}
+	return geocodes, nil
+}
+
+func (c geocodesCollection) Get(key geo.CacheKey) (*geo.CacheEntry, error) {
+	var geocode model.Geocode
+	err := c.Find(bson.M{
+		"provider": key.Provider,
+		"address":  key.Address,
+		"region":   key.Region,
+	}).One(&geocode)
+	if err == mgo.ErrNotFound {
+		return nil, geo.ErrorCacheMiss
+	} else if err != nil {
+		return nil, err
+	}
+	return &geo.CacheEntry{
+		Candidates: geocode.Candidates,
+		Address:    geocode.ReverseAddress,
+		Error:      geocode.Error,
+		ExpiresAt:  geocode.ExpiresAt,
+	}, nil
+}
+
+func (c geocodesCollection) Set(key geo.CacheKey, entry *geo.CacheEntry) error {
+	_, err := c.Upsert(bson.M{
+		"provider": key.Provider,
+		"address":  key.Address,
+		"region":   key.Region,
+	}, &model.Geocode{
+		Provider:       key.Provider,
+		Address:        key.Address,
+		Region:         key.Region,
+		Candidates:     entry.Candidates,
+		ReverseAddress: entry.Address,
+		Error:          entry.Error,
+		ExpiresAt:      entry.ExpiresAt,
+	})
+	return err
+}
+
+func (c geocodesCollection) ensureKeyIndex() error {
+	// The key used to not include the provider, which would prevent caching the results of
+	// several providers for the same address
+	if err := c.DropIndex("address", "region"); err != nil && !isIndexNotFound(err) {
+		return err
+	}
+	return c.EnsureIndex(mgo.Index{
+		Key:    []string{"provider", "address", "region"},
+		Unique: true,
+	})
+}
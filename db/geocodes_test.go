@@ -0,0 +1,96 @@
+package db_test
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/geo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Geocodes", func() {
+	var key = geo.NewCacheKey(geo.ProviderGoogle, "Küüni 5, Tartu", "ee")
+
+	Describe("Get", func() {
+		It("returns ErrorCacheMiss if nothing cached", func() {
+			_, err := geocodesCollection.Get(key)
+			Expect(err).To(Equal(geo.ErrorCacheMiss))
+		})
+	})
+
+	Describe("Set", func() {
+		RebuildDBAfterEach()
+		var expiresAt = time.Now().Add(time.Hour).Truncate(time.Millisecond)
+
+		BeforeEach(func() {
+			err := geocodesCollection.Set(key, &geo.CacheEntry{
+				Candidates: []geo.Candidate{
+					{
+						FormattedAddress: "Küüni 5, Tartu, Estonia",
+						Location: geo.Location{
+							Lat: 58.38,
+							Lng: 26.72,
+						},
+						Quality: geo.MatchExact,
+					},
+				},
+				ExpiresAt: expiresAt,
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("stores the entry", func() {
+			entry, err := geocodesCollection.Get(key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Candidates).To(Equal([]geo.Candidate{
+				{
+					FormattedAddress: "Küüni 5, Tartu, Estonia",
+					Location:         geo.Location{Lat: 58.38, Lng: 26.72},
+					Quality:          geo.MatchExact,
+				},
+			}))
+			Expect(entry.Error).To(BeEmpty())
+			Expect(entry.ExpiresAt.Equal(expiresAt)).To(BeTrue())
+		})
+
+		It("replaces a previous entry for the same key", func() {
+			err := geocodesCollection.Set(key, &geo.CacheEntry{
+				Error:     "no_results",
+				ExpiresAt: expiresAt,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			entry, err := geocodesCollection.Get(key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Error).To(Equal("no_results"))
+			Expect(entry.Candidates).To(BeEmpty())
+		})
+
+		It("doesn't return the entry for other regions", func() {
+			_, err := geocodesCollection.Get(geo.NewCacheKey(geo.ProviderGoogle, "Küüni 5, Tartu", "uk"))
+			Expect(err).To(Equal(geo.ErrorCacheMiss))
+		})
+
+		It("doesn't return the entry for other providers", func() {
+			_, err := geocodesCollection.Get(geo.NewCacheKey(geo.ProviderNominatim, "Küüni 5, Tartu", "ee"))
+			Expect(err).To(Equal(geo.ErrorCacheMiss))
+		})
+
+		It("stores reverse geocoding results", func() {
+			reverseKey := geo.NewReverseCacheKey(geo.ProviderGoogle, geo.Location{Lat: 58.38, Lng: 26.72})
+			address := &geo.Address{
+				FormattedAddress: "Küüni 5, Tartu, Estonia",
+				City:             "Tartu",
+				CountryCode:      "ee",
+				Location:         geo.Location{Lat: 58.38, Lng: 26.72},
+			}
+			err := geocodesCollection.Set(reverseKey, &geo.CacheEntry{
+				Address:   address,
+				ExpiresAt: expiresAt,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			entry, err := geocodesCollection.Get(reverseKey)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Address).To(Equal(address))
+		})
+	})
+})
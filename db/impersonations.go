@@ -0,0 +1,74 @@
+package db
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type Impersonations interface {
+	Insert(*model.Impersonation) (*model.Impersonation, error)
+	// End marks the admin's ongoing impersonations of the user as ended
+	End(adminID, userID bson.ObjectId, endedAt time.Time) error
+	// GetRecent returns the most recently started impersonations
+	GetRecent(limit int) ([]*model.Impersonation, error)
+	// GetForUser returns the impersonations of the user and the impersonations by the
+	// user, most recently started first
+	GetForUser(userID bson.ObjectId) ([]*model.Impersonation, error)
+}
+
+type impersonationsCollection struct {
+	*mgo.Collection
+}
+
+func NewImpersonations(client *Client) (Impersonations, error) {
+	collection := client.database.C(model.ImpersonationCollectionName)
+	impersonations := &impersonationsCollection{collection}
+	if err := impersonations.ensureStartedAtIndex(); err != nil {
+		return nil, err
+	}
+	return impersonations, nil
+}
+
+func (c impersonationsCollection) Insert(impersonation *model.Impersonation) (*model.Impersonation, error) {
+	if impersonation.ID == "" {
+		impersonation.ID = bson.NewObjectId()
+	}
+	return impersonation, c.Collection.Insert(impersonation)
+}
+
+func (c impersonationsCollection) End(adminID, userID bson.ObjectId, endedAt time.Time) error {
+	_, err := c.UpdateAll(bson.M{
+		"admin_id": adminID,
+		"user_id":  userID,
+		"ended_at": bson.M{"$exists": false},
+	}, bson.M{
+		"$set": bson.M{"ended_at": endedAt},
+	})
+	return err
+}
+
+func (c impersonationsCollection) GetRecent(limit int) ([]*model.Impersonation, error) {
+	var impersonations []*model.Impersonation
+	err := c.Find(nil).Sort("-started_at").Limit(limit).All(&impersonations)
+	return impersonations, err
+}
+
+func (c impersonationsCollection) GetForUser(userID bson.ObjectId) ([]*model.Impersonation, error) {
+	var impersonations []*model.Impersonation
+	err := c.Find(bson.M{
+		"$or": []bson.M{
+			{"user_id": userID},
+			{"admin_id": userID},
+		},
+	}).Sort("-started_at").All(&impersonations)
+	return impersonations, err
+}
+
+func (c impersonationsCollection) ensureStartedAtIndex() error {
+	return c.EnsureIndex(mgo.Index{
+		Key: []string{"-started_at"},
+	})
+}
@@ -0,0 +1,68 @@
+package db_test
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Impersonations", func() {
+	RebuildDBAfterEach()
+	var (
+		adminID bson.ObjectId
+		userID  bson.ObjectId
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		adminID = bson.NewObjectId()
+		userID = bson.NewObjectId()
+		now = time.Now().Truncate(time.Millisecond)
+		_, err := impersonationsCollection.Insert(&model.Impersonation{
+			AdminID:   adminID,
+			UserID:    userID,
+			Reason:    "An earlier ticket",
+			StartedAt: now.Add(-time.Hour),
+			EndedAt:   now.Add(-time.Hour + time.Minute),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = impersonationsCollection.Insert(&model.Impersonation{
+			AdminID:   adminID,
+			UserID:    userID,
+			Reason:    "Ticket #42",
+			StartedAt: now,
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("GetRecent", func() {
+		It("should return the latest impersonations first", func() {
+			impersonations, err := impersonationsCollection.GetRecent(10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(impersonations).To(HaveLen(2))
+			Expect(impersonations[0].Reason).To(Equal("Ticket #42"))
+		})
+
+		It("should respect the limit", func() {
+			impersonations, err := impersonationsCollection.GetRecent(1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(impersonations).To(HaveLen(1))
+		})
+	})
+
+	Describe("End", func() {
+		It("should only end the ongoing impersonation", func() {
+			endedAt := now.Add(time.Minute)
+			err := impersonationsCollection.End(adminID, userID, endedAt)
+			Expect(err).NotTo(HaveOccurred())
+			impersonations, err := impersonationsCollection.GetRecent(10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(impersonations[0].EndedAt).To(BeTemporally("==", endedAt))
+			Expect(impersonations[1].EndedAt).To(BeTemporally("==", now.Add(-time.Hour+time.Minute)))
+		})
+	})
+})
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2"
+)
+
+// indexNotFoundCode is the code of the error MongoDB responds with when dropping an index that doesn't exist
+const indexNotFoundCode = 27
+
+// ensureTTLIndex makes MongoDB remove the documents once the time in the field has passed. mgo
+// doesn't allow a zero expiry, so the documents are removed a second after the time instead.
+func ensureTTLIndex(c *mgo.Collection, field string) error {
+	return c.EnsureIndex(mgo.Index{
+		Key:         []string{field},
+		ExpireAfter: time.Second,
+	})
+}
+
+func isIndexNotFound(err error) bool {
+	queryErr, ok := err.(*mgo.QueryError)
+	return ok && (queryErr.Code == indexNotFoundCode || strings.HasPrefix(queryErr.Message, "index not found"))
+}
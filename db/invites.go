@@ -0,0 +1,105 @@
+package db
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type Invites interface {
+	Insert(*model.Invite) (*model.Invite, error)
+	// GetToken returns the unexpired invite with the token or mgo.ErrNotFound
+	GetToken(model.Token) (*model.Invite, error)
+	GetForRestaurant(restaurantID bson.ObjectId) ([]*model.Invite, error)
+	// Remove removes the restaurant's invite with the specified ID. Returns
+	// mgo.ErrNotFound if the restaurant has no such invite.
+	Remove(id, restaurantID bson.ObjectId) error
+	// RemoveInvitedBy removes all the invites the user has sent
+	RemoveInvitedBy(userID bson.ObjectId) error
+	// RemoveForEmail removes all the invites addressed to the email address
+	RemoveForEmail(email string) error
+	// RemoveForRestaurant removes all of the restaurant's invites
+	RemoveForRestaurant(restaurantID bson.ObjectId) error
+}
+
+type invitesCollection struct {
+	*mgo.Collection
+}
+
+func NewInvites(c *Client) (Invites, error) {
+	collection := c.database.C(model.InviteCollectionName)
+	invites := &invitesCollection{collection}
+	if err := ensureTTLIndex(invites.Collection, "expires_at"); err != nil {
+		return nil, err
+	}
+	if err := invites.ensureTokenIndex(); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (c invitesCollection) Insert(invite *model.Invite) (*model.Invite, error) {
+	if invite.ID == "" {
+		invite.ID = bson.NewObjectId()
+	}
+	return invite, c.Collection.Insert(invite)
+}
+
+func (c invitesCollection) GetToken(token model.Token) (*model.Invite, error) {
+	var invite model.Invite
+	err := c.Find(bson.M{
+		"token": token,
+		"expires_at": bson.M{
+			"$gt": time.Now(),
+		},
+	}).One(&invite)
+	return &invite, err
+}
+
+func (c invitesCollection) GetForRestaurant(restaurantID bson.ObjectId) ([]*model.Invite, error) {
+	var invites []*model.Invite
+	err := c.Find(bson.M{
+		"restaurant_id": restaurantID,
+		"expires_at": bson.M{
+			"$gt": time.Now(),
+		},
+	}).Sort("expires_at").All(&invites)
+	return invites, err
+}
+
+func (c invitesCollection) Remove(id, restaurantID bson.ObjectId) error {
+	return c.Collection.Remove(bson.M{
+		"_id":           id,
+		"restaurant_id": restaurantID,
+	})
+}
+
+func (c invitesCollection) RemoveInvitedBy(userID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"invited_by": userID,
+	})
+	return err
+}
+
+func (c invitesCollection) RemoveForEmail(email string) error {
+	_, err := c.RemoveAll(bson.M{
+		"email": NormalizeEmail(email),
+	})
+	return err
+}
+
+func (c invitesCollection) RemoveForRestaurant(restaurantID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"restaurant_id": restaurantID,
+	})
+	return err
+}
+
+func (c invitesCollection) ensureTokenIndex() error {
+	return c.EnsureIndex(mgo.Index{
+		Key:    []string{"token"},
+		Unique: true,
+	})
+}
@@ -0,0 +1,119 @@
+package db_test
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var _ = Describe("Invites", func() {
+	RebuildDBAfterEach()
+	var (
+		restaurantID bson.ObjectId
+		invite       *model.Invite
+	)
+
+	BeforeEach(func() {
+		var err error
+		restaurantID = bson.NewObjectId()
+		invite, err = model.NewInvite(restaurantID, bson.NewObjectId(), model.RoleEditor, "", time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		invite, err = invitesCollection.Insert(invite)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("GetToken", func() {
+		It("returns the invite", func() {
+			foundInvite, err := invitesCollection.GetToken(invite.Token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(foundInvite.ID).To(Equal(invite.ID))
+			Expect(foundInvite.RestaurantID).To(Equal(restaurantID))
+			Expect(foundInvite.Role).To(Equal(model.RoleEditor))
+		})
+
+		Context("with an expired invite", func() {
+			BeforeEach(func() {
+				var err error
+				invite, err = model.NewInvite(restaurantID, bson.NewObjectId(), model.RoleEditor, "", -time.Minute)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = invitesCollection.Insert(invite)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns mgo.ErrNotFound", func() {
+				_, err := invitesCollection.GetToken(invite.Token)
+				Expect(err).To(Equal(mgo.ErrNotFound))
+			})
+		})
+	})
+
+	Describe("GetForRestaurant", func() {
+		BeforeEach(func() {
+			otherInvite, err := model.NewInvite(bson.NewObjectId(), bson.NewObjectId(), model.RoleViewer, "", time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = invitesCollection.Insert(otherInvite)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns only the restaurant's invites", func() {
+			invites, err := invitesCollection.GetForRestaurant(restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(invites).To(HaveLen(1))
+			Expect(invites[0].ID).To(Equal(invite.ID))
+		})
+	})
+
+	Describe("Remove", func() {
+		It("removes the invite", func() {
+			err := invitesCollection.Remove(invite.ID, restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = invitesCollection.GetToken(invite.Token)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+
+		It("doesn't remove invites of other restaurants", func() {
+			err := invitesCollection.Remove(invite.ID, bson.NewObjectId())
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+	})
+
+	Describe("RemoveForEmail", func() {
+		var addressedInvite *model.Invite
+
+		BeforeEach(func() {
+			var err error
+			addressedInvite, err = model.NewInvite(restaurantID, bson.NewObjectId(), model.RoleViewer, "user@example.com", time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = invitesCollection.Insert(addressedInvite)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("removes only the invites addressed to the email", func() {
+			err := invitesCollection.RemoveForEmail("User@Example.com")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = invitesCollection.GetToken(addressedInvite.Token)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+			_, err = invitesCollection.GetToken(invite.Token)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("RemoveForRestaurant", func() {
+		It("removes only the restaurant's invites", func() {
+			otherInvite, err := model.NewInvite(bson.NewObjectId(), bson.NewObjectId(), model.RoleViewer, "", time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = invitesCollection.Insert(otherInvite)
+			Expect(err).NotTo(HaveOccurred())
+			err = invitesCollection.RemoveForRestaurant(restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = invitesCollection.GetToken(invite.Token)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+			_, err = invitesCollection.GetToken(otherInvite.Token)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type Memberships interface {
+	// Get returns the user's membership for the restaurant or mgo.ErrNotFound
+	// if the user has no role in managing the restaurant
+	Get(userID, restaurantID bson.ObjectId) (*model.Membership, error)
+	GetForUser(userID bson.ObjectId) ([]*model.Membership, error)
+	GetForRestaurant(restaurantID bson.ObjectId) ([]*model.Membership, error)
+	// Set gives the user the role for the restaurant, replacing the user's
+	// previous role for the restaurant, if any
+	Set(userID, restaurantID bson.ObjectId, role model.Role) error
+	RemoveForRestaurant(restaurantID bson.ObjectId) error
+	RemoveForUser(userID bson.ObjectId) error
+}
+
+type membershipsCollection struct {
+	*mgo.Collection
+}
+
+func NewMemberships(client *Client) (Memberships, error) {
+	collection := client.database.C(model.MembershipCollectionName)
+	memberships := &membershipsCollection{collection}
+	if err := memberships.ensureUserRestaurantIndex(); err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+func (c membershipsCollection) Get(userID, restaurantID bson.ObjectId) (*model.Membership, error) {
+	var membership model.Membership
+	err := c.Find(bson.M{
+		"user_id":       userID,
+		"restaurant_id": restaurantID,
+	}).One(&membership)
+	return &membership, err
+}
+
+func (c membershipsCollection) GetForUser(userID bson.ObjectId) ([]*model.Membership, error) {
+	var memberships []*model.Membership
+	err := c.Find(bson.M{
+		"user_id": userID,
+	}).All(&memberships)
+	return memberships, err
+}
+
+func (c membershipsCollection) GetForRestaurant(restaurantID bson.ObjectId) ([]*model.Membership, error) {
+	var memberships []*model.Membership
+	err := c.Find(bson.M{
+		"restaurant_id": restaurantID,
+	}).All(&memberships)
+	return memberships, err
+}
+
+func (c membershipsCollection) Set(userID, restaurantID bson.ObjectId, role model.Role) error {
+	_, err := c.Upsert(bson.M{
+		"user_id":       userID,
+		"restaurant_id": restaurantID,
+	}, bson.M{
+		"$set": bson.M{
+			"role": role,
+		},
+	})
+	return err
+}
+
+func (c membershipsCollection) RemoveForRestaurant(restaurantID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"restaurant_id": restaurantID,
+	})
+	return err
+}
+
+func (c membershipsCollection) RemoveForUser(userID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"user_id": userID,
+	})
+	return err
+}
+
+func (c membershipsCollection) ensureUserRestaurantIndex() error {
+	return c.EnsureIndex(mgo.Index{
+		Key:    []string{"user_id", "restaurant_id"},
+		Unique: true,
+	})
+}
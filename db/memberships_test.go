@@ -0,0 +1,117 @@
+package db_test
+
+import (
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var _ = Describe("Memberships", func() {
+	RebuildDBAfterEach()
+	var (
+		userID       bson.ObjectId
+		restaurantID bson.ObjectId
+	)
+
+	BeforeEach(func() {
+		userID = bson.NewObjectId()
+		restaurantID = bson.NewObjectId()
+	})
+
+	Describe("Get", func() {
+		It("returns mgo.ErrNotFound for users without a role", func() {
+			_, err := membershipsCollection.Get(userID, restaurantID)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+
+		Context("with a role set", func() {
+			BeforeEach(func() {
+				err := membershipsCollection.Set(userID, restaurantID, model.RoleEditor)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns the membership", func() {
+				membership, err := membershipsCollection.Get(userID, restaurantID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(membership.UserID).To(Equal(userID))
+				Expect(membership.RestaurantID).To(Equal(restaurantID))
+				Expect(membership.Role).To(Equal(model.RoleEditor))
+			})
+
+			It("doesn't return it for other restaurants", func() {
+				_, err := membershipsCollection.Get(userID, bson.NewObjectId())
+				Expect(err).To(Equal(mgo.ErrNotFound))
+			})
+		})
+	})
+
+	Describe("Set", func() {
+		It("replaces the previous role", func() {
+			err := membershipsCollection.Set(userID, restaurantID, model.RoleViewer)
+			Expect(err).NotTo(HaveOccurred())
+			err = membershipsCollection.Set(userID, restaurantID, model.RoleOwner)
+			Expect(err).NotTo(HaveOccurred())
+			memberships, err := membershipsCollection.GetForUser(userID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(memberships).To(HaveLen(1))
+			Expect(memberships[0].Role).To(Equal(model.RoleOwner))
+		})
+	})
+
+	Describe("RemoveForRestaurant", func() {
+		var otherRestaurantID bson.ObjectId
+
+		BeforeEach(func() {
+			otherRestaurantID = bson.NewObjectId()
+			err := membershipsCollection.Set(userID, restaurantID, model.RoleOwner)
+			Expect(err).NotTo(HaveOccurred())
+			err = membershipsCollection.Set(bson.NewObjectId(), restaurantID, model.RoleViewer)
+			Expect(err).NotTo(HaveOccurred())
+			err = membershipsCollection.Set(userID, otherRestaurantID, model.RoleEditor)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("removes only the restaurant's memberships", func() {
+			err := membershipsCollection.RemoveForRestaurant(restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			memberships, err := membershipsCollection.GetForUser(userID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(memberships).To(HaveLen(1))
+			Expect(memberships[0].RestaurantID).To(Equal(otherRestaurantID))
+		})
+	})
+
+	Describe("GetForRestaurant and RemoveForUser", func() {
+		var otherUserID bson.ObjectId
+
+		BeforeEach(func() {
+			otherUserID = bson.NewObjectId()
+			err := membershipsCollection.Set(userID, restaurantID, model.RoleOwner)
+			Expect(err).NotTo(HaveOccurred())
+			err = membershipsCollection.Set(otherUserID, restaurantID, model.RoleViewer)
+			Expect(err).NotTo(HaveOccurred())
+			err = membershipsCollection.Set(userID, bson.NewObjectId(), model.RoleEditor)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns all of the restaurant's memberships", func() {
+			memberships, err := membershipsCollection.GetForRestaurant(restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(memberships).To(HaveLen(2))
+		})
+
+		It("removes all of the user's memberships", func() {
+			err := membershipsCollection.RemoveForUser(userID)
+			Expect(err).NotTo(HaveOccurred())
+			memberships, err := membershipsCollection.GetForUser(userID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(memberships).To(BeEmpty())
+			memberships, err = membershipsCollection.GetForRestaurant(restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(memberships).To(HaveLen(1))
+			Expect(memberships[0].UserID).To(Equal(otherUserID))
+		})
+	})
+})
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// APIKeyCollectionName is the collection name used in the DB for API keys
+	APIKeyCollectionName = "api_keys"
+
+	apiKeyStart       = "lk_"
+	apiKeyPrefixLen   = len(apiKeyStart) + 8
+	apiKeyRandomBytes = 24
+)
+
+// APIKey lets an external system, e.g. a restaurant's POS, access the
+// restaurant through the API. The key acts on behalf of the user who created
+// it, but it's never allowed more than its own role. Only a hash of the key is
+// stored.
+type APIKey struct {
+	ID           bson.ObjectId `json:"_id"           bson:"_id,omitempty"`
+	RestaurantID bson.ObjectId `json:"restaurant_id" bson:"restaurant_id"`
+	Name         string        `json:"name"          bson:"name"`
+	// Prefix is the beginning of the key, so that the users could tell their
+	// keys apart
+	Prefix     string        `json:"prefix"       bson:"prefix"`
+	Hash       []byte        `json:"-"            bson:"hash"`
+	Role       Role          `json:"role"         bson:"role"`
+	CreatedBy  bson.ObjectId `json:"created_by"   bson:"created_by"`
+	CreatedAt  time.Time     `json:"created_at"   bson:"created_at"`
+	LastUsedAt time.Time     `json:"last_used_at" bson:"last_used_at,omitempty"`
+}
+
+// NewAPIKey returns a new API key for the restaurant along with the key itself.
+// The key can't be recovered from the APIKey, so it has to be handed to the user
+// right away.
+func NewAPIKey(restaurantID, createdBy bson.ObjectId, name string, role Role) (*APIKey, string, error) {
+	b := make([]byte, apiKeyRandomBytes)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, "", err
+	}
+	key := apiKeyStart + base64.RawURLEncoding.EncodeToString(b)
+	return &APIKey{
+		RestaurantID: restaurantID,
+		Name:         name,
+		Prefix:       APIKeyPrefix(key),
+		Hash:         HashAPIKey(key),
+		Role:         role,
+		CreatedBy:    createdBy,
+		CreatedAt:    time.Now(),
+	}, key, nil
+}
+
+// HashAPIKey returns the hash the key is stored as. The keys are long and random,
+// so a fast hash is enough and lets the keys be looked up by their hash.
+func HashAPIKey(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+	return hash[:]
+}
+
+// LooksLikeAPIKey returns true if the string has the format of an API key
+func LooksLikeAPIKey(s string) bool {
+	return strings.HasPrefix(s, apiKeyStart) && len(s) > apiKeyPrefixLen
+}
+
+// APIKeyPrefix returns the beginning of the key that is stored as the key's
+// Prefix or an empty string if the string isn't an API key
+func APIKeyPrefix(key string) string {
+	if !LooksLikeAPIKey(key) {
+		return ""
+	}
+	return key[:apiKeyPrefixLen]
+}
+
+// IsAllowedAPIKeyRole returns true if API keys can be given the role. Keys can't
+// be owners, so that a leaked key couldn't be used to take over the restaurant.
+func IsAllowedAPIKeyRole(role Role) bool {
+	return role.IsValid() && !role.Includes(RoleOwner)
+}
@@ -0,0 +1,56 @@
+package model_test
+
+import (
+	. "github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("APIKey", func() {
+	Describe("NewAPIKey", func() {
+		var (
+			apiKey *APIKey
+			key    string
+		)
+
+		BeforeEach(func() {
+			var err error
+			apiKey, key, err = NewAPIKey(bson.NewObjectId(), bson.NewObjectId(), "POS", RoleEditor)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should only store the key's hash", func() {
+			Expect(apiKey.Hash).To(Equal(HashAPIKey(key)))
+			Expect(string(apiKey.Hash)).NotTo(ContainSubstring(key))
+		})
+
+		It("should store the key's prefix", func() {
+			Expect(key).To(HavePrefix(apiKey.Prefix))
+			Expect(len(apiKey.Prefix)).To(BeNumerically("<", len(key)))
+		})
+
+		It("should create a key that looks like an API key", func() {
+			Expect(LooksLikeAPIKey(key)).To(BeTrue())
+		})
+
+		It("should create unique keys", func() {
+			_, anotherKey, err := NewAPIKey(bson.NewObjectId(), bson.NewObjectId(), "POS", RoleEditor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(anotherKey).NotTo(Equal(key))
+		})
+	})
+
+	Describe("IsAllowedAPIKeyRole", func() {
+		It("should allow viewers and editors", func() {
+			Expect(IsAllowedAPIKeyRole(RoleViewer)).To(BeTrue())
+			Expect(IsAllowedAPIKeyRole(RoleEditor)).To(BeTrue())
+		})
+
+		It("should not allow owners or unknown roles", func() {
+			Expect(IsAllowedAPIKeyRole(RoleOwner)).To(BeFalse())
+			Expect(IsAllowedAPIKeyRole(Role("admin"))).To(BeFalse())
+		})
+	})
+})
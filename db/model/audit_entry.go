@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// AuditEntryCollectionName is the collection name used in the DB for the audit log
+const AuditEntryCollectionName = "audit_entries"
+
+// AuditOutcome tells whether an audited action succeeded
+type AuditOutcome string
+
+const (
+	AuditOutcomeSuccess AuditOutcome = "success"
+	AuditOutcomeFailure AuditOutcome = "failure"
+)
+
+// AuditEntry records an action a user took, e.g. publishing an offer or removing
+// a restaurant's API key
+type AuditEntry struct {
+	ID      bson.ObjectId `json:"_id"      bson:"_id,omitempty"`
+	Time    time.Time     `json:"time"     bson:"time"`
+	ActorID bson.ObjectId `json:"actor_id" bson:"actor_id,omitempty"`
+	// ImpersonatorID is set if an administrator took the action on behalf of the actor
+	ImpersonatorID bson.ObjectId `json:"impersonator_id,omitempty" bson:"impersonator_id,omitempty"`
+	// APIKeyPrefix is set if the action was taken with one of the actor's API keys
+	APIKeyPrefix string        `json:"api_key_prefix,omitempty" bson:"api_key_prefix,omitempty"`
+	RestaurantID bson.ObjectId `json:"restaurant_id,omitempty"  bson:"restaurant_id,omitempty"`
+	// Action describes what was done, e.g. "offer.delete"
+	Action string `json:"action" bson:"action"`
+	// Target identifies what the action was taken on within the restaurant, e.g. an offer's ID
+	Target  string       `json:"target,omitempty" bson:"target,omitempty"`
+	Outcome AuditOutcome `json:"outcome"          bson:"outcome"`
+	// Error describes why the action failed
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const EmailTokenCollectionName = "email_tokens"
+
+const (
+	// EmailTokenVerifyEmail tokens are sent to confirm that the user owns the email address
+	EmailTokenVerifyEmail EmailTokenPurpose = "verify_email"
+	// EmailTokenResetPassword tokens are sent to let the user choose a new password
+	EmailTokenResetPassword EmailTokenPurpose = "reset_password"
+)
+
+type (
+	// EmailToken is a single use token that gets emailed to the user to prove
+	// that they have access to their email address
+	EmailToken struct {
+		ID        bson.ObjectId     `bson:"_id,omitempty"`
+		Token     Token             `bson:"token"`
+		UserID    bson.ObjectId     `bson:"user_id"`
+		Purpose   EmailTokenPurpose `bson:"purpose"`
+		ExpiresAt time.Time         `bson:"expires_at"`
+	}
+
+	EmailTokenPurpose string
+)
+
+func NewEmailToken(userID bson.ObjectId, purpose EmailTokenPurpose, validFor time.Duration) (*EmailToken, error) {
+	token, err := NewToken()
+	if err != nil {
+		return nil, err
+	}
+	return &EmailToken{
+		Token:     token,
+		UserID:    userID,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(validFor),
+	}, nil
+}
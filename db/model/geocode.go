@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/geo"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const GeocodeCollectionName = "geocodes"
+
+// Geocode is a cached geocoding result
+type Geocode struct {
+	ID         bson.ObjectId   `bson:"_id,omitempty"`
+	Provider   string          `bson:"provider"`
+	Address    string          `bson:"address"`
+	Region     string          `bson:"region"`
+	Candidates []geo.Candidate `bson:"candidates"`
+	// ReverseAddress is the result of reverse geocoding the location in Address
+	ReverseAddress *geo.Address `bson:"reverse_address,omitempty"`
+	Error          string       `bson:"error,omitempty"`
+	ExpiresAt      time.Time    `bson:"expires_at"`
+}
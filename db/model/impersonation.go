@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ImpersonationCollectionName is the collection name used in the DB for impersonations
+const ImpersonationCollectionName = "impersonations"
+
+// Impersonation records an administrator acting as another user for support purposes
+type Impersonation struct {
+	ID        bson.ObjectId `json:"_id"                bson:"_id,omitempty"`
+	AdminID   bson.ObjectId `json:"admin_id"           bson:"admin_id"`
+	UserID    bson.ObjectId `json:"user_id"            bson:"user_id"`
+	Reason    string        `json:"reason"             bson:"reason"`
+	StartedAt time.Time     `json:"started_at"         bson:"started_at"`
+	EndedAt   time.Time     `json:"ended_at,omitempty" bson:"ended_at,omitempty"`
+}
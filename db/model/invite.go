@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// InviteCollectionName is the collection name used in the DB for invites
+const InviteCollectionName = "invites"
+
+// Invite lets whoever has the token become a member of the restaurant. Invites
+// sent to a specific email address can only be accepted once, the others can
+// be shared with all the staff members until they expire or are revoked.
+type Invite struct {
+	ID           bson.ObjectId `json:"_id"             bson:"_id,omitempty"`
+	Token        Token         `json:"token"           bson:"token"`
+	RestaurantID bson.ObjectId `json:"restaurant_id"   bson:"restaurant_id"`
+	Role         Role          `json:"role"            bson:"role"`
+	Email        string        `json:"email,omitempty" bson:"email,omitempty"`
+	InvitedBy    bson.ObjectId `json:"invited_by"      bson:"invited_by"`
+	ExpiresAt    time.Time     `json:"expires_at"      bson:"expires_at"`
+}
+
+func NewInvite(restaurantID, invitedBy bson.ObjectId, role Role, email string, validFor time.Duration) (*Invite, error) {
+	token, err := NewToken()
+	if err != nil {
+		return nil, err
+	}
+	return &Invite{
+		Token:        token,
+		RestaurantID: restaurantID,
+		Role:         role,
+		Email:        email,
+		InvitedBy:    invitedBy,
+		ExpiresAt:    time.Now().Add(validFor),
+	}, nil
+}
+
+// IsSingleUse returns true if the invite can only be accepted once
+func (i *Invite) IsSingleUse() bool {
+	return i.Email != ""
+}
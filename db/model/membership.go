@@ -0,0 +1,46 @@
+package model
+
+import "gopkg.in/mgo.v2/bson"
+
+// MembershipCollectionName is the collection name used in the DB for memberships
+const MembershipCollectionName = "memberships"
+
+const (
+	// RoleViewer can see the restaurant's data, but can't change anything
+	RoleViewer Role = "viewer"
+	// RoleEditor can additionally manage the restaurant's offers and posts
+	RoleEditor Role = "editor"
+	// RoleOwner can additionally change the restaurant's settings and remove the restaurant
+	RoleOwner Role = "owner"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+type (
+	// Membership gives a user a role in managing a restaurant
+	Membership struct {
+		ID           bson.ObjectId `json:"_id"           bson:"_id,omitempty"`
+		UserID       bson.ObjectId `json:"user_id"       bson:"user_id"`
+		RestaurantID bson.ObjectId `json:"restaurant_id" bson:"restaurant_id"`
+		Role         Role          `json:"role"          bson:"role"`
+	}
+
+	// Role determines what a user is allowed to do with a restaurant. Every role
+	// includes the permissions of the roles below it.
+	Role string
+)
+
+// IsValid returns true if the role is one of the known roles
+func (r Role) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes returns true if the role grants all the permissions of the other role
+func (r Role) Includes(other Role) bool {
+	return r.IsValid() && roleRanks[r] >= roleRanks[other]
+}
@@ -0,0 +1,34 @@
+package model_test
+
+import (
+	"github.com/Lunchr/luncher-api/db/model"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Membership", func() {
+	Describe("Role", func() {
+		Describe("Includes", func() {
+			It("includes itself", func() {
+				Expect(model.RoleEditor.Includes(model.RoleEditor)).To(BeTrue())
+			})
+
+			It("includes lower roles", func() {
+				Expect(model.RoleOwner.Includes(model.RoleEditor)).To(BeTrue())
+				Expect(model.RoleOwner.Includes(model.RoleViewer)).To(BeTrue())
+				Expect(model.RoleEditor.Includes(model.RoleViewer)).To(BeTrue())
+			})
+
+			It("doesn't include higher roles", func() {
+				Expect(model.RoleViewer.Includes(model.RoleEditor)).To(BeFalse())
+				Expect(model.RoleEditor.Includes(model.RoleOwner)).To(BeFalse())
+			})
+
+			It("doesn't include anything for an unknown role", func() {
+				Expect(model.Role("").Includes(model.RoleViewer)).To(BeFalse())
+				Expect(model.Role("admin").Includes(model.RoleViewer)).To(BeFalse())
+			})
+		})
+	})
+})
@@ -55,6 +55,9 @@ type (
 		Address  string        `json:"address"  bson:"address"`
 		Location Location      `json:"location" bson:"location"`
 		Phone    string        `json:"phone"    bson:"phone"`
+		// Deactivated is only used for hiding the offers of deactivated restaurants from
+		// the public queries and is therefore not included in the JSON representation
+		Deactivated bool `json:"-" bson:"deactivated,omitempty"`
 	}
 
 	// OfferRestaurantWithDistance wraps an OfferRestaurant and adds a distance field.
@@ -111,3 +114,18 @@ func MapOfferWithDistanceToJSON(offer *OfferWithDistance, imageToPathMapper func
 		Restaurant: offer.Restaurant,
 	}, nil
 }
+
+// MapRestaurantToOfferRestaurant creates the copy of the restaurant's information
+// that gets included in every offer
+func MapRestaurantToOfferRestaurant(restaurant *Restaurant) OfferRestaurant {
+	return OfferRestaurant{
+		ID:       restaurant.ID,
+		Name:     restaurant.Name,
+		Region:   restaurant.Region,
+		Address:  restaurant.Address,
+		Location: restaurant.Location,
+		Phone:    restaurant.Phone,
+
+		Deactivated: restaurant.Deactivated,
+	}
+}
@@ -17,6 +17,8 @@ type (
 		MessageTemplate     string `json:"message_template"      bson:"message_template"`
 		FBPostID            string `json:"fb_post_id,omitempty"  bson:"fb_post_id"`
 		PostedImageChecksum uint32 `json:"posted_image_checksum" bson:"posted_image_checksum"`
+		// PublishTime is when the post was, or is scheduled to be, published on Facebook
+		PublishTime time.Time `json:"-" bson:"publish_time,omitempty"`
 	}
 
 	DateWithoutTime string
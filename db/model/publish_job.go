@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// PublishJobCollectionName is the collection name used in the DB for the Facebook publishing jobs
+const PublishJobCollectionName = "publish_jobs"
+
+// PublishJobStatus tells whether the restaurant's post for the date has been brought up to
+// date with the restaurant's offers on Facebook
+type PublishJobStatus string
+
+const (
+	// PublishJobPending jobs are waiting for the offers to stop changing, for a worker or
+	// are being processed by one
+	PublishJobPending PublishJobStatus = "pending"
+	// PublishJobDone jobs have been successfully processed
+	PublishJobDone PublishJobStatus = "done"
+	// PublishJobFailed jobs have run out of attempts and won't be retried before the
+	// restaurant's offers for the date change again
+	PublishJobFailed PublishJobStatus = "failed"
+)
+
+// PublishJob is a request to update the restaurant's Facebook post for the date. There's a
+// single job for every restaurant and date, which gets queued again every time the
+// restaurant's offers for the date change.
+type PublishJob struct {
+	ID           bson.ObjectId   `json:"_id"           bson:"_id,omitempty"`
+	RestaurantID bson.ObjectId   `json:"restaurant_id" bson:"restaurant_id"`
+	Date         DateWithoutTime `json:"date"          bson:"date"`
+	// UserID is the user who queued the job. Their Facebook tokens are used for publishing if
+	// they can post on the restaurant's page, otherwise another user's tokens are used. It's
+	// removed if the user is deleted.
+	UserID   bson.ObjectId    `json:"user_id"  bson:"user_id"`
+	Status   PublishJobStatus `json:"status"   bson:"status"`
+	Attempts int              `json:"attempts" bson:"attempts"`
+	// RunAt is when the job will be attempted next
+	RunAt time.Time `json:"run_at" bson:"run_at"`
+	// Deadline is when the job is run at the latest, no matter how many times it gets queued again
+	// before that. It's set by the change that queues the job when it's not already pending.
+	Deadline time.Time `json:"deadline" bson:"deadline"`
+	// LockedUntil is set while a worker is processing the job. The lock expires, so that
+	// the jobs of workers that have crashed would be picked up again.
+	LockedUntil time.Time `json:"-"                    bson:"locked_until,omitempty"`
+	LastError   string    `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"           bson:"updated_at"`
+	// Version is incremented every time the job is queued, so that a job that's queued
+	// again while it's being processed wouldn't get marked as done
+	Version int `json:"-" bson:"version"`
+}
@@ -11,13 +11,31 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
-const RegistrationAccessTokenCollectionName = "registration_access_tokens"
+const (
+	RegistrationAccessTokenCollectionName = "registration_access_tokens"
+	// RegistrationAccessTokenTTL is how long after their creation or last extension the unclaimed
+	// tokens are removed from the DB
+	RegistrationAccessTokenTTL = 30 * 24 * time.Hour
+)
 
 type (
+	// RegistrationAccessToken allows a single user to register through Facebook
 	RegistrationAccessToken struct {
-		ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty"`
-		CreatedAt time.Time     `json:"created_at"    bson:"created_at"`
-		Token     Token         `json:"token"         bson:"token"`
+		ID bson.ObjectId `json:"_id,omitempty"        bson:"_id,omitempty"`
+		// CreatedAt is when the token was created
+		CreatedAt time.Time `json:"created_at"           bson:"created_at"`
+		Token     Token     `json:"token"                bson:"token"`
+		Notes     string    `json:"notes,omitempty"      bson:"notes,omitempty"`
+		// TTLFrom is when the token was created or last extended. The TTL of the tokens'
+		// collection counts from this. It's removed when the token is claimed, so that the
+		// used tokens would be kept.
+		TTLFrom time.Time `json:"-" bson:"ttl_from,omitempty"`
+		// ExpiresAt can be used to make the token expire sooner than the
+		// RegistrationAccessTokenTTL would
+		ExpiresAt time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+		// ClaimedBy is the ID of the user who registered with the token
+		ClaimedBy bson.ObjectId `json:"claimed_by,omitempty" bson:"claimed_by,omitempty"`
+		ClaimedAt time.Time     `json:"claimed_at,omitempty" bson:"claimed_at,omitempty"`
 	}
 
 	Token [16]byte
@@ -28,12 +46,34 @@ func NewRegistrationAccessToken() (*RegistrationAccessToken, error) {
 	if err != nil {
 		return nil, err
 	}
+	now := time.Now()
 	return &RegistrationAccessToken{
-		CreatedAt: time.Now(),
+		CreatedAt: now,
+		TTLFrom:   now,
 		Token:     token,
 	}, nil
 }
 
+// Expiry returns when the token stops being usable, which is either its ExpiresAt or
+// when the TTL removes it, whichever comes first
+func (t RegistrationAccessToken) Expiry() time.Time {
+	ttlFrom := t.TTLFrom
+	if ttlFrom.IsZero() {
+		// Claimed tokens and the tokens created before TTLFrom was introduced
+		ttlFrom = t.CreatedAt
+	}
+	ttlExpiry := ttlFrom.Add(RegistrationAccessTokenTTL)
+	if !t.ExpiresAt.IsZero() && t.ExpiresAt.Before(ttlExpiry) {
+		return t.ExpiresAt
+	}
+	return ttlExpiry
+}
+
+// IsUsable returns true if the token hasn't been used and hasn't expired
+func (t RegistrationAccessToken) IsUsable(now time.Time) bool {
+	return t.ClaimedBy == "" && t.Expiry().After(now)
+}
+
 func NewToken() (Token, error) {
 	var t [16]byte
 	_, err := rand.Read(t[:])
@@ -59,3 +99,17 @@ func TokenFromString(s string) (Token, error) {
 func (t Token) String() string {
 	return fmt.Sprintf("%X-%X-%X-%X-%X", t[0:4], t[4:6], t[6:8], t[8:10], t[10:])
 }
+
+// MarshalText makes the token appear in its string form in JSON
+func (t Token) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *Token) UnmarshalText(text []byte) error {
+	token, err := TokenFromString(string(text))
+	if err != nil {
+		return err
+	}
+	*t = token
+	return nil
+}
@@ -1,13 +1,79 @@
 package model_test
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2/bson"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("RegistrationAccessToken", func() {
+	Describe("Expiry", func() {
+		var createdAt time.Time
+
+		BeforeEach(func() {
+			createdAt = time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+		})
+
+		It("is derived from the TTL by default", func() {
+			token := model.RegistrationAccessToken{CreatedAt: createdAt}
+			Expect(token.Expiry()).To(Equal(createdAt.Add(30 * 24 * time.Hour)))
+		})
+
+		It("can be sooner than the TTL", func() {
+			token := model.RegistrationAccessToken{CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)}
+			Expect(token.Expiry()).To(Equal(createdAt.Add(time.Hour)))
+		})
+
+		It("can't be later than the TTL", func() {
+			token := model.RegistrationAccessToken{CreatedAt: createdAt, ExpiresAt: createdAt.Add(60 * 24 * time.Hour)}
+			Expect(token.Expiry()).To(Equal(createdAt.Add(30 * 24 * time.Hour)))
+		})
+
+		It("counts the TTL from the last extension", func() {
+			extendedAt := createdAt.Add(10 * 24 * time.Hour)
+			token := model.RegistrationAccessToken{CreatedAt: createdAt, TTLFrom: extendedAt}
+			Expect(token.Expiry()).To(Equal(extendedAt.Add(30 * 24 * time.Hour)))
+		})
+	})
+
+	Describe("IsUsable", func() {
+		var now time.Time
+
+		BeforeEach(func() {
+			now = time.Now()
+		})
+
+		It("is usable without an expiry", func() {
+			token := model.RegistrationAccessToken{CreatedAt: now}
+			Expect(token.IsUsable(now)).To(BeTrue())
+		})
+
+		It("is usable before the expiry", func() {
+			token := model.RegistrationAccessToken{CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+			Expect(token.IsUsable(now)).To(BeTrue())
+		})
+
+		It("isn't usable after the expiry", func() {
+			token := model.RegistrationAccessToken{CreatedAt: now, ExpiresAt: now.Add(-time.Hour)}
+			Expect(token.IsUsable(now)).To(BeFalse())
+		})
+
+		It("isn't usable after the TTL", func() {
+			token := model.RegistrationAccessToken{CreatedAt: now.Add(-31 * 24 * time.Hour)}
+			Expect(token.IsUsable(now)).To(BeFalse())
+		})
+
+		It("isn't usable once claimed", func() {
+			token := model.RegistrationAccessToken{CreatedAt: now, ClaimedBy: bson.NewObjectId()}
+			Expect(token.IsUsable(now)).To(BeFalse())
+		})
+	})
+
 	Describe("Token", func() {
 		Describe("NewToken", func() {
 			It("doesn't return duplicate items", func() {
@@ -35,5 +101,19 @@ var _ = Describe("RegistrationAccessToken", func() {
 				Expect(t.String()).To(Equal("EF4120DA-0302-BCEE-712B-1C258D2FB6D4"))
 			})
 		})
+
+		Describe("JSON", func() {
+			It("uses the string form of the token", func() {
+				t := model.Token{0xef, 0x41, 0x20, 0xda, 0x3, 0x2, 0xbc, 0xee, 0x71, 0x2b, 0x1c, 0x25, 0x8d,
+					0x2f, 0xb6, 0xd4}
+				b, err := json.Marshal(t)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(b)).To(Equal(`"EF4120DA-0302-BCEE-712B-1C258D2FB6D4"`))
+				var unmarshalled model.Token
+				err = json.Unmarshal(b, &unmarshalled)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(unmarshalled).To(Equal(t))
+			})
+		})
 	})
 })
@@ -18,8 +18,79 @@ type (
 		Email          string        `json:"email,omitempty"            bson:"email,omitempty"`
 		Website        string        `json:"website,omitempty"          bson:"website,omitempty"`
 		FacebookPageID string        `json:"facebook_page_id,omitempty" bson:"facebook_page_id,omitempty"`
+		Deactivated    bool          `json:"deactivated,omitempty"      bson:"deactivated,omitempty"`
 
 		DefaultGroupPostMessageTemplate string `json:"default_group_post_message_template" bson:"default_group_post_message_template"`
+		// CollageSettings configure the photo collages of the restaurant's Facebook posts. The
+		// defaults are used if not set.
+		CollageSettings *CollageSettings `json:"collage_settings,omitempty" bson:"collage_settings,omitempty"`
+	}
+
+	// CollageSettings configure how the photo collages of the offers' images that get posted on
+	// Facebook look. The zero values stand for the defaults.
+	CollageSettings struct {
+		// Layout is the name of the layout the images are arranged in, e.g. "top_heavy"
+		Layout string `json:"layout,omitempty" bson:"layout,omitempty"`
+		// MaxImages is the maximum number of offers' images included in the collage
+		MaxImages int `json:"max_images,omitempty" bson:"max_images,omitempty"`
+		// Width and Height are the dimensions of the collages, in pixels. Single images are
+		// resized to them as well, or posted in their original size if they're not set.
+		Width  int `json:"width,omitempty"  bson:"width,omitempty"`
+		Height int `json:"height,omitempty" bson:"height,omitempty"`
+		// BorderColor is the color of the borders between the images as a hex triplet, e.g. "#ffffff"
+		BorderColor string `json:"border_color,omitempty" bson:"border_color,omitempty"`
+		// BorderWidth is the width of the borders between the images in pixels. A pointer,
+		// because 0 (no borders) differs from the default.
+		BorderWidth *int `json:"border_width,omitempty" bson:"border_width,omitempty"`
+		// LogoChecksum refers to the stored image that gets overlaid on the bottom right
+		// corner of the collage
+		LogoChecksum string `json:"logo_checksum,omitempty" bson:"logo_checksum,omitempty"`
+		// ShowDate overlays the date of the offers on the top left corner of the collage
+		ShowDate bool `json:"show_date" bson:"show_date"`
+	}
+
+	// CollageSettingsPOST is the view of the collage settings that the clients send. A new
+	// logo can be uploaded as a data URL.
+	CollageSettingsPOST struct {
+		CollageSettings
+		LogoImageData string `json:"logo_image_data,omitempty"`
+	}
+
+	// RestaurantPOST is the view of a restaurant that the clients send when registering or
+	// updating a restaurant
+	RestaurantPOST struct {
+		Restaurant
+		// ConfirmedLocation can be used to bypass the geocoding of the restaurant's address,
+		// e.g. to pick one of the candidates in a LocationConfirmationRequest
+		ConfirmedLocation *geo.Location `json:"confirmed_location,omitempty"`
+	}
+
+	// LocationConfirmationRequest gets sent back to the client if the restaurant's address
+	// couldn't be unambiguously geocoded. The client should either pick one of the candidates
+	// or specify the location manually and resubmit the restaurant with a ConfirmedLocation.
+	LocationConfirmationRequest struct {
+		Reason     string          `json:"reason"`
+		Address    string          `json:"address"`
+		Candidates []geo.Candidate `json:"candidates"`
+	}
+
+	// PublicRestaurant is the view of a restaurant that gets sent to diners. It leaves
+	// out the fields that are only relevant to the restaurant's administrators.
+	PublicRestaurant struct {
+		ID       bson.ObjectId `json:"_id"`
+		Name     string        `json:"name"`
+		Region   string        `json:"region"`
+		Address  string        `json:"address"`
+		Location Location      `json:"location"`
+		Phone    string        `json:"phone,omitempty"`
+		Website  string        `json:"website,omitempty"`
+	}
+
+	// PublicRestaurantWithOffers wraps a PublicRestaurant and adds the restaurant's
+	// upcoming offers.
+	PublicRestaurantWithOffers struct {
+		PublicRestaurant
+		Offers []*OfferJSON `json:"offers"`
 	}
 
 	// Location is a (limited) representation of a GeoJSON object
@@ -37,3 +108,16 @@ func NewPoint(loc geo.Location) Location {
 		Coordinates: []float64{loc.Lng, loc.Lat},
 	}
 }
+
+// MapRestaurantToPublic creates the public view of the restaurant
+func MapRestaurantToPublic(restaurant *Restaurant) *PublicRestaurant {
+	return &PublicRestaurant{
+		ID:       restaurant.ID,
+		Name:     restaurant.Name,
+		Region:   restaurant.Region,
+		Address:  restaurant.Address,
+		Location: restaurant.Location,
+		Phone:    restaurant.Phone,
+		Website:  restaurant.Website,
+	}
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// SessionCollectionName is the collection name used in the DB for sessions
+const SessionCollectionName = "sessions"
+
+// Session is a logged in session of a user on a single device. The token is
+// stored in a cookie on the device and must never be exposed otherwise.
+type Session struct {
+	ID         bson.ObjectId `json:"_id"          bson:"_id,omitempty"`
+	Token      string        `json:"-"            bson:"token"`
+	UserID     bson.ObjectId `json:"user_id"      bson:"user_id"`
+	CreatedAt  time.Time     `json:"created_at"   bson:"created_at"`
+	LastSeenAt time.Time     `json:"last_seen_at" bson:"last_seen_at"`
+	UserAgent  string        `json:"user_agent"   bson:"user_agent"`
+	ExpiresAt  time.Time     `json:"expires_at"   bson:"expires_at"`
+	// ImpersonatorID is the ID of the administrator who is using the session to
+	// act as the user for support purposes
+	ImpersonatorID bson.ObjectId `json:"impersonator_id,omitempty" bson:"impersonator_id,omitempty"`
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"golang.org/x/oauth2"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -9,20 +11,40 @@ import (
 const UserCollectionName = "users"
 
 type (
-	// User provides the mapping to the users as represented in the DB
+	// User provides the mapping to the users as represented in the DB. A user
+	// logs in either through Facebook or with an email and a password. Admins
+	// have access to the platform administration API.
 	User struct {
 		ID             bson.ObjectId   `bson:"_id,omitempty"`
 		RestaurantIDs  []bson.ObjectId `bson:"restaurant_ids,omitempty"`
-		FacebookUserID string          `bson:"facebook_user_id"`
+		FacebookUserID string          `bson:"facebook_user_id,omitempty"`
+		Email          string          `bson:"email,omitempty"`
+		PasswordHash   []byte          `bson:"password_hash,omitempty"`
+		EmailVerified  bool            `bson:"email_verified,omitempty"`
+		IsAdmin        bool            `bson:"is_admin,omitempty"`
 		Session        UserSession     `bson:"session,omitempty"`
 	}
-	// UserSession holds data about the current user session. Some of this data
-	// (facebook auth tokens, for example) may persist throughout multiple client
-	// sessions, however.
+	// UserSession holds the Facebook auth tokens of the user. The tokens persist
+	// throughout multiple client sessions, which are stored separately as Sessions.
 	UserSession struct {
-		ID                 string              `bson:"id,omitempty"`
 		FacebookUserToken  oauth2.Token        `bson:"facebook_user_token,omitempty"`
 		FacebookPageTokens []FacebookPageToken `bson:"facebook_page_tokens,omitempty"`
+		// FacebookTokenHealth is the result of the last periodic check of the tokens. It
+		// gets reset when the user logs in through Facebook again.
+		FacebookTokenHealth FacebookTokenHealth `bson:"facebook_token_health,omitempty"`
+	}
+
+	// FacebookTokenHealth describes whether the user's Facebook tokens can still be used
+	// to publish posts on the restaurants' pages
+	FacebookTokenHealth struct {
+		CheckedAt time.Time `bson:"checked_at,omitempty"`
+		// ExpiresAt is when the user token expires. It is zero for tokens that never expire.
+		ExpiresAt time.Time `bson:"expires_at,omitempty"`
+		// UserTokenInvalid is set if the user token is invalid or about to expire
+		UserTokenInvalid bool `bson:"user_token_invalid,omitempty"`
+		// InvalidPageIDs lists the pages whose access tokens have been invalidated, e.g.
+		// because the user is no longer an admin of the page
+		InvalidPageIDs []string `bson:"invalid_page_ids,omitempty"`
 	}
 
 	FacebookPageToken struct {
@@ -30,3 +52,23 @@ type (
 		Token  string `bson:"token"`
 	}
 )
+
+// NeedsReauth returns true if the user has to log in through Facebook again to renew
+// the tokens
+func (h FacebookTokenHealth) NeedsReauth() bool {
+	return h.UserTokenInvalid || len(h.InvalidPageIDs) > 0
+}
+
+// PageNeedsReauth returns true if the user has to log in through Facebook again to be
+// able to post on the page
+func (h FacebookTokenHealth) PageNeedsReauth(pageID string) bool {
+	if h.UserTokenInvalid {
+		return true
+	}
+	for _, invalidPageID := range h.InvalidPageIDs {
+		if invalidPageID == pageID {
+			return true
+		}
+	}
+	return false
+}
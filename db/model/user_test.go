@@ -0,0 +1,31 @@
+package model_test
+
+import (
+	"github.com/Lunchr/luncher-api/db/model"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("User", func() {
+	Describe("FacebookTokenHealth", func() {
+		It("doesn't need reauthorization by default", func() {
+			health := model.FacebookTokenHealth{}
+			Expect(health.NeedsReauth()).To(BeFalse())
+			Expect(health.PageNeedsReauth("page")).To(BeFalse())
+		})
+
+		It("needs reauthorization for all pages if the user token is invalid", func() {
+			health := model.FacebookTokenHealth{UserTokenInvalid: true}
+			Expect(health.NeedsReauth()).To(BeTrue())
+			Expect(health.PageNeedsReauth("page")).To(BeTrue())
+		})
+
+		It("needs reauthorization only for the pages with invalid tokens", func() {
+			health := model.FacebookTokenHealth{InvalidPageIDs: []string{"page1"}}
+			Expect(health.NeedsReauth()).To(BeTrue())
+			Expect(health.PageNeedsReauth("page1")).To(BeTrue())
+			Expect(health.PageNeedsReauth("page2")).To(BeFalse())
+		})
+	})
+})
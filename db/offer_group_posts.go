@@ -11,6 +11,8 @@ type OfferGroupPosts interface {
 	UpdateByID(bson.ObjectId, *model.OfferGroupPost) error
 	GetByID(bson.ObjectId) (*model.OfferGroupPost, error)
 	GetByDate(model.DateWithoutTime, bson.ObjectId) (*model.OfferGroupPost, error)
+	GetByRestaurantID(bson.ObjectId) ([]*model.OfferGroupPost, error)
+	RemoveByRestaurantID(bson.ObjectId) error
 }
 
 type offerGroupPostCollection struct {
@@ -53,3 +55,18 @@ func (c offerGroupPostCollection) GetByDate(date model.DateWithoutTime, restaura
 	}).One(&post)
 	return &post, err
 }
+
+func (c offerGroupPostCollection) GetByRestaurantID(restaurantID bson.ObjectId) ([]*model.OfferGroupPost, error) {
+	var posts []*model.OfferGroupPost
+	err := c.Find(bson.M{
+		"restaurant_id": restaurantID,
+	}).All(&posts)
+	return posts, err
+}
+
+func (c offerGroupPostCollection) RemoveByRestaurantID(restaurantID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"restaurant_id": restaurantID,
+	})
+	return err
+}
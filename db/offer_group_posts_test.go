@@ -94,4 +94,35 @@ var _ = Describe("OfferGroupPosts", func() {
 			})
 		})
 	})
+
+	Describe("by restaurant ID", func() {
+		var restaurantID = bson.NewObjectId()
+		RebuildDBAfterEach()
+
+		BeforeEach(func() {
+			post := aPost()
+			post.RestaurantID = restaurantID
+			_, err := offerGroupPostsCollection.Insert(post, aPost())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Describe("GetByRestaurantID", func() {
+			It("returns the restaurant's posts", func() {
+				result, err := offerGroupPostsCollection.GetByRestaurantID(restaurantID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(HaveLen(1))
+				Expect(result[0].RestaurantID).To(Equal(restaurantID))
+			})
+		})
+
+		Describe("RemoveByRestaurantID", func() {
+			It("removes the restaurant's posts", func() {
+				err := offerGroupPostsCollection.RemoveByRestaurantID(restaurantID)
+				Expect(err).NotTo(HaveOccurred())
+				result, err := offerGroupPostsCollection.GetByRestaurantID(restaurantID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(BeEmpty())
+			})
+		})
+	})
 })
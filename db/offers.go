@@ -21,6 +21,8 @@ type Offers interface {
 	UpdateID(bson.ObjectId, *model.Offer) error
 	GetID(bson.ObjectId) (*model.Offer, error)
 	RemoveID(bson.ObjectId) error
+	UpdateRestaurant(model.OfferRestaurant) error
+	RemoveForRestaurant(restaurantID bson.ObjectId) error
 }
 
 type offersCollection struct {
@@ -70,6 +72,9 @@ func (c offersCollection) GetForRegion(region string, startTime, endTime time.Ti
 			"$gte": startTime,
 		},
 		"restaurant.region": region,
+		"restaurant.deactivated": bson.M{
+			"$ne": true,
+		},
 	}).All(&offers)
 	return offers, err
 }
@@ -129,3 +134,23 @@ func (c offersCollection) GetID(id bson.ObjectId) (*model.Offer, error) {
 func (c offersCollection) RemoveID(id bson.ObjectId) error {
 	return c.RemoveId(id)
 }
+
+// UpdateRestaurant replaces the restaurant information included in all of the
+// offers of the specified restaurant
+func (c offersCollection) UpdateRestaurant(restaurant model.OfferRestaurant) error {
+	_, err := c.UpdateAll(bson.M{
+		"restaurant.id": restaurant.ID,
+	}, bson.M{
+		"$set": bson.M{
+			"restaurant": restaurant,
+		},
+	})
+	return err
+}
+
+func (c offersCollection) RemoveForRestaurant(restaurantID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"restaurant.id": restaurantID,
+	})
+	return err
+}
@@ -18,6 +18,9 @@ func (c offersCollection) GetNear(loc geo.Location, startTime, endTime time.Time
 			"to_time": bson.M{
 				"$gte": startTime,
 			},
+			"restaurant.deactivated": bson.M{
+				"$ne": true,
+			},
 		},
 		"maxDistance": 5000,
 		"spherical":   true,
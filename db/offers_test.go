@@ -120,6 +120,51 @@ var _ = Describe("Offers", func() {
 		})
 	})
 
+	Describe("UpdateRestaurant", func() {
+		RebuildDBAfterEach()
+		It("should update the restaurant in all the offers of that restaurant", func(done Done) {
+			defer close(done)
+			restaurant := mocks.offers[0].Restaurant
+			restaurant.Name = "Asian Chef Deluxe"
+			restaurant.Address = "Võru 26, Tartu"
+			err := offersCollection.UpdateRestaurant(restaurant)
+			Expect(err).NotTo(HaveOccurred())
+			offers, err := offersCollection.GetForRestaurant(mocks.restaurantID, earliestTime)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(offers).NotTo(BeEmpty())
+			for _, offer := range offers {
+				Expect(offer.Restaurant.Name).To(Equal("Asian Chef Deluxe"))
+				Expect(offer.Restaurant.Address).To(Equal("Võru 26, Tartu"))
+			}
+		})
+
+		It("should leave the offers of other restaurants untouched", func(done Done) {
+			defer close(done)
+			restaurant := mocks.offers[0].Restaurant
+			restaurant.Name = "Asian Chef Deluxe"
+			err := offersCollection.UpdateRestaurant(restaurant)
+			Expect(err).NotTo(HaveOccurred())
+			offers, err := offersCollection.GetForRegion("Tallinn", earliestTime, latestTime)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(offers).To(HaveLen(1))
+			Expect(offers[0].Restaurant.Name).To(Equal("Bulgarian Dude"))
+		})
+	})
+
+	Describe("RemoveForRestaurant", func() {
+		RebuildDBAfterEach()
+		It("should remove only the offers of the specified restaurant", func() {
+			err := offersCollection.RemoveForRestaurant(mocks.restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			offers, err := offersCollection.GetForRestaurant(mocks.restaurantID, earliestTime)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(offers).To(BeEmpty())
+			offers, err = offersCollection.GetForRegion("Tallinn", earliestTime, latestTime)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(offers).To(HaveLen(1))
+		})
+	})
+
 	var ItHandlesStartAndEndTime = func(getOffers func(startTime, endTime time.Time) ([]*model.Offer, error)) {
 		var (
 			startTime             time.Time
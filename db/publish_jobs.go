@@ -0,0 +1,222 @@
+package db
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type PublishJobs interface {
+	// Enqueue queues the restaurant's post for the date to be published at runAt on behalf of
+	// the user. The job for the date is reset and postponed to runAt if it already exists, so
+	// that a series of changes would result in a single update. A pending job is never postponed
+	// past the deadline it was first queued with, so that a steady stream of changes couldn't
+	// keep the post from being published.
+	Enqueue(restaurantID bson.ObjectId, date model.DateWithoutTime, userID bson.ObjectId, runAt, deadline time.Time) error
+	// Claim locks the due pending job that has waited the longest for the duration of
+	// the lease and counts it as an attempt. Returns mgo.ErrNotFound if no jobs are due.
+	Claim(lease time.Duration) (*model.PublishJob, error)
+	// Complete marks the claimed job as done
+	Complete(*model.PublishJob) error
+	// Retry schedules the claimed job to be attempted again at runAt
+	Retry(job *model.PublishJob, runAt time.Time, lastError string) error
+	// Fail marks the claimed job as failed, so that it won't be attempted again
+	Fail(job *model.PublishJob, lastError string) error
+	// GetForRestaurant returns the restaurant's jobs, latest date first, up to the limit
+	GetForRestaurant(restaurantID bson.ObjectId, limit int) ([]*model.PublishJob, error)
+	// GetByDate returns the restaurant's job for the date
+	GetByDate(date model.DateWithoutTime, restaurantID bson.ObjectId) (*model.PublishJob, error)
+	// UnsetUser removes the user from the jobs they've queued. The jobs are then published with
+	// the tokens of the other users who can post on the restaurants' pages.
+	UnsetUser(userID bson.ObjectId) error
+	// RemoveForRestaurant removes all of the restaurant's jobs
+	RemoveForRestaurant(restaurantID bson.ObjectId) error
+}
+
+type publishJobsCollection struct {
+	*mgo.Collection
+}
+
+func NewPublishJobs(client *Client) (PublishJobs, error) {
+	collection := client.database.C(model.PublishJobCollectionName)
+	publishJobs := &publishJobsCollection{collection}
+	if err := publishJobs.ensureRestaurantDateIndex(); err != nil {
+		return nil, err
+	}
+	if err := publishJobs.ensureStatusRunAtIndex(); err != nil {
+		return nil, err
+	}
+	return publishJobs, nil
+}
+
+func (c publishJobsCollection) Enqueue(restaurantID bson.ObjectId, date model.DateWithoutTime, userID bson.ObjectId,
+	runAt, deadline time.Time) error {
+	var job model.PublishJob
+	_, err := c.Find(bson.M{
+		"restaurant_id": restaurantID,
+		"date":          date,
+		"status":        model.PublishJobPending,
+	}).Apply(mgo.Change{
+		Update: bson.M{
+			"$set": bson.M{
+				"user_id":    userID,
+				"attempts":   0,
+				"run_at":     runAt,
+				"updated_at": time.Now(),
+			},
+			"$unset": bson.M{"last_error": ""},
+			"$inc":   bson.M{"version": 1},
+		},
+		ReturnNew: true,
+	}, &job)
+	if err == mgo.ErrNotFound {
+		if deadline.Before(runAt) {
+			runAt = deadline
+		}
+		_, err = c.Upsert(bson.M{
+			"restaurant_id": restaurantID,
+			"date":          date,
+		}, bson.M{
+			"$set": bson.M{
+				"user_id":    userID,
+				"status":     model.PublishJobPending,
+				"attempts":   0,
+				"run_at":     runAt,
+				"deadline":   deadline,
+				"updated_at": time.Now(),
+			},
+			"$unset": bson.M{"last_error": ""},
+			"$inc":   bson.M{"version": 1},
+		})
+		return err
+	} else if err != nil {
+		return err
+	}
+	if job.Deadline.IsZero() || !job.Deadline.Before(runAt) {
+		return nil
+	}
+	return c.UpdateId(job.ID, bson.M{
+		"$min": bson.M{"run_at": job.Deadline},
+	})
+}
+
+func (c publishJobsCollection) Claim(lease time.Duration) (*model.PublishJob, error) {
+	now := time.Now()
+	var job model.PublishJob
+	_, err := c.Find(bson.M{
+		"status": model.PublishJobPending,
+		"run_at": bson.M{"$lte": now},
+		"$or": []bson.M{
+			{"locked_until": bson.M{"$exists": false}},
+			{"locked_until": bson.M{"$lt": now}},
+		},
+	}).Sort("run_at").Apply(mgo.Change{
+		Update: bson.M{
+			"$set": bson.M{"locked_until": now.Add(lease)},
+			"$inc": bson.M{"attempts": 1},
+		},
+		ReturnNew: true,
+	}, &job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (c publishJobsCollection) Complete(job *model.PublishJob) error {
+	return c.finish(job, bson.M{
+		"$set": bson.M{
+			"status":     model.PublishJobDone,
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{
+			"locked_until": "",
+			"last_error":   "",
+		},
+	})
+}
+
+func (c publishJobsCollection) Retry(job *model.PublishJob, runAt time.Time, lastError string) error {
+	return c.finish(job, bson.M{
+		"$set": bson.M{
+			"run_at":     runAt,
+			"last_error": lastError,
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{"locked_until": ""},
+	})
+}
+
+func (c publishJobsCollection) Fail(job *model.PublishJob, lastError string) error {
+	return c.finish(job, bson.M{
+		"$set": bson.M{
+			"status":     model.PublishJobFailed,
+			"last_error": lastError,
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{"locked_until": ""},
+	})
+}
+
+// finish applies the update to the claimed job. If the job has been queued again while it
+// was being processed, only the lock is released, so that the job would be processed again.
+func (c publishJobsCollection) finish(job *model.PublishJob, update bson.M) error {
+	err := c.Update(bson.M{
+		"_id":     job.ID,
+		"version": job.Version,
+	}, update)
+	if err == mgo.ErrNotFound {
+		return c.UpdateId(job.ID, bson.M{
+			"$unset": bson.M{"locked_until": ""},
+		})
+	}
+	return err
+}
+
+func (c publishJobsCollection) GetForRestaurant(restaurantID bson.ObjectId, limit int) ([]*model.PublishJob, error) {
+	var jobs []*model.PublishJob
+	err := c.Find(bson.M{
+		"restaurant_id": restaurantID,
+	}).Sort("-date").Limit(limit).All(&jobs)
+	return jobs, err
+}
+
+func (c publishJobsCollection) GetByDate(date model.DateWithoutTime, restaurantID bson.ObjectId) (*model.PublishJob, error) {
+	var job model.PublishJob
+	err := c.Find(bson.M{
+		"restaurant_id": restaurantID,
+		"date":          date,
+	}).One(&job)
+	return &job, err
+}
+
+func (c publishJobsCollection) UnsetUser(userID bson.ObjectId) error {
+	_, err := c.UpdateAll(bson.M{
+		"user_id": userID,
+	}, bson.M{
+		"$unset": bson.M{"user_id": ""},
+	})
+	return err
+}
+
+func (c publishJobsCollection) RemoveForRestaurant(restaurantID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"restaurant_id": restaurantID,
+	})
+	return err
+}
+
+func (c publishJobsCollection) ensureRestaurantDateIndex() error {
+	return c.EnsureIndex(mgo.Index{
+		Key:    []string{"restaurant_id", "date"},
+		Unique: true,
+	})
+}
+
+func (c publishJobsCollection) ensureStatusRunAtIndex() error {
+	return c.EnsureIndex(mgo.Index{
+		Key: []string{"status", "run_at"},
+	})
+}
@@ -0,0 +1,189 @@
+package db_test
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PublishJobs", func() {
+	RebuildDBAfterEach()
+	var (
+		restaurantID bson.ObjectId
+		userID       bson.ObjectId
+		date         model.DateWithoutTime
+	)
+
+	BeforeEach(func() {
+		restaurantID = bson.NewObjectId()
+		userID = bson.NewObjectId()
+		date = model.DateWithoutTime("2016-03-04")
+		err := publishJobsCollection.Enqueue(restaurantID, date, userID, time.Now(), time.Now())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("Enqueue", func() {
+		It("keeps a single job for the restaurant and date", func() {
+			err := publishJobsCollection.Enqueue(restaurantID, date, userID, time.Now(), time.Now())
+			Expect(err).NotTo(HaveOccurred())
+			jobs, err := publishJobsCollection.GetForRestaurant(restaurantID, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobs).To(HaveLen(1))
+			Expect(jobs[0].Status).To(Equal(model.PublishJobPending))
+			Expect(jobs[0].UserID).To(Equal(userID))
+		})
+
+		It("doesn't postpone a pending job past its deadline", func() {
+			err := publishJobsCollection.Enqueue(restaurantID, date, userID, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = publishJobsCollection.Claim(time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("with the job done", func() {
+			BeforeEach(func() {
+				job, err := publishJobsCollection.Claim(time.Minute)
+				Expect(err).NotTo(HaveOccurred())
+				err = publishJobsCollection.Complete(job)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("postpones the job until runAt with a new deadline", func() {
+				err := publishJobsCollection.Enqueue(restaurantID, date, userID, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))
+				Expect(err).NotTo(HaveOccurred())
+				_, err = publishJobsCollection.Claim(time.Minute)
+				Expect(err).To(Equal(mgo.ErrNotFound))
+				job, err := publishJobsCollection.GetByDate(date, restaurantID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(job.Status).To(Equal(model.PublishJobPending))
+				Expect(job.Deadline.Sub(time.Now())).To(BeNumerically("~", 2*time.Hour, time.Minute))
+			})
+		})
+	})
+
+	Describe("Claim", func() {
+		It("locks the job and counts the attempt", func() {
+			job, err := publishJobsCollection.Claim(time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.RestaurantID).To(Equal(restaurantID))
+			Expect(job.Date).To(Equal(date))
+			Expect(job.Attempts).To(Equal(1))
+			_, err = publishJobsCollection.Claim(time.Minute)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+
+		It("claims the job again after the lease has expired", func() {
+			_, err := publishJobsCollection.Claim(-time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			job, err := publishJobsCollection.Claim(time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Attempts).To(Equal(2))
+		})
+	})
+
+	Describe("Complete", func() {
+		var job *model.PublishJob
+
+		BeforeEach(func() {
+			var err error
+			job, err = publishJobsCollection.Claim(time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("marks the job as done", func() {
+			err := publishJobsCollection.Complete(job)
+			Expect(err).NotTo(HaveOccurred())
+			jobs, err := publishJobsCollection.GetForRestaurant(restaurantID, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobs[0].Status).To(Equal(model.PublishJobDone))
+		})
+
+		Context("with the job queued again while it was being processed", func() {
+			BeforeEach(func() {
+				err := publishJobsCollection.Enqueue(restaurantID, date, userID, time.Now(), time.Now())
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("leaves the job pending and releases the lock", func() {
+				err := publishJobsCollection.Complete(job)
+				Expect(err).NotTo(HaveOccurred())
+				claimedJob, err := publishJobsCollection.Claim(time.Minute)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimedJob.ID).To(Equal(job.ID))
+				Expect(claimedJob.Attempts).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("Retry", func() {
+		It("schedules the job to be attempted later", func() {
+			job, err := publishJobsCollection.Claim(time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			err = publishJobsCollection.Retry(job, time.Now().Add(time.Hour), "Failed to post the offers to Facebook")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = publishJobsCollection.Claim(time.Minute)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+			jobs, err := publishJobsCollection.GetForRestaurant(restaurantID, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobs[0].Status).To(Equal(model.PublishJobPending))
+			Expect(jobs[0].LastError).To(Equal("Failed to post the offers to Facebook"))
+		})
+	})
+
+	Describe("Fail", func() {
+		It("marks the job as failed until it's queued again", func() {
+			job, err := publishJobsCollection.Claim(time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			err = publishJobsCollection.Fail(job, "Failed to post the offers to Facebook")
+			Expect(err).NotTo(HaveOccurred())
+			jobs, err := publishJobsCollection.GetForRestaurant(restaurantID, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobs[0].Status).To(Equal(model.PublishJobFailed))
+			err = publishJobsCollection.Enqueue(restaurantID, date, userID, time.Now(), time.Now())
+			Expect(err).NotTo(HaveOccurred())
+			job, err = publishJobsCollection.Claim(time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Attempts).To(Equal(1))
+			Expect(job.LastError).To(BeEmpty())
+		})
+	})
+
+	Describe("UnsetUser", func() {
+		It("removes the user from the jobs", func() {
+			err := publishJobsCollection.UnsetUser(userID)
+			Expect(err).NotTo(HaveOccurred())
+			job, err := publishJobsCollection.GetByDate(date, restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.UserID).To(BeEmpty())
+		})
+	})
+
+	Describe("RemoveForRestaurant", func() {
+		It("removes the restaurant's jobs", func() {
+			err := publishJobsCollection.RemoveForRestaurant(restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = publishJobsCollection.GetByDate(date, restaurantID)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+	})
+
+	Describe("GetByDate", func() {
+		It("returns the restaurant's job for the date", func() {
+			job, err := publishJobsCollection.GetByDate(date, restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.RestaurantID).To(Equal(restaurantID))
+			Expect(job.Date).To(Equal(date))
+			Expect(job.Status).To(Equal(model.PublishJobPending))
+		})
+
+		It("returns ErrNotFound for other dates", func() {
+			_, err := publishJobsCollection.GetByDate(model.DateWithoutTime("2016-03-05"), restaurantID)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+	})
+})
@@ -10,7 +10,28 @@ import (
 
 type RegistrationAccessTokens interface {
 	Insert(*model.RegistrationAccessToken) (*model.RegistrationAccessToken, error)
-	Exists(model.Token) (bool, error)
+	Get(model.Token) (*model.RegistrationAccessToken, error)
+	// Claim atomically marks the token as used by the user. Returns mgo.ErrNotFound if the
+	// token doesn't exist, has already been claimed or has expired.
+	Claim(token model.Token, userID bson.ObjectId) error
+	// Release undoes the claim of the token by the user, restoring the TTL the token had
+	Release(token model.Token, userID bson.ObjectId) error
+	GetAll() RegistrationAccessTokenIter
+	// Remove revokes the token. Returns mgo.ErrNotFound if the token doesn't exist or
+	// has already been claimed.
+	Remove(model.Token) error
+	// RemoveClaimedBy removes the tokens the user has claimed
+	RemoveClaimedBy(userID bson.ObjectId) error
+	// Extend restarts the TTL of the token and replaces its ExpiresAt. CreatedAt is left as it is. A zero expiresAt
+	// makes the token valid for the full TTL. Returns mgo.ErrNotFound if the token doesn't
+	// exist or has already been claimed.
+	Extend(token model.Token, expiresAt time.Time) error
+}
+
+// RegistrationAccessTokenIter is a wrapper around *mgo.Iter that allows type safe iteration
+type RegistrationAccessTokenIter interface {
+	Close() error
+	Next(*model.RegistrationAccessToken) bool
 }
 
 type registrationAccessTokensCollection struct {
@@ -34,16 +55,107 @@ func (c registrationAccessTokensCollection) Insert(t *model.RegistrationAccessTo
 	return t, c.Collection.Insert(t)
 }
 
-func (c registrationAccessTokensCollection) Exists(token model.Token) (bool, error) {
-	count, err := c.Find(bson.M{
+func (c registrationAccessTokensCollection) Get(token model.Token) (*model.RegistrationAccessToken, error) {
+	var registrationAccessToken model.RegistrationAccessToken
+	err := c.Find(bson.M{
 		"token": token,
-	}).Count()
-	return count > 0, err
+	}).One(&registrationAccessToken)
+	return &registrationAccessToken, err
+}
+
+func (c registrationAccessTokensCollection) Claim(token model.Token, userID bson.ObjectId) error {
+	now := time.Now()
+	return c.Update(bson.M{
+		"token":      token,
+		"claimed_by": bson.M{"$exists": false},
+		"$and": []bson.M{
+			// The TTL monitor only removes the expired tokens once a minute
+			{"$or": []bson.M{
+				{"ttl_from": bson.M{"$gt": now.Add(-model.RegistrationAccessTokenTTL)}},
+				// The tokens created before ttl_from was introduced
+				{
+					"ttl_from":   bson.M{"$exists": false},
+					"created_at": bson.M{"$gt": now.Add(-model.RegistrationAccessTokenTTL)},
+				},
+			}},
+			{"$or": []bson.M{
+				{"expires_at": bson.M{"$exists": false}},
+				{"expires_at": bson.M{"$gt": now}},
+			}},
+		},
+	}, bson.M{
+		"$set": bson.M{
+			"claimed_by": userID,
+			"claimed_at": now,
+		},
+		// Moving the field out of the TTL index's reach keeps the claimed token in the DB, while
+		// still allowing Release to restore it
+		"$rename": bson.M{"ttl_from": "claimed_ttl_from"},
+	})
+}
+
+func (c registrationAccessTokensCollection) Release(token model.Token, userID bson.ObjectId) error {
+	return c.Update(bson.M{
+		"token":      token,
+		"claimed_by": userID,
+	}, bson.M{
+		"$unset": bson.M{
+			"claimed_by": "",
+			"claimed_at": "",
+		},
+		"$rename": bson.M{"claimed_ttl_from": "ttl_from"},
+	})
+}
+
+func (c registrationAccessTokensCollection) GetAll() RegistrationAccessTokenIter {
+	i := c.Find(nil).Sort("-created_at").Iter()
+	return &registrationAccessTokenIter{i}
+}
+
+func (c registrationAccessTokensCollection) Remove(token model.Token) error {
+	return c.Collection.Remove(bson.M{
+		"token":      token,
+		"claimed_by": bson.M{"$exists": false},
+	})
+}
+
+func (c registrationAccessTokensCollection) Extend(token model.Token, expiresAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{"ttl_from": time.Now()},
+	}
+	if expiresAt.IsZero() {
+		update["$unset"] = bson.M{"expires_at": ""}
+	} else {
+		update["$set"].(bson.M)["expires_at"] = expiresAt
+	}
+	return c.Update(bson.M{
+		"token":      token,
+		"claimed_by": bson.M{"$exists": false},
+	}, update)
+}
+
+func (c registrationAccessTokensCollection) RemoveClaimedBy(userID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"claimed_by": userID,
+	})
+	return err
 }
 
 func (c registrationAccessTokensCollection) ensureTTLIndex() error {
+	// The TTL used to count from created_at, which removed the claimed tokens as well
+	if err := c.DropIndex("created_at"); err != nil && !isIndexNotFound(err) {
+		return err
+	}
 	return c.EnsureIndex(mgo.Index{
-		Key:         []string{"created_at"},
-		ExpireAfter: time.Hour * 24 * 30, // 30 days
+		Key:         []string{"ttl_from"},
+		ExpireAfter: model.RegistrationAccessTokenTTL,
 	})
 }
+
+type registrationAccessTokenIter struct {
+	*mgo.Iter
+}
+
+func (i *registrationAccessTokenIter) Next(token *model.RegistrationAccessToken) bool {
+	return i.Iter.Next(token)
+}
@@ -6,13 +6,16 @@ import (
 	"github.com/Lunchr/luncher-api/db/model"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
 var _ = Describe("RegistrationAccessTokens", func() {
 	var aToken = func() *model.RegistrationAccessToken {
+		now := time.Now()
 		return &model.RegistrationAccessToken{
-			CreatedAt: time.Now(),
+			CreatedAt: now,
+			TTLFrom:   now,
 		}
 	}
 
@@ -34,17 +37,16 @@ var _ = Describe("RegistrationAccessTokens", func() {
 		})
 	})
 
-	Describe("Exists", func() {
+	Describe("Get", func() {
 		RebuildDBAfterEach()
-		It("returns false if no such token in the DB", func() {
+		It("returns mgo.ErrNotFound if no such token in the DB", func() {
 			token, err := model.NewToken()
 			Expect(err).NotTo(HaveOccurred())
-			exists, err := registrationAccessTokensCollection.Exists(token)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(exists).To(BeFalse())
+			_, err = registrationAccessTokensCollection.Get(token)
+			Expect(err).To(Equal(mgo.ErrNotFound))
 		})
 
-		Context("with an known token inserted", func() {
+		Context("with a known token inserted", func() {
 			var token model.Token
 			BeforeEach(func() {
 				var err error
@@ -52,14 +54,221 @@ var _ = Describe("RegistrationAccessTokens", func() {
 				Expect(err).NotTo(HaveOccurred())
 				regToken := aToken()
 				regToken.Token = token
+				regToken.Notes = "For Asian Chef"
 				_, err = registrationAccessTokensCollection.Insert(regToken)
 				Expect(err).NotTo(HaveOccurred())
 			})
 
-			It("returns true for that token", func() {
-				exists, err := registrationAccessTokensCollection.Exists(token)
+			It("returns that token", func() {
+				regToken, err := registrationAccessTokensCollection.Get(token)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(exists).To(BeTrue())
+				Expect(regToken.Token).To(Equal(token))
+				Expect(regToken.Notes).To(Equal("For Asian Chef"))
+			})
+		})
+	})
+
+	Describe("Claim", func() {
+		RebuildDBAfterEach()
+		var (
+			token    model.Token
+			regToken *model.RegistrationAccessToken
+			userID   bson.ObjectId
+		)
+
+		BeforeEach(func() {
+			var err error
+			token, err = model.NewToken()
+			Expect(err).NotTo(HaveOccurred())
+			regToken = aToken()
+			regToken.Token = token
+			userID = bson.NewObjectId()
+		})
+
+		JustBeforeEach(func() {
+			_, err := registrationAccessTokensCollection.Insert(regToken)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("records the user who claimed the token", func() {
+			err := registrationAccessTokensCollection.Claim(token, userID)
+			Expect(err).NotTo(HaveOccurred())
+			claimedToken, err := registrationAccessTokensCollection.Get(token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimedToken.ClaimedBy).To(Equal(userID))
+			Expect(claimedToken.ClaimedAt).NotTo(BeZero())
+		})
+
+		It("keeps the claimed token out of the TTL", func() {
+			err := registrationAccessTokensCollection.Claim(token, userID)
+			Expect(err).NotTo(HaveOccurred())
+			claimedToken, err := registrationAccessTokensCollection.Get(token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimedToken.TTLFrom.IsZero()).To(BeTrue())
+			Expect(claimedToken.CreatedAt).To(BeTemporally("~", regToken.CreatedAt, time.Millisecond))
+		})
+
+		It("restores the TTL when the token is released", func() {
+			err := registrationAccessTokensCollection.Claim(token, userID)
+			Expect(err).NotTo(HaveOccurred())
+			err = registrationAccessTokensCollection.Release(token, userID)
+			Expect(err).NotTo(HaveOccurred())
+			releasedToken, err := registrationAccessTokensCollection.Get(token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releasedToken.TTLFrom).To(BeTemporally("~", regToken.TTLFrom, time.Millisecond))
+		})
+
+		It("doesn't allow the token to be claimed twice", func() {
+			err := registrationAccessTokensCollection.Claim(token, userID)
+			Expect(err).NotTo(HaveOccurred())
+			err = registrationAccessTokensCollection.Claim(token, bson.NewObjectId())
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+
+		It("allows the token to be claimed again after being released", func() {
+			err := registrationAccessTokensCollection.Claim(token, userID)
+			Expect(err).NotTo(HaveOccurred())
+			err = registrationAccessTokensCollection.Release(token, userID)
+			Expect(err).NotTo(HaveOccurred())
+			err = registrationAccessTokensCollection.Claim(token, bson.NewObjectId())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("with the token not expired yet", func() {
+			BeforeEach(func() {
+				regToken.ExpiresAt = time.Now().Add(time.Hour)
+			})
+
+			It("succeeds", func() {
+				err := registrationAccessTokensCollection.Claim(token, userID)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("with the token created before the TTL was moved to ttl_from", func() {
+			BeforeEach(func() {
+				regToken.TTLFrom = time.Time{}
+			})
+
+			It("succeeds", func() {
+				err := registrationAccessTokensCollection.Claim(token, userID)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("with the token past its TTL", func() {
+			BeforeEach(func() {
+				regToken.TTLFrom = time.Now().Add(-model.RegistrationAccessTokenTTL - time.Minute)
+			})
+
+			It("fails with mgo.ErrNotFound", func() {
+				err := registrationAccessTokensCollection.Claim(token, userID)
+				Expect(err).To(Equal(mgo.ErrNotFound))
+			})
+		})
+
+		Context("with the token expired", func() {
+			BeforeEach(func() {
+				regToken.ExpiresAt = time.Now().Add(-time.Hour)
+			})
+
+			It("fails with mgo.ErrNotFound", func() {
+				err := registrationAccessTokensCollection.Claim(token, userID)
+				Expect(err).To(Equal(mgo.ErrNotFound))
+			})
+		})
+	})
+
+	Describe("management", func() {
+		RebuildDBAfterEach()
+		var (
+			unclaimedToken model.Token
+			claimedToken   model.Token
+		)
+
+		BeforeEach(func() {
+			var err error
+			unclaimedToken, err = model.NewToken()
+			Expect(err).NotTo(HaveOccurred())
+			claimedToken, err = model.NewToken()
+			Expect(err).NotTo(HaveOccurred())
+			regToken := aToken()
+			regToken.Token = unclaimedToken
+			regToken.ExpiresAt = time.Now().Add(time.Hour)
+			_, err = registrationAccessTokensCollection.Insert(regToken)
+			Expect(err).NotTo(HaveOccurred())
+			regToken = aToken()
+			regToken.Token = claimedToken
+			regToken.ClaimedBy = bson.NewObjectId()
+			_, err = registrationAccessTokensCollection.Insert(regToken)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Describe("GetAll", func() {
+			It("returns all the tokens", func() {
+				iter := registrationAccessTokensCollection.GetAll()
+				var token model.RegistrationAccessToken
+				count := 0
+				for iter.Next(&token) {
+					count++
+				}
+				Expect(iter.Close()).To(Succeed())
+				Expect(count).To(Equal(2))
+			})
+		})
+
+		Describe("Remove", func() {
+			It("removes an unclaimed token", func() {
+				err := registrationAccessTokensCollection.Remove(unclaimedToken)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = registrationAccessTokensCollection.Get(unclaimedToken)
+				Expect(err).To(Equal(mgo.ErrNotFound))
+			})
+
+			It("doesn't remove a claimed token", func() {
+				err := registrationAccessTokensCollection.Remove(claimedToken)
+				Expect(err).To(Equal(mgo.ErrNotFound))
+			})
+		})
+
+		Describe("RemoveClaimedBy", func() {
+			It("removes the tokens the user has claimed", func() {
+				claimed, err := registrationAccessTokensCollection.Get(claimedToken)
+				Expect(err).NotTo(HaveOccurred())
+				err = registrationAccessTokensCollection.RemoveClaimedBy(claimed.ClaimedBy)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = registrationAccessTokensCollection.Get(claimedToken)
+				Expect(err).To(Equal(mgo.ErrNotFound))
+				_, err = registrationAccessTokensCollection.Get(unclaimedToken)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Describe("Extend", func() {
+			It("restarts the TTL and replaces the expiry", func() {
+				before, err := registrationAccessTokensCollection.Get(unclaimedToken)
+				Expect(err).NotTo(HaveOccurred())
+				expiresAt := time.Now().Add(48 * time.Hour).Truncate(time.Millisecond)
+				err = registrationAccessTokensCollection.Extend(unclaimedToken, expiresAt)
+				Expect(err).NotTo(HaveOccurred())
+				regToken, err := registrationAccessTokensCollection.Get(unclaimedToken)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(regToken.TTLFrom).To(BeTemporally("~", time.Now(), time.Second))
+				Expect(regToken.CreatedAt).To(BeTemporally("==", before.CreatedAt))
+				Expect(regToken.ExpiresAt).To(BeTemporally("==", expiresAt))
+			})
+
+			It("removes the expiry if none is given", func() {
+				err := registrationAccessTokensCollection.Extend(unclaimedToken, time.Time{})
+				Expect(err).NotTo(HaveOccurred())
+				regToken, err := registrationAccessTokensCollection.Get(unclaimedToken)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(regToken.ExpiresAt.IsZero()).To(BeTrue())
+			})
+
+			It("doesn't extend a claimed token", func() {
+				err := registrationAccessTokensCollection.Extend(claimedToken, time.Time{})
+				Expect(err).To(Equal(mgo.ErrNotFound))
 			})
 		})
 	})
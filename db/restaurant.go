@@ -1,6 +1,8 @@
 package db
 
 import (
+	"regexp"
+
 	"github.com/Lunchr/luncher-api/db/model"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -9,11 +11,20 @@ import (
 type Restaurants interface {
 	Insert(...*model.Restaurant) ([]*model.Restaurant, error)
 	GetAll() RestaurantIter
+	// Search finds the restaurants whose name contains the query, case insensitively, or
+	// whose ID or Facebook page ID matches it. An empty query matches all restaurants.
+	Search(query string, limit int) ([]*model.Restaurant, error)
 	GetByIDs([]bson.ObjectId) ([]*model.Restaurant, error)
 	GetByFacebookPageIDs([]string) ([]*model.Restaurant, error)
+	GetByRegion(string) ([]*model.Restaurant, error)
 	GetID(bson.ObjectId) (*model.Restaurant, error)
 	Exists(name string) (bool, error)
 	UpdateID(bson.ObjectId, *model.Restaurant) error
+	// UpdateDetails updates the fields the restaurant's owners can edit. Unlike UpdateID, it
+	// removes the optional contact details that are empty.
+	UpdateDetails(bson.ObjectId, *model.Restaurant) error
+	SetDeactivated(bson.ObjectId, bool) error
+	RemoveID(bson.ObjectId) error
 }
 
 // RestaurantIter is a wrapper around *mgo.Iter that allows type safe iteration
@@ -49,6 +60,15 @@ func (c restaurantsCollection) GetAll() RestaurantIter {
 	return &restaurantIter{i}
 }
 
+func (c restaurantsCollection) Search(query string, limit int) ([]*model.Restaurant, error) {
+	var restaurants []*model.Restaurant
+	err := c.Find(searchQuery(query, []bson.M{
+		{"name": bson.RegEx{Pattern: regexp.QuoteMeta(query), Options: "i"}},
+		{"facebook_page_id": query},
+	})).Sort("name").Limit(limit).All(&restaurants)
+	return restaurants, err
+}
+
 func (c restaurantsCollection) GetByIDs(ids []bson.ObjectId) ([]*model.Restaurant, error) {
 	var restaurants []*model.Restaurant
 	err := c.FindId(bson.M{
@@ -67,6 +87,17 @@ func (c restaurantsCollection) GetByFacebookPageIDs(ids []string) ([]*model.Rest
 	return restaurants, err
 }
 
+func (c restaurantsCollection) GetByRegion(region string) ([]*model.Restaurant, error) {
+	var restaurants []*model.Restaurant
+	err := c.Find(bson.M{
+		"region": region,
+		"deactivated": bson.M{
+			"$ne": true,
+		},
+	}).All(&restaurants)
+	return restaurants, err
+}
+
 func (c restaurantsCollection) GetID(id bson.ObjectId) (*model.Restaurant, error) {
 	var restaurant model.Restaurant
 	err := c.FindId(id).One(&restaurant)
@@ -85,6 +116,43 @@ func (c restaurantsCollection) UpdateID(id bson.ObjectId, restaurant *model.Rest
 	return c.UpdateId(id, bson.M{"$set": restaurant})
 }
 
+func (c restaurantsCollection) UpdateDetails(id bson.ObjectId, restaurant *model.Restaurant) error {
+	set := bson.M{
+		"name":                                restaurant.Name,
+		"address":                             restaurant.Address,
+		"location":                            restaurant.Location,
+		"default_group_post_message_template": restaurant.DefaultGroupPostMessageTemplate,
+	}
+	unset := bson.M{}
+	optional := map[string]string{
+		"phone":   restaurant.Phone,
+		"email":   restaurant.Email,
+		"website": restaurant.Website,
+	}
+	for field, value := range optional {
+		if value == "" {
+			unset[field] = ""
+		} else {
+			set[field] = value
+		}
+	}
+	update := bson.M{"$set": set}
+	if len(unset) != 0 {
+		update["$unset"] = unset
+	}
+	return c.UpdateId(id, update)
+}
+
+func (c restaurantsCollection) SetDeactivated(id bson.ObjectId, deactivated bool) error {
+	return c.UpdateId(id, bson.M{
+		"$set": bson.M{"deactivated": deactivated},
+	})
+}
+
+func (c restaurantsCollection) RemoveID(id bson.ObjectId) error {
+	return c.RemoveId(id)
+}
+
 type restaurantIter struct {
 	*mgo.Iter
 }
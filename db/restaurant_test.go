@@ -4,6 +4,7 @@ import (
 	"github.com/Lunchr/luncher-api/db/model"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -66,6 +67,57 @@ var _ = Describe("Restaurant", func() {
 		})
 	})
 
+	Describe("GetByRegion", func() {
+		It("lists all restaurants in the region", func() {
+			restaurants, err := restaurantsCollection.GetByRegion("Tartu")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restaurants).To(HaveLen(2))
+			Expect(restaurants).To(ConsistOf(mocks.restaurants[1], mocks.restaurants[2]))
+		})
+
+		It("returns an empty list if nothing found", func() {
+			restaurants, err := restaurantsCollection.GetByRegion("London")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restaurants).To(BeEmpty())
+		})
+	})
+
+	Describe("Search", func() {
+		It("should find the restaurants by a part of their name", func() {
+			restaurants, err := restaurantsCollection.Search("ian", 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restaurants).To(HaveLen(2))
+			Expect(restaurants[0].Name).To(Equal("Asian Chef"))
+			Expect(restaurants[1].Name).To(Equal("Caesarian Kitchen"))
+		})
+
+		It("should find the restaurants by their Facebook page ID", func() {
+			restaurants, err := restaurantsCollection.Search(facebookPageID, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restaurants).To(HaveLen(1))
+			Expect(restaurants[0].Name).To(Equal("Asian Chef"))
+		})
+
+		It("should find the restaurants by their ID", func() {
+			restaurants, err := restaurantsCollection.Search(mocks.restaurantID.Hex(), 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restaurants).To(HaveLen(1))
+			Expect(restaurants[0].Name).To(Equal("Asian Chef"))
+		})
+
+		It("should limit the results of an empty query", func() {
+			restaurants, err := restaurantsCollection.Search("", 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restaurants).To(HaveLen(2))
+		})
+
+		It("should treat the query literally", func() {
+			restaurants, err := restaurantsCollection.Search(".*", 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restaurants).To(BeEmpty())
+		})
+	})
+
 	Describe("GetAll", func() {
 		It("should list all the restaurants", func(done Done) {
 			defer close(done)
@@ -148,4 +200,78 @@ var _ = Describe("Restaurant", func() {
 			})
 		})
 	})
+
+	Describe("UpdateDetails", func() {
+		RebuildDBAfterEach()
+		var id bson.ObjectId
+
+		BeforeEach(func(done Done) {
+			defer close(done)
+			id = bson.NewObjectId()
+			_, err := restaurantsCollection.Insert(&model.Restaurant{
+				ID:             id,
+				Name:           "a name",
+				Phone:          "+372 1234567",
+				Email:          "an.email@address.com",
+				Website:        "https://some.address.com",
+				FacebookPageID: "fbpageid",
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should update the details and remove the cleared contact details", func(done Done) {
+			defer close(done)
+			err := restaurantsCollection.UpdateDetails(id, &model.Restaurant{
+				Name:  "an updated name",
+				Phone: "+372 7654321",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			restaurant, err := restaurantsCollection.GetID(id)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restaurant.Name).To(Equal("an updated name"))
+			Expect(restaurant.Phone).To(Equal("+372 7654321"))
+			Expect(restaurant.Email).To(BeEmpty())
+			Expect(restaurant.Website).To(BeEmpty())
+			Expect(restaurant.FacebookPageID).To(Equal("fbpageid"))
+		})
+	})
+
+	Describe("SetDeactivated", func() {
+		RebuildDBAfterEach()
+		BeforeEach(func() {
+			err := restaurantsCollection.SetDeactivated(mocks.restaurantID, true)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should mark the restaurant as deactivated", func() {
+			restaurant, err := restaurantsCollection.GetID(mocks.restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restaurant.Deactivated).To(BeTrue())
+		})
+
+		It("should hide the restaurant from its region", func() {
+			restaurants, err := restaurantsCollection.GetByRegion("Tartu")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restaurants).To(HaveLen(1))
+			Expect(restaurants[0].Name).To(Equal("Caesarian Kitchen"))
+		})
+
+		It("should be reversible", func() {
+			err := restaurantsCollection.SetDeactivated(mocks.restaurantID, false)
+			Expect(err).NotTo(HaveOccurred())
+			restaurants, err := restaurantsCollection.GetByRegion("Tartu")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restaurants).To(HaveLen(2))
+		})
+	})
+
+	Describe("RemoveID", func() {
+		RebuildDBAfterEach()
+		It("should remove the restaurant from DB", func() {
+			err := restaurantsCollection.RemoveID(mocks.restaurantID)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = restaurantsCollection.GetID(mocks.restaurantID)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+	})
 })
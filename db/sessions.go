@@ -0,0 +1,105 @@
+package db
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type Sessions interface {
+	Insert(*model.Session) error
+	// GetToken returns the unexpired session with the token or mgo.ErrNotFound
+	GetToken(string) (*model.Session, error)
+	GetForUser(userID bson.ObjectId) ([]*model.Session, error)
+	// Touch marks the session as seen at the specified time and extends its expiry
+	Touch(id bson.ObjectId, lastSeenAt, expiresAt time.Time) error
+	// Remove removes the user's session with the specified ID. Returns
+	// mgo.ErrNotFound if the user has no such session.
+	Remove(id, userID bson.ObjectId) error
+	RemoveToken(string) error
+	RemoveForUser(userID bson.ObjectId) error
+}
+
+type sessionsCollection struct {
+	*mgo.Collection
+}
+
+func NewSessions(c *Client) (Sessions, error) {
+	collection := c.database.C(model.SessionCollectionName)
+	sessions := &sessionsCollection{collection}
+	if err := ensureTTLIndex(sessions.Collection, "expires_at"); err != nil {
+		return nil, err
+	}
+	if err := sessions.ensureTokenIndex(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (c sessionsCollection) Insert(session *model.Session) error {
+	if session.ID == "" {
+		session.ID = bson.NewObjectId()
+	}
+	return c.Collection.Insert(session)
+}
+
+func (c sessionsCollection) GetToken(token string) (*model.Session, error) {
+	var session model.Session
+	err := c.Find(bson.M{
+		"token": token,
+		"expires_at": bson.M{
+			"$gt": time.Now(),
+		},
+	}).One(&session)
+	return &session, err
+}
+
+func (c sessionsCollection) GetForUser(userID bson.ObjectId) ([]*model.Session, error) {
+	var sessions []*model.Session
+	err := c.Find(bson.M{
+		"user_id": userID,
+		"expires_at": bson.M{
+			"$gt": time.Now(),
+		},
+	}).Sort("-last_seen_at").All(&sessions)
+	return sessions, err
+}
+
+func (c sessionsCollection) Touch(id bson.ObjectId, lastSeenAt, expiresAt time.Time) error {
+	return c.UpdateId(id, bson.M{
+		"$set": bson.M{
+			"last_seen_at": lastSeenAt,
+			"expires_at":   expiresAt,
+		},
+	})
+}
+
+func (c sessionsCollection) Remove(id, userID bson.ObjectId) error {
+	return c.Collection.Remove(bson.M{
+		"_id":     id,
+		"user_id": userID,
+	})
+}
+
+func (c sessionsCollection) RemoveToken(token string) error {
+	_, err := c.RemoveAll(bson.M{
+		"token": token,
+	})
+	return err
+}
+
+func (c sessionsCollection) RemoveForUser(userID bson.ObjectId) error {
+	_, err := c.RemoveAll(bson.M{
+		"user_id": userID,
+	})
+	return err
+}
+
+func (c sessionsCollection) ensureTokenIndex() error {
+	return c.EnsureIndex(mgo.Index{
+		Key:    []string{"token"},
+		Unique: true,
+	})
+}
@@ -0,0 +1,111 @@
+package db_test
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var _ = Describe("Sessions", func() {
+	RebuildDBAfterEach()
+	var (
+		userID  bson.ObjectId
+		session *model.Session
+	)
+
+	BeforeEach(func() {
+		userID = bson.NewObjectId()
+		session = &model.Session{
+			Token:      "a-token",
+			UserID:     userID,
+			CreatedAt:  time.Now(),
+			LastSeenAt: time.Now(),
+			UserAgent:  "a browser",
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}
+		err := sessionsCollection.Insert(session)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("GetToken", func() {
+		It("returns the session", func() {
+			foundSession, err := sessionsCollection.GetToken("a-token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(foundSession.ID).To(Equal(session.ID))
+			Expect(foundSession.UserID).To(Equal(userID))
+			Expect(foundSession.UserAgent).To(Equal("a browser"))
+		})
+
+		Context("with an expired session", func() {
+			BeforeEach(func() {
+				err := sessionsCollection.Touch(session.ID, time.Now(), time.Now().Add(-time.Minute))
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns mgo.ErrNotFound", func() {
+				_, err := sessionsCollection.GetToken("a-token")
+				Expect(err).To(Equal(mgo.ErrNotFound))
+			})
+		})
+	})
+
+	Describe("Touch", func() {
+		It("extends the session", func() {
+			expiresAt := time.Now().Add(48 * time.Hour)
+			err := sessionsCollection.Touch(session.ID, time.Now(), expiresAt)
+			Expect(err).NotTo(HaveOccurred())
+			foundSession, err := sessionsCollection.GetToken("a-token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(foundSession.ExpiresAt).To(BeTemporally("~", expiresAt, time.Second))
+		})
+	})
+
+	Context("with the user logged in on another device", func() {
+		BeforeEach(func() {
+			err := sessionsCollection.Insert(&model.Session{
+				Token:     "another-token",
+				UserID:    userID,
+				ExpiresAt: time.Now().Add(time.Hour),
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Describe("GetForUser", func() {
+			It("returns both sessions", func() {
+				sessions, err := sessionsCollection.GetForUser(userID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sessions).To(HaveLen(2))
+			})
+		})
+
+		Describe("Remove", func() {
+			It("removes only the specified session", func() {
+				err := sessionsCollection.Remove(session.ID, userID)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = sessionsCollection.GetToken("a-token")
+				Expect(err).To(Equal(mgo.ErrNotFound))
+				_, err = sessionsCollection.GetToken("another-token")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("doesn't remove other users' sessions", func() {
+				err := sessionsCollection.Remove(session.ID, bson.NewObjectId())
+				Expect(err).To(Equal(mgo.ErrNotFound))
+			})
+		})
+
+		Describe("RemoveForUser", func() {
+			It("removes all the sessions", func() {
+				err := sessionsCollection.RemoveForUser(userID)
+				Expect(err).NotTo(HaveOccurred())
+				sessions, err := sessionsCollection.GetForUser(userID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sessions).To(BeEmpty())
+			})
+		})
+	})
+})
@@ -1,6 +1,9 @@
 package db
 
 import (
+	"regexp"
+	"strings"
+
 	"github.com/Lunchr/luncher-api/db/model"
 	"golang.org/x/oauth2"
 	"gopkg.in/mgo.v2"
@@ -10,13 +13,30 @@ import (
 type Users interface {
 	Insert(...*model.User) error
 	GetFbID(string) (*model.User, error)
-	GetSessionID(string) (*model.User, error)
+	// GetForFacebookPage finds the users who have an access token for the Facebook page
+	GetForFacebookPage(pageID string) ([]*model.User, error)
+	GetEmail(string) (*model.User, error)
+	GetID(bson.ObjectId) (*model.User, error)
 	GetAll() UserIter
+	// Search finds the users whose email address contains the query or whose ID or
+	// Facebook user ID matches it. An empty query matches all users.
+	Search(query string, limit int) ([]*model.User, error)
 	Update(string, *model.User) error
+	UpdateID(bson.ObjectId, *model.User) error
 	SetAccessToken(string, oauth2.Token) error
 	SetPageAccessTokens(string, []model.FacebookPageToken) error
-	SetSessionID(bson.ObjectId, string) error
-	UnsetSessionID(bson.ObjectId) error
+	SetFacebookTokenHealth(bson.ObjectId, model.FacebookTokenHealth) error
+	SetPasswordHash(bson.ObjectId, []byte) error
+	SetEmailVerified(bson.ObjectId) error
+	// SetVerifiedEmail gives a user without an email address the verified address. Returns mgo.ErrNotFound
+	// if the user already has an address.
+	SetVerifiedEmail(id bson.ObjectId, email string) error
+	SetAdmin(id bson.ObjectId, isAdmin bool) error
+	RemoveRestaurant(restaurantID bson.ObjectId, facebookPageID string) error
+	// UnsetRestaurantIDs removes the restaurants linked to the user directly, which is how restaurants
+	// were assigned to users before memberships were introduced
+	UnsetRestaurantIDs(bson.ObjectId) error
+	RemoveID(bson.ObjectId) error
 }
 
 // UserIter is a wrapper around *mgo.Iter that allows type safe iteration
@@ -29,9 +49,13 @@ type usersCollection struct {
 	*mgo.Collection
 }
 
-func NewUsers(client *Client) Users {
+func NewUsers(client *Client) (Users, error) {
 	collection := client.database.C(model.UserCollectionName)
-	return &usersCollection{collection}
+	users := &usersCollection{collection}
+	if err := users.ensureEmailIndex(); err != nil {
+		return nil, err
+	}
+	return users, nil
 }
 
 func (c usersCollection) Insert(usersToInsert ...*model.User) error {
@@ -48,9 +72,23 @@ func (c usersCollection) GetFbID(facebookUserID string) (*model.User, error) {
 	return &user, err
 }
 
-func (c usersCollection) GetSessionID(sessionID string) (*model.User, error) {
+func (c usersCollection) GetForFacebookPage(pageID string) ([]*model.User, error) {
+	var users []*model.User
+	err := c.Find(bson.M{"session.facebook_page_tokens.page_id": pageID}).All(&users)
+	return users, err
+}
+
+// GetEmail finds the user by their email address. The address is case insensitive
+// and should be normalized with NormalizeEmail before storing.
+func (c usersCollection) GetEmail(email string) (*model.User, error) {
 	var user model.User
-	err := c.Find(bson.M{"session.id": sessionID}).One(&user)
+	err := c.Find(bson.M{"email": NormalizeEmail(email)}).One(&user)
+	return &user, err
+}
+
+func (c usersCollection) GetID(id bson.ObjectId) (*model.User, error) {
+	var user model.User
+	err := c.FindId(id).One(&user)
 	return &user, err
 }
 
@@ -59,13 +97,29 @@ func (c usersCollection) GetAll() UserIter {
 	return &userIter{i}
 }
 
+func (c usersCollection) Search(query string, limit int) ([]*model.User, error) {
+	var users []*model.User
+	err := c.Find(searchQuery(query, []bson.M{
+		{"email": bson.RegEx{Pattern: regexp.QuoteMeta(NormalizeEmail(query))}},
+		{"facebook_user_id": query},
+	})).Limit(limit).All(&users)
+	return users, err
+}
+
 func (c usersCollection) Update(facebookUserID string, user *model.User) error {
 	return c.Collection.Update(bson.M{"facebook_user_id": facebookUserID}, bson.M{"$set": user})
 }
 
+func (c usersCollection) UpdateID(id bson.ObjectId, user *model.User) error {
+	return c.Collection.UpdateId(id, bson.M{"$set": user})
+}
+
+// SetAccessToken stores a new Facebook user token for the user and forgets the health of
+// the previous token
 func (c usersCollection) SetAccessToken(facebookUserID string, tok oauth2.Token) error {
 	return c.Collection.Update(bson.M{"facebook_user_id": facebookUserID}, bson.M{
-		"$set": bson.M{"session.facebook_user_token": tok},
+		"$set":   bson.M{"session.facebook_user_token": tok},
+		"$unset": bson.M{"session.facebook_token_health": ""},
 	})
 }
 
@@ -75,18 +129,87 @@ func (c usersCollection) SetPageAccessTokens(facebookUserID string, tokens []mod
 	})
 }
 
-func (c usersCollection) SetSessionID(id bson.ObjectId, sessionID string) error {
+func (c usersCollection) SetFacebookTokenHealth(id bson.ObjectId, health model.FacebookTokenHealth) error {
+	return c.Collection.UpdateId(id, bson.M{
+		"$set": bson.M{"session.facebook_token_health": health},
+	})
+}
+
+func (c usersCollection) SetPasswordHash(id bson.ObjectId, passwordHash []byte) error {
+	return c.Collection.UpdateId(id, bson.M{
+		"$set": bson.M{"password_hash": passwordHash},
+	})
+}
+
+func (c usersCollection) SetEmailVerified(id bson.ObjectId) error {
+	return c.Collection.UpdateId(id, bson.M{
+		"$set": bson.M{"email_verified": true},
+	})
+}
+
+func (c usersCollection) SetVerifiedEmail(id bson.ObjectId, email string) error {
+	return c.Collection.Update(bson.M{
+		"_id":   id,
+		"email": bson.M{"$exists": false},
+	}, bson.M{
+		"$set": bson.M{
+			"email":          NormalizeEmail(email),
+			"email_verified": true,
+		},
+	})
+}
+
+func (c usersCollection) SetAdmin(id bson.ObjectId, isAdmin bool) error {
+	if isAdmin {
+		return c.Collection.UpdateId(id, bson.M{
+			"$set": bson.M{"is_admin": true},
+		})
+	}
 	return c.Collection.UpdateId(id, bson.M{
-		"$set": bson.M{"session.id": sessionID},
+		"$unset": bson.M{"is_admin": ""},
+	})
+}
+
+// RemoveRestaurant removes all references to the restaurant from all of the users
+func (c usersCollection) RemoveRestaurant(restaurantID bson.ObjectId, facebookPageID string) error {
+	pull := bson.M{
+		"restaurant_ids": restaurantID,
+	}
+	if facebookPageID != "" {
+		pull["session.facebook_page_tokens"] = bson.M{
+			"page_id": facebookPageID,
+		}
+	}
+	_, err := c.UpdateAll(nil, bson.M{
+		"$pull": pull,
 	})
+	return err
 }
 
-func (c usersCollection) UnsetSessionID(id bson.ObjectId) error {
+func (c usersCollection) UnsetRestaurantIDs(id bson.ObjectId) error {
 	return c.Collection.UpdateId(id, bson.M{
-		"$unset": bson.M{"session.id": ""},
+		"$unset": bson.M{"restaurant_ids": ""},
 	})
 }
 
+func (c usersCollection) RemoveID(id bson.ObjectId) error {
+	return c.Collection.RemoveId(id)
+}
+
+func (c usersCollection) ensureEmailIndex() error {
+	return c.EnsureIndex(mgo.Index{
+		Key:    []string{"email"},
+		Unique: true,
+		// Users who log in through Facebook don't have an email address
+		Sparse: true,
+	})
+}
+
+// NormalizeEmail makes email addresses comparable by trimming them and converting them to lower case
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 type userIter struct {
 	*mgo.Iter
 }
@@ -94,3 +217,15 @@ type userIter struct {
 func (u *userIter) Next(user *model.User) bool {
 	return u.Iter.Next(user)
 }
+
+// searchQuery matches documents by any of the conditions or by the query as an ID. An
+// empty query matches all documents.
+func searchQuery(query string, conditions []bson.M) bson.M {
+	if query == "" {
+		return nil
+	}
+	if bson.IsObjectIdHex(query) {
+		conditions = append(conditions, bson.M{"_id": bson.ObjectIdHex(query)})
+	}
+	return bson.M{"$or": conditions}
+}
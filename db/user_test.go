@@ -1,10 +1,13 @@
 package db_test
 
 import (
+	"time"
+
 	"github.com/Lunchr/luncher-api/db/model"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"golang.org/x/oauth2"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -102,6 +105,63 @@ var _ = Describe("User", func() {
 					Expect(user).NotTo(BeNil())
 					Expect(user.Session.FacebookPageTokens).To(Equal(tokens))
 				})
+
+				It("should find the user by the page", func() {
+					users, err := usersCollection.GetForFacebookPage("pageid")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(users).To(HaveLen(1))
+					Expect(users[0].FacebookUserID).To(Equal(facebookUserID))
+					users, err = usersCollection.GetForFacebookPage("anotherpageid")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(users).To(BeEmpty())
+				})
+			})
+		})
+
+		Describe("SetFacebookTokenHealth", func() {
+			var health model.FacebookTokenHealth
+
+			BeforeEach(func() {
+				health = model.FacebookTokenHealth{
+					CheckedAt:        time.Now().Truncate(time.Millisecond),
+					UserTokenInvalid: true,
+					InvalidPageIDs:   []string{"pageid"},
+				}
+				err := usersCollection.SetFacebookTokenHealth(mocks.userID, health)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should be included in the Get", func() {
+				user, err := usersCollection.GetID(mocks.userID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(user.Session.FacebookTokenHealth.UserTokenInvalid).To(BeTrue())
+				Expect(user.Session.FacebookTokenHealth.InvalidPageIDs).To(Equal(health.InvalidPageIDs))
+				Expect(user.Session.FacebookTokenHealth.CheckedAt).To(BeTemporally("==", health.CheckedAt))
+			})
+
+			It("should be reset by a new access token", func() {
+				err := usersCollection.SetAccessToken(facebookUserID, oauth2.Token{AccessToken: "new"})
+				Expect(err).NotTo(HaveOccurred())
+				user, err := usersCollection.GetID(mocks.userID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(user.Session.FacebookTokenHealth.NeedsReauth()).To(BeFalse())
+				Expect(user.Session.FacebookTokenHealth.InvalidPageIDs).To(BeEmpty())
+			})
+		})
+
+		Describe("SetAdmin", func() {
+			It("should grant and revoke the admin rights", func() {
+				err := usersCollection.SetAdmin(mocks.userID, true)
+				Expect(err).NotTo(HaveOccurred())
+				user, err := usersCollection.GetID(mocks.userID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(user.IsAdmin).To(BeTrue())
+
+				err = usersCollection.SetAdmin(mocks.userID, false)
+				Expect(err).NotTo(HaveOccurred())
+				user, err = usersCollection.GetID(mocks.userID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(user.IsAdmin).To(BeFalse())
 			})
 		})
 
@@ -128,46 +188,179 @@ var _ = Describe("User", func() {
 			})
 		})
 
-		Describe("SessionID", func() {
-			Context("with SessionID set", func() {
-				var id string
+		Describe("UpdateID", func() {
+			var newID bson.ObjectId
 
-				BeforeEach(func() {
-					id = "someid"
-					err := usersCollection.SetSessionID(mocks.userID, id)
+			BeforeEach(func() {
+				updatedUser := *mocks.users[0]
+				newID = bson.NewObjectId()
+				updatedUser.RestaurantIDs = []bson.ObjectId{newID}
+				err := usersCollection.UpdateID(mocks.userID, &updatedUser)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should be reflected in the Get", func() {
+				user, err := usersCollection.GetFbID(facebookUserID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(user.RestaurantIDs).To(Equal([]bson.ObjectId{newID}))
+			})
+		})
+
+		Describe("with a user registered by email", func() {
+			var id bson.ObjectId
+
+			BeforeEach(func() {
+				id = bson.NewObjectId()
+				err := usersCollection.Insert(&model.User{
+					ID:           id,
+					Email:        "owner@restaurant.test",
+					PasswordHash: []byte("a hash"),
+				})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			Describe("GetEmail", func() {
+				It("should find the user regardless of the case of the address", func() {
+					user, err := usersCollection.GetEmail(" Owner@Restaurant.test")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(user.ID).To(Equal(id))
+					Expect(user.EmailVerified).To(BeFalse())
+				})
+
+				It("should get nothing for an unknown address", func() {
+					_, err := usersCollection.GetEmail("someone@else.test")
+					Expect(err).To(Equal(mgo.ErrNotFound))
+				})
+			})
+
+			It("should not allow another user with the same email", func() {
+				err := usersCollection.Insert(&model.User{
+					Email: "owner@restaurant.test",
+				})
+				Expect(mgo.IsDup(err)).To(BeTrue())
+			})
+
+			Describe("SetEmailVerified", func() {
+				It("should mark the email as verified", func() {
+					err := usersCollection.SetEmailVerified(id)
 					Expect(err).NotTo(HaveOccurred())
+					user, err := usersCollection.GetEmail("owner@restaurant.test")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(user.EmailVerified).To(BeTrue())
 				})
+			})
 
-				Describe("SetSessionID", func() {
-					It("should be included in the Get", func() {
-						user, err := usersCollection.GetFbID(facebookUserID)
-						Expect(err).NotTo(HaveOccurred())
-						Expect(user).NotTo(BeNil())
-						Expect(user.Session.ID).To(Equal("someid"))
-					})
+			Describe("SetVerifiedEmail", func() {
+				It("should not replace the address of a user who has one", func() {
+					err := usersCollection.SetVerifiedEmail(id, "someone@else.test")
+					Expect(err).To(Equal(mgo.ErrNotFound))
 				})
 
-				Describe("UnsetSessionID", func() {
-					It("should remove the session ID", func() {
-						err := usersCollection.UnsetSessionID(mocks.userID)
-						Expect(err).NotTo(HaveOccurred())
+				It("should give a Facebook user a verified address", func() {
+					user, err := usersCollection.GetFbID(facebookUserID)
+					Expect(err).NotTo(HaveOccurred())
+					err = usersCollection.SetVerifiedEmail(user.ID, " Cook@Restaurant.test")
+					Expect(err).NotTo(HaveOccurred())
+					user, err = usersCollection.GetEmail("cook@restaurant.test")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(user.FacebookUserID).To(Equal(facebookUserID))
+					Expect(user.EmailVerified).To(BeTrue())
+				})
 
-						user, err := usersCollection.GetFbID(facebookUserID)
-						Expect(err).NotTo(HaveOccurred())
-						Expect(user).NotTo(BeNil())
-						Expect(user.Session.ID).To(BeEmpty())
-					})
+				It("should not give a Facebook user an address that's taken", func() {
+					user, err := usersCollection.GetFbID(facebookUserID)
+					Expect(err).NotTo(HaveOccurred())
+					err = usersCollection.SetVerifiedEmail(user.ID, "owner@restaurant.test")
+					Expect(mgo.IsDup(err)).To(BeTrue())
 				})
+			})
 
-				Describe("GetBySessionID", func() {
-					It("should be included in the Get", func() {
-						user, err := usersCollection.GetSessionID(id)
-						Expect(err).NotTo(HaveOccurred())
-						Expect(user).NotTo(BeNil())
-						Expect(user.FacebookUserID).To(Equal(facebookUserID))
-					})
+			Describe("SetPasswordHash", func() {
+				It("should replace the password hash", func() {
+					err := usersCollection.SetPasswordHash(id, []byte("another hash"))
+					Expect(err).NotTo(HaveOccurred())
+					user, err := usersCollection.GetEmail("owner@restaurant.test")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(user.PasswordHash).To(Equal([]byte("another hash")))
 				})
 			})
 		})
+
+		Describe("UnsetRestaurantIDs", func() {
+			It("should remove the directly linked restaurants", func() {
+				user, err := usersCollection.GetFbID(facebookUserID)
+				Expect(err).NotTo(HaveOccurred())
+				err = usersCollection.UnsetRestaurantIDs(user.ID)
+				Expect(err).NotTo(HaveOccurred())
+				user, err = usersCollection.GetFbID(facebookUserID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(user.RestaurantIDs).To(BeEmpty())
+			})
+		})
+
+		Describe("RemoveRestaurant", func() {
+			BeforeEach(func() {
+				err := usersCollection.SetPageAccessTokens(facebookUserID, []model.FacebookPageToken{model.FacebookPageToken{
+					PageID: "pageid",
+					Token:  "atoken",
+				}, model.FacebookPageToken{
+					PageID: "anotherpageid",
+					Token:  "anothertoken",
+				}})
+				Expect(err).NotTo(HaveOccurred())
+				err = usersCollection.RemoveRestaurant(mocks.restaurantID, "pageid")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should remove the restaurant from all users", func() {
+				for _, facebookUserID := range []string{facebookUserID, "another user"} {
+					user, err := usersCollection.GetFbID(facebookUserID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(user.RestaurantIDs).To(BeEmpty())
+				}
+			})
+
+			It("should remove the page access token for the restaurant's page", func() {
+				user, err := usersCollection.GetFbID(facebookUserID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(user.Session.FacebookPageTokens).To(HaveLen(1))
+				Expect(user.Session.FacebookPageTokens[0].PageID).To(Equal("anotherpageid"))
+			})
+		})
+
+		Describe("Search", func() {
+			It("should find the users by their Facebook user ID", func() {
+				users, err := usersCollection.Search(facebookUserID, 10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(users).To(HaveLen(1))
+				Expect(users[0].ID).To(Equal(mocks.userID))
+			})
+
+			It("should find the users by their ID", func() {
+				users, err := usersCollection.Search(mocks.userID.Hex(), 10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(users).To(HaveLen(1))
+				Expect(users[0].FacebookUserID).To(Equal(facebookUserID))
+			})
+
+			It("should list all users for an empty query", func() {
+				users, err := usersCollection.Search("", 10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(users).To(HaveLen(2))
+			})
+		})
+
+		Describe("GetID", func() {
+			It("should find the user", func() {
+				user, err := usersCollection.GetID(mocks.userID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(user.FacebookUserID).To(Equal(facebookUserID))
+			})
+
+			It("should return mgo.ErrNotFound for unknown IDs", func() {
+				_, err := usersCollection.GetID(bson.NewObjectId())
+				Expect(err).To(Equal(mgo.ErrNotFound))
+			})
+		})
 	})
 })
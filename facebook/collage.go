@@ -0,0 +1,205 @@
+package facebook
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/deiwin/picasso"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	defaultCollageMaxImages   = 4
+	defaultCollageBorderWidth = 2
+	maxCollageImages          = 9
+	minCollageSize            = 200
+	maxCollageSize            = 2048
+	maxCollageBorderWidth     = 50
+	// collageDateLayout is the layout the date is overlaid on the collages in
+	collageDateLayout = "02.01.2006"
+)
+
+var (
+	defaultCollageBorderColor = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	collageDateColor          = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	collageDateBackground     = color.RGBA{0xff, 0xff, 0xff, 0xdd}
+)
+
+// collageLayouts maps the names of the layouts the restaurants can pick for their collages to the layouts
+var collageLayouts = map[string]func() picasso.Layout{
+	"top_heavy":     picasso.TopHeavyLayout,
+	"bottom_heavy":  picasso.BottomHeavyLayout,
+	"golden_spiral": picasso.GoldenSpiralLayout,
+}
+
+// ValidateCollageSettings checks that the restaurant's collage settings are supported
+func ValidateCollageSettings(settings *model.CollageSettings) error {
+	if _, ok := collageLayouts[settings.Layout]; settings.Layout != "" && !ok {
+		names := make([]string, 0, len(collageLayouts))
+		for name := range collageLayouts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("Unknown layout %q, the available layouts are: %s", settings.Layout, strings.Join(names, ", "))
+	}
+	if settings.MaxImages < 0 || settings.MaxImages > maxCollageImages {
+		return fmt.Errorf("The maximum number of images must be between 1 and %d", maxCollageImages)
+	}
+	if (settings.Width == 0) != (settings.Height == 0) {
+		return errors.New("Both the width and the height of the collage must be specified")
+	}
+	if settings.Width != 0 && !isValidCollageSize(settings.Width) || settings.Height != 0 && !isValidCollageSize(settings.Height) {
+		return fmt.Errorf("The dimensions of the collage must be between %d and %d pixels", minCollageSize, maxCollageSize)
+	}
+	if settings.BorderColor != "" {
+		if _, err := parseHexColor(settings.BorderColor); err != nil {
+			return err
+		}
+	}
+	if settings.BorderWidth != nil && (*settings.BorderWidth < 0 || *settings.BorderWidth > maxCollageBorderWidth) {
+		return fmt.Errorf("The border width must be between 0 and %d pixels", maxCollageBorderWidth)
+	}
+	return nil
+}
+
+func isValidCollageSize(size int) bool {
+	return size >= minCollageSize && size <= maxCollageSize
+}
+
+func parseHexColor(s string) (color.RGBA, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("Invalid color %q, expected a hex triplet like #ffffff", s)
+	}
+	rgb, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("Invalid color %q, expected a hex triplet like #ffffff", s)
+	}
+	return color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 0xff}, nil
+}
+
+// collageStyle holds the restaurant's collage settings with the defaults filled in
+type collageStyle struct {
+	layout       picasso.Layout
+	maxImages    int
+	width        int
+	height       int
+	borderColor  color.RGBA
+	borderWidth  int
+	logoChecksum string
+	showDate     bool
+}
+
+func (f *facebookPost) collageStyleFor(restaurant *model.Restaurant) *collageStyle {
+	style := &collageStyle{
+		layout:      f.collageLayout,
+		maxImages:   defaultCollageMaxImages,
+		borderColor: defaultCollageBorderColor,
+		borderWidth: defaultCollageBorderWidth,
+	}
+	settings := restaurant.CollageSettings
+	if settings == nil {
+		return style
+	}
+	// The settings are validated when they're saved, so the invalid ones are just ignored here
+	if newLayout, ok := collageLayouts[settings.Layout]; ok {
+		style.layout = newLayout()
+	}
+	if settings.MaxImages > 0 {
+		style.maxImages = settings.MaxImages
+	}
+	style.width, style.height = settings.Width, settings.Height
+	if borderColor, err := parseHexColor(settings.BorderColor); err == nil {
+		style.borderColor = borderColor
+	}
+	if settings.BorderWidth != nil {
+		style.borderWidth = *settings.BorderWidth
+	}
+	style.logoChecksum = settings.LogoChecksum
+	style.showDate = settings.ShowDate
+	return style
+}
+
+// drawCollageOverlay draws the restaurant's logo on the bottom right corner and the date on the top left corner
+// of a copy of the collage, if configured
+func (f *facebookPost) drawCollageOverlay(collage image.Image, style *collageStyle, date model.DateWithoutTime) (image.Image,
+	*router.HandlerError) {
+	if style.logoChecksum == "" && !style.showDate {
+		return collage, nil
+	}
+	bounds := collage.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), collage, bounds.Min, draw.Src)
+	margin := bounds.Dx() / 40
+	if style.logoChecksum != "" {
+		logo, err := f.images.GetOriginal(style.logoChecksum)
+		if err != nil {
+			return nil, router.NewHandlerError(err, "Failed to read the restaurant's logo from disk", http.StatusInternalServerError)
+		}
+		// The logo is scaled down to fit within a fifth of the collage
+		width, height := getLogoSize(logo.Bounds(), bounds.Dx()/5, bounds.Dy()/5)
+		if width != logo.Bounds().Dx() || height != logo.Bounds().Dy() {
+			scaledLogo := image.NewRGBA(image.Rect(0, 0, width, height))
+			draw.CatmullRom.Scale(scaledLogo, scaledLogo.Bounds(), logo, logo.Bounds(), draw.Src, nil)
+			logo = scaledLogo
+		}
+		logoBounds := logo.Bounds()
+		at := image.Pt(bounds.Dx()-margin-logoBounds.Dx(), bounds.Dy()-margin-logoBounds.Dy())
+		draw.Draw(canvas, logoBounds.Sub(logoBounds.Min).Add(at), logo, logoBounds.Min, draw.Over)
+	}
+	if style.showDate {
+		startTime, _, err := date.TimeBounds(time.UTC)
+		if err != nil {
+			return nil, router.NewHandlerError(err, "Failed to parse a date", http.StatusInternalServerError)
+		}
+		drawText(canvas, startTime.Format(collageDateLayout), image.Pt(margin, margin))
+	}
+	return canvas, nil
+}
+
+// drawText draws the text on a box in the canvas's top left corner. The text is drawn in a fixed size
+// font and then scaled up along with the canvas, so that it would be just as legible on big collages.
+func drawText(canvas draw.Image, text string, at image.Point) {
+	face := basicfont.Face7x13
+	drawer := &font.Drawer{
+		Src:  image.NewUniform(collageDateColor),
+		Face: face,
+	}
+	padding := 2
+	textImage := image.NewRGBA(image.Rect(0, 0, drawer.MeasureString(text).Ceil()+2*padding, face.Height+2*padding))
+	draw.Draw(textImage, textImage.Bounds(), image.NewUniform(collageDateBackground), image.ZP, draw.Src)
+	drawer.Dst = textImage
+	drawer.Dot = fixed.P(padding, padding+face.Ascent)
+	drawer.DrawString(text)
+	scale := canvas.Bounds().Dx() / 300
+	if scale < 1 {
+		scale = 1
+	}
+	textBounds := textImage.Bounds()
+	box := image.Rect(0, 0, textBounds.Dx()*scale, textBounds.Dy()*scale).Add(at)
+	draw.NearestNeighbor.Scale(canvas, box, textImage, textBounds, draw.Over, nil)
+}
+
+// getLogoSize returns the dimensions of the logo scaled down to fit within the maximum dimensions, keeping
+// its aspect ratio
+func getLogoSize(logo image.Rectangle, maxWidth, maxHeight int) (int, int) {
+	width, height := logo.Dx(), logo.Dy()
+	if width > maxWidth {
+		width, height = maxWidth, height*maxWidth/width
+	}
+	if height > maxHeight {
+		width, height = width*maxHeight/height, maxHeight
+	}
+	return width, height
+}
@@ -0,0 +1,65 @@
+package facebook_test
+
+import (
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/facebook"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateCollageSettings", func() {
+	var settings *model.CollageSettings
+
+	BeforeEach(func() {
+		borderWidth := 4
+		settings = &model.CollageSettings{
+			Layout:      "golden_spiral",
+			MaxImages:   6,
+			Width:       1200,
+			Height:      630,
+			BorderColor: "#1a2B3c",
+			BorderWidth: &borderWidth,
+			ShowDate:    true,
+		}
+	})
+
+	It("should accept the defaults", func() {
+		Expect(facebook.ValidateCollageSettings(&model.CollageSettings{})).To(Succeed())
+	})
+
+	It("should accept valid settings", func() {
+		Expect(facebook.ValidateCollageSettings(settings)).To(Succeed())
+	})
+
+	It("should reject unknown layouts", func() {
+		settings.Layout = "spiral"
+		Expect(facebook.ValidateCollageSettings(settings)).NotTo(Succeed())
+	})
+
+	It("should reject too many images", func() {
+		settings.MaxImages = 10
+		Expect(facebook.ValidateCollageSettings(settings)).NotTo(Succeed())
+	})
+
+	It("should reject specifying only one of the dimensions", func() {
+		settings.Height = 0
+		Expect(facebook.ValidateCollageSettings(settings)).NotTo(Succeed())
+	})
+
+	It("should reject too large dimensions", func() {
+		settings.Width = 4096
+		Expect(facebook.ValidateCollageSettings(settings)).NotTo(Succeed())
+	})
+
+	It("should reject invalid colors", func() {
+		settings.BorderColor = "white"
+		Expect(facebook.ValidateCollageSettings(settings)).NotTo(Succeed())
+	})
+
+	It("should reject negative border widths", func() {
+		borderWidth := -1
+		settings.BorderWidth = &borderWidth
+		Expect(facebook.ValidateCollageSettings(settings)).NotTo(Succeed())
+	})
+})
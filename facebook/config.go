@@ -0,0 +1,51 @@
+package facebook
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/deiwin/gonfigure"
+)
+
+// graphRequestTimeout limits how long the requests to the Graph API may take, so that a stalled
+// request wouldn't block the background jobs for good
+const graphRequestTimeout = 30 * time.Second
+
+var (
+	appIDProperty     = gonfigure.NewEnvProperty("FACEBOOK_APP_ID", "")
+	appSecretProperty = gonfigure.NewEnvProperty("FACEBOOK_APP_SECRET", "")
+	graphURLProperty  = gonfigure.NewEnvProperty("FACEBOOK_GRAPH_URL", "https://graph.facebook.com")
+)
+
+type Config struct {
+	// AppID and AppSecret form the app access token that is required to inspect
+	// the users' tokens. These are the same credentials the Facebook login uses.
+	AppID     string
+	AppSecret string
+	// GraphURL is the root of the Graph API
+	GraphURL string
+	// HTTPClient is used to make the requests to the Graph API. A client that times out
+	// after graphRequestTimeout is used if not set.
+	HTTPClient *http.Client
+}
+
+func NewConfig() (*Config, error) {
+	appID := appIDProperty.Value()
+	appSecret := appSecretProperty.Value()
+	if appID == "" || appSecret == "" {
+		return nil, errors.New("facebook: FACEBOOK_APP_ID and FACEBOOK_APP_SECRET have to be set")
+	}
+	return &Config{
+		AppID:     appID,
+		AppSecret: appSecret,
+		GraphURL:  graphURLProperty.Value(),
+	}, nil
+}
+
+func (c *Config) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return &http.Client{Timeout: graphRequestTimeout}
+	}
+	return c.HTTPClient
+}
@@ -0,0 +1,315 @@
+package facebook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+)
+
+// maxMessageLength is the maximum length of a Facebook post's message
+const maxMessageLength = 63206
+
+var errMessageTooLong = errors.New("The message is too long")
+
+type (
+	// MessageData is the data the message templates of the group posts are executed with
+	MessageData struct {
+		Date       model.DateWithoutTime
+		Restaurant MessageRestaurant
+		Offers     []MessageOffer
+	}
+
+	// MessageRestaurant holds the restaurant's details available to the message templates
+	MessageRestaurant struct {
+		Name    string
+		Address string
+		Phone   string
+		Website string
+		Email   string
+	}
+
+	// MessageOffer holds an offer's details available to the message templates. The times
+	// are in the restaurant's region's time zone.
+	MessageOffer struct {
+		Title       string
+		Description string
+		Price       float64
+		Tags        []string
+		FromTime    time.Time
+		ToTime      time.Time
+	}
+
+	// limitedWriter fails the writes that would grow the buffer beyond the limit
+	limitedWriter struct {
+		buf   bytes.Buffer
+		limit int
+	}
+)
+
+var messageTemplateFuncs = template.FuncMap{
+	// TODO get rid of the hard-coded €
+	"price": func(price float64) string { return fmt.Sprintf("%.2f€", price) },
+	"join":  strings.Join,
+}
+
+var messageDataType = reflect.TypeOf(MessageData{})
+
+// ValidateMessageTemplate checks that the message template can be parsed and that
+// the fields and methods it refers to exist. The template isn't executed, because
+// any sample data would reject templates that are valid for other offers, e.g. ones
+// that index the offers. Templates that don't contain any actions are used as
+// headers for the list of offers, as they were before the templates were
+// introduced, and are always valid.
+func ValidateMessageTemplate(messageTemplate string) error {
+	if !isTemplated(messageTemplate) {
+		return nil
+	} else if len(messageTemplate) > maxMessageLength {
+		return errMessageTooLong
+	}
+	tmpl, err := parseMessageTemplate(messageTemplate)
+	if err != nil {
+		return err
+	}
+	return checkFields(tmpl.Tree.Root, messageDataType)
+}
+
+func formFBMessage(post *model.OfferGroupPost, restaurant *model.Restaurant, offers []*model.Offer,
+	location *time.Location) string {
+	if !isTemplated(post.MessageTemplate) {
+		return fmt.Sprintf("%s\n\n%s", post.MessageTemplate, formFBOffersMessage(offers))
+	}
+	message, err := executeMessageTemplate(post.MessageTemplate, newMessageData(post.Date, restaurant, offers, location))
+	if err != nil {
+		// The templates are validated when they're saved, so this should only happen if
+		// an offer contains something the template doesn't expect. Post the offers without
+		// the template rather than not posting at all.
+		log.Printf("Failed to execute the message template of restaurant %s for %s: %v", restaurant.ID.Hex(),
+			post.Date, err)
+		if header := messageTemplateHeader(post.MessageTemplate); header != "" {
+			return fmt.Sprintf("%s\n\n%s", header, formFBOffersMessage(offers))
+		}
+		return formFBOffersMessage(offers)
+	}
+	return message
+}
+
+// messageTemplateHeader returns the text the template starts with, before any actions
+func messageTemplateHeader(messageTemplate string) string {
+	return strings.TrimSpace(messageTemplate[:strings.Index(messageTemplate, "{{")])
+}
+
+func formFBOffersMessage(offers []*model.Offer) string {
+	offerMessages := make([]string, len(offers))
+	for i, offer := range offers {
+		offerMessages[i] = formFBOfferMessage(offer)
+	}
+	return strings.Join(offerMessages, "\n")
+}
+
+func formFBOfferMessage(o *model.Offer) string {
+	// TODO get rid of the hard-coded €
+	return fmt.Sprintf("%s - %.2f€", o.Title, o.Price)
+}
+
+func newMessageData(date model.DateWithoutTime, restaurant *model.Restaurant, offers []*model.Offer,
+	location *time.Location) *MessageData {
+	messageOffers := make([]MessageOffer, len(offers))
+	for i, offer := range offers {
+		messageOffers[i] = MessageOffer{
+			Title:       offer.Title,
+			Description: offer.Description,
+			Price:       offer.Price,
+			Tags:        offer.Tags,
+			FromTime:    offer.FromTime.In(location),
+			ToTime:      offer.ToTime.In(location),
+		}
+	}
+	return &MessageData{
+		Date: date,
+		Restaurant: MessageRestaurant{
+			Name:    restaurant.Name,
+			Address: restaurant.Address,
+			Phone:   restaurant.Phone,
+			Website: restaurant.Website,
+			Email:   restaurant.Email,
+		},
+		Offers: messageOffers,
+	}
+}
+
+func isTemplated(messageTemplate string) bool {
+	return strings.Contains(messageTemplate, "{{")
+}
+
+func parseMessageTemplate(messageTemplate string) (*template.Template, error) {
+	return template.New("message").Funcs(messageTemplateFuncs).Parse(messageTemplate)
+}
+
+func executeMessageTemplate(messageTemplate string, data *MessageData) (string, error) {
+	tmpl, err := parseMessageTemplate(messageTemplate)
+	if err != nil {
+		return "", err
+	}
+	w := &limitedWriter{limit: maxMessageLength}
+	if err = tmpl.Execute(w, data); err != nil {
+		return "", err
+	}
+	return w.buf.String(), nil
+}
+
+// checkFields checks the fields referred to in the node against the type of dot. The
+// type is followed into range and with, and whatever can't be typed without executing
+// the template, e.g. the results of functions, is left unchecked.
+func checkFields(node parse.Node, dot reflect.Type) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkFields(child, dot); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		_, err := pipeType(n.Pipe, dot)
+		return err
+	case *parse.TemplateNode:
+		_, err := pipeType(n.Pipe, dot)
+		return err
+	case *parse.IfNode:
+		if _, err := pipeType(n.Pipe, dot); err != nil {
+			return err
+		}
+		return checkBranchFields(&n.BranchNode, dot, dot)
+	case *parse.WithNode:
+		t, err := pipeType(n.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		return checkBranchFields(&n.BranchNode, t, dot)
+	case *parse.RangeNode:
+		t, err := pipeType(n.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		return checkBranchFields(&n.BranchNode, elemType(t), dot)
+	}
+	return nil
+}
+
+func checkBranchFields(n *parse.BranchNode, dot, elseDot reflect.Type) error {
+	if err := checkFields(n.List, dot); err != nil {
+		return err
+	}
+	return checkFields(n.ElseList, elseDot)
+}
+
+// pipeType returns the type of the pipeline's result, or nil if it's unknown
+func pipeType(pipe *parse.PipeNode, dot reflect.Type) (reflect.Type, error) {
+	if pipe == nil {
+		return nil, nil
+	}
+	var t reflect.Type
+	for _, cmd := range pipe.Cmds {
+		for i, arg := range cmd.Args {
+			argT, err := argType(arg, dot)
+			if err != nil {
+				return nil, err
+			} else if i == 0 {
+				// A function's result is unknown, because the identifier's type is nil
+				t = argT
+			}
+		}
+	}
+	return t, nil
+}
+
+func argType(arg parse.Node, dot reflect.Type) (reflect.Type, error) {
+	switch n := arg.(type) {
+	case *parse.DotNode:
+		return dot, nil
+	case *parse.FieldNode:
+		return fieldType(dot, n.Ident)
+	case *parse.VariableNode:
+		if n.Ident[0] != "$" {
+			// Only the root data's type is known
+			return nil, nil
+		}
+		return fieldType(messageDataType, n.Ident[1:])
+	case *parse.ChainNode:
+		t, err := argType(n.Node, dot)
+		if err != nil {
+			return nil, err
+		}
+		return fieldType(t, n.Field)
+	case *parse.PipeNode:
+		return pipeType(n, dot)
+	}
+	return nil, nil
+}
+
+// fieldType follows the chain of fields and methods starting from t and returns the
+// type at its end
+func fieldType(t reflect.Type, names []string) (reflect.Type, error) {
+	for _, name := range names {
+		if t == nil {
+			return nil, nil
+		}
+		if method, ok := reflect.PtrTo(t).MethodByName(name); ok {
+			if method.Type.NumOut() == 0 {
+				return nil, fmt.Errorf("method %s of type %s doesn't return a value", name, t)
+			}
+			t = method.Type.Out(0)
+			continue
+		}
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := t.FieldByName(name)
+			if !ok || field.PkgPath != "" {
+				return nil, fmt.Errorf("can't evaluate field %s in type %s", name, t)
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Interface:
+			t = nil
+		default:
+			return nil, fmt.Errorf("can't evaluate field %s in type %s", name, t)
+		}
+	}
+	return t, nil
+}
+
+// elemType returns the type of the elements ranged over, or nil if it's unknown
+func elemType(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.Chan:
+		return t.Elem()
+	}
+	return nil
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.limit {
+		return 0, errMessageTooLong
+	}
+	return w.buf.Write(p)
+}
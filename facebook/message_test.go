@@ -0,0 +1,53 @@
+package facebook_test
+
+import (
+	"strings"
+
+	"github.com/Lunchr/luncher-api/facebook"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateMessageTemplate", func() {
+	It("should accept plain text headers", func() {
+		Expect(facebook.ValidateMessageTemplate("Today's offers are:")).To(Succeed())
+	})
+
+	It("should accept templates using the offers' fields", func() {
+		err := facebook.ValidateMessageTemplate("{{.Restaurant.Name}} {{.Date}}{{range .Offers}}\n{{.Title}}: " +
+			"{{.Description}} {{join .Tags \", \"}} {{.FromTime.Format \"15:04\"}} {{price .Price}}{{end}}")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should reject templates that can't be parsed", func() {
+		Expect(facebook.ValidateMessageTemplate("{{range .Offers}}")).NotTo(Succeed())
+	})
+
+	It("should reject templates referring to unknown fields", func() {
+		Expect(facebook.ValidateMessageTemplate("{{.Restaurant.Token}}")).NotTo(Succeed())
+	})
+
+	It("should accept templates indexing the offers", func() {
+		Expect(facebook.ValidateMessageTemplate("{{with index .Offers 2}}{{.Title}}{{end}}")).To(Succeed())
+	})
+
+	It("should accept templates changing dot", func() {
+		err := facebook.ValidateMessageTemplate("{{with .Restaurant}}{{.Name}} {{$.Date}}{{end}}" +
+			"{{range $i, $offer := .Offers}}{{$offer.Title}} {{.ToTime.Hour}}{{else}}{{.Restaurant.Phone}}{{end}}")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should reject templates referring to unknown fields of the offers", func() {
+		Expect(facebook.ValidateMessageTemplate("{{range .Offers}}{{.Name}}{{end}}")).NotTo(Succeed())
+	})
+
+	It("should reject templates referring to fields of the offers list", func() {
+		Expect(facebook.ValidateMessageTemplate("{{.Offers.Title}}")).NotTo(Succeed())
+	})
+
+	It("should reject templates longer than a message", func() {
+		template := "{{.Date}}" + strings.Repeat("x", 70000)
+		Expect(facebook.ValidateMessageTemplate(template)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,13 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/db/model"
+
+type Log struct {
+	mock.Mock
+}
+
+func (_m *Log) Record(_a0 *model.AuditEntry) {
+	_m.Called(_a0)
+}
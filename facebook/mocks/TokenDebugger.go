@@ -0,0 +1,30 @@
+package mocks
+
+import "github.com/Lunchr/luncher-api/facebook"
+import "github.com/stretchr/testify/mock"
+
+type TokenDebugger struct {
+	mock.Mock
+}
+
+func (_m *TokenDebugger) Debug(token string) (*facebook.TokenInfo, error) {
+	ret := _m.Called(token)
+
+	var r0 *facebook.TokenInfo
+	if rf, ok := ret.Get(0).(func(string) *facebook.TokenInfo); ok {
+		r0 = rf(token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*facebook.TokenInfo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -2,20 +2,21 @@ package facebook
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"hash/crc32"
 	"image"
-	"image/color"
 	"image/jpeg"
 	"io"
+	"io/ioutil"
 	"net/http"
-	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
 
 	"gopkg.in/mgo.v2"
 
+	"github.com/Lunchr/luncher-api/audit"
 	"github.com/Lunchr/luncher-api/db"
 	"github.com/Lunchr/luncher-api/db/model"
 	"github.com/Lunchr/luncher-api/router"
@@ -26,21 +27,43 @@ import (
 )
 
 const (
-	// postUpdateDebounceDuration is the period we leave for the users to modify a day's offers in before
-	// publishing the offers to Facebook. That is, the last modification time to today's offers + this time
-	// will be when the post goes live. NB: May not be less than 10 minutes, as per Facebook's documentation.
-	// That is, Facebook doesn't allow posts to be scheduled less than 10 minutes (or more than 6 months, but
-	// I don't think that will be an issue) from now.
-	postUpdateDebounceDuration       = 11 * time.Minute
+	// minScheduledPublishDelay is how far in the future the posts are scheduled to go live at the earliest.
+	// NB: May not be less than 10 minutes, as per Facebook's documentation. That is, Facebook doesn't allow
+	// posts to be scheduled less than 10 minutes (or more than 6 months, but I don't think that will be an
+	// issue) from now. Coalescing the users' changes is up to the PublishQueue.
+	minScheduledPublishDelay         = 11 * time.Minute
 	publishDurationBeforeOfferActive = 15 * time.Minute
+
+	auditActionPublish = "facebook.publish"
+	auditActionUpdate  = "facebook.update"
+	auditActionDelete  = "facebook.delete"
 )
 
 type Post interface {
 	Update(model.DateWithoutTime, *model.User, *model.Restaurant) *router.HandlerError
+	Delete(*model.OfferGroupPost, *model.User, *model.Restaurant) *router.HandlerError
+	// Preview returns what would be posted on Facebook for the restaurant's offers for the date, if the post
+	// were updated right now, without calling Facebook. For posts that are already on Facebook, the publish
+	// time and state are the ones stored for the post.
+	Preview(model.DateWithoutTime, *model.Restaurant) (*PostPreview, *router.HandlerError)
+}
+
+// PostPreview is what would be posted on Facebook for a restaurant's offers for a date
+type PostPreview struct {
+	Message              string    `json:"message"`
+	ScheduledPublishTime time.Time `json:"scheduled_publish_time"`
+	// Image is the JPEG collage of the offers' images as a data URI. Empty if none of the offers have an image.
+	Image string `json:"image,omitempty"`
+	// FBPostID is set if the post is already on Facebook
+	FBPostID string `json:"fb_post_id,omitempty"`
+	// Published tells whether the post has gone live on Facebook
+	Published bool `json:"published"`
+	// earliestPublishTime is how early the offers allow the post to be published, for rescheduling the post
+	earliestPublishTime time.Time
 }
 
 func NewPost(groupPosts db.OfferGroupPosts, offers db.Offers, regions db.Regions, fbAuth facebook.Authenticator, images storage.Images,
-	collageLayout picasso.Layout) Post {
+	collageLayout picasso.Layout, auditLog audit.Log) Post {
 	return &facebookPost{
 		groupPosts:    groupPosts,
 		offers:        offers,
@@ -48,6 +71,7 @@ func NewPost(groupPosts db.OfferGroupPosts, offers db.Offers, regions db.Regions
 		fbAuth:        fbAuth,
 		images:        images,
 		collageLayout: collageLayout,
+		auditLog:      auditLog,
 	}
 }
 
@@ -58,6 +82,7 @@ type facebookPost struct {
 	fbAuth        facebook.Authenticator
 	images        storage.Images
 	collageLayout picasso.Layout
+	auditLog      audit.Log
 }
 
 func (f *facebookPost) Update(date model.DateWithoutTime, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
@@ -82,6 +107,69 @@ func (f *facebookPost) Update(date model.DateWithoutTime, user *model.User, rest
 	return f.updatePost(post, user, restaurant)
 }
 
+// Delete removes the group post from Facebook, if it has been posted there
+func (f *facebookPost) Delete(post *model.OfferGroupPost, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+	if restaurant.FacebookPageID == "" || post.FBPostID == "" {
+		return nil
+	}
+	pageAccessToken := getPageAccessToken(user, restaurant.FacebookPageID)
+	if pageAccessToken == "" {
+		return router.NewSimpleHandlerError("Couldn't find the page access token for the restaurant", http.StatusInternalServerError)
+	}
+	handlerErr := f.deleteExistingPost(post, &user.Session.FacebookUserToken, pageAccessToken, restaurant.FacebookPageID)
+	return f.audited(auditActionDelete, post, user, restaurant, handlerErr)
+}
+
+func (f *facebookPost) Preview(date model.DateWithoutTime, restaurant *model.Restaurant) (*PostPreview, *router.HandlerError) {
+	post, err := f.groupPosts.GetByDate(date, restaurant.ID)
+	if err == mgo.ErrNotFound {
+		// Update would create the post with the restaurant's defaults
+		post = &model.OfferGroupPost{
+			RestaurantID:    restaurant.ID,
+			Date:            date,
+			MessageTemplate: restaurant.DefaultGroupPostMessageTemplate,
+		}
+	} else if err != nil {
+		return nil, router.NewHandlerError(err, "Failed to fetch a group post for that date", http.StatusInternalServerError)
+	}
+	offersForDate, location, handlerErr := f.getOffersForDate(date, restaurant)
+	if handlerErr != nil {
+		return nil, handlerErr
+	} else if len(offersForDate) == 0 {
+		return nil, router.NewSimpleHandlerError("There are no offers to post for that date", http.StatusNotFound)
+	}
+	earliestPublishTime, handlerErr := calculateEarliestPublishTime(offersForDate)
+	if handlerErr != nil {
+		return nil, handlerErr
+	}
+	preview := &PostPreview{
+		Message:              formFBMessage(post, restaurant, offersForDate, location),
+		ScheduledPublishTime: scheduledPublishTime(earliestPublishTime, time.Now()),
+		earliestPublishTime:  earliestPublishTime,
+	}
+	if post.FBPostID != "" {
+		preview.FBPostID = post.FBPostID
+		preview.ScheduledPublishTime = post.PublishTime
+		preview.Published = !post.PublishTime.After(time.Now())
+	}
+	collage, handlerErr := f.createOfferPhotoCollage(offersForDate, restaurant, post.Date)
+	if handlerErr != nil {
+		return nil, handlerErr
+	}
+	if collage != nil {
+		encodedCollage, _, handlerErr := encodeCollage(collage)
+		if handlerErr != nil {
+			return nil, handlerErr
+		}
+		collageData, err := ioutil.ReadAll(encodedCollage)
+		if err != nil {
+			return nil, router.NewHandlerError(err, "Failed to read the encoded collage", http.StatusInternalServerError)
+		}
+		preview.Image = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(collageData)
+	}
+	return preview, nil
+}
+
 func (f *facebookPost) updatePost(post *model.OfferGroupPost, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
 	if restaurant.FacebookPageID == "" {
 		return nil
@@ -92,20 +180,32 @@ func (f *facebookPost) updatePost(post *model.OfferGroupPost, user *model.User,
 		return router.NewSimpleHandlerError("Couldn't find the page access token for the restaurant", http.StatusInternalServerError)
 	}
 
-	offersForDate, handlerErr := f.getOffersForDate(post.Date, restaurant)
+	offersForDate, location, handlerErr := f.getOffersForDate(post.Date, restaurant)
 	if handlerErr != nil {
 		return handlerErr
 	}
+	message := formFBMessage(post, restaurant, offersForDate, location)
 	if post.FBPostID == "" {
 		if len(offersForDate) == 0 {
 			return nil
 		}
-		return f.publishNewPost(post, offersForDate, userAccessToken, pageAccessToken, restaurant.FacebookPageID)
+		handlerErr = f.publishNewPost(post, message, offersForDate, restaurant, userAccessToken, pageAccessToken, restaurant.FacebookPageID)
+		return f.audited(auditActionPublish, post, user, restaurant, handlerErr)
 	}
 	if len(offersForDate) == 0 {
-		return f.deleteExistingPost(post, userAccessToken, pageAccessToken, restaurant.FacebookPageID)
+		handlerErr = f.deleteExistingPost(post, userAccessToken, pageAccessToken, restaurant.FacebookPageID)
+		return f.audited(auditActionDelete, post, user, restaurant, handlerErr)
 	}
-	return f.updateExistingPost(post, offersForDate, userAccessToken, pageAccessToken, restaurant.FacebookPageID)
+	handlerErr = f.updateExistingPost(post, message, offersForDate, restaurant, userAccessToken, pageAccessToken, restaurant.FacebookPageID)
+	return f.audited(auditActionUpdate, post, user, restaurant, handlerErr)
+}
+
+// audited records the outcome of publishing, updating or deleting the post on Facebook in the audit log and
+// returns the handlerErr it was given
+func (f *facebookPost) audited(action string, post *model.OfferGroupPost, user *model.User, restaurant *model.Restaurant,
+	handlerErr *router.HandlerError) *router.HandlerError {
+	f.auditLog.Record(audit.NewEntry(user.ID, restaurant.ID, action, string(post.Date), handlerErr))
+	return handlerErr
 }
 
 func getPageAccessToken(user *model.User, pageID string) string {
@@ -117,15 +217,15 @@ func getPageAccessToken(user *model.User, pageID string) string {
 	return ""
 }
 
-func (f *facebookPost) publishNewPost(post *model.OfferGroupPost, offersForDate []*model.Offer, userAccessToken *oauth2.Token,
-	pageAccessToken, pageID string) *router.HandlerError {
-	fbPost, handlerErr := formFBPost(post, offersForDate)
+func (f *facebookPost) publishNewPost(post *model.OfferGroupPost, message string, offersForDate []*model.Offer,
+	restaurant *model.Restaurant, userAccessToken *oauth2.Token, pageAccessToken, pageID string) *router.HandlerError {
+	fbPost, handlerErr := formFBPost(message, offersForDate)
 	if handlerErr != nil {
 		return handlerErr
 	}
 	fbAPI := f.fbAuth.APIConnection(userAccessToken)
 
-	collage, handlerErr := f.createOfferPhotoCollage(offersForDate)
+	collage, handlerErr := f.createOfferPhotoCollage(offersForDate, restaurant, post.Date)
 	if handlerErr != nil {
 		return handlerErr
 	}
@@ -152,6 +252,7 @@ func (f *facebookPost) publishNewPost(post *model.OfferGroupPost, offersForDate
 		}
 		post.FBPostID = fbPostResponse.ID
 	}
+	post.PublishTime = fbPost.ScheduledPublishTime
 	if err := f.groupPosts.UpdateByID(post.ID, post); err != nil {
 		return router.NewHandlerError(err, "Failed to update a group post in the DB", http.StatusInternalServerError)
 	}
@@ -182,20 +283,21 @@ func (f *facebookPost) deleteExistingPost(post *model.OfferGroupPost, userAccess
 		return router.NewHandlerError(err, "Failed to delete the current post from Facebook", http.StatusBadGateway)
 	}
 	post.FBPostID = ""
+	post.PublishTime = time.Time{}
 	if err = f.groupPosts.UpdateByID(post.ID, post); err != nil {
 		return router.NewHandlerError(err, "Failed to update a group post in the DB", http.StatusInternalServerError)
 	}
 	return nil
 }
 
-func (f *facebookPost) updateExistingPost(post *model.OfferGroupPost, offersForDate []*model.Offer, userAccessToken *oauth2.Token,
-	pageAccessToken, pageID string) *router.HandlerError {
+func (f *facebookPost) updateExistingPost(post *model.OfferGroupPost, message string, offersForDate []*model.Offer,
+	restaurant *model.Restaurant, userAccessToken *oauth2.Token, pageAccessToken, pageID string) *router.HandlerError {
 	fbAPI := f.fbAuth.APIConnection(userAccessToken)
 	currentPost, err := fbAPI.Post(pageAccessToken, post.FBPostID)
 	if err != nil {
 		return router.NewHandlerError(err, "Failed to retrieve the current post from FB", http.StatusBadGateway)
 	}
-	collage, handlerErr := f.createOfferPhotoCollage(offersForDate)
+	collage, handlerErr := f.createOfferPhotoCollage(offersForDate, restaurant, post.Date)
 	if handlerErr != nil {
 		return handlerErr
 	}
@@ -205,7 +307,7 @@ func (f *facebookPost) updateExistingPost(post *model.OfferGroupPost, offersForD
 			return handlerErr
 		}
 		if collageChecksum != post.PostedImageChecksum {
-			fbPost, handlerErr := formFBPostForBackdatedUpdate(post, offersForDate, currentPost)
+			fbPost, handlerErr := formFBPostForBackdatedUpdate(message, offersForDate, currentPost)
 			if handlerErr != nil {
 				return handlerErr
 			}
@@ -225,6 +327,7 @@ func (f *facebookPost) updateExistingPost(post *model.OfferGroupPost, offersForD
 			}
 			post.FBPostID = getPostIDFromPhotoResponse(fbPhotoResponse, pageID)
 			post.PostedImageChecksum = collageChecksum
+			post.PublishTime = getPublishTime(fbPost)
 
 			if err := f.groupPosts.UpdateByID(post.ID, post); err != nil {
 				return router.NewHandlerError(err, "Failed to update a group post in the DB", http.StatusInternalServerError)
@@ -232,7 +335,7 @@ func (f *facebookPost) updateExistingPost(post *model.OfferGroupPost, offersForD
 			return nil
 		}
 	} else if post.PostedImageChecksum != 0 {
-		fbPost, handlerErr := formFBPostForBackdatedUpdate(post, offersForDate, currentPost)
+		fbPost, handlerErr := formFBPostForBackdatedUpdate(message, offersForDate, currentPost)
 		if handlerErr != nil {
 			return handlerErr
 		}
@@ -246,13 +349,14 @@ func (f *facebookPost) updateExistingPost(post *model.OfferGroupPost, offersForD
 		}
 		post.FBPostID = fbPostResponse.ID
 		post.PostedImageChecksum = 0
+		post.PublishTime = getPublishTime(fbPost)
 		if err := f.groupPosts.UpdateByID(post.ID, post); err != nil {
 			return router.NewHandlerError(err, "Failed to update a group post in the DB", http.StatusInternalServerError)
 		}
 		return nil
 	}
 
-	fbPost, handlerErr := formFBPostForUpdate(post, offersForDate, currentPost)
+	fbPost, handlerErr := formFBPostForUpdate(message, offersForDate, currentPost)
 	if handlerErr != nil {
 		return handlerErr
 	}
@@ -261,55 +365,69 @@ func (f *facebookPost) updateExistingPost(post *model.OfferGroupPost, offersForD
 	if err != nil {
 		return router.NewHandlerError(err, "Failed updating the offers in Facebook", http.StatusBadGateway)
 	}
+	if currentPost.IsPublished {
+		return nil
+	}
+	// The update reschedules the post
+	post.PublishTime = fbPost.ScheduledPublishTime
+	if err := f.groupPosts.UpdateByID(post.ID, post); err != nil {
+		return router.NewHandlerError(err, "Failed to update a group post in the DB", http.StatusInternalServerError)
+	}
 	return nil
 }
 
-func formFBPostForBackdatedUpdate(post *model.OfferGroupPost, offersForDate []*model.Offer, currentPost *fbmodel.PostResponse) (*fbmodel.Post, *router.HandlerError) {
+// getPublishTime returns the time the post that's sent to Facebook goes, or went, live at
+func getPublishTime(fbPost *fbmodel.Post) time.Time {
+	if !fbPost.BackdatedTime.IsZero() {
+		return fbPost.BackdatedTime
+	}
+	return fbPost.ScheduledPublishTime
+}
+
+func formFBPostForBackdatedUpdate(message string, offersForDate []*model.Offer, currentPost *fbmodel.PostResponse) (*fbmodel.Post, *router.HandlerError) {
 	if currentPost.IsPublished {
 		return &fbmodel.Post{
-			Message:       formFBMessage(post, offersForDate),
+			Message:       message,
 			Published:     true,
 			BackdatedTime: currentPost.CreatedTime,
 		}, nil
 	}
-	return formFBPost(post, offersForDate)
+	return formFBPost(message, offersForDate)
 }
 
-func formFBPostForUpdate(post *model.OfferGroupPost, offersForDate []*model.Offer, currentPost *fbmodel.PostResponse) (*fbmodel.Post, *router.HandlerError) {
+func formFBPostForUpdate(message string, offersForDate []*model.Offer, currentPost *fbmodel.PostResponse) (*fbmodel.Post, *router.HandlerError) {
 	if currentPost.IsPublished {
 		return &fbmodel.Post{
-			Message:   formFBMessage(post, offersForDate),
+			Message:   message,
 			Published: true,
 		}, nil
 	}
-	return formFBPost(post, offersForDate)
+	return formFBPost(message, offersForDate)
 }
 
-func formFBPost(post *model.OfferGroupPost, offersForDate []*model.Offer) (*fbmodel.Post, *router.HandlerError) {
+func formFBPost(message string, offersForDate []*model.Offer) (*fbmodel.Post, *router.HandlerError) {
 	publishTime, handlerErr := calculatePublishTime(offersForDate)
 	if handlerErr != nil {
 		return nil, handlerErr
 	}
 	return &fbmodel.Post{
-		Message:              formFBMessage(post, offersForDate),
+		Message:              message,
 		ScheduledPublishTime: publishTime,
 		Published:            publishTime.Before(time.Now()),
 	}, nil
 }
 
-func (f *facebookPost) createOfferPhotoCollage(offers []*model.Offer) (image.Image, *router.HandlerError) {
+// createOfferPhotoCollage arranges the offers' images into a collage according to the restaurant's collage
+// settings. Returns nil if none of the offers have an image.
+func (f *facebookPost) createOfferPhotoCollage(offers []*model.Offer, restaurant *model.Restaurant,
+	date model.DateWithoutTime) (image.Image, *router.HandlerError) {
+	style := f.collageStyleFor(restaurant)
 	checksums := getImageChecksums(offers)
 	if len(checksums) == 0 {
 		return nil, nil
-	} else if len(checksums) == 1 {
-		image, err := f.images.GetOriginal(checksums[0])
-		if err != nil {
-			return nil, router.NewHandlerError(err, "Failed to read an image of an offer from disk", http.StatusInternalServerError)
-		}
-		return image, nil
-	} else if len(checksums) > 4 {
-		// Limit the amount of pictures in the collage to 4 because it might get too crowded otherwise
-		checksums = checksums[:4]
+	} else if len(checksums) > style.maxImages {
+		// Limit the amount of pictures in the collage because it might get too crowded otherwise
+		checksums = checksums[:style.maxImages]
 	}
 	images := make([]image.Image, len(checksums))
 	for i, checksum := range checksums {
@@ -319,10 +437,19 @@ func (f *facebookPost) createOfferPhotoCollage(offers []*model.Offer) (image.Ima
 		}
 		images[i] = image
 	}
-	width, height := getCollageSizeForNumberOfImages(len(images))
-	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
-	collage := f.collageLayout.Compose(images).DrawWithBorder(width, height, white, 2)
-	return collage, nil
+	if len(images) == 1 {
+		collage := images[0]
+		if style.width != 0 && style.height != 0 {
+			collage = style.layout.Compose(images).Draw(style.width, style.height)
+		}
+		return f.drawCollageOverlay(collage, style, date)
+	}
+	width, height := style.width, style.height
+	if width == 0 || height == 0 {
+		width, height = getCollageSizeForNumberOfImages(len(images))
+	}
+	collage := style.layout.Compose(images).DrawWithBorder(width, height, style.borderColor, style.borderWidth)
+	return f.drawCollageOverlay(collage, style, date)
 }
 
 func getImageChecksums(offers []*model.Offer) []string {
@@ -342,23 +469,18 @@ func getCollageSizeForNumberOfImages(nr int) (int, int) {
 	return 800, 800
 }
 
-func formFBMessage(post *model.OfferGroupPost, offers []*model.Offer) string {
-	offerMessages := make([]string, len(offers))
-	for i, offer := range offers {
-		offerMessages[i] = formFBOfferMessage(offer)
+// calculatePublishTime returns a time either 11 minutes (the minimum scheduling delay) from now or 15 minutes before
+// the earliest FromTime of an offer, whichever is later.
+func calculatePublishTime(offers []*model.Offer) (time.Time, *router.HandlerError) {
+	earliestPublishTime, handlerErr := calculateEarliestPublishTime(offers)
+	if handlerErr != nil {
+		return time.Time{}, handlerErr
 	}
-	offersMessage := strings.Join(offerMessages, "\n")
-	return fmt.Sprintf("%s\n\n%s", post.MessageTemplate, offersMessage)
-}
-
-func formFBOfferMessage(o *model.Offer) string {
-	// TODO get rid of the hard-coded €
-	return fmt.Sprintf("%s - %.2f€", o.Title, o.Price)
+	return scheduledPublishTime(earliestPublishTime, time.Now()), nil
 }
 
-// calculatePublishTime returns a time either 5 minutes (the debounce period) from now or the earliest FromTime of an offer,
-// whichever is later.
-func calculatePublishTime(offers []*model.Offer) (time.Time, *router.HandlerError) {
+// calculateEarliestPublishTime returns the time the post should go live at, if it were posted early enough
+func calculateEarliestPublishTime(offers []*model.Offer) (time.Time, *router.HandlerError) {
 	if len(offers) == 0 {
 		return time.Time{}, router.NewSimpleHandlerError("Cannot calculate a publish time for 0 offers", http.StatusInternalServerError)
 	}
@@ -372,30 +494,35 @@ func calculatePublishTime(offers []*model.Offer) (time.Time, *router.HandlerErro
 			earliestTime = offer.FromTime
 		}
 	}
-	earliestPublishTime := earliestTime.Add(-publishDurationBeforeOfferActive)
-	afterDebounceBuffer := time.Now().Add(postUpdateDebounceDuration)
-	if earliestPublishTime.Before(afterDebounceBuffer) {
-		return afterDebounceBuffer, nil
+	return earliestTime.Add(-publishDurationBeforeOfferActive), nil
+}
+
+// scheduledPublishTime returns the time a post that's posted to Facebook at postedAt will go live at
+func scheduledPublishTime(earliestPublishTime, postedAt time.Time) time.Time {
+	earliestScheduledTime := postedAt.Add(minScheduledPublishDelay)
+	if earliestPublishTime.Before(earliestScheduledTime) {
+		return earliestScheduledTime
 	}
-	return earliestPublishTime, nil
+	return earliestPublishTime
 }
 
-func (f *facebookPost) getOffersForDate(date model.DateWithoutTime, restaurant *model.Restaurant) ([]*model.Offer, *router.HandlerError) {
+func (f *facebookPost) getOffersForDate(date model.DateWithoutTime, restaurant *model.Restaurant) ([]*model.Offer, *time.Location,
+	*router.HandlerError) {
 	region, err := f.regions.GetName(restaurant.Region)
 	if err != nil {
-		return nil, router.NewHandlerError(err, "Failed to find the restaurant's region", http.StatusInternalServerError)
+		return nil, nil, router.NewHandlerError(err, "Failed to find the restaurant's region", http.StatusInternalServerError)
 	}
 	location, err := time.LoadLocation(region.Location)
 	if err != nil {
-		return nil, router.NewHandlerError(err, "Failed to load region's location", http.StatusInternalServerError)
+		return nil, nil, router.NewHandlerError(err, "Failed to load region's location", http.StatusInternalServerError)
 	}
 	startTime, endTime, err := date.TimeBounds(location)
 	if err != nil {
-		return nil, router.NewHandlerError(err, "Failed to parse a date", http.StatusInternalServerError)
+		return nil, nil, router.NewHandlerError(err, "Failed to parse a date", http.StatusInternalServerError)
 	}
 	offersForDate, err := f.offers.GetForRestaurantWithinTimeBounds(restaurant.ID, startTime, endTime)
 	if err != nil {
-		return nil, router.NewHandlerError(err, "Failed to find offers for this date", http.StatusInternalServerError)
+		return nil, nil, router.NewHandlerError(err, "Failed to find offers for this date", http.StatusInternalServerError)
 	}
-	return offersForDate, nil
+	return offersForDate, location, nil
 }
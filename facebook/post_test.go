@@ -2,13 +2,16 @@ package facebook_test
 
 import (
 	"bytes"
+	"encoding/base64"
 	"errors"
 	"hash/crc32"
 	"image"
 	"image/color"
+	"image/draw"
 	"image/jpeg"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Lunchr/luncher-api/db/model"
@@ -34,6 +37,8 @@ var _ = Describe("Post", func() {
 		fbAuth           *mocks.Authenticator
 		images           *mocks.Images
 		collageLayout    *mocks.Layout
+		auditLog         *mocks.Log
+		auditEntries     []*model.AuditEntry
 
 		user       *model.User
 		restaurant *model.Restaurant
@@ -46,8 +51,13 @@ var _ = Describe("Post", func() {
 		fbAuth = new(mocks.Authenticator)
 		images = new(mocks.Images)
 		collageLayout = new(mocks.Layout)
+		auditLog = new(mocks.Log)
+		auditEntries = nil
+		auditLog.On("Record", mock.AnythingOfType("*model.AuditEntry")).Return().Run(func(args mock.Arguments) {
+			auditEntries = append(auditEntries, args.Get(0).(*model.AuditEntry))
+		})
 
-		facebookPost = facebook.NewPost(groupPosts, offersCollection, regions, fbAuth, images, collageLayout)
+		facebookPost = facebook.NewPost(groupPosts, offersCollection, regions, fbAuth, images, collageLayout, auditLog)
 	})
 
 	JustBeforeEach(func() {
@@ -135,8 +145,21 @@ var _ = Describe("Post", func() {
 					greenPixel         image.Image
 					greenPixelJPEGData io.Reader
 					greenPixelChecksum uint32
+
+					updatedPublishTime time.Time
 				)
 
+				// expectGroupPostUpdate expects the group post to be stored as expected, apart from the publish time,
+				// which is stored in updatedPublishTime
+				var expectGroupPostUpdate = func(expected *model.OfferGroupPost) {
+					groupPosts.On("UpdateByID", id, mock.AnythingOfType("*model.OfferGroupPost")).Return(nil).Run(func(args mock.Arguments) {
+						updated := *args.Get(1).(*model.OfferGroupPost)
+						updatedPublishTime = updated.PublishTime
+						updated.PublishTime = time.Time{}
+						Expect(&updated).To(Equal(expected))
+					})
+				}
+
 				var getSinglePixelImage = func(c color.Color) image.Image {
 					i := image.NewRGBA(image.Rect(0, 0, 1, 1))
 					i.Set(0, 0, c)
@@ -180,16 +203,67 @@ var _ = Describe("Post", func() {
 					fbAuth.AssertExpectations(GinkgoT())
 				})
 
+				Context("with a templated message", func() {
+					var expectMessage = func(message string) {
+						fbAPI.On("PagePublish", facebookPageToken, facebookPageID, &fbmodel.Post{
+							Message:              message,
+							Published:            false,
+							ScheduledPublishTime: time.Date(2115, 01, 02, 9, 45, 0, 0, time.UTC),
+						}).Return(&fbmodel.PostResponse{
+							ID: facebookPostID,
+						}, nil)
+					}
+
+					BeforeEach(func() {
+						restaurant.Name = "a restaurant"
+						fbAuth.On("APIConnection", facebookUserToken).Return(fbAPI)
+						groupPosts.On("UpdateByID", id, mock.AnythingOfType("*model.OfferGroupPost")).Return(nil)
+						offersCollection.On("GetForRestaurantWithinTimeBounds", restaurantID, startTime, endTime).Return([]*model.Offer{
+							&model.Offer{
+								CommonOfferFields: model.CommonOfferFields{
+									Title:    "atitle",
+									Price:    5.670000000000,
+									Tags:     []string{"fish", "soup"},
+									FromTime: time.Date(2115, 01, 02, 10, 0, 0, 0, time.UTC),
+									ToTime:   time.Date(2115, 01, 02, 14, 0, 0, 0, time.UTC),
+								},
+							},
+						}, nil)
+					})
+
+					It("should render the template with the restaurant's offers", func() {
+						offerGroupPost.MessageTemplate = "{{.Restaurant.Name}} {{.Date}}:{{range .Offers}}\n" +
+							"{{.Title}} ({{join .Tags \", \"}}) {{.FromTime.Format \"15:04\"}}-{{.ToTime.Format \"15:04\"}} {{price .Price}}{{end}}"
+						expectMessage("a restaurant 2011-04-24:\natitle (fish, soup) 10:00-14:00 5.67€")
+						err := facebookPost.Update(date, user, restaurant)
+						Expect(err).To(BeNil())
+					})
+
+					It("should fall back to listing the offers if the template fails", func() {
+						offerGroupPost.MessageTemplate = "{{.Offers.Title}}"
+						expectMessage("atitle - 5.67€")
+						err := facebookPost.Update(date, user, restaurant)
+						Expect(err).To(BeNil())
+					})
+
+					It("should keep the template's header when falling back", func() {
+						offerGroupPost.MessageTemplate = "Today's offers: {{with index .Offers 2}}{{.Title}}{{end}}"
+						expectMessage("Today's offers:\n\natitle - 5.67€")
+						err := facebookPost.Update(date, user, restaurant)
+						Expect(err).To(BeNil())
+					})
+				})
+
 				Context("without a previous associated FB post", func() {
 					Context("with there being offers for that date", func() {
 						BeforeEach(func() {
 							fbAuth.On("APIConnection", facebookUserToken).Return(fbAPI)
-							groupPosts.On("UpdateByID", id, &model.OfferGroupPost{
+							expectGroupPostUpdate(&model.OfferGroupPost{
 								ID:              id,
 								Date:            date,
 								MessageTemplate: messageTemplate,
 								FBPostID:        facebookPostID,
-							}).Return(nil)
+							})
 						})
 
 						Context("for far future offers", func() {
@@ -223,6 +297,10 @@ var _ = Describe("Post", func() {
 
 								err := facebookPost.Update(date, user, restaurant)
 								Expect(err).To(BeNil())
+								Expect(updatedPublishTime).To(Equal(time.Date(2115, 01, 02, 8, 45, 0, 0, time.UTC)))
+								Expect(auditEntries).To(HaveLen(1))
+								Expect(auditEntries[0].Action).To(Equal("facebook.publish"))
+								Expect(auditEntries[0].Outcome).To(Equal(model.AuditOutcomeSuccess))
 							})
 						})
 
@@ -257,6 +335,7 @@ var _ = Describe("Post", func() {
 								Expect(post.Message).To(Equal(messageTemplate + "\n\natitle - 5.67€\nbtitle - 4.67€"))
 								Expect(post.Published).To(BeFalse())
 								Expect(post.ScheduledPublishTime.Sub(time.Now())).To(BeNumerically("~", 11*time.Minute, time.Second))
+								Expect(updatedPublishTime).To(Equal(post.ScheduledPublishTime))
 							})
 						})
 
@@ -291,6 +370,7 @@ var _ = Describe("Post", func() {
 								Expect(post.Message).To(Equal(messageTemplate + "\n\natitle - 5.67€\nbtitle - 4.67€"))
 								Expect(post.Published).To(BeFalse())
 								Expect(post.ScheduledPublishTime.Sub(time.Now())).To(BeNumerically("~", 11*time.Minute, time.Second))
+								Expect(updatedPublishTime).To(Equal(post.ScheduledPublishTime))
 							})
 						})
 					})
@@ -302,13 +382,13 @@ var _ = Describe("Post", func() {
 
 						Describe("with one of the offers having an image", func() {
 							BeforeEach(func() {
-								groupPosts.On("UpdateByID", id, &model.OfferGroupPost{
+								expectGroupPostUpdate(&model.OfferGroupPost{
 									ID:                  id,
 									Date:                date,
 									MessageTemplate:     messageTemplate,
 									FBPostID:            facebookPageID + "_" + photoID,
 									PostedImageChecksum: bluePixelChecksum,
-								}).Return(nil)
+								})
 							})
 
 							Context("for past offers", func() {
@@ -348,6 +428,7 @@ var _ = Describe("Post", func() {
 										Expect(post.Message).To(Equal(messageTemplate + "\n\natitle - 5.67€\nbtitle - 4.67€"))
 										Expect(post.Published).To(BeFalse())
 										Expect(post.ScheduledPublishTime.Sub(time.Now())).To(BeNumerically("~", 11*time.Minute, time.Second))
+										Expect(updatedPublishTime).To(Equal(post.ScheduledPublishTime))
 										Expect(post.Photo).To(Equal(bluePixelJPEGData))
 									})
 								})
@@ -366,6 +447,7 @@ var _ = Describe("Post", func() {
 										Expect(post.Message).To(Equal(messageTemplate + "\n\natitle - 5.67€\nbtitle - 4.67€"))
 										Expect(post.Published).To(BeFalse())
 										Expect(post.ScheduledPublishTime.Sub(time.Now())).To(BeNumerically("~", 11*time.Minute, time.Second))
+										Expect(updatedPublishTime).To(Equal(post.ScheduledPublishTime))
 										Expect(post.Photo).To(Equal(bluePixelJPEGData))
 									})
 								})
@@ -374,13 +456,13 @@ var _ = Describe("Post", func() {
 
 						Describe("with two of the offers having an image", func() {
 							BeforeEach(func() {
-								groupPosts.On("UpdateByID", id, &model.OfferGroupPost{
+								expectGroupPostUpdate(&model.OfferGroupPost{
 									ID:                  id,
 									Date:                date,
 									MessageTemplate:     messageTemplate,
 									FBPostID:            facebookPageID + "_" + photoID,
 									PostedImageChecksum: greenPixelChecksum,
-								}).Return(nil)
+								})
 							})
 
 							Context("for past offers", func() {
@@ -426,6 +508,7 @@ var _ = Describe("Post", func() {
 										Expect(post.Message).To(Equal(messageTemplate + "\n\natitle - 5.67€\nbtitle - 4.67€"))
 										Expect(post.Published).To(BeFalse())
 										Expect(post.ScheduledPublishTime.Sub(time.Now())).To(BeNumerically("~", 11*time.Minute, time.Second))
+										Expect(updatedPublishTime).To(Equal(post.ScheduledPublishTime))
 										Expect(post.Photo).To(Equal(greenPixelJPEGData))
 									})
 								})
@@ -434,13 +517,13 @@ var _ = Describe("Post", func() {
 
 						Describe("with five (> 4) of the offers having an image", func() {
 							BeforeEach(func() {
-								groupPosts.On("UpdateByID", id, &model.OfferGroupPost{
+								expectGroupPostUpdate(&model.OfferGroupPost{
 									ID:                  id,
 									Date:                date,
 									MessageTemplate:     messageTemplate,
 									FBPostID:            facebookPageID + "_" + photoID,
 									PostedImageChecksum: greenPixelChecksum,
-								}).Return(nil)
+								})
 							})
 
 							Context("for past offers", func() {
@@ -510,6 +593,7 @@ var _ = Describe("Post", func() {
 										Expect(post.Message).To(Equal(messageTemplate + "\n\natitle - 5.67€\nbtitle - 4.67€\nbtitle - 4.67€\nbtitle - 4.67€\nbtitle - 4.67€"))
 										Expect(post.Published).To(BeFalse())
 										Expect(post.ScheduledPublishTime.Sub(time.Now())).To(BeNumerically("~", 11*time.Minute, time.Second))
+										Expect(updatedPublishTime).To(Equal(post.ScheduledPublishTime))
 										Expect(post.Photo).To(Equal(greenPixelJPEGData))
 									})
 								})
@@ -576,6 +660,7 @@ var _ = Describe("Post", func() {
 
 									err := facebookPost.Update(date, user, restaurant)
 									Expect(err).To(BeNil())
+									groupPosts.AssertNotCalled(GinkgoT(), "UpdateByID", mock.Anything, mock.Anything)
 								})
 
 								Context("with previous post having a collage", func() {
@@ -588,12 +673,12 @@ var _ = Describe("Post", func() {
 										fbAPI.On("PagePublish", facebookPageToken, facebookPageID, mock.AnythingOfType("*model.Post")).Return(&fbmodel.PostResponse{
 											ID: facebookPostID,
 										}, nil)
-										groupPosts.On("UpdateByID", id, &model.OfferGroupPost{
+										expectGroupPostUpdate(&model.OfferGroupPost{
 											ID:              id,
 											Date:            date,
 											MessageTemplate: messageTemplate,
 											FBPostID:        facebookPostID,
-										}).Return(nil)
+										})
 
 										err := facebookPost.Update(date, user, restaurant)
 										Expect(err).To(BeNil())
@@ -601,6 +686,7 @@ var _ = Describe("Post", func() {
 										Expect(post.Message).To(Equal(messageTemplate + "\n\natitle - 5.67€\nbtitle - 4.67€"))
 										Expect(post.Published).To(BeTrue())
 										Expect(post.BackdatedTime).To(Equal(originalPublishTime))
+										Expect(updatedPublishTime).To(Equal(originalPublishTime))
 									})
 								})
 							})
@@ -655,13 +741,13 @@ var _ = Describe("Post", func() {
 												ID:     "whatever",
 												PostID: facebookPageID + "_" + photoID,
 											}, nil)
-											groupPosts.On("UpdateByID", id, &model.OfferGroupPost{
+											expectGroupPostUpdate(&model.OfferGroupPost{
 												ID:                  id,
 												Date:                date,
 												MessageTemplate:     messageTemplate,
 												FBPostID:            facebookPageID + "_" + photoID,
 												PostedImageChecksum: bluePixelChecksum,
-											}).Return(nil)
+											})
 
 											err := facebookPost.Update(date, user, restaurant)
 											Expect(err).To(BeNil())
@@ -669,6 +755,7 @@ var _ = Describe("Post", func() {
 											Expect(post.Message).To(Equal(messageTemplate + "\n\natitle - 5.67€\nbtitle - 4.67€"))
 											Expect(post.Published).To(BeTrue())
 											Expect(post.BackdatedTime).To(Equal(originalPublishTime))
+											Expect(updatedPublishTime).To(Equal(originalPublishTime))
 											Expect(post.Photo).To(Equal(bluePixelJPEGData))
 										})
 									})
@@ -681,13 +768,13 @@ var _ = Describe("Post", func() {
 											ID:     "whatever",
 											PostID: facebookPageID + "_" + photoID,
 										}, nil)
-										groupPosts.On("UpdateByID", id, &model.OfferGroupPost{
+										expectGroupPostUpdate(&model.OfferGroupPost{
 											ID:                  id,
 											Date:                date,
 											MessageTemplate:     messageTemplate,
 											FBPostID:            facebookPageID + "_" + photoID,
 											PostedImageChecksum: bluePixelChecksum,
-										}).Return(nil)
+										})
 
 										err := facebookPost.Update(date, user, restaurant)
 										Expect(err).To(BeNil())
@@ -695,6 +782,7 @@ var _ = Describe("Post", func() {
 										Expect(post.Message).To(Equal(messageTemplate + "\n\natitle - 5.67€\nbtitle - 4.67€"))
 										Expect(post.Published).To(BeTrue())
 										Expect(post.BackdatedTime).To(Equal(originalPublishTime))
+										Expect(updatedPublishTime).To(Equal(originalPublishTime))
 										Expect(post.Photo).To(Equal(bluePixelJPEGData))
 									})
 								})
@@ -706,6 +794,12 @@ var _ = Describe("Post", func() {
 								fbAPI.On("Post", facebookPageToken, facebookPostID).Return(&fbmodel.PostResponse{
 									IsPublished: false,
 								}, nil)
+								expectGroupPostUpdate(&model.OfferGroupPost{
+									ID:              id,
+									Date:            date,
+									MessageTemplate: messageTemplate,
+									FBPostID:        facebookPostID,
+								})
 							})
 
 							Context("for far future offers", func() {
@@ -737,6 +831,7 @@ var _ = Describe("Post", func() {
 
 									err := facebookPost.Update(date, user, restaurant)
 									Expect(err).To(BeNil())
+									Expect(updatedPublishTime).To(Equal(time.Date(2115, 01, 02, 8, 45, 0, 0, time.UTC)))
 								})
 							})
 
@@ -769,6 +864,7 @@ var _ = Describe("Post", func() {
 									Expect(post.Message).To(Equal(messageTemplate + "\n\natitle - 5.67€\nbtitle - 4.67€"))
 									Expect(post.Published).To(BeFalse())
 									Expect(post.ScheduledPublishTime.Sub(time.Now())).To(BeNumerically("~", 11*time.Minute, time.Second))
+									Expect(updatedPublishTime).To(Equal(post.ScheduledPublishTime))
 								})
 							})
 
@@ -801,6 +897,7 @@ var _ = Describe("Post", func() {
 									Expect(post.Message).To(Equal(messageTemplate + "\n\natitle - 5.67€\nbtitle - 4.67€"))
 									Expect(post.Published).To(BeFalse())
 									Expect(post.ScheduledPublishTime.Sub(time.Now())).To(BeNumerically("~", 11*time.Minute, time.Second))
+									Expect(updatedPublishTime).To(Equal(post.ScheduledPublishTime))
 								})
 							})
 						})
@@ -826,6 +923,15 @@ var _ = Describe("Post", func() {
 								Expect(handlerErr.Err).To(Equal(err))
 								Expect(handlerErr.Code).To(Equal(http.StatusBadGateway))
 							})
+
+							It("records the failed deletion in the audit log", func() {
+								facebookPost.Update(date, user, restaurant)
+								Expect(auditEntries).To(HaveLen(1))
+								Expect(auditEntries[0].Action).To(Equal("facebook.delete"))
+								Expect(auditEntries[0].RestaurantID).To(Equal(restaurantID))
+								Expect(auditEntries[0].Target).To(Equal(string(date)))
+								Expect(auditEntries[0].Outcome).To(Equal(model.AuditOutcomeFailure))
+							})
 						})
 
 						Context("with post deletion succeeding", func() {
@@ -833,16 +939,18 @@ var _ = Describe("Post", func() {
 								fbAPI.On("PostDelete", facebookPageToken, facebookPostID).Return(nil)
 							})
 
-							It("removes the post ID from memory", func() {
-								groupPosts.On("UpdateByID", id, &model.OfferGroupPost{
+							It("removes the post ID and the publish time from memory", func() {
+								offerGroupPost.PublishTime = time.Date(2115, 01, 02, 8, 45, 0, 0, time.UTC)
+								expectGroupPostUpdate(&model.OfferGroupPost{
 									ID:              id,
 									Date:            date,
 									MessageTemplate: messageTemplate,
 									FBPostID:        "",
-								}).Return(nil)
+								})
 
 								err := facebookPost.Update(date, user, restaurant)
 								Expect(err).To(BeNil())
+								Expect(updatedPublishTime.IsZero()).To(BeTrue())
 							})
 						})
 					})
@@ -883,4 +991,308 @@ var _ = Describe("Post", func() {
 			})
 		})
 	})
+
+	Describe("Delete", func() {
+		var (
+			post              *model.OfferGroupPost
+			facebookUserToken *oauth2.Token
+			fbAPI             *mocks.API
+		)
+
+		BeforeEach(func() {
+			facebookUserToken = &oauth2.Token{
+				AccessToken: "a user token",
+			}
+			restaurant = &model.Restaurant{
+				ID:             bson.NewObjectId(),
+				FacebookPageID: "a page ID",
+			}
+			user = &model.User{
+				Session: model.UserSession{
+					FacebookUserToken: *facebookUserToken,
+					FacebookPageTokens: []model.FacebookPageToken{model.FacebookPageToken{
+						PageID: "a page ID",
+						Token:  "a page token",
+					}},
+				},
+			}
+			post = &model.OfferGroupPost{
+				ID:           bson.NewObjectId(),
+				RestaurantID: restaurant.ID,
+				FBPostID:     "a post ID",
+			}
+			fbAPI = new(mocks.API)
+		})
+
+		AfterEach(func() {
+			fbAPI.AssertExpectations(GinkgoT())
+			groupPosts.AssertExpectations(GinkgoT())
+		})
+
+		Context("for a post that hasn't been posted to Facebook", func() {
+			BeforeEach(func() {
+				post.FBPostID = ""
+			})
+
+			It("does nothing", func() {
+				err := facebookPost.Delete(post, user, restaurant)
+				Expect(err).To(BeNil())
+			})
+		})
+
+		Context("for a post that has been posted to Facebook", func() {
+			BeforeEach(func() {
+				fbAuth.On("APIConnection", facebookUserToken).Return(fbAPI)
+				fbAPI.On("PostDelete", "a page token", "a post ID").Return(nil)
+				groupPosts.On("UpdateByID", post.ID, mock.AnythingOfType("*model.OfferGroupPost")).Return(nil)
+			})
+
+			It("deletes the post from Facebook and forgets its ID", func() {
+				err := facebookPost.Delete(post, user, restaurant)
+				Expect(err).To(BeNil())
+				Expect(post.FBPostID).To(BeEmpty())
+			})
+
+			It("records the deletion in the audit log", func() {
+				err := facebookPost.Delete(post, user, restaurant)
+				Expect(err).To(BeNil())
+				Expect(auditEntries).To(HaveLen(1))
+				Expect(auditEntries[0].Action).To(Equal("facebook.delete"))
+				Expect(auditEntries[0].ActorID).To(Equal(user.ID))
+				Expect(auditEntries[0].Outcome).To(Equal(model.AuditOutcomeSuccess))
+			})
+		})
+	})
+
+	Describe("Preview", func() {
+		var (
+			date          model.DateWithoutTime
+			storedPost    *model.OfferGroupPost
+			storedPostErr error
+		)
+
+		BeforeEach(func() {
+			date = model.DateWithoutTime("2115-01-02")
+			regions.On("GetName", "a region").Return(&model.Region{
+				Name:     "a region",
+				Location: "UTC",
+			}, nil)
+			restaurant = &model.Restaurant{
+				ID:                              bson.NewObjectId(),
+				Region:                          "a region",
+				DefaultGroupPostMessageTemplate: "a default template",
+			}
+			storedPost = nil
+			storedPostErr = mgo.ErrNotFound
+		})
+
+		JustBeforeEach(func() {
+			groupPosts.On("GetByDate", date, restaurant.ID).Return(storedPost, storedPostErr)
+		})
+
+		AfterEach(func() {
+			groupPosts.AssertExpectations(GinkgoT())
+			offersCollection.AssertExpectations(GinkgoT())
+			fbAuth.AssertNotCalled(GinkgoT(), "APIConnection", mock.Anything)
+			groupPosts.AssertNotCalled(GinkgoT(), "Insert", mock.Anything)
+		})
+
+		Context("with offers for the date", func() {
+			var pixel image.Image
+
+			BeforeEach(func() {
+				pixel = image.NewRGBA(image.Rect(0, 0, 1, 1))
+				images.On("GetOriginal", "checksum1").Return(pixel, nil)
+				offersCollection.On("GetForRestaurantWithinTimeBounds", restaurant.ID, mock.AnythingOfType("time.Time"),
+					mock.AnythingOfType("time.Time")).Return([]*model.Offer{
+					&model.Offer{
+						CommonOfferFields: model.CommonOfferFields{
+							Title:    "atitle",
+							Price:    5.67,
+							FromTime: time.Date(2115, 01, 02, 10, 0, 0, 0, time.UTC),
+						},
+						ImageChecksum: "checksum1",
+					},
+				}, nil)
+			})
+
+			It("returns the message, the publish time and the collage without posting anything", func() {
+				preview, err := facebookPost.Preview(date, restaurant)
+				Expect(err).To(BeNil())
+				Expect(preview.Message).To(Equal("a default template\n\natitle - 5.67€"))
+				Expect(preview.ScheduledPublishTime).To(Equal(time.Date(2115, 01, 02, 9, 45, 0, 0, time.UTC)))
+				Expect(preview.Image).To(HavePrefix("data:image/jpeg;base64,"))
+				Expect(preview.FBPostID).To(BeEmpty())
+				Expect(preview.Published).To(BeFalse())
+				Expect(auditEntries).To(BeEmpty())
+			})
+
+			Context("with the post already on Facebook", func() {
+				var publishTime time.Time
+
+				BeforeEach(func() {
+					publishTime = time.Date(2115, 01, 02, 8, 0, 0, 0, time.UTC)
+					storedPost = &model.OfferGroupPost{
+						RestaurantID:    restaurant.ID,
+						Date:            date,
+						MessageTemplate: "a template",
+						FBPostID:        "post ID",
+						PublishTime:     publishTime,
+					}
+					storedPostErr = nil
+				})
+
+				It("returns the stored publish time of the scheduled post", func() {
+					preview, err := facebookPost.Preview(date, restaurant)
+					Expect(err).To(BeNil())
+					Expect(preview.Message).To(Equal("a template\n\natitle - 5.67€"))
+					Expect(preview.FBPostID).To(Equal("post ID"))
+					Expect(preview.ScheduledPublishTime).To(Equal(publishTime))
+					Expect(preview.Published).To(BeFalse())
+				})
+
+				Context("with the post having been published", func() {
+					BeforeEach(func() {
+						publishTime = time.Now().Add(-time.Hour)
+						storedPost.PublishTime = publishTime
+					})
+
+					It("returns the post as published", func() {
+						preview, err := facebookPost.Preview(date, restaurant)
+						Expect(err).To(BeNil())
+						Expect(preview.ScheduledPublishTime).To(Equal(publishTime))
+						Expect(preview.Published).To(BeTrue())
+					})
+				})
+			})
+		})
+
+		Context("with collage settings", func() {
+			var (
+				red   image.Image
+				blue  image.Image
+				green image.Image
+			)
+
+			var singleColorImage = func(c color.Color, width, height int) image.Image {
+				i := image.NewRGBA(image.Rect(0, 0, width, height))
+				draw.Draw(i, i.Bounds(), image.NewUniform(c), image.ZP, draw.Src)
+				return i
+			}
+
+			var decodePreviewImage = func(dataURI string) image.Image {
+				data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(dataURI, "data:image/jpeg;base64,"))
+				Expect(err).NotTo(HaveOccurred())
+				decoded, err := jpeg.Decode(bytes.NewReader(data))
+				Expect(err).NotTo(HaveOccurred())
+				return decoded
+			}
+
+			BeforeEach(func() {
+				red = singleColorImage(color.RGBA{0xff, 0x00, 0x00, 0xff}, 400, 400)
+				blue = singleColorImage(color.RGBA{0x00, 0x00, 0xff, 0xff}, 100, 100)
+				green = singleColorImage(color.RGBA{0x00, 0xff, 0x00, 0xff}, 400, 300)
+				images.On("GetOriginal", "checksum1").Return(red, nil)
+				images.On("GetOriginal", "checksum2").Return(blue, nil)
+				offers := make([]*model.Offer, 3)
+				for i := range offers {
+					offers[i] = &model.Offer{
+						CommonOfferFields: model.CommonOfferFields{
+							Title:    "atitle",
+							FromTime: time.Date(2115, 01, 02, 10, 0, 0, 0, time.UTC),
+						},
+						ImageChecksum: "checksum" + string(rune('1'+i%2)),
+					}
+				}
+				offersCollection.On("GetForRestaurantWithinTimeBounds", restaurant.ID, mock.AnythingOfType("time.Time"),
+					mock.AnythingOfType("time.Time")).Return(offers, nil)
+			})
+
+			It("composes the collage with the configured number of images, size and borders", func() {
+				borderWidth := 0
+				restaurant.CollageSettings = &model.CollageSettings{
+					MaxImages:   2,
+					Width:       400,
+					Height:      300,
+					BorderColor: "#000000",
+					BorderWidth: &borderWidth,
+				}
+				picassoNode := new(mocks.Node)
+				collageLayout.On("Compose", []image.Image{red, blue}).Return(picassoNode)
+				picassoNode.On("DrawWithBorder", 400, 300, color.RGBA{0x00, 0x00, 0x00, 0xff}, 0).Return(green)
+				preview, err := facebookPost.Preview(date, restaurant)
+				Expect(err).To(BeNil())
+				Expect(decodePreviewImage(preview.Image).Bounds().Dx()).To(Equal(400))
+				picassoNode.AssertExpectations(GinkgoT())
+			})
+
+			It("resizes single images to the configured size", func() {
+				restaurant.CollageSettings = &model.CollageSettings{
+					MaxImages: 1,
+					Width:     400,
+					Height:    300,
+				}
+				picassoNode := new(mocks.Node)
+				collageLayout.On("Compose", []image.Image{red}).Return(picassoNode)
+				picassoNode.On("Draw", 400, 300).Return(green)
+				preview, err := facebookPost.Preview(date, restaurant)
+				Expect(err).To(BeNil())
+				bounds := decodePreviewImage(preview.Image).Bounds()
+				Expect(bounds.Dx()).To(Equal(400))
+				Expect(bounds.Dy()).To(Equal(300))
+				picassoNode.AssertExpectations(GinkgoT())
+			})
+
+			It("overlays the logo scaled down to a fifth of the collage", func() {
+				restaurant.CollageSettings = &model.CollageSettings{
+					MaxImages:    1,
+					LogoChecksum: "checksum2",
+				}
+				preview, err := facebookPost.Preview(date, restaurant)
+				Expect(err).To(BeNil())
+				collage := decodePreviewImage(preview.Image)
+				// The logo is in the bottom right corner, 10px from the edges
+				r, g, b, _ := collage.At(400-10-40, 400-10-40).RGBA()
+				Expect(b >> 8).To(BeNumerically(">", 200))
+				Expect(r >> 8).To(BeNumerically("<", 60))
+				Expect(g >> 8).To(BeNumerically("<", 60))
+				r, _, b, _ = collage.At(400-10-80-5, 400-10-40).RGBA()
+				Expect(r >> 8).To(BeNumerically(">", 200))
+				Expect(b >> 8).To(BeNumerically("<", 60))
+				// And the rest of the collage is left as it was
+				r, _, _, _ = collage.At(11, 11).RGBA()
+				Expect(r >> 8).To(BeNumerically(">", 200))
+				collageLayout.AssertNotCalled(GinkgoT(), "Compose", []image.Image{blue})
+			})
+
+			It("overlays the date on the top left corner", func() {
+				restaurant.CollageSettings = &model.CollageSettings{
+					MaxImages: 1,
+					ShowDate:  true,
+				}
+				preview, err := facebookPost.Preview(date, restaurant)
+				Expect(err).To(BeNil())
+				collage := decodePreviewImage(preview.Image)
+				// The date is on a light box, 10px from the edges
+				_, g, _, _ := collage.At(11, 11).RGBA()
+				Expect(g >> 8).To(BeNumerically(">", 200))
+				// And the rest of the collage is left as it was
+				r, g, _, _ := collage.At(200, 200).RGBA()
+				Expect(r >> 8).To(BeNumerically(">", 200))
+				Expect(g >> 8).To(BeNumerically("<", 60))
+			})
+		})
+
+		Context("without offers for the date", func() {
+			BeforeEach(func() {
+				offersCollection.On("GetForRestaurantWithinTimeBounds", restaurant.ID, mock.AnythingOfType("time.Time"),
+					mock.AnythingOfType("time.Time")).Return([]*model.Offer{}, nil)
+			})
+
+			It("fails with 404", func() {
+				_, err := facebookPost.Preview(date, restaurant)
+				Expect(err.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
 })
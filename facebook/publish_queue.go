@@ -0,0 +1,231 @@
+package facebook
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/mgo.v2"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/router"
+)
+
+const (
+	// publishQuietPeriod is how long the offers of a restaurant for a date have to stay unchanged before
+	// they're published to Facebook. Every change postpones the publishing, so that a series of edits
+	// would result in a single update of the post instead of one for every edit.
+	publishQuietPeriod = 5 * time.Minute
+	// publishMaxDelay is how long the changes can postpone the publishing at most, counting from the
+	// change that queued the job
+	publishMaxDelay = 30 * time.Minute
+	// PublishWorkers is the number of workers main starts for processing the publishing jobs
+	PublishWorkers = 4
+	// publishJobLease is how long a worker may take to process a job before the job is
+	// considered abandoned and is picked up by another worker
+	publishJobLease = 5 * time.Minute
+	// publishQueuePollInterval is how long an idle worker waits before checking for due jobs again
+	publishQueuePollInterval = 5 * time.Second
+	// publishRetryBaseDelay is the delay before the first retry of a failed job. The delay
+	// doubles with every attempt, up to publishRetryMaxDelay.
+	publishRetryBaseDelay = 30 * time.Second
+	publishRetryMaxDelay  = time.Hour
+	// publishMaxAttempts is the number of attempts after which a job is marked as failed
+	publishMaxAttempts = 8
+)
+
+// PublishQueue is a Post that updates the restaurants' posts on Facebook in the background,
+// so that a slow or failing Graph API wouldn't fail the requests that change the offers.
+// Update queues a job for the restaurant's post to run once the offers have been left unchanged
+// for the quiet period, or once the maximum delay has passed since the change that queued it. The jobs are stored in the DB, so the pending updates survive restarts.
+// The workers then process the jobs, retrying with an exponential backoff. Delete is passed straight
+// through to the underlying Post.
+type PublishQueue interface {
+	Post
+	// ProcessNext claims and processes the next due job. Returns false if no jobs were due.
+	ProcessNext() (bool, error)
+	// Run starts the workers that process the jobs, logging the errors. It never returns.
+	Run(workers int)
+}
+
+func NewPublishQueue(jobs db.PublishJobs, post Post, users db.Users, restaurants db.Restaurants) PublishQueue {
+	return publishQueue{
+		jobs:        jobs,
+		post:        post,
+		users:       users,
+		restaurants: restaurants,
+	}
+}
+
+type publishQueue struct {
+	jobs        db.PublishJobs
+	post        Post
+	users       db.Users
+	restaurants db.Restaurants
+}
+
+func (q publishQueue) Update(date model.DateWithoutTime, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+	if restaurant.FacebookPageID == "" {
+		return nil
+	}
+	now := time.Now()
+	if err := q.jobs.Enqueue(restaurant.ID, date, user.ID, now.Add(publishQuietPeriod), now.Add(publishMaxDelay)); err != nil {
+		return router.NewHandlerError(err, "Failed to queue the offers for publishing on Facebook", http.StatusInternalServerError)
+	}
+	return nil
+}
+
+func (q publishQueue) Delete(post *model.OfferGroupPost, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+	return q.post.Delete(post, user, restaurant)
+}
+
+// Preview schedules the post from when the restaurant's pending job will run, or, if there are no pending jobs,
+// from when the job queued by the next change would run. Posts that are already on Facebook keep their stored
+// publish time, unless the pending job will reschedule them.
+func (q publishQueue) Preview(date model.DateWithoutTime, restaurant *model.Restaurant) (*PostPreview, *router.HandlerError) {
+	preview, handlerErr := q.post.Preview(date, restaurant)
+	if handlerErr != nil {
+		return nil, handlerErr
+	} else if preview.Published {
+		return preview, nil
+	}
+	runAt := time.Now().Add(publishQuietPeriod)
+	job, err := q.jobs.GetByDate(date, restaurant.ID)
+	if err == nil && job.Status == model.PublishJobPending {
+		runAt = job.RunAt
+	} else if err != nil && err != mgo.ErrNotFound {
+		return nil, router.NewHandlerError(err, "Failed to fetch the publishing job for that date", http.StatusInternalServerError)
+	} else if preview.FBPostID != "" {
+		return preview, nil
+	}
+	if now := time.Now(); runAt.Before(now) {
+		runAt = now
+	}
+	preview.ScheduledPublishTime = scheduledPublishTime(preview.earliestPublishTime, runAt)
+	return preview, nil
+}
+
+func (q publishQueue) Run(workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work()
+		}()
+	}
+	wg.Wait()
+}
+
+func (q publishQueue) work() {
+	for {
+		processed, err := q.ProcessNext()
+		if err != nil {
+			log.Printf("Failed to process a Facebook publishing job: %v", err)
+		}
+		if !processed {
+			time.Sleep(publishQueuePollInterval)
+		}
+	}
+}
+
+func (q publishQueue) ProcessNext() (bool, error) {
+	job, err := q.jobs.Claim(publishJobLease)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	restaurant, err := q.restaurants.GetID(job.RestaurantID)
+	if err == mgo.ErrNotFound {
+		return true, q.jobs.Fail(job, "The restaurant no longer exists")
+	} else if err != nil {
+		return true, q.retry(job, err.Error())
+	} else if restaurant.FacebookPageID == "" {
+		// The page has been unlinked since the job was queued
+		return true, q.jobs.Complete(job)
+	}
+	user, err := q.publisherFor(job, restaurant.FacebookPageID)
+	if err == mgo.ErrNotFound {
+		return true, q.jobs.Fail(job, "None of the users can post on the restaurant's Facebook page")
+	} else if err != nil {
+		return true, q.retry(job, err.Error())
+	}
+	if handlerErr := q.post.Update(job.Date, user, restaurant); handlerErr != nil {
+		log.Printf("Attempt %d of publishing the post of restaurant %s for %s failed: %s: %v", job.Attempts,
+			job.RestaurantID.Hex(), job.Date, handlerErr.Message, handlerErr.Err)
+		return true, q.retry(job, handlerErr.Message)
+	}
+	return true, q.jobs.Complete(job)
+}
+
+// publisherFor finds the user whose tokens are used to post on the page. The user who queued the job is
+// preferred, so that the post would be published on their behalf, but they may not be able to post on
+// the page, e.g. because they log in with an email address, or may have been removed since. Then another
+// user who has a token for the page is used, preferring the users whose tokens are known to be valid.
+// Returns mgo.ErrNotFound if none of the users have a token for the page.
+func (q publishQueue) publisherFor(job *model.PublishJob, pageID string) (*model.User, error) {
+	user, err := q.users.GetID(job.UserID)
+	if err == nil && canPostOn(user, pageID) {
+		return user, nil
+	} else if err != nil && err != mgo.ErrNotFound {
+		return nil, err
+	}
+	pageAdmins, err := q.users.GetForFacebookPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+	for _, pageAdmin := range pageAdmins {
+		if canPostOn(pageAdmin, pageID) {
+			return pageAdmin, nil
+		}
+	}
+	for _, pageAdmin := range pageAdmins {
+		if getPageAccessToken(pageAdmin, pageID) != "" {
+			// The token health is only checked periodically, so the tokens might work nevertheless
+			return pageAdmin, nil
+		}
+	}
+	return nil, mgo.ErrNotFound
+}
+
+func canPostOn(user *model.User, pageID string) bool {
+	if getPageAccessToken(user, pageID) == "" {
+		return false
+	}
+	health := user.Session.FacebookTokenHealth
+	if health.UserTokenInvalid {
+		return false
+	}
+	for _, invalidPageID := range health.InvalidPageIDs {
+		if invalidPageID == pageID {
+			return false
+		}
+	}
+	return true
+}
+
+// retry schedules the job to be attempted again after the backoff or marks it as failed if
+// it has run out of attempts
+func (q publishQueue) retry(job *model.PublishJob, lastError string) error {
+	if job.Attempts >= publishMaxAttempts {
+		return q.jobs.Fail(job, fmt.Sprintf("%s (gave up after %d attempts)", lastError, job.Attempts))
+	}
+	return q.jobs.Retry(job, time.Now().Add(retryDelay(job.Attempts)), lastError)
+}
+
+// retryDelay returns the delay before the next attempt of a job that has failed the
+// specified number of times
+func retryDelay(attempts int) time.Duration {
+	delay := publishRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= publishRetryMaxDelay {
+			return publishRetryMaxDelay
+		}
+	}
+	return delay
+}
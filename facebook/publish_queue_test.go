@@ -0,0 +1,365 @@
+package facebook_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/facebook"
+	"github.com/Lunchr/luncher-api/facebook/mocks"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PublishQueue", func() {
+	var (
+		queue       facebook.PublishQueue
+		jobs        *mocks.PublishJobs
+		post        *mocks.Post
+		users       *mocks.Users
+		restaurants *mocks.Restaurants
+		user        *model.User
+		restaurant  *model.Restaurant
+		date        model.DateWithoutTime
+	)
+
+	BeforeEach(func() {
+		jobs = new(mocks.PublishJobs)
+		post = new(mocks.Post)
+		users = new(mocks.Users)
+		restaurants = new(mocks.Restaurants)
+		queue = facebook.NewPublishQueue(jobs, post, users, restaurants)
+		user = &model.User{ID: bson.NewObjectId()}
+		restaurant = &model.Restaurant{
+			ID:             bson.NewObjectId(),
+			FacebookPageID: "page ID",
+		}
+		date = model.DateWithoutTime("2016-03-04")
+	})
+
+	AfterEach(func() {
+		jobs.AssertExpectations(GinkgoT())
+		post.AssertExpectations(GinkgoT())
+	})
+
+	Describe("Update", func() {
+		It("should queue a job for the restaurant's post to run after the quiet period, but within the maximum delay", func() {
+			jobs.On("Enqueue", restaurant.ID, date, user.ID, mock.AnythingOfType("time.Time"),
+				mock.AnythingOfType("time.Time")).Return(nil).Run(func(args mock.Arguments) {
+				runAt := args.Get(3).(time.Time)
+				Expect(runAt.Sub(time.Now())).To(BeNumerically("~", 5*time.Minute, time.Second))
+				deadline := args.Get(4).(time.Time)
+				Expect(deadline.Sub(time.Now())).To(BeNumerically("~", 30*time.Minute, time.Second))
+			})
+			handlerErr := queue.Update(date, user, restaurant)
+			Expect(handlerErr).To(BeNil())
+		})
+
+		It("should fail if the job can't be stored", func() {
+			jobs.On("Enqueue", restaurant.ID, date, user.ID, mock.AnythingOfType("time.Time"),
+				mock.AnythingOfType("time.Time")).Return(errors.New("something went wrong"))
+			handlerErr := queue.Update(date, user, restaurant)
+			Expect(handlerErr.Code).To(Equal(http.StatusInternalServerError))
+		})
+
+		Context("with a restaurant without a Facebook page", func() {
+			BeforeEach(func() {
+				restaurant.FacebookPageID = ""
+			})
+
+			It("should do nothing", func() {
+				handlerErr := queue.Update(date, user, restaurant)
+				Expect(handlerErr).To(BeNil())
+				jobs.AssertNotCalled(GinkgoT(), "Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+	})
+
+	Describe("Preview", func() {
+		var preview *facebook.PostPreview
+
+		BeforeEach(func() {
+			preview = &facebook.PostPreview{Message: "a message"}
+			post.On("Preview", date, restaurant).Return(preview, nil)
+		})
+
+		Context("with a pending job", func() {
+			var runAt time.Time
+
+			BeforeEach(func() {
+				runAt = time.Now().Add(3 * time.Minute)
+				jobs.On("GetByDate", date, restaurant.ID).Return(&model.PublishJob{
+					Status: model.PublishJobPending,
+					RunAt:  runAt,
+				}, nil)
+			})
+
+			It("should schedule the post from when the job runs", func() {
+				result, handlerErr := queue.Preview(date, restaurant)
+				Expect(handlerErr).To(BeNil())
+				Expect(result.Message).To(Equal("a message"))
+				Expect(result.ScheduledPublishTime).To(Equal(runAt.Add(11 * time.Minute)))
+			})
+
+			Context("with the post already on Facebook", func() {
+				BeforeEach(func() {
+					preview.FBPostID = "post ID"
+				})
+
+				It("should reschedule the post from when the job runs", func() {
+					result, handlerErr := queue.Preview(date, restaurant)
+					Expect(handlerErr).To(BeNil())
+					Expect(result.ScheduledPublishTime).To(Equal(runAt.Add(11 * time.Minute)))
+				})
+			})
+		})
+
+		Context("without a pending job", func() {
+			BeforeEach(func() {
+				jobs.On("GetByDate", date, restaurant.ID).Return(&model.PublishJob{
+					Status: model.PublishJobDone,
+					RunAt:  time.Now().Add(-time.Hour),
+				}, nil)
+			})
+
+			It("should schedule the post from after the quiet period", func() {
+				result, handlerErr := queue.Preview(date, restaurant)
+				Expect(handlerErr).To(BeNil())
+				Expect(result.ScheduledPublishTime.Sub(time.Now())).To(BeNumerically("~", 16*time.Minute, time.Second))
+			})
+
+			Context("with the post already on Facebook", func() {
+				var publishTime = time.Date(2115, 01, 02, 10, 0, 0, 0, time.UTC)
+
+				BeforeEach(func() {
+					preview.FBPostID = "post ID"
+					preview.ScheduledPublishTime = publishTime
+				})
+
+				It("should keep the post's publish time", func() {
+					result, handlerErr := queue.Preview(date, restaurant)
+					Expect(handlerErr).To(BeNil())
+					Expect(result.ScheduledPublishTime).To(Equal(publishTime))
+				})
+			})
+		})
+
+		Context("without any jobs for the date", func() {
+			BeforeEach(func() {
+				jobs.On("GetByDate", date, restaurant.ID).Return(nil, mgo.ErrNotFound)
+			})
+
+			It("should schedule the post from after the quiet period", func() {
+				result, handlerErr := queue.Preview(date, restaurant)
+				Expect(handlerErr).To(BeNil())
+				Expect(result.ScheduledPublishTime.Sub(time.Now())).To(BeNumerically("~", 16*time.Minute, time.Second))
+			})
+		})
+
+		Context("with the jobs failing to load", func() {
+			BeforeEach(func() {
+				jobs.On("GetByDate", date, restaurant.ID).Return(nil, errors.New("something went wrong"))
+			})
+
+			It("should fail", func() {
+				_, handlerErr := queue.Preview(date, restaurant)
+				Expect(handlerErr.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
+		Context("with the post already published", func() {
+			var publishTime = time.Date(2015, 01, 02, 10, 0, 0, 0, time.UTC)
+
+			BeforeEach(func() {
+				preview.FBPostID = "post ID"
+				preview.ScheduledPublishTime = publishTime
+				preview.Published = true
+			})
+
+			It("should return the post's publish state", func() {
+				result, handlerErr := queue.Preview(date, restaurant)
+				Expect(handlerErr).To(BeNil())
+				Expect(result.Published).To(BeTrue())
+				Expect(result.ScheduledPublishTime).To(Equal(publishTime))
+				jobs.AssertNotCalled(GinkgoT(), "GetByDate", mock.Anything, mock.Anything)
+			})
+		})
+	})
+
+	Describe("ProcessNext", func() {
+		It("should report when no jobs are due", func() {
+			jobs.On("Claim", mock.AnythingOfType("time.Duration")).Return(nil, mgo.ErrNotFound)
+			processed, err := queue.ProcessNext()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(processed).To(BeFalse())
+		})
+
+		Context("with a due job", func() {
+			var job *model.PublishJob
+
+			BeforeEach(func() {
+				job = &model.PublishJob{
+					ID:           bson.NewObjectId(),
+					RestaurantID: restaurant.ID,
+					Date:         date,
+					UserID:       user.ID,
+					Status:       model.PublishJobPending,
+					Attempts:     1,
+				}
+				user.Session.FacebookPageTokens = []model.FacebookPageToken{{PageID: "page ID", Token: "page token"}}
+				jobs.On("Claim", mock.AnythingOfType("time.Duration")).Return(job, nil)
+				users.On("GetID", user.ID).Return(user, nil)
+				restaurants.On("GetID", restaurant.ID).Return(restaurant, nil)
+			})
+
+			It("should update the post and complete the job", func() {
+				post.On("Update", date, user, restaurant).Return(nil)
+				jobs.On("Complete", job).Return(nil)
+				processed, err := queue.ProcessNext()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(processed).To(BeTrue())
+			})
+
+			Context("with the job queued by a user who can't post on the page", func() {
+				var (
+					editor       *model.User
+					pageAdmin    *model.User
+					expiredAdmin *model.User
+				)
+
+				BeforeEach(func() {
+					editor = &model.User{ID: bson.NewObjectId(), Email: "editor@restaurant.test"}
+					job.UserID = editor.ID
+					pageAdmin = &model.User{
+						ID: bson.NewObjectId(),
+						Session: model.UserSession{
+							FacebookPageTokens: []model.FacebookPageToken{{PageID: "page ID", Token: "page token"}},
+						},
+					}
+					expiredAdmin = &model.User{
+						ID: bson.NewObjectId(),
+						Session: model.UserSession{
+							FacebookPageTokens:  []model.FacebookPageToken{{PageID: "page ID", Token: "expired token"}},
+							FacebookTokenHealth: model.FacebookTokenHealth{InvalidPageIDs: []string{"page ID"}},
+						},
+					}
+					users.On("GetID", editor.ID).Return(editor, nil)
+				})
+
+				It("should publish with the tokens of a user who can post on the page", func() {
+					users.On("GetForFacebookPage", "page ID").Return([]*model.User{expiredAdmin, pageAdmin}, nil)
+					post.On("Update", date, pageAdmin, restaurant).Return(nil)
+					jobs.On("Complete", job).Return(nil)
+					_, err := queue.ProcessNext()
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("should try the tokens that are known to be invalid if there are no others", func() {
+					users.On("GetForFacebookPage", "page ID").Return([]*model.User{expiredAdmin}, nil)
+					post.On("Update", date, expiredAdmin, restaurant).Return(nil)
+					jobs.On("Complete", job).Return(nil)
+					_, err := queue.ProcessNext()
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("should fail the job if nobody can post on the page", func() {
+					users.On("GetForFacebookPage", "page ID").Return([]*model.User{}, nil)
+					jobs.On("Fail", job, "None of the users can post on the restaurant's Facebook page").Return(nil)
+					_, err := queue.ProcessNext()
+					Expect(err).NotTo(HaveOccurred())
+					post.AssertNotCalled(GinkgoT(), "Update", mock.Anything, mock.Anything, mock.Anything)
+				})
+			})
+
+			Context("with the job queued by a user who has been removed since", func() {
+				BeforeEach(func() {
+					job.UserID = bson.NewObjectId()
+					users.On("GetID", job.UserID).Return(nil, mgo.ErrNotFound)
+					users.On("GetForFacebookPage", "page ID").Return([]*model.User{user}, nil)
+				})
+
+				It("should publish with the tokens of another user", func() {
+					post.On("Update", date, user, restaurant).Return(nil)
+					jobs.On("Complete", job).Return(nil)
+					_, err := queue.ProcessNext()
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			Context("with the restaurant's page unlinked since", func() {
+				BeforeEach(func() {
+					restaurant.FacebookPageID = ""
+				})
+
+				It("should complete the job without posting", func() {
+					jobs.On("Complete", job).Return(nil)
+					_, err := queue.ProcessNext()
+					Expect(err).NotTo(HaveOccurred())
+					post.AssertNotCalled(GinkgoT(), "Update", mock.Anything, mock.Anything, mock.Anything)
+				})
+			})
+
+			Context("with Facebook failing", func() {
+				BeforeEach(func() {
+					post.On("Update", date, user, restaurant).Return(router.NewHandlerError(errors.New("timeout"),
+						"Failed to post the offers to Facebook", http.StatusBadGateway))
+				})
+
+				It("should retry the job after a backoff", func() {
+					jobs.On("Retry", job, mock.AnythingOfType("time.Time"), "Failed to post the offers to Facebook").Return(
+						func(job *model.PublishJob, runAt time.Time, lastError string) error {
+							Expect(runAt).To(BeTemporally("~", time.Now().Add(30*time.Second), time.Second))
+							return nil
+						})
+					_, err := queue.ProcessNext()
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("should back off exponentially", func() {
+					job.Attempts = 3
+					jobs.On("Retry", job, mock.AnythingOfType("time.Time"), mock.AnythingOfType("string")).Return(
+						func(job *model.PublishJob, runAt time.Time, lastError string) error {
+							Expect(runAt).To(BeTemporally("~", time.Now().Add(2*time.Minute), time.Second))
+							return nil
+						})
+					_, err := queue.ProcessNext()
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("should give up after the last attempt", func() {
+					job.Attempts = 8
+					jobs.On("Fail", job, mock.AnythingOfType("string")).Return(nil)
+					_, err := queue.ProcessNext()
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+		})
+
+		Context("with a job for a restaurant that no longer exists", func() {
+			BeforeEach(func() {
+				job := &model.PublishJob{
+					ID:           bson.NewObjectId(),
+					RestaurantID: restaurant.ID,
+					Date:         date,
+					UserID:       user.ID,
+					Attempts:     1,
+				}
+				jobs.On("Claim", mock.AnythingOfType("time.Duration")).Return(job, nil)
+				restaurants.On("GetID", restaurant.ID).Return(nil, mgo.ErrNotFound)
+				jobs.On("Fail", job, mock.AnythingOfType("string")).Return(nil)
+			})
+
+			It("should fail the job right away", func() {
+				processed, err := queue.ProcessNext()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(processed).To(BeTrue())
+			})
+		})
+	})
+})
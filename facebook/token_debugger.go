@@ -0,0 +1,91 @@
+package facebook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TokenDebugger inspects access tokens through the debug_token endpoint of the Graph API
+type TokenDebugger interface {
+	Debug(token string) (*TokenInfo, error)
+}
+
+// TokenInfo describes an access token as seen by Facebook
+type TokenInfo struct {
+	IsValid bool
+	// ExpiresAt is zero for tokens that never expire, e.g. page tokens derived
+	// from long-term user tokens
+	ExpiresAt time.Time
+	// DataAccessExpiresAt is when the app loses access to the user's data unless
+	// the user logs in again. It is zero if not applicable.
+	DataAccessExpiresAt time.Time
+}
+
+func NewTokenDebugger(conf *Config) TokenDebugger {
+	return tokenDebugger{conf}
+}
+
+type tokenDebugger struct {
+	conf *Config
+}
+
+type debugTokenResponse struct {
+	Data  debugTokenData `json:"data"`
+	Error *graphError    `json:"error"`
+}
+
+type debugTokenData struct {
+	IsValid             bool  `json:"is_valid"`
+	ExpiresAt           int64 `json:"expires_at"`
+	DataAccessExpiresAt int64 `json:"data_access_expires_at"`
+}
+
+type graphError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+func (d tokenDebugger) Debug(token string) (*TokenInfo, error) {
+	parameters := url.Values{
+		"input_token": {token},
+	}
+	endpoint := strings.TrimSuffix(d.conf.GraphURL, "/") + "/debug_token?" + parameters.Encode()
+	request, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The app access token is sent in a header, so that it wouldn't end up in the errors and logs with the URL
+	request.Header.Set("Authorization", "OAuth "+d.conf.AppID+"|"+d.conf.AppSecret)
+	httpResponse, err := d.conf.httpClient().Do(request)
+	if urlErr, ok := err.(*url.Error); ok {
+		// The URL includes the token that's inspected
+		return nil, fmt.Errorf("Facebook token debugging failed! (%v)", urlErr.Err)
+	} else if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	var response debugTokenResponse
+	if err = json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("Facebook token debugging failed! (%d - %s)", response.Error.Code, response.Error.Message)
+	}
+	return &TokenInfo{
+		IsValid:             response.Data.IsValid,
+		ExpiresAt:           unixTimeOrZero(response.Data.ExpiresAt),
+		DataAccessExpiresAt: unixTimeOrZero(response.Data.DataAccessExpiresAt),
+	}, nil
+}
+
+func unixTimeOrZero(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
@@ -0,0 +1,109 @@
+package facebook_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Lunchr/luncher-api/facebook"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TokenDebugger", func() {
+	var (
+		server       *httptest.Server
+		lastRequest  *http.Request
+		responseBody string
+		debugger     facebook.TokenDebugger
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lastRequest = r
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(responseBody))
+		}))
+		debugger = facebook.NewTokenDebugger(&facebook.Config{
+			AppID:     "appid",
+			AppSecret: "appsecret",
+			GraphURL:  server.URL + "/",
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("with a valid expiring token", func() {
+		BeforeEach(func() {
+			responseBody = `{"data": {"app_id": "appid", "is_valid": true, "expires_at": 1500000000,
+				"data_access_expires_at": 1600000000}}`
+		})
+
+		It("should ask about the token using the app access token", func() {
+			_, err := debugger.Debug("a token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lastRequest.URL.Path).To(Equal("/debug_token"))
+			Expect(lastRequest.URL.Query().Get("input_token")).To(Equal("a token"))
+			Expect(lastRequest.URL.Query().Get("access_token")).To(BeEmpty())
+			Expect(lastRequest.Header.Get("Authorization")).To(Equal("OAuth appid|appsecret"))
+		})
+
+		It("should return the expiry times", func() {
+			info, err := debugger.Debug("a token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.IsValid).To(BeTrue())
+			Expect(info.ExpiresAt).To(Equal(time.Unix(1500000000, 0)))
+			Expect(info.DataAccessExpiresAt).To(Equal(time.Unix(1600000000, 0)))
+		})
+	})
+
+	Context("with a token that never expires", func() {
+		BeforeEach(func() {
+			responseBody = `{"data": {"is_valid": true, "expires_at": 0}}`
+		})
+
+		It("should return a zero expiry time", func() {
+			info, err := debugger.Debug("a token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.ExpiresAt.IsZero()).To(BeTrue())
+		})
+	})
+
+	Context("with an invalidated token", func() {
+		BeforeEach(func() {
+			responseBody = `{"data": {"is_valid": false, "error": {"code": 190, "message": "Session has expired"}}}`
+		})
+
+		It("should report the token as invalid", func() {
+			info, err := debugger.Debug("a token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.IsValid).To(BeFalse())
+		})
+	})
+
+	Context("with the app credentials rejected", func() {
+		BeforeEach(func() {
+			responseBody = `{"error": {"code": 190, "message": "Invalid OAuth access token."}}`
+		})
+
+		It("should fail", func() {
+			_, err := debugger.Debug("a token")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("with the Graph API unreachable", func() {
+		BeforeEach(func() {
+			server.Close()
+		})
+
+		It("should fail without exposing the tokens", func() {
+			_, err := debugger.Debug("a token")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).NotTo(ContainSubstring("appsecret"))
+			Expect(err.Error()).NotTo(ContainSubstring("a+token"))
+		})
+	})
+})
@@ -0,0 +1,98 @@
+package facebook
+
+import (
+	"log"
+	"time"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+)
+
+const (
+	// TokenCheckInterval is how often the TokenMonitor started from main checks all the tokens
+	TokenCheckInterval = 6 * time.Hour
+	// tokenRenewalPeriod is how long before a user token expires we start asking the user to
+	// log in again. Long-term user tokens can't be extended without the user's involvement.
+	tokenRenewalPeriod = 7 * 24 * time.Hour
+)
+
+// TokenMonitor checks the validity of the Facebook tokens stored for the users and marks
+// the users who have to log in through Facebook again for their restaurants' posts to
+// keep working
+type TokenMonitor interface {
+	// CheckAll checks the tokens of all users who have logged in through Facebook
+	CheckAll() error
+	// Run calls CheckAll every interval, logging the errors. It never returns.
+	Run(interval time.Duration)
+}
+
+func NewTokenMonitor(users db.Users, debugger TokenDebugger) TokenMonitor {
+	return tokenMonitor{
+		users:    users,
+		debugger: debugger,
+	}
+}
+
+type tokenMonitor struct {
+	users    db.Users
+	debugger TokenDebugger
+}
+
+func (m tokenMonitor) Run(interval time.Duration) {
+	for {
+		if err := m.CheckAll(); err != nil {
+			log.Printf("Failed to check the Facebook tokens: %v", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (m tokenMonitor) CheckAll() error {
+	iter := m.users.GetAll()
+	var user model.User
+	for iter.Next(&user) {
+		if user.Session.FacebookUserToken.AccessToken == "" {
+			continue
+		}
+		health, err := m.check(&user.Session, time.Now())
+		if err != nil {
+			// Skip the user and try again on the next round, as the problem is most likely
+			// not specific to the user
+			log.Printf("Failed to check the Facebook tokens of user %s: %v", user.ID.Hex(), err)
+			continue
+		}
+		if err = m.users.SetFacebookTokenHealth(user.ID, health); err != nil {
+			iter.Close()
+			return err
+		}
+		user = model.User{}
+	}
+	return iter.Close()
+}
+
+func (m tokenMonitor) check(userSession *model.UserSession, now time.Time) (model.FacebookTokenHealth, error) {
+	userTokenInfo, err := m.debugger.Debug(userSession.FacebookUserToken.AccessToken)
+	if err != nil {
+		return model.FacebookTokenHealth{}, err
+	}
+	health := model.FacebookTokenHealth{
+		CheckedAt: now,
+		ExpiresAt: userTokenInfo.ExpiresAt,
+		UserTokenInvalid: !userTokenInfo.IsValid || expiresSoon(userTokenInfo.ExpiresAt, now) ||
+			expiresSoon(userTokenInfo.DataAccessExpiresAt, now),
+	}
+	for _, pageToken := range userSession.FacebookPageTokens {
+		pageTokenInfo, err := m.debugger.Debug(pageToken.Token)
+		if err != nil {
+			return model.FacebookTokenHealth{}, err
+		}
+		if !pageTokenInfo.IsValid || expiresSoon(pageTokenInfo.ExpiresAt, now) {
+			health.InvalidPageIDs = append(health.InvalidPageIDs, pageToken.PageID)
+		}
+	}
+	return health, nil
+}
+
+func expiresSoon(expiresAt, now time.Time) bool {
+	return !expiresAt.IsZero() && expiresAt.Before(now.Add(tokenRenewalPeriod))
+}
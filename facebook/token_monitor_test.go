@@ -0,0 +1,149 @@
+package facebook_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/facebook"
+	"github.com/Lunchr/luncher-api/facebook/mocks"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/oauth2"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TokenMonitor", func() {
+	var (
+		monitor  facebook.TokenMonitor
+		users    *mocks.Users
+		debugger *mocks.TokenDebugger
+		user     *model.User
+		health   model.FacebookTokenHealth
+	)
+
+	BeforeEach(func() {
+		users = new(mocks.Users)
+		debugger = new(mocks.TokenDebugger)
+		monitor = facebook.NewTokenMonitor(users, debugger)
+		user = &model.User{
+			ID: bson.NewObjectId(),
+			Session: model.UserSession{
+				FacebookUserToken: oauth2.Token{AccessToken: "user token"},
+				FacebookPageTokens: []model.FacebookPageToken{
+					{PageID: "page1", Token: "page token 1"},
+					{PageID: "page2", Token: "page token 2"},
+				},
+			},
+		}
+		emailUser := &model.User{
+			ID:    bson.NewObjectId(),
+			Email: "mary@example.com",
+		}
+		users.On("GetAll").Return(&mockUserIter{users: []*model.User{emailUser, user}})
+		users.On("SetFacebookTokenHealth", user.ID, mock.AnythingOfType("model.FacebookTokenHealth")).Return(func(id bson.ObjectId, h model.FacebookTokenHealth) error {
+			health = h
+			return nil
+		})
+		debugger.On("Debug", "page token 1").Return(&facebook.TokenInfo{IsValid: true}, nil)
+	})
+
+	Context("with all tokens valid for a long time", func() {
+		var expiresAt time.Time
+
+		BeforeEach(func() {
+			expiresAt = time.Now().Add(50 * 24 * time.Hour)
+			debugger.On("Debug", "user token").Return(&facebook.TokenInfo{IsValid: true, ExpiresAt: expiresAt}, nil)
+			debugger.On("Debug", "page token 2").Return(&facebook.TokenInfo{IsValid: true}, nil)
+		})
+
+		It("should mark the user healthy", func() {
+			err := monitor.CheckAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(health.NeedsReauth()).To(BeFalse())
+			Expect(health.InvalidPageIDs).To(BeEmpty())
+			Expect(health.ExpiresAt).To(Equal(expiresAt))
+			Expect(health.CheckedAt).To(BeTemporally("~", time.Now(), time.Second))
+		})
+
+		It("should skip the users without Facebook tokens", func() {
+			monitor.CheckAll()
+			users.AssertNumberOfCalls(GinkgoT(), "SetFacebookTokenHealth", 1)
+		})
+	})
+
+	Context("with the user token about to expire", func() {
+		BeforeEach(func() {
+			debugger.On("Debug", "user token").Return(&facebook.TokenInfo{IsValid: true, ExpiresAt: time.Now().Add(24 * time.Hour)}, nil)
+			debugger.On("Debug", "page token 2").Return(&facebook.TokenInfo{IsValid: true}, nil)
+		})
+
+		It("should ask the user to log in again", func() {
+			err := monitor.CheckAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(health.UserTokenInvalid).To(BeTrue())
+		})
+	})
+
+	Context("with the data access about to expire", func() {
+		BeforeEach(func() {
+			debugger.On("Debug", "user token").Return(&facebook.TokenInfo{IsValid: true, DataAccessExpiresAt: time.Now().Add(24 * time.Hour)}, nil)
+			debugger.On("Debug", "page token 2").Return(&facebook.TokenInfo{IsValid: true}, nil)
+		})
+
+		It("should ask the user to log in again", func() {
+			err := monitor.CheckAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(health.UserTokenInvalid).To(BeTrue())
+		})
+	})
+
+	Context("with a page token invalidated", func() {
+		BeforeEach(func() {
+			debugger.On("Debug", "user token").Return(&facebook.TokenInfo{IsValid: true}, nil)
+			debugger.On("Debug", "page token 2").Return(&facebook.TokenInfo{IsValid: false}, nil)
+		})
+
+		It("should list the page and ask the user to log in again", func() {
+			err := monitor.CheckAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(health.UserTokenInvalid).To(BeFalse())
+			Expect(health.NeedsReauth()).To(BeTrue())
+			Expect(health.InvalidPageIDs).To(Equal([]string{"page2"}))
+		})
+	})
+
+	Context("with Facebook failing", func() {
+		BeforeEach(func() {
+			debugger.On("Debug", "user token").Return(nil, errors.New("something went wrong"))
+		})
+
+		It("should leave the user's health as it was", func() {
+			err := monitor.CheckAll()
+			Expect(err).NotTo(HaveOccurred())
+			users.AssertNotCalled(GinkgoT(), "SetFacebookTokenHealth", user.ID, mock.Anything)
+		})
+	})
+})
+
+type mockUserIter struct {
+	users []*model.User
+	i     int
+	db.UserIter
+}
+
+func (m *mockUserIter) Next(user *model.User) bool {
+	if m.i >= len(m.users) {
+		return false
+	}
+	*user = *m.users[m.i]
+	m.i++
+	return true
+}
+
+func (m *mockUserIter) Close() error {
+	return nil
+}
@@ -0,0 +1,199 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCacheTTL is how long successful geocoding results are cached for
+	DefaultCacheTTL = 30 * 24 * time.Hour
+	// DefaultNegativeCacheTTL is how long failed geocoding results are cached for.
+	// Failures are cached for a shorter time, because they are more likely to be
+	// fixed by the provider.
+	DefaultNegativeCacheTTL = 24 * time.Hour
+	// reverseCacheRegion is the region of the keys of the reverse geocoding results. NewCacheKey
+	// lowercases the regions, so it can't collide with the keys of the forward geocoding results.
+	reverseCacheRegion = "REVERSE"
+)
+
+// ErrorCacheMiss should be returned by a Cache if it doesn't have an entry for the key
+var ErrorCacheMiss = errors.New("Geocoding result not found in cache.")
+
+// Cache stores geocoding results
+type Cache interface {
+	Get(CacheKey) (*CacheEntry, error)
+	Set(CacheKey, *CacheEntry) error
+}
+
+// CacheKey identifies a geocoding request. Use NewCacheKey to create normalized keys.
+type CacheKey struct {
+	// Provider is the geocoding provider the result is from, so that switching providers
+	// wouldn't serve the results of the previous one
+	Provider string
+	Address  string
+	Region   string
+}
+
+// CacheEntry is a geocoding result as stored in a Cache
+type CacheEntry struct {
+	Candidates []Candidate
+	// Address is the result of reverse geocoding
+	Address *Address
+	// Error holds the name of the geocoder's error for this request, if it returned one
+	Error     string
+	ExpiresAt time.Time
+}
+
+// NewCacheKey creates a key that's insensitive to the case and spacing of the address
+func NewCacheKey(provider, address, region string) CacheKey {
+	return CacheKey{
+		Provider: providerName(provider),
+		Address:  strings.Join(strings.Fields(strings.ToLower(address)), " "),
+		Region:   strings.ToLower(normalize(region)),
+	}
+}
+
+// NewReverseCacheKey creates a key for reverse geocoding the location. The coordinates
+// are rounded to about 10 meters, so that nearby locations share the result.
+func NewReverseCacheKey(provider string, location Location) CacheKey {
+	return CacheKey{
+		Provider: providerName(provider),
+		Address:  fmt.Sprintf("%.4f,%.4f", location.Lat, location.Lng),
+		Region:   reverseCacheRegion,
+	}
+}
+
+// providerName returns the name of the provider NewCoder picks for the configured provider
+func providerName(provider string) string {
+	if provider == "" {
+		return ProviderGoogle
+	}
+	return provider
+}
+
+// CachingCoder is a Coder that caches the results of another Coder
+type CachingCoder struct {
+	coder       Coder
+	provider    string
+	cache       Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	bypass      bool
+}
+
+// NewCachingCoder wraps the coder of the provider so that its results are stored in and, if
+// available, retrieved from the cache
+func NewCachingCoder(coder Coder, provider string, cache Cache, ttl, negativeTTL time.Duration) *CachingCoder {
+	return &CachingCoder{
+		coder:       coder,
+		provider:    provider,
+		cache:       cache,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// Bypassing returns a Coder that always asks the underlying Coder and then
+// refreshes the cache with the result
+func (c *CachingCoder) Bypassing() Coder {
+	bypassing := *c
+	bypassing.bypass = true
+	return &bypassing
+}
+
+func (c *CachingCoder) Code(address string) ([]Candidate, error) {
+	return c.cachedCandidates(NewCacheKey(c.provider, address, ""), func() ([]Candidate, error) {
+		return c.coder.Code(address)
+	})
+}
+
+func (c *CachingCoder) CodeForRegion(address, region string) ([]Candidate, error) {
+	return c.cachedCandidates(NewCacheKey(c.provider, address, region), func() ([]Candidate, error) {
+		return c.coder.CodeForRegion(address, region)
+	})
+}
+
+// ReverseCode caches the results by the rounded coordinates of the location
+func (c *CachingCoder) ReverseCode(location Location) (Address, error) {
+	entry, err := c.cached(NewReverseCacheKey(c.provider, location), func() (*CacheEntry, error) {
+		address, err := c.coder.ReverseCode(location)
+		if err != nil {
+			return &CacheEntry{}, err
+		}
+		return &CacheEntry{Address: &address}, nil
+	})
+	if entry.Address == nil {
+		return Address{}, err
+	}
+	return *entry.Address, err
+}
+
+// Suggest isn't cached, because the input changes with every keystroke
+func (c *CachingCoder) Suggest(input, region string) ([]Address, error) {
+	return c.coder.Suggest(input, region)
+}
+
+func (c *CachingCoder) cachedCandidates(key CacheKey, code func() ([]Candidate, error)) ([]Candidate, error) {
+	entry, err := c.cached(key, func() (*CacheEntry, error) {
+		candidates, err := code()
+		return &CacheEntry{Candidates: candidates}, err
+	})
+	return entry.Candidates, err
+}
+
+func (c *CachingCoder) cached(key CacheKey, code func() (*CacheEntry, error)) (*CacheEntry, error) {
+	if !c.bypass {
+		entry, err := c.cache.Get(key)
+		if err == nil && entry.usable() {
+			return entry, cachedErrors[entry.Error]
+		} else if err != nil && err != ErrorCacheMiss {
+			// A broken cache shouldn't prevent geocoding
+			log.Println(err)
+		}
+	}
+	entry, err := code()
+	errorName, cacheable := cacheableErrorName(err)
+	if !cacheable {
+		return entry, err
+	}
+	ttl := c.ttl
+	if err != nil {
+		ttl = c.negativeTTL
+	}
+	entry.Error = errorName
+	entry.ExpiresAt = time.Now().Add(ttl)
+	if cacheErr := c.cache.Set(key, entry); cacheErr != nil {
+		log.Println(cacheErr)
+	}
+	return entry, err
+}
+
+// cachedErrors lists the errors that are a result of the address itself, rather
+// than, for example, a temporary network failure, and are therefore worth caching
+var cachedErrors = map[string]error{
+	"":           nil,
+	"no_results": ErrorNoResults,
+}
+
+// usable checks that the entry hasn't expired and that it isn't left over from
+// an older format of the cache
+func (e *CacheEntry) usable() bool {
+	if !e.ExpiresAt.After(time.Now()) {
+		return false
+	}
+	cachedErr, known := cachedErrors[e.Error]
+	return known && (cachedErr != nil || len(e.Candidates) > 0 || e.Address != nil)
+}
+
+func cacheableErrorName(err error) (string, bool) {
+	for name, cachedErr := range cachedErrors {
+		if err == cachedErr {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,235 @@
+package geo_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/Lunchr/luncher-api/geo"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CachingCoder", func() {
+	var (
+		coder        *fakeCoder
+		cache        *fakeCache
+		cachingCoder *CachingCoder
+	)
+
+	BeforeEach(func() {
+		coder = &fakeCoder{
+			candidates: []Candidate{
+				{FormattedAddress: "Küüni 5, Tartu", Location: Location{Lat: 58.38, Lng: 26.72}, Quality: MatchExact},
+			},
+		}
+		cache = &fakeCache{entries: make(map[CacheKey]*CacheEntry)}
+		cachingCoder = NewCachingCoder(coder, ProviderGoogle, cache, time.Hour, time.Minute)
+	})
+
+	Describe("NewCacheKey", func() {
+		It("ignores the case and spacing of the address", func() {
+			Expect(NewCacheKey(ProviderGoogle, " Küüni  5, TARTU", "EE")).To(Equal(NewCacheKey(ProviderGoogle, "küüni 5, tartu", "ee")))
+		})
+
+		It("distinguishes the providers", func() {
+			Expect(NewCacheKey(ProviderGoogle, "Küüni 5, Tartu", "ee")).NotTo(Equal(NewCacheKey(ProviderNominatim, "Küüni 5, Tartu", "ee")))
+			Expect(NewCacheKey("", "Küüni 5, Tartu", "ee")).To(Equal(NewCacheKey(ProviderGoogle, "Küüni 5, Tartu", "ee")))
+		})
+	})
+
+	Context("with nothing cached", func() {
+		It("asks the underlying coder", func() {
+			candidates, err := cachingCoder.CodeForRegion("Küüni 5, Tartu", "ee")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(candidates).To(Equal(coder.candidates))
+			Expect(coder.calls).To(Equal(1))
+		})
+
+		It("caches the result", func() {
+			cachingCoder.CodeForRegion("Küüni 5, Tartu", "ee")
+			candidates, err := cachingCoder.CodeForRegion("küüni 5, tartu", "ee")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(candidates).To(Equal(coder.candidates))
+			Expect(coder.calls).To(Equal(1))
+			entry := cache.entries[NewCacheKey(ProviderGoogle, "Küüni 5, Tartu", "ee")]
+			Expect(entry.ExpiresAt.Sub(time.Now())).To(BeNumerically("~", time.Hour, time.Second))
+		})
+
+		Context("with the underlying coder not finding the address", func() {
+			BeforeEach(func() {
+				coder.candidates = nil
+				coder.err = ErrorNoResults
+			})
+
+			It("caches the failure for the negative TTL", func() {
+				cachingCoder.Code("gibberish")
+				_, err := cachingCoder.Code("gibberish")
+				Expect(err).To(Equal(ErrorNoResults))
+				Expect(coder.calls).To(Equal(1))
+				entry := cache.entries[NewCacheKey(ProviderGoogle, "gibberish", "")]
+				Expect(entry.ExpiresAt.Sub(time.Now())).To(BeNumerically("~", time.Minute, time.Second))
+			})
+		})
+
+		Context("with the underlying coder failing for other reasons", func() {
+			BeforeEach(func() {
+				coder.err = errors.New("something went wrong")
+			})
+
+			It("doesn't cache the failure", func() {
+				cachingCoder.Code("Küüni 5, Tartu")
+				_, err := cachingCoder.Code("Küüni 5, Tartu")
+				Expect(err).To(Equal(coder.err))
+				Expect(coder.calls).To(Equal(2))
+			})
+		})
+	})
+
+	Describe("ReverseCode", func() {
+		BeforeEach(func() {
+			coder.address = Address{FormattedAddress: "Küüni 5, Tartu", CountryCode: "ee"}
+		})
+
+		It("caches the result for nearby locations", func() {
+			cachingCoder.ReverseCode(Location{Lat: 58.378012, Lng: 26.729011})
+			address, err := cachingCoder.ReverseCode(Location{Lat: 58.378021, Lng: 26.728998})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(address).To(Equal(coder.address))
+			Expect(coder.calls).To(Equal(1))
+		})
+
+		It("asks the underlying coder for more distant locations", func() {
+			cachingCoder.ReverseCode(Location{Lat: 58.3780, Lng: 26.7290})
+			cachingCoder.ReverseCode(Location{Lat: 58.3790, Lng: 26.7290})
+			Expect(coder.calls).To(Equal(2))
+		})
+
+		It("doesn't collide with forward geocoding", func() {
+			cachingCoder.ReverseCode(Location{Lat: 58.378, Lng: 26.729})
+			candidates, err := cachingCoder.Code("58.3780,26.7290")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(candidates).To(Equal(coder.candidates))
+			Expect(coder.calls).To(Equal(2))
+		})
+
+		Context("with the underlying coder not finding an address", func() {
+			BeforeEach(func() {
+				coder.err = ErrorNoResults
+			})
+
+			It("caches the failure", func() {
+				cachingCoder.ReverseCode(Location{Lat: 1, Lng: 2})
+				_, err := cachingCoder.ReverseCode(Location{Lat: 1, Lng: 2})
+				Expect(err).To(Equal(ErrorNoResults))
+				Expect(coder.calls).To(Equal(1))
+			})
+		})
+	})
+
+	Context("with an expired entry cached", func() {
+		BeforeEach(func() {
+			cache.entries[NewCacheKey(ProviderGoogle, "Küüni 5, Tartu", "ee")] = &CacheEntry{
+				Candidates: []Candidate{{Location: Location{Lat: 1, Lng: 2}}},
+				ExpiresAt:  time.Now().Add(-time.Minute),
+			}
+		})
+
+		It("asks the underlying coder", func() {
+			candidates, _ := cachingCoder.CodeForRegion("Küüni 5, Tartu", "ee")
+			Expect(candidates).To(Equal(coder.candidates))
+			Expect(coder.calls).To(Equal(1))
+		})
+	})
+
+	Context("with an entry without candidates cached", func() {
+		BeforeEach(func() {
+			cache.entries[NewCacheKey(ProviderGoogle, "Küüni 5, Tartu", "ee")] = &CacheEntry{
+				ExpiresAt: time.Now().Add(time.Minute),
+			}
+		})
+
+		It("asks the underlying coder", func() {
+			candidates, _ := cachingCoder.CodeForRegion("Küüni 5, Tartu", "ee")
+			Expect(candidates).To(Equal(coder.candidates))
+			Expect(coder.calls).To(Equal(1))
+		})
+	})
+
+	Context("with multiple candidates cached", func() {
+		var cachedCandidates []Candidate
+
+		BeforeEach(func() {
+			cachedCandidates = []Candidate{
+				{FormattedAddress: "Küüni 5, Tartu", Location: Location{Lat: 1, Lng: 2}, Quality: MatchExact},
+				{FormattedAddress: "Küüni 5, Viljandi", Location: Location{Lat: 3, Lng: 4}, Quality: MatchExact},
+			}
+			cache.entries[NewCacheKey(ProviderGoogle, "Küüni 5, Tartu", "ee")] = &CacheEntry{
+				Candidates: cachedCandidates,
+				ExpiresAt:  time.Now().Add(time.Minute),
+			}
+		})
+
+		It("returns the cached candidates", func() {
+			candidates, err := cachingCoder.CodeForRegion("Küüni 5, Tartu", "ee")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(candidates).To(Equal(cachedCandidates))
+			Expect(coder.calls).To(Equal(0))
+		})
+
+		Describe("Bypassing", func() {
+			It("asks the underlying coder and refreshes the cache", func() {
+				candidates, err := cachingCoder.Bypassing().CodeForRegion("Küüni 5, Tartu", "ee")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(candidates).To(Equal(coder.candidates))
+				Expect(coder.calls).To(Equal(1))
+				entry := cache.entries[NewCacheKey(ProviderGoogle, "Küüni 5, Tartu", "ee")]
+				Expect(entry.Candidates).To(Equal(coder.candidates))
+			})
+		})
+	})
+})
+
+type fakeCoder struct {
+	candidates []Candidate
+	address    Address
+	err        error
+	calls      int
+}
+
+func (c *fakeCoder) Code(address string) ([]Candidate, error) {
+	c.calls++
+	return c.candidates, c.err
+}
+
+func (c *fakeCoder) CodeForRegion(address, region string) ([]Candidate, error) {
+	c.calls++
+	return c.candidates, c.err
+}
+
+func (c *fakeCoder) ReverseCode(location Location) (Address, error) {
+	c.calls++
+	return c.address, c.err
+}
+
+func (c *fakeCoder) Suggest(input, region string) ([]Address, error) {
+	c.calls++
+	return nil, c.err
+}
+
+type fakeCache struct {
+	entries map[CacheKey]*CacheEntry
+}
+
+func (c *fakeCache) Get(key CacheKey) (*CacheEntry, error) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, ErrorCacheMiss
+	}
+	return entry, nil
+}
+
+func (c *fakeCache) Set(key CacheKey, entry *CacheEntry) error {
+	c.entries[key] = entry
+	return nil
+}
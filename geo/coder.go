@@ -1,48 +1,43 @@
 package geo
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"math"
 	"net/url"
 	"strings"
 )
 
-var (
-	// ErrorPartialMatch will be used when the geocoder API responds with a partial
-	// match. In this case, the received location will still be included in the
-	// return values, so the client can decide whether to continue or not.
-	ErrorPartialMatch = errors.New("Geocoder returned a partial match.")
-)
-
-const apiEndpoint = "https://maps.googleapis.com/maps/api/geocode/json"
+// ErrorNoResults will be used when the geocoder API couldn't find the address.
+var ErrorNoResults = errors.New("Geocoder returned no results.")
 
 // Coder is an object that knows how to geocode an address
 type Coder interface {
-	Code(address string) (Location, error)
-	CodeForRegion(address, region string) (Location, error)
-}
-
-type coder struct {
-	conf *Config
-}
-
-func NewCoder(conf *Config) Coder {
-	return coder{conf}
+	// Code returns all the candidates the geocoder found for the address, the
+	// best match first. ErrorNoResults is returned instead of an empty list.
+	Code(address string) ([]Candidate, error)
+	// CodeForRegion is like Code, but prefers the results in the region
+	CodeForRegion(address, region string) ([]Candidate, error)
+	// ReverseCode finds the address of the location
+	ReverseCode(Location) (Address, error)
+	// Suggest lists addresses that match the (possibly partial or misspelled) input
+	// within the region
+	Suggest(input, region string) ([]Address, error)
 }
 
-func (c coder) Code(address string) (Location, error) {
-	parameters := c.paramsForAddress(address)
-	url := urlFor(parameters)
-	return fetch(url)
-}
-
-func (c coder) CodeForRegion(address, region string) (Location, error) {
-	parameters := c.paramsForAddress(address)
-	parameters.Add("region", escape(region))
-	url := urlFor(parameters)
-	return fetch(url)
+// NewCoder creates a Coder for the provider specified in the configuration
+func NewCoder(conf *Config) (Coder, error) {
+	switch conf.Provider {
+	case ProviderGoogle, "":
+		if conf.APIKey == "" {
+			return nil, errors.New("An API key is required for the Google geocoder")
+		}
+		return googleCoder{conf}, nil
+	case ProviderNominatim:
+		return nominatimCoder{conf}, nil
+	default:
+		return nil, fmt.Errorf("Unknown geocoding provider: %s", conf.Provider)
+	}
 }
 
 // Location defines a geographical coordinate
@@ -51,63 +46,86 @@ type Location struct {
 	Lng float64 `json:"lng"`
 }
 
-type response struct {
-	Status       string   `json:"status"`
-	ErrorMessage string   `json:"error_message"`
-	Results      []result `json:"results"`
-}
+// earthRadius is the mean radius of the Earth in meters
+const earthRadius = 6371000
 
-type result struct {
-	Geometry     geometry `json:"geometry"`
-	PartialMatch bool     `json:"partial_match"`
+// IsValid checks that the latitude and the longitude are within their ranges
+func (l Location) IsValid() bool {
+	return l.Lat >= -90 && l.Lat <= 90 && l.Lng >= -180 && l.Lng <= 180
 }
 
-type geometry struct {
-	Location Location `json:"location"`
+// DistanceTo returns the great-circle distance to the other location in meters
+func (l Location) DistanceTo(other Location) float64 {
+	lat1, lat2 := l.Lat*math.Pi/180, other.Lat*math.Pi/180
+	dLat, dLng := lat2-lat1, (other.Lng-l.Lng)*math.Pi/180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
 }
 
-func (c coder) paramsForAddress(address string) url.Values {
-	return url.Values{
-		"address": {escape(address)},
-		"key":     {c.conf.APIKey},
-	}
-}
+// MatchQuality describes how well a Candidate matches the geocoded address
+type MatchQuality string
+
+const (
+	// MatchExact means that the geocoder found the exact address
+	MatchExact MatchQuality = "exact"
+	// MatchApproximate means that the geocoder found the address, but only knows its
+	// approximate location, e.g. the street or the neighbourhood
+	MatchApproximate MatchQuality = "approximate"
+	// MatchPartial means that the geocoder only matched a part of the address
+	MatchPartial MatchQuality = "partial"
+)
 
-func escape(s string) string {
-	return url.QueryEscape(strings.TrimSpace(s))
+// Candidate is one of the possible matches for a geocoded address
+type Candidate struct {
+	FormattedAddress string       `json:"formatted_address" bson:"formatted_address"`
+	Location         Location     `json:"location" bson:"location"`
+	Quality          MatchQuality `json:"quality" bson:"quality"`
 }
 
-func urlFor(params url.Values) string {
-	return fmt.Sprintf("%s?%s", apiEndpoint, params.Encode())
+// Unambiguous returns the location of the only candidate if it's an exact match.
+// Otherwise the user should be asked to pick one of the candidates.
+func Unambiguous(candidates []Candidate) (Location, bool) {
+	if len(candidates) != 1 || candidates[0].Quality != MatchExact {
+		return Location{}, false
+	}
+	return candidates[0].Location, true
 }
 
-func fetch(url string) (Location, error) {
-	httpResponse, err := http.Get(url)
-	if err != nil {
-		return Location{}, err
-	}
-	defer httpResponse.Body.Close()
+// Address describes a location in human readable terms
+type Address struct {
+	FormattedAddress string   `json:"formatted_address" bson:"formatted_address"`
+	Street           string   `json:"street,omitempty" bson:"street,omitempty"`
+	HouseNumber      string   `json:"house_number,omitempty" bson:"house_number,omitempty"`
+	Neighbourhood    string   `json:"neighbourhood,omitempty" bson:"neighbourhood,omitempty"`
+	City             string   `json:"city,omitempty" bson:"city,omitempty"`
+	CountryCode      string   `json:"country_code" bson:"country_code"`
+	Location         Location `json:"location" bson:"location"`
+}
 
-	var response = new(response)
-	err = json.NewDecoder(httpResponse.Body).Decode(response)
-	if err != nil {
-		return Location{}, err
+// CountryCodeForCCTLD maps the ccTLDs used for the regions to lower case ISO 3166-1
+// alpha-2 country codes, which is what the geocoders use. The two only differ for
+// a few countries.
+func CountryCodeForCCTLD(cctld string) string {
+	cctld = strings.ToLower(normalize(cctld))
+	if cctld == "uk" {
+		return "gb"
 	}
+	return cctld
+}
 
-	if response.Status != "OK" {
-		if response.ErrorMessage != "" {
-			return Location{}, fmt.Errorf("Geocoder service error!  (%s - %s)", response.Status, response.ErrorMessage)
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
 		}
-		return Location{}, fmt.Errorf("Geocoder service error!  (%s)", response.Status)
-	}
-	if len(response.Results) > 1 {
-		return Location{}, errors.New("More than one response received from the Geocoder service")
-	}
-	result := response.Results[0]
-	location := result.Geometry.Location
-	if result.PartialMatch {
-		return location, ErrorPartialMatch
 	}
+	return ""
+}
+
+func urlFor(endpoint string, params url.Values) string {
+	return fmt.Sprintf("%s?%s", endpoint, params.Encode())
+}
 
-	return location, nil
+func normalize(s string) string {
+	return strings.TrimSpace(s)
 }
@@ -0,0 +1,391 @@
+package geo_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/Lunchr/luncher-api/geo"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Coder", func() {
+	var (
+		server       *httptest.Server
+		lastRequest  *http.Request
+		responseBody string
+		conf         *Config
+		coder        Coder
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lastRequest = r
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(responseBody))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		coder, err = NewCoder(conf)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("NewCoder", func() {
+		BeforeEach(func() {
+			conf = &Config{
+				Provider: ProviderNominatim,
+			}
+		})
+
+		It("should require an API key for Google", func() {
+			_, err := NewCoder(&Config{
+				Provider: ProviderGoogle,
+			})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail for an unknown provider", func() {
+			_, err := NewCoder(&Config{
+				Provider: "gibberish",
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Google", func() {
+		BeforeEach(func() {
+			conf = &Config{
+				Provider: ProviderGoogle,
+				BaseURL:  server.URL + "/",
+				APIKey:   "a key",
+			}
+		})
+
+		Context("with a single exact match", func() {
+			BeforeEach(func() {
+				responseBody = `{"status": "OK", "results": [{"formatted_address": "Küüni 5, Tartu, Estonia",
+					"geometry": {"location": {"lat": 58.38, "lng": 26.72}, "location_type": "ROOFTOP"}}]}`
+			})
+
+			It("should return the exact match", func() {
+				candidates, err := coder.CodeForRegion(" Küüni 5, Tartu ", "ee")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(candidates).To(Equal([]Candidate{{
+					FormattedAddress: "Küüni 5, Tartu, Estonia",
+					Location:         Location{Lat: 58.38, Lng: 26.72},
+					Quality:          MatchExact,
+				}}))
+				location, ok := Unambiguous(candidates)
+				Expect(ok).To(BeTrue())
+				Expect(location).To(Equal(Location{Lat: 58.38, Lng: 26.72}))
+			})
+
+			It("should include the address, region and key in the request", func() {
+				coder.CodeForRegion(" Küüni 5, Tartu ", "ee")
+				query := lastRequest.URL.Query()
+				Expect(lastRequest.URL.Path).To(Equal("/geocode/json"))
+				Expect(query.Get("address")).To(Equal("Küüni 5, Tartu"))
+				Expect(query.Get("region")).To(Equal("ee"))
+				Expect(query.Get("key")).To(Equal("a key"))
+			})
+		})
+
+		Context("with a partial match", func() {
+			BeforeEach(func() {
+				responseBody = `{"status": "OK", "results": [{"geometry": {"location": {"lat": 58.38, "lng": 26.72}, "location_type": "ROOFTOP"}, "partial_match": true}]}`
+			})
+
+			It("should return a partial candidate", func() {
+				candidates, err := coder.Code("Küüni 5, Tartu")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(candidates).To(HaveLen(1))
+				Expect(candidates[0].Quality).To(Equal(MatchPartial))
+				_, ok := Unambiguous(candidates)
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		Context("with an approximate match", func() {
+			BeforeEach(func() {
+				responseBody = `{"status": "OK", "results": [{"geometry": {"location": {"lat": 58.38, "lng": 26.72}, "location_type": "GEOMETRIC_CENTER"}}]}`
+			})
+
+			It("should return an approximate candidate", func() {
+				candidates, err := coder.Code("Küüni, Tartu")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(candidates).To(HaveLen(1))
+				Expect(candidates[0].Quality).To(Equal(MatchApproximate))
+			})
+		})
+
+		Context("with multiple results", func() {
+			BeforeEach(func() {
+				responseBody = `{"status": "OK", "results": [{"geometry": {"location": {"lat": 58.38, "lng": 26.72}, "location_type": "ROOFTOP"}},
+					{"geometry": {"location": {"lat": 59.43, "lng": 24.75}, "location_type": "ROOFTOP"}}]}`
+			})
+
+			It("should return all the candidates", func() {
+				candidates, err := coder.Code("Küüni 5")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(candidates).To(HaveLen(2))
+				Expect(candidates[0].Location).To(Equal(Location{Lat: 58.38, Lng: 26.72}))
+				Expect(candidates[1].Location).To(Equal(Location{Lat: 59.43, Lng: 24.75}))
+				_, ok := Unambiguous(candidates)
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		Context("with no results", func() {
+			BeforeEach(func() {
+				responseBody = `{"status": "ZERO_RESULTS", "results": []}`
+			})
+
+			It("should return ErrorNoResults", func() {
+				_, err := coder.Code("gibberish")
+				Expect(err).To(Equal(ErrorNoResults))
+			})
+		})
+
+		Describe("ReverseCode", func() {
+			BeforeEach(func() {
+				responseBody = `{"status": "OK", "results": [{
+					"formatted_address": "Küüni 5, 51004 Tartu, Estonia",
+					"address_components": [
+						{"long_name": "5", "short_name": "5", "types": ["street_number"]},
+						{"long_name": "Küüni", "short_name": "Küüni", "types": ["route"]},
+						{"long_name": "Kesklinn", "short_name": "Kesklinn", "types": ["neighborhood", "political"]},
+						{"long_name": "Tartu", "short_name": "Tartu", "types": ["locality", "political"]},
+						{"long_name": "Estonia", "short_name": "EE", "types": ["country", "political"]}
+					],
+					"geometry": {"location": {"lat": 58.38, "lng": 26.72}}
+				}]}`
+			})
+
+			It("should return the address", func() {
+				address, err := coder.ReverseCode(Location{Lat: 58.38, Lng: 26.72})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(address).To(Equal(Address{
+					FormattedAddress: "Küüni 5, 51004 Tartu, Estonia",
+					Street:           "Küüni",
+					HouseNumber:      "5",
+					Neighbourhood:    "Kesklinn",
+					City:             "Tartu",
+					CountryCode:      "ee",
+					Location:         Location{Lat: 58.38, Lng: 26.72},
+				}))
+			})
+
+			It("should include the location in the request", func() {
+				coder.ReverseCode(Location{Lat: 58.38, Lng: 26.72})
+				Expect(lastRequest.URL.Query().Get("latlng")).To(Equal("58.38,26.72"))
+			})
+		})
+
+		Describe("Suggest", func() {
+			BeforeEach(func() {
+				responseBody = `{"status": "OK", "results": [
+					{"formatted_address": "Küüni 5, Tartu, Estonia", "geometry": {"location": {"lat": 58.38, "lng": 26.72}}},
+					{"formatted_address": "Küüni 5, Viljandi, Estonia", "geometry": {"location": {"lat": 58.36, "lng": 25.59}}}
+				]}`
+			})
+
+			It("should return all the results", func() {
+				addresses, err := coder.Suggest("Kyyni 5", "ee")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(addresses).To(HaveLen(2))
+				Expect(addresses[1].FormattedAddress).To(Equal("Küüni 5, Viljandi, Estonia"))
+			})
+
+			It("should limit the results to the region's country", func() {
+				coder.Suggest("Kyyni 5", "uk")
+				Expect(lastRequest.URL.Query().Get("components")).To(Equal("country:gb"))
+			})
+
+			Context("with no results", func() {
+				BeforeEach(func() {
+					responseBody = `{"status": "ZERO_RESULTS", "results": []}`
+				})
+
+				It("should return no suggestions", func() {
+					addresses, err := coder.Suggest("gibberish", "ee")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(addresses).To(BeEmpty())
+				})
+			})
+		})
+
+		Context("with the service responding with an error", func() {
+			BeforeEach(func() {
+				responseBody = `{"status": "REQUEST_DENIED", "error_message": "invalid key"}`
+			})
+
+			It("should fail", func() {
+				_, err := coder.Code("Küüni 5, Tartu")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid key"))
+			})
+		})
+	})
+
+	Describe("Nominatim", func() {
+		BeforeEach(func() {
+			conf = &Config{
+				Provider:  ProviderNominatim,
+				BaseURL:   server.URL + "/",
+				UserAgent: "luncher-test",
+			}
+		})
+
+		Context("with a single match", func() {
+			BeforeEach(func() {
+				responseBody = `[{"lat": "58.38", "lon": "26.72", "display_name": "Küüni 5, Tartu, Estonia", "address": {"house_number": "5"}}]`
+			})
+
+			It("should return the exact match", func() {
+				candidates, err := coder.CodeForRegion("Küüni 5, Tartu", "ee")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(candidates).To(Equal([]Candidate{{
+					FormattedAddress: "Küüni 5, Tartu, Estonia",
+					Location:         Location{Lat: 58.38, Lng: 26.72},
+					Quality:          MatchExact,
+				}}))
+			})
+
+			It("should search within the region's country", func() {
+				coder.CodeForRegion("Küüni 5, Tartu", "uk")
+				query := lastRequest.URL.Query()
+				Expect(lastRequest.URL.Path).To(Equal("/search"))
+				Expect(query.Get("q")).To(Equal("Küüni 5, Tartu"))
+				Expect(query.Get("format")).To(Equal("json"))
+				Expect(query.Get("countrycodes")).To(Equal("gb"))
+			})
+
+			It("should identify itself with the configured user agent", func() {
+				coder.Code("Küüni 5, Tartu")
+				Expect(lastRequest.Header.Get("User-Agent")).To(Equal("luncher-test"))
+			})
+		})
+
+		Context("with multiple results", func() {
+			BeforeEach(func() {
+				responseBody = `[{"lat": "58.38", "lon": "26.72", "address": {"house_number": "5"}}, {"lat": "59.43", "lon": "24.75"}]`
+			})
+
+			It("should return all the candidates", func() {
+				candidates, err := coder.Code("Küüni 5")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(candidates).To(HaveLen(2))
+				Expect(candidates[0].Quality).To(Equal(MatchExact))
+				Expect(candidates[1].Location).To(Equal(Location{Lat: 59.43, Lng: 24.75}))
+				Expect(candidates[1].Quality).To(Equal(MatchApproximate))
+			})
+		})
+
+		Context("with only the city matching", func() {
+			BeforeEach(func() {
+				responseBody = `[{"lat": "58.38", "lon": "26.72", "addresstype": "city", "address": {"city": "Tartu"}}]`
+			})
+
+			It("should return a partial match", func() {
+				candidates, err := coder.Code("Nonexistent 5, Tartu")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(candidates).To(HaveLen(1))
+				Expect(candidates[0].Quality).To(Equal(MatchPartial))
+			})
+		})
+
+		Context("with no results", func() {
+			BeforeEach(func() {
+				responseBody = `[]`
+			})
+
+			It("should return ErrorNoResults", func() {
+				_, err := coder.Code("gibberish")
+				Expect(err).To(Equal(ErrorNoResults))
+			})
+		})
+
+		Describe("ReverseCode", func() {
+			BeforeEach(func() {
+				responseBody = `{"lat": "58.38", "lon": "26.72", "display_name": "5, Küüni, Kesklinn, Tartu, Estonia",
+					"address": {"house_number": "5", "road": "Küüni", "suburb": "Kesklinn", "city": "Tartu", "country_code": "ee"}}`
+			})
+
+			It("should return the address", func() {
+				address, err := coder.ReverseCode(Location{Lat: 58.38, Lng: 26.72})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(address).To(Equal(Address{
+					FormattedAddress: "5, Küüni, Kesklinn, Tartu, Estonia",
+					Street:           "Küüni",
+					HouseNumber:      "5",
+					Neighbourhood:    "Kesklinn",
+					City:             "Tartu",
+					CountryCode:      "ee",
+					Location:         Location{Lat: 58.38, Lng: 26.72},
+				}))
+			})
+
+			It("should include the location in the request", func() {
+				coder.ReverseCode(Location{Lat: 58.38, Lng: 26.72})
+				query := lastRequest.URL.Query()
+				Expect(lastRequest.URL.Path).To(Equal("/reverse"))
+				Expect(query.Get("lat")).To(Equal("58.38"))
+				Expect(query.Get("lon")).To(Equal("26.72"))
+			})
+
+			Context("with no address found", func() {
+				BeforeEach(func() {
+					responseBody = `{"error": "Unable to geocode"}`
+				})
+
+				It("should return ErrorNoResults", func() {
+					_, err := coder.ReverseCode(Location{Lat: 0, Lng: 0})
+					Expect(err).To(Equal(ErrorNoResults))
+				})
+			})
+		})
+
+		Describe("Suggest", func() {
+			BeforeEach(func() {
+				responseBody = `[{"lat": "58.38", "lon": "26.72", "display_name": "Küüni 5, Tartu", "address": {"town": "Tartu"}},
+					{"lat": "58.36", "lon": "25.59", "display_name": "Küüni 5, Viljandi", "address": {"village": "Viljandi"}}]`
+			})
+
+			It("should return all the results", func() {
+				addresses, err := coder.Suggest("Kyyni 5", "ee")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(addresses).To(HaveLen(2))
+				Expect(addresses[1].City).To(Equal("Viljandi"))
+			})
+
+			It("should search within the region's country", func() {
+				coder.Suggest("Kyyni 5", "uk")
+				query := lastRequest.URL.Query()
+				Expect(query.Get("countrycodes")).To(Equal("gb"))
+				Expect(query.Get("addressdetails")).To(Equal("1"))
+			})
+		})
+	})
+})
+
+var _ = Describe("Location", func() {
+	It("should validate the ranges of the coordinates", func() {
+		Expect(Location{Lat: 58.38, Lng: 26.72}.IsValid()).To(BeTrue())
+		Expect(Location{Lat: 90.1, Lng: 26.72}.IsValid()).To(BeFalse())
+		Expect(Location{Lat: 58.38, Lng: -180.1}.IsValid()).To(BeFalse())
+	})
+
+	It("should calculate the distance between locations", func() {
+		tartu := Location{Lat: 58.3780, Lng: 26.7290}
+		tallinn := Location{Lat: 59.4370, Lng: 24.7536}
+		Expect(tartu.DistanceTo(tallinn)).To(BeNumerically("~", 163500, 1000))
+		Expect(tartu.DistanceTo(tartu)).To(BeZero())
+	})
+})
@@ -1,17 +1,65 @@
 package geo
 
-import "github.com/deiwin/gonfigure"
+import (
+	"net/http"
+	"strings"
+
+	"github.com/deiwin/gonfigure"
+)
+
+const (
+	// ProviderGoogle selects the Google Maps Geocoding API
+	ProviderGoogle = "google"
+	// ProviderNominatim selects an OpenStreetMap Nominatim compatible API
+	ProviderNominatim = "nominatim"
+)
 
 var (
-	apiKeyProperty = gonfigure.NewRequiredEnvProperty("GOOGLE_GEOCODING_API_KEY")
+	providerProperty  = gonfigure.NewEnvProperty("GEOCODING_PROVIDER", ProviderGoogle)
+	baseURLProperty   = gonfigure.NewEnvProperty("GEOCODING_BASE_URL", "")
+	apiKeyProperty    = gonfigure.NewEnvProperty("GOOGLE_GEOCODING_API_KEY", "")
+	userAgentProperty = gonfigure.NewEnvProperty("GEOCODING_USER_AGENT", "luncher-api")
 )
 
 type Config struct {
+	// Provider is either ProviderGoogle or ProviderNominatim
+	Provider string
+	// BaseURL overrides the root of the provider's API, e.g.
+	// "https://maps.googleapis.com/maps/api" for Google or
+	// "https://nominatim.openstreetmap.org" for Nominatim
+	BaseURL string
+	// APIKey is required for the Google provider
 	APIKey string
+	// UserAgent identifies the application to the provider, as is required by the
+	// Nominatim usage policy
+	UserAgent string
+	// HTTPClient is used to make the requests to the provider. http.DefaultClient
+	// is used if not set.
+	HTTPClient *http.Client
 }
 
 func NewConfig() *Config {
 	return &Config{
-		APIKey: apiKeyProperty.Value(),
+		Provider:  providerProperty.Value(),
+		BaseURL:   baseURLProperty.Value(),
+		APIKey:    apiKeyProperty.Value(),
+		UserAgent: userAgentProperty.Value(),
+	}
+}
+
+// endpoint returns the URL of the path in the provider's API, rooted at the BaseURL if
+// it's set
+func (c *Config) endpoint(defaultBaseURL, path string) string {
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return strings.TrimSuffix(baseURL, "/") + path
+}
+
+func (c *Config) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
 	}
+	return c.HTTPClient
 }
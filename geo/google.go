@@ -0,0 +1,166 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	googleBaseURL     = "https://maps.googleapis.com/maps/api"
+	googleGeocodePath = "/geocode/json"
+)
+
+type googleCoder struct {
+	conf *Config
+}
+
+func (c googleCoder) Code(address string) ([]Candidate, error) {
+	parameters := c.paramsForAddress(address)
+	return c.fetch(parameters)
+}
+
+func (c googleCoder) CodeForRegion(address, region string) ([]Candidate, error) {
+	parameters := c.paramsForAddress(address)
+	parameters.Add("region", normalize(region))
+	return c.fetch(parameters)
+}
+
+func (c googleCoder) ReverseCode(location Location) (Address, error) {
+	parameters := url.Values{
+		"latlng": {fmt.Sprintf("%s,%s", strconv.FormatFloat(location.Lat, 'f', -1, 64), strconv.FormatFloat(location.Lng, 'f', -1, 64))},
+		"key":    {c.conf.APIKey},
+	}
+	results, err := c.get(parameters)
+	if err != nil {
+		return Address{}, err
+	}
+	// The results are ordered from the most to the least specific
+	return results[0].address(), nil
+}
+
+// Suggest uses the geocoding API with a country filter, because the geocoder is
+// quite forgiving with partial and misspelled addresses
+func (c googleCoder) Suggest(input, region string) ([]Address, error) {
+	parameters := c.paramsForAddress(input)
+	parameters.Add("components", "country:"+CountryCodeForCCTLD(region))
+	results, err := c.get(parameters)
+	if err == ErrorNoResults {
+		return []Address{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	addresses := make([]Address, len(results))
+	for i, result := range results {
+		addresses[i] = result.address()
+	}
+	return addresses, nil
+}
+
+type googleResponse struct {
+	Status       string         `json:"status"`
+	ErrorMessage string         `json:"error_message"`
+	Results      []googleResult `json:"results"`
+}
+
+type googleResult struct {
+	FormattedAddress  string                   `json:"formatted_address"`
+	AddressComponents []googleAddressComponent `json:"address_components"`
+	Geometry          googleGeometry           `json:"geometry"`
+	PartialMatch      bool                     `json:"partial_match"`
+}
+
+type googleAddressComponent struct {
+	LongName  string   `json:"long_name"`
+	ShortName string   `json:"short_name"`
+	Types     []string `json:"types"`
+}
+
+func (r googleResult) address() Address {
+	return Address{
+		FormattedAddress: r.FormattedAddress,
+		Street:           r.component("route").LongName,
+		HouseNumber:      r.component("street_number").LongName,
+		Neighbourhood:    firstNonEmpty(r.component("neighborhood").LongName, r.component("sublocality").LongName),
+		City:             r.component("locality").LongName,
+		CountryCode:      strings.ToLower(r.component("country").ShortName),
+		Location:         r.Geometry.Location,
+	}
+}
+
+func (r googleResult) component(componentType string) googleAddressComponent {
+	for _, component := range r.AddressComponents {
+		for _, t := range component.Types {
+			if t == componentType {
+				return component
+			}
+		}
+	}
+	return googleAddressComponent{}
+}
+
+func (r googleResult) candidate() Candidate {
+	quality := MatchExact
+	if r.PartialMatch {
+		quality = MatchPartial
+	} else if r.Geometry.LocationType != "ROOFTOP" && r.Geometry.LocationType != "RANGE_INTERPOLATED" {
+		quality = MatchApproximate
+	}
+	return Candidate{
+		FormattedAddress: r.FormattedAddress,
+		Location:         r.Geometry.Location,
+		Quality:          quality,
+	}
+}
+
+type googleGeometry struct {
+	Location     Location `json:"location"`
+	LocationType string   `json:"location_type"`
+}
+
+func (c googleCoder) paramsForAddress(address string) url.Values {
+	return url.Values{
+		"address": {normalize(address)},
+		"key":     {c.conf.APIKey},
+	}
+}
+
+func (c googleCoder) get(parameters url.Values) ([]googleResult, error) {
+	httpResponse, err := c.conf.httpClient().Get(urlFor(c.conf.endpoint(googleBaseURL, googleGeocodePath), parameters))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	var response = new(googleResponse)
+	err = json.NewDecoder(httpResponse.Body).Decode(response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Status == "ZERO_RESULTS" {
+		return nil, ErrorNoResults
+	} else if response.Status != "OK" {
+		if response.ErrorMessage != "" {
+			return nil, fmt.Errorf("Geocoder service error!  (%s - %s)", response.Status, response.ErrorMessage)
+		}
+		return nil, fmt.Errorf("Geocoder service error!  (%s)", response.Status)
+	} else if len(response.Results) == 0 {
+		return nil, ErrorNoResults
+	}
+	return response.Results, nil
+}
+
+func (c googleCoder) fetch(parameters url.Values) ([]Candidate, error) {
+	results, err := c.get(parameters)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]Candidate, len(results))
+	for i, result := range results {
+		candidates[i] = result.candidate()
+	}
+	return candidates, nil
+}
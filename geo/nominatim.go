@@ -0,0 +1,207 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	nominatimBaseURL = "https://nominatim.openstreetmap.org"
+	nominatimLimit   = 5
+)
+
+// nominatimAreaAddressTypes are the address types of the results that only match the
+// area the address is in, rather than the address itself
+var nominatimAreaAddressTypes = map[string]bool{
+	"country":       true,
+	"state":         true,
+	"region":        true,
+	"county":        true,
+	"municipality":  true,
+	"city":          true,
+	"town":          true,
+	"village":       true,
+	"hamlet":        true,
+	"city_district": true,
+	"district":      true,
+	"borough":       true,
+	"suburb":        true,
+	"quarter":       true,
+	"neighbourhood": true,
+	"postcode":      true,
+}
+
+type nominatimCoder struct {
+	conf *Config
+}
+
+func (c nominatimCoder) Code(address string) ([]Candidate, error) {
+	parameters := c.paramsForAddress(address)
+	return c.search(parameters)
+}
+
+// CodeForRegion limits the search to the country specified by the region's ccTLD
+func (c nominatimCoder) CodeForRegion(address, region string) ([]Candidate, error) {
+	parameters := c.paramsForAddress(address)
+	parameters.Add("countrycodes", CountryCodeForCCTLD(region))
+	return c.search(parameters)
+}
+
+func (c nominatimCoder) ReverseCode(location Location) (Address, error) {
+	parameters := url.Values{
+		"lat":            {strconv.FormatFloat(location.Lat, 'f', -1, 64)},
+		"lon":            {strconv.FormatFloat(location.Lng, 'f', -1, 64)},
+		"format":         {"json"},
+		"addressdetails": {"1"},
+	}
+	var result nominatimResult
+	if err := c.get("/reverse", parameters, &result); err != nil {
+		return Address{}, err
+	}
+	if result.Error != "" {
+		return Address{}, ErrorNoResults
+	}
+	return result.address()
+}
+
+func (c nominatimCoder) Suggest(input, region string) ([]Address, error) {
+	parameters := c.paramsForAddress(input)
+	parameters.Add("countrycodes", CountryCodeForCCTLD(region))
+	var results []nominatimResult
+	if err := c.get("/search", parameters, &results); err != nil {
+		return nil, err
+	}
+	addresses := make([]Address, len(results))
+	for i, result := range results {
+		address, err := result.address()
+		if err != nil {
+			return nil, err
+		}
+		addresses[i] = address
+	}
+	return addresses, nil
+}
+
+type nominatimResult struct {
+	Lat         string           `json:"lat"`
+	Lon         string           `json:"lon"`
+	DisplayName string           `json:"display_name"`
+	AddressType string           `json:"addresstype"`
+	Address     nominatimAddress `json:"address"`
+	Error       string           `json:"error"`
+}
+
+type nominatimAddress struct {
+	Road          string `json:"road"`
+	HouseNumber   string `json:"house_number"`
+	Neighbourhood string `json:"neighbourhood"`
+	Suburb        string `json:"suburb"`
+	City          string `json:"city"`
+	Town          string `json:"town"`
+	Village       string `json:"village"`
+	CountryCode   string `json:"country_code"`
+}
+
+func (r nominatimResult) address() (Address, error) {
+	location, err := r.location()
+	if err != nil {
+		return Address{}, err
+	}
+	return Address{
+		FormattedAddress: r.DisplayName,
+		Street:           r.Address.Road,
+		HouseNumber:      r.Address.HouseNumber,
+		Neighbourhood:    firstNonEmpty(r.Address.Neighbourhood, r.Address.Suburb),
+		City:             firstNonEmpty(r.Address.City, r.Address.Town, r.Address.Village),
+		CountryCode:      strings.ToLower(r.Address.CountryCode),
+		Location:         location,
+	}, nil
+}
+
+// candidate derives the quality of the match from the result's address type,
+// because Nominatim doesn't report partial matches. The results for areas, e.g.
+// cities, are partial matches and the other results without a house number, e.g.
+// streets, approximate matches.
+func (r nominatimResult) candidate() (Candidate, error) {
+	location, err := r.location()
+	if err != nil {
+		return Candidate{}, err
+	}
+	quality := MatchExact
+	if nominatimAreaAddressTypes[r.AddressType] {
+		quality = MatchPartial
+	} else if r.Address.HouseNumber == "" {
+		quality = MatchApproximate
+	}
+	return Candidate{
+		FormattedAddress: r.DisplayName,
+		Location:         location,
+		Quality:          quality,
+	}, nil
+}
+
+func (r nominatimResult) location() (Location, error) {
+	lat, err := strconv.ParseFloat(r.Lat, 64)
+	if err != nil {
+		return Location{}, err
+	}
+	lng, err := strconv.ParseFloat(r.Lon, 64)
+	if err != nil {
+		return Location{}, err
+	}
+	return Location{
+		Lat: lat,
+		Lng: lng,
+	}, nil
+}
+
+func (c nominatimCoder) paramsForAddress(address string) url.Values {
+	return url.Values{
+		"q":              {normalize(address)},
+		"format":         {"json"},
+		"limit":          {strconv.Itoa(nominatimLimit)},
+		"addressdetails": {"1"},
+	}
+}
+
+func (c nominatimCoder) get(path string, parameters url.Values, v interface{}) error {
+	request, err := http.NewRequest("GET", urlFor(c.conf.endpoint(nominatimBaseURL, path), parameters), nil)
+	if err != nil {
+		return err
+	}
+	if c.conf.UserAgent != "" {
+		request.Header.Set("User-Agent", c.conf.UserAgent)
+	}
+	httpResponse, err := c.conf.httpClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode != http.StatusOK {
+		return fmt.Errorf("Geocoder service error!  (%s)", httpResponse.Status)
+	}
+	return json.NewDecoder(httpResponse.Body).Decode(v)
+}
+
+func (c nominatimCoder) search(parameters url.Values) ([]Candidate, error) {
+	var results []nominatimResult
+	if err := c.get("/search", parameters, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrorNoResults
+	}
+	candidates := make([]Candidate, len(results))
+	for i, result := range results {
+		candidate, err := result.candidate()
+		if err != nil {
+			return nil, err
+		}
+		candidates[i] = candidate
+	}
+	return candidates, nil
+}
@@ -0,0 +1,405 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Lunchr/luncher-api/audit"
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/session"
+	"github.com/Lunchr/luncher-api/userdata"
+)
+
+const (
+	// adminSearchLimit limits the number of users or restaurants returned by a single search
+	adminSearchLimit = 50
+	// adminImpersonationsLimit limits the number of impersonations listed
+	adminImpersonationsLimit = 100
+)
+
+// AdminUser defines the response format for users in the administration API. It leaves out the user's
+// credentials and tokens.
+type AdminUser struct {
+	ID             bson.ObjectId `json:"_id"`
+	Email          string        `json:"email,omitempty"`
+	EmailVerified  bool          `json:"email_verified"`
+	FacebookUserID string        `json:"facebook_user_id,omitempty"`
+	IsAdmin        bool          `json:"is_admin"`
+}
+
+type memberPOST struct {
+	UserID string     `json:"user_id"`
+	Role   model.Role `json:"role"`
+}
+
+type impersonationPOST struct {
+	Reason string `json:"reason"`
+}
+
+// AdminUsers returns a handler that searches for users by the q query param. Without the param, all users
+// are listed, up to a limit.
+func AdminUsers(sessionManager session.Manager, users db.Users) router.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request, admin *model.User) *router.HandlerError {
+		foundUsers, err := users.Search(r.URL.Query().Get("q"), adminSearchLimit)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to search for users", http.StatusInternalServerError)
+		}
+		response := make([]AdminUser, len(foundUsers))
+		for i, user := range foundUsers {
+			response[i] = AdminUser{
+				ID:             user.ID,
+				Email:          user.Email,
+				EmailVerified:  user.EmailVerified,
+				FacebookUserID: user.FacebookUserID,
+				IsAdmin:        user.IsAdmin,
+			}
+		}
+		return writeJSON(w, response)
+	}
+	return checkAdmin(sessionManager, users, handler)
+}
+
+// AdminRestaurants returns a handler that searches for restaurants by the q query param. Without the param,
+// all restaurants are listed, up to a limit.
+func AdminRestaurants(sessionManager session.Manager, users db.Users, restaurants db.Restaurants) router.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request, admin *model.User) *router.HandlerError {
+		foundRestaurants, err := restaurants.Search(r.URL.Query().Get("q"), adminSearchLimit)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to search for restaurants", http.StatusInternalServerError)
+		}
+		if foundRestaurants == nil {
+			foundRestaurants = []*model.Restaurant{}
+		}
+		return writeJSON(w, foundRestaurants)
+	}
+	return checkAdmin(sessionManager, users, handler)
+}
+
+// PostAdminRestaurantMember returns a handler that gives the user specified in the request body the specified
+// role in the restaurant
+func PostAdminRestaurantMember(sessionManager session.Manager, users db.Users, restaurants db.Restaurants,
+	memberships db.Memberships, auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, admin *model.User) *router.HandlerError {
+		restaurantIDString := ps.ByName("restaurantID")
+		if !bson.IsObjectIdHex(restaurantIDString) {
+			return router.NewSimpleHandlerError("Invalid restaurant ID", http.StatusBadRequest)
+		}
+		restaurant, err := restaurants.GetID(bson.ObjectIdHex(restaurantIDString))
+		if err == mgo.ErrNotFound {
+			return router.NewHandlerError(err, "Failed to find the specified restaurant", http.StatusNotFound)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to find the restaurant", http.StatusInternalServerError)
+		}
+		var post memberPOST
+		if err = json.NewDecoder(r.Body).Decode(&post); err != nil {
+			return router.NewHandlerError(err, "Failed to parse the member", http.StatusBadRequest)
+		} else if !bson.IsObjectIdHex(post.UserID) {
+			return router.NewSimpleHandlerError("Invalid user ID", http.StatusBadRequest)
+		} else if !post.Role.IsValid() {
+			return router.NewSimpleHandlerError("Please specify a valid role", http.StatusBadRequest)
+		}
+		user, err := users.GetID(bson.ObjectIdHex(post.UserID))
+		if err == mgo.ErrNotFound {
+			return router.NewHandlerError(err, "Failed to find the specified user", http.StatusNotFound)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to find the user", http.StatusInternalServerError)
+		}
+		if err = memberships.Set(user.ID, restaurant.ID, post.Role); err != nil {
+			return router.NewHandlerError(err, "Failed to store the membership in the DB", http.StatusInternalServerError)
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return checkAdminWithParams(sessionManager, users, auditedWithParams(auditLog, "admin.member.set", handler))
+}
+
+// PostAdminImpersonation returns a handler that replaces the administrator's session with a short-lived
+// session for the user specified by the id param. The reason for the impersonation has to be specified in
+// the request body and is stored along with the administrator's ID.
+func PostAdminImpersonation(sessionManager session.Manager, users db.Users, impersonations db.Impersonations,
+	auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, admin *model.User) *router.HandlerError {
+		idString := ps.ByName("id")
+		if !bson.IsObjectIdHex(idString) {
+			return router.NewSimpleHandlerError("Invalid user ID", http.StatusBadRequest)
+		}
+		var post impersonationPOST
+		if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+			return router.NewHandlerError(err, "Failed to parse the impersonation", http.StatusBadRequest)
+		}
+		reason := strings.TrimSpace(post.Reason)
+		if reason == "" {
+			return router.NewSimpleHandlerError("Please specify a reason for the impersonation", http.StatusBadRequest)
+		}
+		user, err := users.GetID(bson.ObjectIdHex(idString))
+		if err == mgo.ErrNotFound {
+			return router.NewHandlerError(err, "Failed to find the specified user", http.StatusNotFound)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to find the user", http.StatusInternalServerError)
+		} else if user.IsAdmin {
+			return router.NewSimpleHandlerError("Administrators can't be impersonated", http.StatusForbidden)
+		}
+		impersonation, err := impersonations.Insert(&model.Impersonation{
+			AdminID:   admin.ID,
+			UserID:    user.ID,
+			Reason:    reason,
+			StartedAt: time.Now(),
+		})
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to store the impersonation in the DB", http.StatusInternalServerError)
+		}
+		if err = sessionManager.Impersonate(w, r, user.ID, admin.ID); err != nil {
+			return router.NewHandlerError(err, "Failed to start a session for the user", http.StatusInternalServerError)
+		}
+		return writeJSONWithCode(w, impersonation, http.StatusCreated)
+	}
+	return checkAdminWithParams(sessionManager, users, auditedWithParams(auditLog, "admin.impersonation.start", handler))
+}
+
+// DeleteAdminImpersonation returns a handler that ends the impersonation the request's session is used for
+// and logs the administrator back in, unless they've stopped being an administrator in the meantime
+func DeleteAdminImpersonation(sessionManager session.Manager, users db.Users, impersonations db.Impersonations,
+	auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User) *router.HandlerError {
+		currentSession := resolvedSession(r)
+		if currentSession.ImpersonatorID == "" {
+			return router.NewSimpleHandlerError("This session isn't impersonating anyone", http.StatusBadRequest)
+		}
+		impersonator, err := users.GetID(currentSession.ImpersonatorID)
+		if err == mgo.ErrNotFound {
+			impersonator = nil
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to find the administrator", http.StatusInternalServerError)
+		}
+		if err = impersonations.End(currentSession.ImpersonatorID, user.ID, time.Now()); err != nil {
+			return router.NewHandlerError(err, "Failed to update the impersonation in the DB", http.StatusInternalServerError)
+		}
+		if err = sessionManager.End(w, r); err != nil {
+			return router.NewHandlerError(err, "Failed to end the session", http.StatusInternalServerError)
+		}
+		// Those who've been removed or have stopped being administrators since the impersonation started are just logged out
+		if impersonator != nil && impersonator.IsAdmin {
+			if err = sessionManager.Start(w, r, impersonator.ID); err != nil {
+				return router.NewHandlerError(err, "Failed to start a session for the administrator", http.StatusInternalServerError)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return checkLoginWithParams(sessionManager, users, auditedWithParams(auditLog, "admin.impersonation.end", handler))
+}
+
+// AdminImpersonations returns a handler that lists the most recent impersonations
+func AdminImpersonations(sessionManager session.Manager, users db.Users, impersonations db.Impersonations) router.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request, admin *model.User) *router.HandlerError {
+		recentImpersonations, err := impersonations.GetRecent(adminImpersonationsLimit)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to find the impersonations", http.StatusInternalServerError)
+		}
+		if recentImpersonations == nil {
+			recentImpersonations = []*model.Impersonation{}
+		}
+		return writeJSON(w, recentImpersonations)
+	}
+	return checkAdmin(sessionManager, users, handler)
+}
+
+// AdminUserDataExport returns a handler that responds with an archive of everything associated with the user
+// specified by the id param, as a JSON file attachment
+func AdminUserDataExport(sessionManager session.Manager, users db.Users, userData userdata.Controller,
+	auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, admin *model.User) *router.HandlerError {
+		idString := ps.ByName("id")
+		if !bson.IsObjectIdHex(idString) {
+			return router.NewSimpleHandlerError("Invalid user ID", http.StatusBadRequest)
+		}
+		return exportUserData(w, userData, bson.ObjectIdHex(idString))
+	}
+	return checkAdminWithParams(sessionManager, users, auditedWithParams(auditLog, "admin.user.export", handler))
+}
+
+// DeleteAdminUser returns a handler that deletes the account of the user specified by the id param. Other
+// administrators can't be deleted through the API.
+func DeleteAdminUser(sessionManager session.Manager, users db.Users, userData userdata.Controller,
+	auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, admin *model.User) *router.HandlerError {
+		idString := ps.ByName("id")
+		if !bson.IsObjectIdHex(idString) {
+			return router.NewSimpleHandlerError("Invalid user ID", http.StatusBadRequest)
+		}
+		user, err := users.GetID(bson.ObjectIdHex(idString))
+		if err == mgo.ErrNotFound {
+			return router.NewHandlerError(err, "Failed to find the specified user", http.StatusNotFound)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to find the user", http.StatusInternalServerError)
+		} else if user.IsAdmin {
+			return router.NewSimpleHandlerError("Administrators can't be deleted", http.StatusForbidden)
+		}
+		if handlerErr := deleteUserData(userData, user.ID); handlerErr != nil {
+			return handlerErr
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return checkAdminWithParams(sessionManager, users, auditedWithParams(auditLog, "admin.user.delete", handler))
+}
+
+// PostAdminTag returns a handler that adds the tag in the request body
+func PostAdminTag(sessionManager session.Manager, users db.Users, tags db.Tags, auditLog audit.Log) router.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request, admin *model.User) *router.HandlerError {
+		tag, handlerErr := parseTag(r)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		if handlerErr = checkTagNameIsUnique(tags, tag.Name); handlerErr != nil {
+			return handlerErr
+		}
+		if err := tags.Insert(tag); err != nil {
+			return router.NewHandlerError(err, "Failed to store the tag in the DB", http.StatusInternalServerError)
+		}
+		return writeJSONWithCode(w, tag, http.StatusCreated)
+	}
+	return checkAdmin(sessionManager, users, audited(auditLog, "admin.tag.create", handler))
+}
+
+// PutAdminTag returns a handler that replaces the tag specified by the name param with the tag in the
+// request body
+func PutAdminTag(sessionManager session.Manager, users db.Users, tags db.Tags, auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, admin *model.User) *router.HandlerError {
+		name := ps.ByName("name")
+		currentTag, err := tags.GetName(name)
+		if err == mgo.ErrNotFound {
+			return router.NewHandlerError(err, "Failed to find the specified tag", http.StatusNotFound)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to find the tag", http.StatusInternalServerError)
+		}
+		tag, handlerErr := parseTag(r)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		if tag.Name != name {
+			if handlerErr = checkTagNameIsUnique(tags, tag.Name); handlerErr != nil {
+				return handlerErr
+			}
+		}
+		tag.ID = currentTag.ID
+		if err = tags.UpdateName(name, tag); err != nil {
+			return router.NewHandlerError(err, "Failed to update the tag in the DB", http.StatusInternalServerError)
+		}
+		return writeJSON(w, tag)
+	}
+	return checkAdminWithParams(sessionManager, users, auditedWithParams(auditLog, "admin.tag.update", handler))
+}
+
+// PostAdminRegion returns a handler that adds the region in the request body
+func PostAdminRegion(sessionManager session.Manager, users db.Users, regions db.Regions, auditLog audit.Log) router.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request, admin *model.User) *router.HandlerError {
+		region, handlerErr := parseRegion(r)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		if handlerErr = checkRegionNameIsUnique(regions, region.Name); handlerErr != nil {
+			return handlerErr
+		}
+		if err := regions.Insert(region); err != nil {
+			return router.NewHandlerError(err, "Failed to store the region in the DB", http.StatusInternalServerError)
+		}
+		return writeJSONWithCode(w, region, http.StatusCreated)
+	}
+	return checkAdmin(sessionManager, users, audited(auditLog, "admin.region.create", handler))
+}
+
+// PutAdminRegion returns a handler that replaces the region specified by the name param with the region in
+// the request body
+func PutAdminRegion(sessionManager session.Manager, users db.Users, regions db.Regions,
+	auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, admin *model.User) *router.HandlerError {
+		name := ps.ByName("name")
+		currentRegion, err := regions.GetName(name)
+		if err == mgo.ErrNotFound {
+			return router.NewHandlerError(err, "Failed to find the specified region", http.StatusNotFound)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to find the region", http.StatusInternalServerError)
+		}
+		region, handlerErr := parseRegion(r)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		if region.Name != name {
+			if handlerErr = checkRegionNameIsUnique(regions, region.Name); handlerErr != nil {
+				return handlerErr
+			}
+		}
+		region.ID = currentRegion.ID
+		if err = regions.UpdateName(name, region); err != nil {
+			return router.NewHandlerError(err, "Failed to update the region in the DB", http.StatusInternalServerError)
+		}
+		return writeJSON(w, region)
+	}
+	return checkAdminWithParams(sessionManager, users, auditedWithParams(auditLog, "admin.region.update", handler))
+}
+
+func parseTag(r *http.Request) (*model.Tag, *router.HandlerError) {
+	var tag model.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		return nil, router.NewHandlerError(err, "Failed to parse the tag", http.StatusBadRequest)
+	}
+	tag.ID = ""
+	tag.Name = strings.TrimSpace(tag.Name)
+	tag.DisplayName = strings.TrimSpace(tag.DisplayName)
+	if tag.Name == "" || strings.ContainsAny(tag.Name, " \t") {
+		return nil, router.NewSimpleHandlerError("Please specify a single word name for the tag", http.StatusBadRequest)
+	} else if tag.DisplayName == "" {
+		return nil, router.NewSimpleHandlerError("Please specify a display name for the tag", http.StatusBadRequest)
+	}
+	return &tag, nil
+}
+
+func checkTagNameIsUnique(tags db.Tags, name string) *router.HandlerError {
+	_, err := tags.GetName(name)
+	if err == nil {
+		return router.NewSimpleHandlerError("A tag with this name already exists", http.StatusConflict)
+	} else if err != mgo.ErrNotFound {
+		return router.NewHandlerError(err, "Failed to check if the tag exists", http.StatusInternalServerError)
+	}
+	return nil
+}
+
+func parseRegion(r *http.Request) (*model.Region, *router.HandlerError) {
+	var region model.Region
+	if err := json.NewDecoder(r.Body).Decode(&region); err != nil {
+		return nil, router.NewHandlerError(err, "Failed to parse the region", http.StatusBadRequest)
+	}
+	region.ID = ""
+	region.Name = strings.TrimSpace(region.Name)
+	region.Location = strings.TrimSpace(region.Location)
+	region.CCTLD = strings.TrimSpace(region.CCTLD)
+	if region.Name == "" || strings.ContainsAny(region.Name, " \t") {
+		return nil, router.NewSimpleHandlerError("Please specify a single word name for the region", http.StatusBadRequest)
+	} else if region.Location == "" || region.Location == "Local" {
+		return nil, router.NewSimpleHandlerError("Please specify the region's location as an IANA time zone", http.StatusBadRequest)
+	} else if _, err := time.LoadLocation(region.Location); err != nil {
+		return nil, router.NewHandlerError(err, "Please specify the region's location as an IANA time zone", http.StatusBadRequest)
+	} else if len(region.CCTLD) != 2 {
+		return nil, router.NewSimpleHandlerError("The region's ccTLD should be two letters long", http.StatusBadRequest)
+	}
+	return &region, nil
+}
+
+func checkRegionNameIsUnique(regions db.Regions, name string) *router.HandlerError {
+	_, err := regions.GetName(name)
+	if err == nil {
+		return router.NewSimpleHandlerError("A region with this name already exists", http.StatusConflict)
+	} else if err != mgo.ErrNotFound {
+		return router.NewHandlerError(err, "Failed to check if the region exists", http.StatusInternalServerError)
+	}
+	return nil
+}
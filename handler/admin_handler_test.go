@@ -0,0 +1,438 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AdminHandler", func() {
+	var (
+		sessionManager  *mocks.Manager
+		usersCollection *mocks.Users
+		restaurants     *mocks.Restaurants
+		memberships     *mocks.Memberships
+		impersonations  *mocks.Impersonations
+		tags            *mocks.Tags
+		regions         *mocks.Regions
+		admin           *model.User
+		userSession     *model.Session
+	)
+
+	BeforeEach(func() {
+		sessionManager = new(mocks.Manager)
+		usersCollection = new(mocks.Users)
+		restaurants = new(mocks.Restaurants)
+		memberships = new(mocks.Memberships)
+		impersonations = new(mocks.Impersonations)
+		tags = new(mocks.Tags)
+		regions = new(mocks.Regions)
+		admin = &model.User{
+			ID:      bson.NewObjectId(),
+			IsAdmin: true,
+		}
+		userSession = &model.Session{UserID: admin.ID}
+		sessionManager.On("Resolve", mock.Anything).Return(func(r *http.Request) *model.Session {
+			return userSession
+		}, nil)
+		usersCollection.On("GetID", admin.ID).Return(admin, nil)
+		requestQuery = url.Values{}
+	})
+
+	AfterEach(func() {
+		memberships.AssertExpectations(GinkgoT())
+		impersonations.AssertExpectations(GinkgoT())
+		tags.AssertExpectations(GinkgoT())
+		regions.AssertExpectations(GinkgoT())
+	})
+
+	Describe("GET /admin/users", func() {
+		var handler router.Handler
+
+		BeforeEach(func() {
+			requestMethod = "GET"
+			requestQuery.Set("q", "example.com")
+			usersCollection.On("Search", "example.com", mock.AnythingOfType("int")).Return([]*model.User{
+				{ID: bson.NewObjectId(), Email: "a@example.com", PasswordHash: []byte("a hash")},
+			}, nil)
+		})
+
+		JustBeforeEach(func() {
+			handler = AdminUsers(sessionManager, usersCollection)
+		})
+
+		It("should list the matching users without their credentials", func() {
+			err := handler(responseRecorder, request)
+			Expect(err).To(BeNil())
+			var result []map[string]interface{}
+			json.Unmarshal(responseRecorder.Body.Bytes(), &result)
+			Expect(result).To(HaveLen(1))
+			Expect(result[0]["email"]).To(Equal("a@example.com"))
+			Expect(result[0]).NotTo(HaveKey("PasswordHash"))
+			Expect(result[0]).NotTo(HaveKey("Session"))
+		})
+
+		Context("with a user who isn't an administrator", func() {
+			BeforeEach(func() {
+				admin.IsAdmin = false
+			})
+
+			It("should be forbidden", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+
+	Describe("POST /admin/restaurants/:restaurantID/members", func() {
+		var (
+			handler    router.HandlerWithParams
+			restaurant *model.Restaurant
+			user       *model.User
+			params     httprouter.Params
+		)
+
+		BeforeEach(func() {
+			requestMethod = "POST"
+			restaurant = &model.Restaurant{ID: bson.NewObjectId()}
+			user = &model.User{ID: bson.NewObjectId()}
+			restaurants.On("GetID", restaurant.ID).Return(restaurant, nil)
+			usersCollection.On("GetID", user.ID).Return(user, nil)
+			params = httprouter.Params{httprouter.Param{
+				Key:   "restaurantID",
+				Value: restaurant.ID.Hex(),
+			}}
+			requestData = map[string]interface{}{
+				"user_id": user.ID.Hex(),
+				"role":    "editor",
+			}
+		})
+
+		JustBeforeEach(func() {
+			handler = PostAdminRestaurantMember(sessionManager, usersCollection, restaurants, memberships, auditLog)
+		})
+
+		It("should give the user the role in the restaurant", func() {
+			memberships.On("Set", user.ID, restaurant.ID, model.RoleEditor).Return(nil)
+			err := handler(responseRecorder, request, params)
+			Expect(err).To(BeNil())
+		})
+
+		Context("with an invalid role", func() {
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"user_id": user.ID.Hex(),
+					"role":    "superuser",
+				}
+			})
+
+			It("should fail", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("with a user that doesn't exist", func() {
+			BeforeEach(func() {
+				unknownID := bson.NewObjectId()
+				usersCollection.On("GetID", unknownID).Return(nil, mgo.ErrNotFound)
+				requestData = map[string]interface{}{
+					"user_id": unknownID.Hex(),
+					"role":    "editor",
+				}
+			})
+
+			It("should fail", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("POST /admin/users/:id/impersonate", func() {
+		var (
+			handler router.HandlerWithParams
+			user    *model.User
+			params  httprouter.Params
+		)
+
+		BeforeEach(func() {
+			requestMethod = "POST"
+			user = &model.User{ID: bson.NewObjectId()}
+			usersCollection.On("GetID", user.ID).Return(user, nil)
+			params = httprouter.Params{httprouter.Param{
+				Key:   "id",
+				Value: user.ID.Hex(),
+			}}
+			requestData = map[string]interface{}{
+				"reason": "Support ticket #42",
+			}
+		})
+
+		JustBeforeEach(func() {
+			handler = PostAdminImpersonation(sessionManager, usersCollection, impersonations, auditLog)
+		})
+
+		It("should record the impersonation and start a session for the user", func() {
+			impersonations.On("Insert", mock.AnythingOfType("*model.Impersonation")).Return(func(i *model.Impersonation) *model.Impersonation {
+				Expect(i.AdminID).To(Equal(admin.ID))
+				Expect(i.UserID).To(Equal(user.ID))
+				Expect(i.Reason).To(Equal("Support ticket #42"))
+				return i
+			}, nil)
+			sessionManager.On("Impersonate", responseRecorder, mock.AnythingOfType("*http.Request"), user.ID, admin.ID).Return(nil)
+			err := handler(responseRecorder, request, params)
+			Expect(err).To(BeNil())
+			Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+			sessionManager.AssertExpectations(GinkgoT())
+		})
+
+		Context("without a reason", func() {
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"reason": " ",
+				}
+			})
+
+			It("should fail", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("with another administrator", func() {
+			BeforeEach(func() {
+				user.IsAdmin = true
+			})
+
+			It("should be forbidden", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+
+	Describe("DELETE /admin/impersonation", func() {
+		var (
+			handler router.HandlerWithParams
+			user    *model.User
+		)
+
+		BeforeEach(func() {
+			requestMethod = "DELETE"
+			user = &model.User{ID: bson.NewObjectId()}
+			usersCollection.On("GetID", user.ID).Return(user, nil)
+			userSession = &model.Session{UserID: user.ID}
+		})
+
+		JustBeforeEach(func() {
+			handler = DeleteAdminImpersonation(sessionManager, usersCollection, impersonations, auditLog)
+		})
+
+		It("should fail for a regular session", func() {
+			err := handler(responseRecorder, request, nil)
+			Expect(err.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		Context("with an impersonating session", func() {
+			BeforeEach(func() {
+				userSession.ImpersonatorID = admin.ID
+			})
+
+			It("should end the impersonation and log the administrator back in", func() {
+				impersonations.On("End", admin.ID, user.ID, mock.AnythingOfType("time.Time")).Return(nil)
+				sessionManager.On("End", responseRecorder, mock.AnythingOfType("*http.Request")).Return(nil)
+				sessionManager.On("Start", responseRecorder, mock.AnythingOfType("*http.Request"), admin.ID).Return(nil)
+				err := handler(responseRecorder, request, nil)
+				Expect(err).To(BeNil())
+				sessionManager.AssertExpectations(GinkgoT())
+			})
+
+			It("should record the administrator in the audit log, resolving the session only once", func() {
+				impersonations.On("End", admin.ID, user.ID, mock.AnythingOfType("time.Time")).Return(nil)
+				sessionManager.On("End", responseRecorder, mock.AnythingOfType("*http.Request")).Return(nil)
+				sessionManager.On("Start", responseRecorder, mock.AnythingOfType("*http.Request"), admin.ID).Return(nil)
+				handler(responseRecorder, request, nil)
+				Expect(auditEntries).To(HaveLen(1))
+				Expect(auditEntries[0].ImpersonatorID).To(Equal(admin.ID))
+				sessionManager.AssertNumberOfCalls(GinkgoT(), "Resolve", 1)
+			})
+
+			Context("with the administrator no longer an administrator", func() {
+				BeforeEach(func() {
+					admin.IsAdmin = false
+				})
+
+				It("should end the impersonation without logging them back in", func() {
+					impersonations.On("End", admin.ID, user.ID, mock.AnythingOfType("time.Time")).Return(nil)
+					sessionManager.On("End", responseRecorder, mock.AnythingOfType("*http.Request")).Return(nil)
+					err := handler(responseRecorder, request, nil)
+					Expect(err).To(BeNil())
+					sessionManager.AssertExpectations(GinkgoT())
+					sessionManager.AssertNotCalled(GinkgoT(), "Start", mock.Anything, mock.Anything, mock.Anything)
+				})
+			})
+
+			Context("with the administrator's account removed", func() {
+				BeforeEach(func() {
+					userSession.ImpersonatorID = bson.NewObjectId()
+					usersCollection.On("GetID", userSession.ImpersonatorID).Return(nil, mgo.ErrNotFound)
+				})
+
+				It("should end the impersonation without logging anyone in", func() {
+					impersonations.On("End", userSession.ImpersonatorID, user.ID, mock.AnythingOfType("time.Time")).Return(nil)
+					sessionManager.On("End", responseRecorder, mock.AnythingOfType("*http.Request")).Return(nil)
+					err := handler(responseRecorder, request, nil)
+					Expect(err).To(BeNil())
+					sessionManager.AssertNotCalled(GinkgoT(), "Start", mock.Anything, mock.Anything, mock.Anything)
+				})
+			})
+		})
+	})
+
+	Describe("POST /admin/tags", func() {
+		var handler router.Handler
+
+		BeforeEach(func() {
+			requestMethod = "POST"
+			requestData = map[string]interface{}{
+				"name":         "kala",
+				"display_name": "Kala",
+			}
+		})
+
+		JustBeforeEach(func() {
+			handler = PostAdminTag(sessionManager, usersCollection, tags, auditLog)
+		})
+
+		It("should add the tag", func() {
+			tags.On("GetName", "kala").Return(nil, mgo.ErrNotFound)
+			tags.On("Insert", []*model.Tag{{Name: "kala", DisplayName: "Kala"}}).Return(nil)
+			err := handler(responseRecorder, request)
+			Expect(err).To(BeNil())
+			Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("should fail if a tag with the name already exists", func() {
+			tags.On("GetName", "kala").Return(&model.Tag{Name: "kala"}, nil)
+			err := handler(responseRecorder, request)
+			Expect(err.Code).To(Equal(http.StatusConflict))
+		})
+	})
+
+	Describe("PUT /admin/regions/:name", func() {
+		var (
+			handler router.HandlerWithParams
+			region  *model.Region
+			params  httprouter.Params
+		)
+
+		BeforeEach(func() {
+			requestMethod = "PUT"
+			region = &model.Region{
+				ID:       bson.NewObjectId(),
+				Name:     "Tartu",
+				Location: "Europe/Tallinn",
+				CCTLD:    "ee",
+			}
+			regions.On("GetName", "Tartu").Return(region, nil)
+			params = httprouter.Params{httprouter.Param{
+				Key:   "name",
+				Value: "Tartu",
+			}}
+			requestData = map[string]interface{}{
+				"name":     "Tartu",
+				"location": "Europe/Helsinki",
+				"cctld":    "ee",
+			}
+		})
+
+		JustBeforeEach(func() {
+			handler = PutAdminRegion(sessionManager, usersCollection, regions, auditLog)
+		})
+
+		It("should update the region", func() {
+			regions.On("UpdateName", "Tartu", &model.Region{
+				ID:       region.ID,
+				Name:     "Tartu",
+				Location: "Europe/Helsinki",
+				CCTLD:    "ee",
+			}).Return(nil)
+			err := handler(responseRecorder, request, params)
+			Expect(err).To(BeNil())
+		})
+
+		Context("with an invalid location", func() {
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"name":     "Tartu",
+					"location": "Europe/Tartu",
+					"cctld":    "ee",
+				}
+			})
+
+			It("should fail", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("DELETE /admin/users/:id", func() {
+		var (
+			handler  router.HandlerWithParams
+			userData *mocks.Controller
+			user     *model.User
+			params   httprouter.Params
+		)
+
+		BeforeEach(func() {
+			requestMethod = "DELETE"
+			userData = new(mocks.Controller)
+			user = &model.User{ID: bson.NewObjectId()}
+			usersCollection.On("GetID", user.ID).Return(user, nil)
+			params = httprouter.Params{httprouter.Param{
+				Key:   "id",
+				Value: user.ID.Hex(),
+			}}
+		})
+
+		JustBeforeEach(func() {
+			handler = DeleteAdminUser(sessionManager, usersCollection, userData, auditLog)
+		})
+
+		It("should delete the user", func() {
+			userData.On("Delete", user.ID).Return(nil)
+			err := handler(responseRecorder, request, params)
+			Expect(err).To(BeNil())
+			userData.AssertExpectations(GinkgoT())
+			Expect(auditEntries).To(HaveLen(1))
+			Expect(auditEntries[0].Action).To(Equal("admin.user.delete"))
+			Expect(auditEntries[0].Target).To(Equal(user.ID.Hex()))
+		})
+
+		Context("with another administrator", func() {
+			BeforeEach(func() {
+				user.IsAdmin = true
+			})
+
+			It("should be forbidden", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+				userData.AssertNotCalled(GinkgoT(), "Delete", mock.Anything)
+			})
+		})
+	})
+})
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Lunchr/luncher-api/audit"
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/session"
+)
+
+type apiKeyPOST struct {
+	Name string     `json:"name"`
+	Role model.Role `json:"role"`
+}
+
+// CreatedAPIKey defines the response format for the PostRestaurantAPIKey() handler. It's the only time the
+// key itself is shown.
+type CreatedAPIKey struct {
+	*model.APIKey
+	Key string `json:"key"`
+}
+
+// RestaurantAPIKeys returns a handler that lists the restaurant's API keys
+func RestaurantAPIKeys(sessionManager session.Manager, users db.Users, memberships db.Memberships, apiKeys db.APIKeys,
+	restaurants db.Restaurants) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+		restaurantAPIKeys, err := apiKeys.GetForRestaurant(restaurant.ID)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to find the restaurant's API keys", http.StatusInternalServerError)
+		}
+		return writeJSON(w, restaurantAPIKeys)
+	}
+	return forRestaurant(sessionManager, users, memberships, apiKeys, restaurants, model.RoleOwner, handler)
+}
+
+// PostRestaurantAPIKey returns a handler that creates an API key for the restaurant. The key acts on behalf of
+// the user creating it, limited to the role specified in the request body.
+func PostRestaurantAPIKey(sessionManager session.Manager, users db.Users, memberships db.Memberships, apiKeys db.APIKeys,
+	restaurants db.Restaurants, auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+		var post apiKeyPOST
+		if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+			return router.NewHandlerError(err, "Failed to parse the API key", http.StatusBadRequest)
+		} else if !model.IsAllowedAPIKeyRole(post.Role) {
+			return router.NewSimpleHandlerError("Please specify either the viewer or the editor role", http.StatusBadRequest)
+		}
+		name := strings.TrimSpace(post.Name)
+		if name == "" {
+			return router.NewSimpleHandlerError("Please specify a name for the API key", http.StatusBadRequest)
+		}
+		apiKey, key, err := model.NewAPIKey(restaurant.ID, user.ID, name, post.Role)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to create an API key", http.StatusInternalServerError)
+		}
+		if apiKey, err = apiKeys.Insert(apiKey); err != nil {
+			return router.NewHandlerError(err, "Failed to store the API key in the DB", http.StatusInternalServerError)
+		}
+		return writeJSONWithCode(w, CreatedAPIKey{apiKey, key}, http.StatusCreated)
+	}
+	return forRestaurant(sessionManager, users, memberships, apiKeys, restaurants, model.RoleOwner,
+		auditedForRestaurant(auditLog, "api_key.create", handler))
+}
+
+// DeleteRestaurantAPIKey returns a handler that revokes the API key specified by the id param
+func DeleteRestaurantAPIKey(sessionManager session.Manager, users db.Users, memberships db.Memberships, apiKeys db.APIKeys,
+	restaurants db.Restaurants, auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User,
+		restaurant *model.Restaurant) *router.HandlerError {
+		idString := ps.ByName("id")
+		if !bson.IsObjectIdHex(idString) {
+			return router.NewSimpleHandlerError("Invalid API key ID", http.StatusBadRequest)
+		}
+		err := apiKeys.Remove(bson.ObjectIdHex(idString), restaurant.ID)
+		if err == mgo.ErrNotFound {
+			return router.NewHandlerError(err, "Failed to find the specified API key", http.StatusNotFound)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to remove the API key from the DB", http.StatusInternalServerError)
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return forRestaurantWithParams(sessionManager, users, memberships, apiKeys, restaurants, model.RoleOwner,
+		auditedForRestaurantWithParams(auditLog, "api_key.delete", handler))
+}
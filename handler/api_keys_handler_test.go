@@ -0,0 +1,324 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("APIKeysHandler", func() {
+	var (
+		sessionManager  *mocks.Manager
+		usersCollection *mocks.Users
+		memberships     *mocks.Memberships
+		apiKeys         *mocks.APIKeys
+		restaurants     *mocks.Restaurants
+		user            *model.User
+		restaurant      *model.Restaurant
+		params          httprouter.Params
+	)
+
+	BeforeEach(func() {
+		sessionManager = new(mocks.Manager)
+		usersCollection = new(mocks.Users)
+		memberships = new(mocks.Memberships)
+		apiKeys = new(mocks.APIKeys)
+		restaurants = new(mocks.Restaurants)
+		restaurant = &model.Restaurant{
+			ID:   bson.NewObjectId(),
+			Name: "Asian Chef",
+		}
+		user = &model.User{
+			ID: bson.NewObjectId(),
+		}
+		memberships.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+			Role: model.RoleOwner,
+		}, nil)
+		restaurants.On("GetID", restaurant.ID).Return(restaurant, nil)
+		params = httprouter.Params{httprouter.Param{
+			Key:   "restaurantID",
+			Value: restaurant.ID.Hex(),
+		}}
+		requestQuery = url.Values{}
+	})
+
+	AfterEach(func() {
+		apiKeys.AssertExpectations(GinkgoT())
+	})
+
+	Context("with the user logged in", func() {
+		BeforeEach(func() {
+			sessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+			usersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+		})
+
+		Describe("GET /restaurants/:restaurantID/api_keys", func() {
+			var handler router.HandlerWithParams
+
+			BeforeEach(func() {
+				requestMethod = "GET"
+				apiKeys.On("GetForRestaurant", restaurant.ID).Return([]*model.APIKey{
+					{ID: bson.NewObjectId(), Name: "POS", Hash: []byte("a hash"), Role: model.RoleEditor},
+				}, nil)
+			})
+
+			JustBeforeEach(func() {
+				handler = RestaurantAPIKeys(sessionManager, usersCollection, memberships, apiKeys, restaurants)
+			})
+
+			It("should list the keys without their hashes", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+				var result []map[string]interface{}
+				json.Unmarshal(responseRecorder.Body.Bytes(), &result)
+				Expect(result).To(HaveLen(1))
+				Expect(result[0]["name"]).To(Equal("POS"))
+				Expect(result[0]).NotTo(HaveKey("hash"))
+			})
+		})
+
+		Describe("POST /restaurants/:restaurantID/api_keys", func() {
+			var handler router.HandlerWithParams
+
+			BeforeEach(func() {
+				requestMethod = "POST"
+				requestData = map[string]interface{}{
+					"name": " POS ",
+					"role": "editor",
+				}
+			})
+
+			JustBeforeEach(func() {
+				handler = PostRestaurantAPIKey(sessionManager, usersCollection, memberships, apiKeys, restaurants, auditLog)
+			})
+
+			Context("with a valid key", func() {
+				var apiKey *model.APIKey
+
+				BeforeEach(func() {
+					apiKeys.On("Insert", mock.AnythingOfType("*model.APIKey")).Return(func(k *model.APIKey) *model.APIKey {
+						apiKey = k
+						return k
+					}, nil)
+				})
+
+				It("should create a key acting on behalf of the user", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err).To(BeNil())
+					Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+					Expect(apiKey.Name).To(Equal("POS"))
+					Expect(apiKey.Role).To(Equal(model.RoleEditor))
+					Expect(apiKey.RestaurantID).To(Equal(restaurant.ID))
+					Expect(apiKey.CreatedBy).To(Equal(user.ID))
+				})
+
+				It("should respond with the key that matches the stored hash", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err).To(BeNil())
+					var result map[string]interface{}
+					json.Unmarshal(responseRecorder.Body.Bytes(), &result)
+					key, ok := result["key"].(string)
+					Expect(ok).To(BeTrue())
+					Expect(model.HashAPIKey(key)).To(Equal(apiKey.Hash))
+				})
+			})
+
+			Context("with the owner role", func() {
+				BeforeEach(func() {
+					requestData = map[string]interface{}{
+						"name": "POS",
+						"role": "owner",
+					}
+				})
+
+				It("should fail", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err.Code).To(Equal(http.StatusBadRequest))
+				})
+			})
+
+			Context("without a name", func() {
+				BeforeEach(func() {
+					requestData = map[string]interface{}{
+						"role": "viewer",
+					}
+				})
+
+				It("should fail", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err.Code).To(Equal(http.StatusBadRequest))
+				})
+			})
+		})
+
+		Describe("DELETE /restaurants/:restaurantID/api_keys/:id", func() {
+			var (
+				handler  router.HandlerWithParams
+				apiKeyID bson.ObjectId
+			)
+
+			BeforeEach(func() {
+				requestMethod = "DELETE"
+				apiKeyID = bson.NewObjectId()
+				params = append(params, httprouter.Param{
+					Key:   "id",
+					Value: apiKeyID.Hex(),
+				})
+			})
+
+			JustBeforeEach(func() {
+				handler = DeleteRestaurantAPIKey(sessionManager, usersCollection, memberships, apiKeys, restaurants, auditLog)
+			})
+
+			Context("with the key existing", func() {
+				BeforeEach(func() {
+					apiKeys.On("Remove", apiKeyID, restaurant.ID).Return(nil)
+				})
+
+				It("should revoke the key", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err).To(BeNil())
+				})
+
+				It("should record the revocation in the audit log", func() {
+					handler(responseRecorder, request, params)
+					Expect(auditEntries).To(HaveLen(1))
+					Expect(auditEntries[0].ActorID).To(Equal(user.ID))
+					Expect(auditEntries[0].RestaurantID).To(Equal(restaurant.ID))
+					Expect(auditEntries[0].Action).To(Equal("api_key.delete"))
+					Expect(auditEntries[0].Target).To(Equal(apiKeyID.Hex()))
+					Expect(auditEntries[0].Outcome).To(Equal(model.AuditOutcomeSuccess))
+				})
+			})
+
+			Context("with the key not existing", func() {
+				BeforeEach(func() {
+					apiKeys.On("Remove", apiKeyID, restaurant.ID).Return(mgo.ErrNotFound)
+				})
+
+				It("should fail with StatusNotFound", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err.Code).To(Equal(http.StatusNotFound))
+				})
+
+				It("should record the failure in the audit log", func() {
+					handler(responseRecorder, request, params)
+					Expect(auditEntries).To(HaveLen(1))
+					Expect(auditEntries[0].Outcome).To(Equal(model.AuditOutcomeFailure))
+					Expect(auditEntries[0].Error).NotTo(BeEmpty())
+				})
+			})
+		})
+	})
+
+	Describe("authenticating with an API key", func() {
+		var (
+			handler router.HandlerWithParams
+			apiKey  *model.APIKey
+			key     string
+		)
+
+		BeforeEach(func() {
+			requestMethod = "GET"
+			var err error
+			apiKey, key, err = model.NewAPIKey(restaurant.ID, user.ID, "POS", model.RoleViewer)
+			Expect(err).NotTo(HaveOccurred())
+			apiKey.ID = bson.NewObjectId()
+			usersCollection.On("GetID", user.ID).Return(user, nil)
+		})
+
+		JustBeforeEach(func() {
+			request.Header.Set("Authorization", "Bearer "+key)
+			handler = Restaurant(restaurants, sessionManager, usersCollection, memberships, apiKeys)
+		})
+
+		Context("with a valid key", func() {
+			BeforeEach(func() {
+				apiKeys.On("GetKey", key).Return(apiKey, nil)
+				apiKeys.On("SetLastUsed", apiKey.ID, mock.AnythingOfType("time.Time")).Return(nil)
+			})
+
+			It("should succeed without a session", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+				sessionManager.AssertNotCalled(GinkgoT(), "Resolve", mock.Anything)
+			})
+		})
+
+		Context("with a key that was just used", func() {
+			BeforeEach(func() {
+				apiKey.LastUsedAt = time.Now()
+				apiKeys.On("GetKey", key).Return(apiKey, nil)
+			})
+
+			It("should not update the last used time", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+			})
+		})
+
+		Context("with a key of another restaurant", func() {
+			BeforeEach(func() {
+				apiKey.RestaurantID = bson.NewObjectId()
+				apiKeys.On("GetKey", key).Return(apiKey, nil)
+				apiKeys.On("SetLastUsed", apiKey.ID, mock.AnythingOfType("time.Time")).Return(nil)
+			})
+
+			It("should be forbidden", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("with a key whose role doesn't allow the request", func() {
+			BeforeEach(func() {
+				apiKeys.On("GetKey", key).Return(apiKey, nil)
+				apiKeys.On("SetLastUsed", apiKey.ID, mock.AnythingOfType("time.Time")).Return(nil)
+			})
+
+			JustBeforeEach(func() {
+				handler = DeleteRestaurant(restaurants, sessionManager, usersCollection, memberships, apiKeys, nil, nil, nil, nil, nil, auditLog)
+			})
+
+			It("should be forbidden", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("with a revoked key", func() {
+			BeforeEach(func() {
+				apiKeys.On("GetKey", key).Return(nil, mgo.ErrNotFound)
+			})
+
+			It("should be unauthorized", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("with something else in the Authorization header", func() {
+			BeforeEach(func() {
+				key = "not a key"
+			})
+
+			It("should be unauthorized", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+})
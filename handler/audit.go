@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Lunchr/luncher-api/audit"
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/session"
+)
+
+const (
+	// defaultAuditLogPeriod is how far back the audit log is listed if the request doesn't specify the start
+	// of the time range
+	defaultAuditLogPeriod = 30 * 24 * time.Hour
+	// auditLogLimit limits the number of audit log entries listed
+	auditLogLimit = 1000
+)
+
+// RestaurantAuditLog returns a handler that lists the restaurant's audit log entries, most recent first. The
+// time range can be limited with the 'from' and 'to' query params in the RFC 3339 format. By default, the
+// entries of the last 30 days are listed.
+func RestaurantAuditLog(sessionManager session.Manager, users db.Users, memberships db.Memberships, apiKeys db.APIKeys,
+	restaurants db.Restaurants, auditEntries db.AuditEntries) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+		to := time.Now()
+		if toString := r.FormValue("to"); toString != "" {
+			var err error
+			if to, err = time.Parse(time.RFC3339, toString); err != nil {
+				return router.NewHandlerError(err, "Failed to parse the end of the time range", http.StatusBadRequest)
+			}
+		}
+		from := to.Add(-defaultAuditLogPeriod)
+		if fromString := r.FormValue("from"); fromString != "" {
+			var err error
+			if from, err = time.Parse(time.RFC3339, fromString); err != nil {
+				return router.NewHandlerError(err, "Failed to parse the start of the time range", http.StatusBadRequest)
+			}
+		}
+		if !from.Before(to) {
+			return router.NewSimpleHandlerError("The start of the time range must be before its end", http.StatusBadRequest)
+		}
+		entries, err := auditEntries.GetForRestaurant(restaurant.ID, from, to, auditLogLimit)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to find the restaurant's audit log", http.StatusInternalServerError)
+		}
+		if entries == nil {
+			entries = []*model.AuditEntry{}
+		}
+		return writeJSON(w, entries)
+	}
+	return forRestaurant(sessionManager, users, memberships, apiKeys, restaurants, model.RoleOwner, handler)
+}
+
+// audited records the handler's outcome in the audit log as the action taken by the user
+func audited(auditLog audit.Log, action string, handler HandlerWithUser) HandlerWithUser {
+	return func(w http.ResponseWriter, r *http.Request, user *model.User) *router.HandlerError {
+		record := auditRecorder(auditLog, r, user, action)
+		handlerErr := handler(w, r, user)
+		record("", "", handlerErr)
+		return handlerErr
+	}
+}
+
+// HandlerWithUserReturningRestaurantID is a HandlerWithUser that also returns the ID of the restaurant it acted
+// on, for the handlers that don't know the restaurant before they've run. The ID is empty if the handler failed
+// before finding the restaurant.
+type HandlerWithUserReturningRestaurantID func(w http.ResponseWriter, r *http.Request, user *model.User) (bson.ObjectId,
+	*router.HandlerError)
+
+// auditedWithRestaurantID is like audited, but the restaurant is the one returned by the handler
+func auditedWithRestaurantID(auditLog audit.Log, action string,
+	handler HandlerWithUserReturningRestaurantID) HandlerWithUser {
+	return func(w http.ResponseWriter, r *http.Request, user *model.User) *router.HandlerError {
+		record := auditRecorder(auditLog, r, user, action)
+		restaurantID, handlerErr := handler(w, r, user)
+		record(restaurantID, "", handlerErr)
+		return handlerErr
+	}
+}
+
+// auditedWithParams is like audited, but the restaurant and the target of the action are taken from the params
+func auditedWithParams(auditLog audit.Log, action string,
+	handler HandlerWithParamsWithUser) HandlerWithParamsWithUser {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User) *router.HandlerError {
+		record := auditRecorder(auditLog, r, user, action)
+		handlerErr := handler(w, r, ps, user)
+		var restaurantID bson.ObjectId
+		if restaurantIDString := ps.ByName("restaurantID"); bson.IsObjectIdHex(restaurantIDString) {
+			restaurantID = bson.ObjectIdHex(restaurantIDString)
+		}
+		record(restaurantID, auditTarget(ps), handlerErr)
+		return handlerErr
+	}
+}
+
+// auditedForRestaurant records the handler's outcome in the audit log as the action taken by the user on
+// the restaurant
+func auditedForRestaurant(auditLog audit.Log, action string,
+	handler HandlerWithRestaurant) HandlerWithRestaurant {
+	return func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+		record := auditRecorder(auditLog, r, user, action)
+		handlerErr := handler(w, r, user, restaurant)
+		record(restaurant.ID, "", handlerErr)
+		return handlerErr
+	}
+}
+
+// auditedForRestaurantWithParams is like auditedForRestaurant, but the target of the action is taken from
+// the params
+func auditedForRestaurantWithParams(auditLog audit.Log, action string,
+	handler HandlerWithParamsWithRestaurant) HandlerWithParamsWithRestaurant {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User,
+		restaurant *model.Restaurant) *router.HandlerError {
+		record := auditRecorder(auditLog, r, user, action)
+		handlerErr := handler(w, r, ps, user, restaurant)
+		record(restaurant.ID, auditTarget(ps), handlerErr)
+		return handlerErr
+	}
+}
+
+// HandlerReturningUserID is a router.Handler that also returns the ID of the user it acted for, for the handlers
+// that don't require a login. The ID is empty if the handler failed before finding or creating the user.
+type HandlerReturningUserID func(w http.ResponseWriter, r *http.Request) (bson.ObjectId, *router.HandlerError)
+
+// auditedWithUserID records the handler's outcome in the audit log as the action taken by the user returned by
+// the handler
+func auditedWithUserID(auditLog audit.Log, action string, handler HandlerReturningUserID) router.Handler {
+	return func(w http.ResponseWriter, r *http.Request) *router.HandlerError {
+		userID, handlerErr := handler(w, r)
+		auditLog.Record(audit.NewEntry(userID, "", action, "", handlerErr))
+		return handlerErr
+	}
+}
+
+// auditRecorder returns a function that records the outcome of the action in the audit log. The API key or
+// the impersonating administrator the request was made through is determined right away, from the session
+// already resolved for the request, because the action may end the session.
+func auditRecorder(auditLog audit.Log, r *http.Request, user *model.User,
+	action string) func(restaurantID bson.ObjectId, target string, handlerErr *router.HandlerError) {
+	var apiKeyPrefix string
+	var impersonatorID bson.ObjectId
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		apiKeyPrefix = model.APIKeyPrefix(strings.TrimPrefix(authorization, apiKeyAuthScheme))
+	} else if userSession := resolvedSession(r); userSession != nil {
+		impersonatorID = userSession.ImpersonatorID
+	}
+	return func(restaurantID bson.ObjectId, target string, handlerErr *router.HandlerError) {
+		entry := audit.NewEntry(user.ID, restaurantID, action, target, handlerErr)
+		entry.APIKeyPrefix = apiKeyPrefix
+		entry.ImpersonatorID = impersonatorID
+		auditLog.Record(entry)
+	}
+}
+
+// auditTarget returns the params other than the restaurant's ID, which identify what the action is taken on
+func auditTarget(ps httprouter.Params) string {
+	var values []string
+	for _, param := range ps {
+		if param.Key != "restaurantID" {
+			values = append(values, param.Value)
+		}
+	}
+	return strings.Join(values, "/")
+}
@@ -0,0 +1,111 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit", func() {
+	var (
+		sessionManager         *mocks.Manager
+		usersCollection        *mocks.Users
+		memberships            *mocks.Memberships
+		apiKeys                *mocks.APIKeys
+		restaurants            *mocks.Restaurants
+		auditEntriesCollection *mocks.AuditEntries
+		user                   *model.User
+		restaurant             *model.Restaurant
+		params                 httprouter.Params
+		handler                router.HandlerWithParams
+	)
+
+	BeforeEach(func() {
+		sessionManager = new(mocks.Manager)
+		usersCollection = new(mocks.Users)
+		memberships = new(mocks.Memberships)
+		apiKeys = new(mocks.APIKeys)
+		restaurants = new(mocks.Restaurants)
+		auditEntriesCollection = new(mocks.AuditEntries)
+		restaurant = &model.Restaurant{
+			ID:   bson.NewObjectId(),
+			Name: "Asian Chef",
+		}
+		user = &model.User{
+			ID: bson.NewObjectId(),
+		}
+		memberships.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+			Role: model.RoleOwner,
+		}, nil)
+		restaurants.On("GetID", restaurant.ID).Return(restaurant, nil)
+		sessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+		usersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+		params = httprouter.Params{httprouter.Param{
+			Key:   "restaurantID",
+			Value: restaurant.ID.Hex(),
+		}}
+		requestMethod = "GET"
+		requestQuery = url.Values{}
+	})
+
+	JustBeforeEach(func() {
+		handler = RestaurantAuditLog(sessionManager, usersCollection, memberships, apiKeys, restaurants, auditEntriesCollection)
+	})
+
+	Describe("GET /restaurants/:restaurantID/audit_log", func() {
+		It("should list the entries of the last 30 days by default", func() {
+			auditEntriesCollection.On("GetForRestaurant", restaurant.ID, mock.AnythingOfType("time.Time"),
+				mock.AnythingOfType("time.Time"), mock.AnythingOfType("int")).Return(func(_ bson.ObjectId, from, to time.Time, _ int) []*model.AuditEntry {
+				Expect(to.Sub(from)).To(Equal(30 * 24 * time.Hour))
+				Expect(to).To(BeTemporally("~", time.Now(), time.Second))
+				return []*model.AuditEntry{{Action: "offer.create", Outcome: model.AuditOutcomeSuccess}}
+			}, nil)
+			err := handler(responseRecorder, request, params)
+			Expect(err).To(BeNil())
+			var result []*model.AuditEntry
+			json.Unmarshal(responseRecorder.Body.Bytes(), &result)
+			Expect(result).To(HaveLen(1))
+			Expect(result[0].Action).To(Equal("offer.create"))
+		})
+
+		Context("with the time range specified", func() {
+			BeforeEach(func() {
+				requestQuery.Set("from", "2016-01-01T00:00:00Z")
+				requestQuery.Set("to", "2016-02-01T00:00:00Z")
+			})
+
+			It("should list the entries in the range", func() {
+				from := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+				to := time.Date(2016, 2, 1, 0, 0, 0, 0, time.UTC)
+				auditEntriesCollection.On("GetForRestaurant", restaurant.ID, from, to, mock.AnythingOfType("int")).Return(nil, nil)
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+				Expect(responseRecorder.Body.String()).To(MatchJSON("[]"))
+			})
+		})
+
+		Context("with the start of the range after its end", func() {
+			BeforeEach(func() {
+				requestQuery.Set("from", "2016-02-01T00:00:00Z")
+				requestQuery.Set("to", "2016-01-01T00:00:00Z")
+			})
+
+			It("should fail", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+})
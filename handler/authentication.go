@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"context"
 	"net/http"
+	"strings"
+	"time"
 
 	"gopkg.in/mgo.v2"
 
@@ -12,9 +15,19 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
+const (
+	apiKeyAuthScheme = "Bearer "
+	// apiKeyLastUsedInterval limits how often the last used time of an API key is updated in the DB
+	apiKeyLastUsedInterval = time.Minute
+)
+
+type contextKey int
+
+const sessionContextKey contextKey = iota
+
 func Logout(sessionManager session.Manager, usersCollection db.Users) router.Handler {
 	handler := func(w http.ResponseWriter, r *http.Request, user *model.User) *router.HandlerError {
-		if err := usersCollection.UnsetSessionID(user.ID); err != nil {
+		if err := sessionManager.End(w, r); err != nil {
 			return router.NewHandlerError(err, "", http.StatusInternalServerError)
 		}
 		http.Redirect(w, r, "/", http.StatusSeeOther)
@@ -28,36 +41,106 @@ type HandlerWithParamsWithUser func(w http.ResponseWriter, r *http.Request, ps h
 
 func checkLogin(sessionManager session.Manager, usersCollection db.Users, handler HandlerWithUser) router.Handler {
 	return func(w http.ResponseWriter, r *http.Request) *router.HandlerError {
-		user, handlerErr := getUserForSession(sessionManager, usersCollection, r)
+		user, userSession, handlerErr := getUserForSession(sessionManager, usersCollection, r)
 		if handlerErr != nil {
 			return handlerErr
 		}
-		return handler(w, r, user)
+		return handler(w, withSession(r, userSession), user)
 	}
 }
 
 func checkLoginWithParams(sessionManager session.Manager, usersCollection db.Users, handler HandlerWithParamsWithUser) router.HandlerWithParams {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) *router.HandlerError {
-		user, handlerErr := getUserForSession(sessionManager, usersCollection, r)
+		user, userSession, handlerErr := getUserForSession(sessionManager, usersCollection, r)
 		if handlerErr != nil {
 			return handlerErr
 		}
-		return handler(w, r, ps, user)
+		return handler(w, withSession(r, userSession), ps, user)
 	}
 }
 
-func getUserForSession(sessionManager session.Manager, usersCollection db.Users, r *http.Request) (*model.User, *router.HandlerError) {
-	sessionID, err := sessionManager.Get(r)
+// checkAdmin only lets platform administrators through to the handler
+func checkAdmin(sessionManager session.Manager, usersCollection db.Users, handler HandlerWithUser) router.Handler {
+	return checkLogin(sessionManager, usersCollection, func(w http.ResponseWriter, r *http.Request, user *model.User) *router.HandlerError {
+		if !user.IsAdmin {
+			return router.NewSimpleHandlerError("Only administrators are allowed to do this", http.StatusForbidden)
+		}
+		return handler(w, r, user)
+	})
+}
+
+func checkAdminWithParams(sessionManager session.Manager, usersCollection db.Users, handler HandlerWithParamsWithUser) router.HandlerWithParams {
+	return checkLoginWithParams(sessionManager, usersCollection, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params,
+		user *model.User) *router.HandlerError {
+		if !user.IsAdmin {
+			return router.NewSimpleHandlerError("Only administrators are allowed to do this", http.StatusForbidden)
+		}
+		return handler(w, r, ps, user)
+	})
+}
+
+// getUserForSession returns the logged in user along with the session, which is resolved only once per
+// request, because resolving it extends the session
+func getUserForSession(sessionManager session.Manager, usersCollection db.Users, r *http.Request) (*model.User,
+	*model.Session, *router.HandlerError) {
+	userSession, err := sessionManager.Resolve(r)
 	if err == session.ErrNotFound {
-		return nil, router.NewHandlerError(err, "Session not established for this connection", http.StatusUnauthorized)
+		return nil, nil, router.NewHandlerError(err, "Session not established for this connection", http.StatusUnauthorized)
+	} else if err != nil {
+		return nil, nil, router.NewHandlerError(err, "Failed to get the session", http.StatusInternalServerError)
+	}
+	user, err := usersCollection.GetID(userSession.UserID)
+	if err == mgo.ErrNotFound {
+		return nil, nil, router.NewHandlerError(err, "User not logged in", http.StatusUnauthorized)
 	} else if err != nil {
-		return nil, router.NewHandlerError(err, "Failed to get the session", http.StatusInternalServerError)
+		return nil, nil, router.NewHandlerError(err, "Failed to find the user for this session", http.StatusInternalServerError)
 	}
-	user, err := usersCollection.GetSessionID(sessionID)
+	return user, userSession, nil
+}
+
+// withSession returns a copy of the request that carries the session resolved for it
+func withSession(r *http.Request, userSession *model.Session) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), sessionContextKey, userSession))
+}
+
+// resolvedSession returns the session the request was made with, or nil if the request was authenticated
+// with an API key
+func resolvedSession(r *http.Request) *model.Session {
+	userSession, _ := r.Context().Value(sessionContextKey).(*model.Session)
+	return userSession
+}
+
+// getUserForSessionOrAPIKey returns the user the API key in the Authorization header acts on behalf of, along
+// with the key. Without the header, the logged in user is returned along with the session.
+func getUserForSessionOrAPIKey(sessionManager session.Manager, usersCollection db.Users, apiKeys db.APIKeys,
+	r *http.Request) (*model.User, *model.Session, *model.APIKey, *router.HandlerError) {
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		user, userSession, handlerErr := getUserForSession(sessionManager, usersCollection, r)
+		return user, userSession, nil, handlerErr
+	}
+	key := strings.TrimPrefix(authorization, apiKeyAuthScheme)
+	if key == authorization || !model.LooksLikeAPIKey(key) {
+		return nil, nil, nil, router.NewSimpleHandlerError("Expecting an API key in the Authorization header in the form of 'Bearer <key>'",
+			http.StatusUnauthorized)
+	}
+	apiKey, err := apiKeys.GetKey(key)
 	if err == mgo.ErrNotFound {
-		return nil, router.NewHandlerError(err, "User not logged in", http.StatusUnauthorized)
+		return nil, nil, nil, router.NewHandlerError(err, "Invalid or revoked API key", http.StatusUnauthorized)
 	} else if err != nil {
-		return nil, router.NewHandlerError(err, "Failed to find the user for this session", http.StatusInternalServerError)
+		return nil, nil, nil, router.NewHandlerError(err, "Failed to check the API key", http.StatusInternalServerError)
+	}
+	user, err := usersCollection.GetID(apiKey.CreatedBy)
+	if err == mgo.ErrNotFound {
+		return nil, nil, nil, router.NewHandlerError(err, "The user who created the API key no longer exists", http.StatusUnauthorized)
+	} else if err != nil {
+		return nil, nil, nil, router.NewHandlerError(err, "Failed to find the user for this API key", http.StatusInternalServerError)
+	}
+	now := time.Now()
+	if now.Sub(apiKey.LastUsedAt) > apiKeyLastUsedInterval {
+		if err = apiKeys.SetLastUsed(apiKey.ID, now); err != nil {
+			return nil, nil, nil, router.NewHandlerError(err, "Failed to update the API key", http.StatusInternalServerError)
+		}
 	}
-	return user, nil
+	return user, nil, apiKey, nil
 }
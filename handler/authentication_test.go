@@ -7,7 +7,6 @@ import (
 	. "github.com/Lunchr/luncher-api/handler"
 	"github.com/Lunchr/luncher-api/router"
 	"github.com/Lunchr/luncher-api/session"
-	"gopkg.in/mgo.v2/bson"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -38,7 +37,7 @@ var _ = Describe("Authentication Handlers", func() {
 
 		Context("with user logged in", func() {
 			BeforeEach(func() {
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 			})
 
 			It("should redirect to root", func() {
@@ -74,8 +73,3 @@ func ExpectUserToBeLoggedIn(handler func() *router.HandlerError, setDependencies
 		})
 	})
 }
-
-func (m mockUsers) UnsetSessionID(id bson.ObjectId) error {
-	Expect(id).To(Equal(objectID))
-	return nil
-}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Lunchr/luncher-api/audit"
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/facebook"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/session"
+	"github.com/Lunchr/luncher-api/storage"
+)
+
+// PutRestaurantCollageSettings handles PUT requests to /restaurants/:restaurantID/collage_settings. It replaces the
+// settings of the photo collages that get posted on Facebook with the restaurant's offers. A new logo can be uploaded
+// as a data URL, which replaces the logo_checksum. The posts of the restaurant's upcoming offers are then updated
+// with the new collages.
+func PutRestaurantCollageSettings(c db.Restaurants, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys, offers db.Offers, regions db.Regions, imageStorage storage.Images, facebookPost facebook.Post,
+	auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+		var settings model.CollageSettingsPOST
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			return router.NewHandlerError(err, "Failed to parse the collage settings", http.StatusBadRequest)
+		}
+		if err := facebook.ValidateCollageSettings(&settings.CollageSettings); err != nil {
+			return router.NewHandlerError(err, fmt.Sprintf("Invalid collage settings: %v", err), http.StatusBadRequest)
+		}
+		if settings.LogoImageData != "" {
+			logoChecksum, err := imageStorage.ChecksumDataURL(settings.LogoImageData)
+			if err != nil {
+				return router.NewHandlerError(err, "Failed to parse the logo", http.StatusBadRequest)
+			}
+			if err = storeImage(settings.LogoImageData, logoChecksum, imageStorage); err != nil {
+				return router.NewHandlerError(err, "Failed to store the logo", http.StatusInternalServerError)
+			}
+			settings.LogoChecksum = logoChecksum
+		} else if settings.LogoChecksum != "" {
+			hasLogo, err := imageStorage.HasChecksum(settings.LogoChecksum)
+			if err != nil {
+				return router.NewHandlerError(err, "Failed to find the logo", http.StatusInternalServerError)
+			} else if !hasLogo {
+				return router.NewSimpleHandlerError("The specified logo doesn't exist", http.StatusBadRequest)
+			}
+		}
+		restaurant.CollageSettings = &settings.CollageSettings
+		if err := c.UpdateID(restaurant.ID, restaurant); err != nil {
+			return router.NewHandlerError(err, "Failed to update the restaurant in the DB", http.StatusInternalServerError)
+		}
+		if handlerErr := updateUpcomingPosts(offers, regions, facebookPost, user, restaurant); handlerErr != nil {
+			return handlerErr
+		}
+		return writeJSON(w, restaurant.CollageSettings)
+	}
+	return forRestaurant(sessionManager, users, memberships, apiKeys, c, model.RoleOwner,
+		auditedForRestaurant(auditLog, "collage_settings.update", handler))
+}
+
+// updateUpcomingPosts updates the restaurant's posts for all the dates that have offers from today on
+func updateUpcomingPosts(offers db.Offers, regions db.Regions, facebookPost facebook.Post, user *model.User,
+	restaurant *model.Restaurant) *router.HandlerError {
+	if restaurant.FacebookPageID == "" {
+		return nil
+	}
+	location, handlerErr := getLocationForRestaurant(restaurant, regions)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	today, _ := getTodaysTimeRange(location)
+	upcomingOffers, err := offers.GetForRestaurant(restaurant.ID, today)
+	if err != nil {
+		return router.NewHandlerError(err, "Failed to find upcoming offers for this restaurant", http.StatusInternalServerError)
+	}
+	updatedDates := make(map[model.DateWithoutTime]bool)
+	for _, offer := range upcomingOffers {
+		date := model.DateFromTime(offer.FromTime, location)
+		if updatedDates[date] {
+			continue
+		}
+		if handlerErr = facebookPost.Update(date, user, restaurant); handlerErr != nil {
+			return handlerErr
+		}
+		updatedDates[date] = true
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CollageSettingsHandler", func() {
+	var (
+		sessionManager  *mocks.Manager
+		usersCollection *mocks.Users
+		memberships     *mocks.Memberships
+		apiKeys         *mocks.APIKeys
+		restaurants     *mocks.Restaurants
+		imageStorage    *mocks.Images
+		offers          *mocks.Offers
+		regions         *mocks.Regions
+		facebookPost    *mocks.Post
+		user            *model.User
+		restaurant      *model.Restaurant
+		params          httprouter.Params
+		handler         router.HandlerWithParams
+	)
+
+	BeforeEach(func() {
+		sessionManager = new(mocks.Manager)
+		usersCollection = new(mocks.Users)
+		memberships = new(mocks.Memberships)
+		apiKeys = new(mocks.APIKeys)
+		restaurants = new(mocks.Restaurants)
+		imageStorage = new(mocks.Images)
+		offers = new(mocks.Offers)
+		regions = new(mocks.Regions)
+		facebookPost = new(mocks.Post)
+		restaurant = &model.Restaurant{ID: bson.NewObjectId()}
+		user = &model.User{
+			ID: bson.NewObjectId(),
+		}
+		memberships.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+			Role: model.RoleOwner,
+		}, nil)
+		restaurants.On("GetID", restaurant.ID).Return(restaurant, nil)
+		sessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+		usersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+		params = httprouter.Params{httprouter.Param{
+			Key:   "restaurantID",
+			Value: restaurant.ID.Hex(),
+		}}
+		requestMethod = "PUT"
+	})
+
+	JustBeforeEach(func() {
+		handler = PutRestaurantCollageSettings(restaurants, sessionManager, usersCollection, memberships, apiKeys,
+			offers, regions, imageStorage, facebookPost, auditLog)
+	})
+
+	AfterEach(func() {
+		restaurants.AssertExpectations(GinkgoT())
+		imageStorage.AssertExpectations(GinkgoT())
+		facebookPost.AssertExpectations(GinkgoT())
+	})
+
+	Describe("PUT /restaurants/:restaurantID/collage_settings", func() {
+		Context("with valid settings and a new logo", func() {
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"layout":          "bottom_heavy",
+					"max_images":      3,
+					"border_color":    "#000000",
+					"border_width":    0,
+					"logo_image_data": "logo data url",
+				}
+				imageStorage.On("ChecksumDataURL", "logo data url").Return("logo checksum", nil)
+				imageStorage.On("HasChecksum", "logo checksum").Return(false, nil)
+				imageStorage.On("StoreDataURL", "logo data url").Return(nil)
+				restaurants.On("UpdateID", restaurant.ID, mock.AnythingOfType("*model.Restaurant")).Return(nil)
+			})
+
+			It("should store the settings with the logo on the restaurant", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+				settings := restaurant.CollageSettings
+				Expect(settings.Layout).To(Equal("bottom_heavy"))
+				Expect(settings.MaxImages).To(Equal(3))
+				Expect(*settings.BorderWidth).To(Equal(0))
+				Expect(settings.LogoChecksum).To(Equal("logo checksum"))
+				Expect(auditEntries).To(HaveLen(1))
+				Expect(auditEntries[0].Action).To(Equal("collage_settings.update"))
+			})
+		})
+
+		Context("with the restaurant posting on Facebook", func() {
+			BeforeEach(func() {
+				restaurant.FacebookPageID = "pageid"
+				restaurant.Region = "Tartu"
+				requestData = map[string]interface{}{
+					"show_date": true,
+				}
+				restaurants.On("UpdateID", restaurant.ID, mock.AnythingOfType("*model.Restaurant")).Return(nil)
+				regions.On("GetName", "Tartu").Return(&model.Region{
+					Name:     "Tartu",
+					Location: "Europe/Tallinn",
+				}, nil)
+				location, err := time.LoadLocation("Europe/Tallinn")
+				Expect(err).NotTo(HaveOccurred())
+				offers.On("GetForRestaurant", restaurant.ID, mock.AnythingOfType("time.Time")).Return([]*model.Offer{
+					{CommonOfferFields: model.CommonOfferFields{FromTime: time.Date(2115, 1, 2, 10, 0, 0, 0, location)}},
+					{CommonOfferFields: model.CommonOfferFields{FromTime: time.Date(2115, 1, 2, 12, 0, 0, 0, location)}},
+					{CommonOfferFields: model.CommonOfferFields{FromTime: time.Date(2115, 1, 3, 10, 0, 0, 0, location)}},
+				}, nil)
+				facebookPost.On("Update", model.DateWithoutTime("2115-01-02"), user, restaurant).Return(nil).Once()
+				facebookPost.On("Update", model.DateWithoutTime("2115-01-03"), user, restaurant).Return(nil).Once()
+			})
+
+			It("should update the posts for each of the upcoming dates once", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+				Expect(restaurant.CollageSettings.ShowDate).To(BeTrue())
+			})
+		})
+
+		Context("with invalid logo data", func() {
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"logo_image_data": "not a data url",
+				}
+				imageStorage.On("ChecksumDataURL", "not a data url").Return("", errors.New("invalid data URL"))
+			})
+
+			It("should fail with 400 without updating the restaurant", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+				Expect(restaurant.CollageSettings).To(BeNil())
+			})
+		})
+
+		Context("with the logo failing to be stored", func() {
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"logo_image_data": "logo data url",
+				}
+				imageStorage.On("ChecksumDataURL", "logo data url").Return("logo checksum", nil)
+				imageStorage.On("HasChecksum", "logo checksum").Return(false, nil)
+				imageStorage.On("StoreDataURL", "logo data url").Return(errors.New("disk full"))
+			})
+
+			It("should fail with 500", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
+		Context("with an unknown logo checksum", func() {
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"logo_checksum": "some checksum",
+				}
+				imageStorage.On("HasChecksum", "some checksum").Return(false, nil)
+			})
+
+			It("should fail with 400", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("with invalid settings", func() {
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"layout": "spiral",
+				}
+			})
+
+			It("should fail with 400 without updating the restaurant", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+				Expect(restaurant.CollageSettings).To(BeNil())
+			})
+		})
+	})
+})
@@ -9,12 +9,17 @@ import (
 )
 
 func writeJSON(w http.ResponseWriter, v interface{}) *HandlerError {
+	return writeJSONWithCode(w, v, http.StatusOK)
+}
+
+func writeJSONWithCode(w http.ResponseWriter, v interface{}, code int) *HandlerError {
 	data, err := json.Marshal(v)
 	if err != nil {
 		return &HandlerError{err, "", http.StatusInternalServerError}
 	}
 	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	w.Write(data)
 	return nil
 }
@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Lunchr/luncher-api/audit"
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/mail"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/session"
+)
+
+const (
+	minPasswordLength        = 8
+	emailVerificationTTL     = 24 * time.Hour
+	passwordResetTTL         = time.Hour
+	emailVerificationSubject = "Please confirm your email address for Luncher"
+	emailVerificationBody    = `Hi!
+
+Please confirm your email address by following this link:
+%s
+
+If you didn't create an account on Luncher, you can safely ignore this email.`
+	passwordResetSubject = "Resetting your Luncher password"
+	passwordResetBody    = `Hi!
+
+You can choose a new password for your Luncher account by following this link:
+%s
+
+The link is valid for an hour. If you didn't ask to reset your password, you can safely ignore this email.`
+	alreadyRegisteredSubject = "Your Luncher account"
+	alreadyRegisteredBody    = `Hi!
+
+Someone tried to create a Luncher account with this email address, but you already have one. You can log in
+with your password or, if you've forgotten it, ask for a password reset link on the login page.
+
+If you didn't try to create an account, you can safely ignore this email.`
+)
+
+// dummyPasswordHash is compared against when there's no password to check, so that the response wouldn't
+// be faster for the emails that aren't registered
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("luncher dummy password"), bcrypt.DefaultCost)
+
+type emailCredentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type emailRegistration struct {
+	emailCredentials
+	// Token is the registration access token the user was given
+	Token string `json:"token"`
+}
+
+type passwordReset struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// RegisterWithEmail returns a handler that creates a user who logs in with an email and a password. Just like
+// with the Facebook registration, the request has to include a registration access token, which gets claimed
+// for the new user. The user can't log in before they've confirmed their email address by following the link
+// sent to them, which should lead to verificationURL, which in turn should be handled by VerifyEmail. The response
+// is the same if the address has already been registered, in which case its owner is notified by email instead.
+func RegisterWithEmail(users db.Users, emailTokens db.EmailTokens, tokens db.RegistrationAccessTokens, sender mail.Sender,
+	verificationURL string, auditLog audit.Log) router.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) (bson.ObjectId, *router.HandlerError) {
+		var registration emailRegistration
+		if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+			return "", router.NewHandlerError(err, "Failed to parse the email and password", http.StatusBadRequest)
+		}
+		if registration.Token == "" {
+			return "", router.NewSimpleHandlerError("Expecting a registration access token", http.StatusBadRequest)
+		}
+		token, err := model.TokenFromString(registration.Token)
+		if err != nil {
+			return "", router.NewHandlerError(err, "Failed to parse the token", http.StatusBadRequest)
+		}
+		email := db.NormalizeEmail(registration.Email)
+		if !strings.Contains(email, "@") {
+			return "", router.NewStringHandlerError("Invalid email", "Please specify a valid email address", http.StatusBadRequest)
+		}
+		passwordHash, handlerErr := hashPassword(registration.Password)
+		if handlerErr != nil {
+			return "", handlerErr
+		}
+		userID := bson.NewObjectId()
+		if handlerErr = claimRegistrationAccessToken(tokens, token, userID); handlerErr != nil {
+			return "", handlerErr
+		}
+		if _, err = users.GetEmail(email); err == nil {
+			// Respond just like for a new address, so that this couldn't be used to find out who has
+			// registered, and let the owner of the address know instead
+			releaseRegistrationAccessToken(tokens, token, userID)
+			if err = sender.Send(mail.Message{
+				To:      email,
+				Subject: alreadyRegisteredSubject,
+				Body:    alreadyRegisteredBody,
+			}); err != nil {
+				return "", router.NewHandlerError(err, "Failed to send the email", http.StatusBadGateway)
+			}
+			w.WriteHeader(http.StatusCreated)
+			return "", nil
+		} else if err != mgo.ErrNotFound {
+			releaseRegistrationAccessToken(tokens, token, userID)
+			return "", router.NewHandlerError(err, "Failed to check the DB for users", http.StatusInternalServerError)
+		}
+		user := &model.User{
+			ID:           userID,
+			Email:        email,
+			PasswordHash: passwordHash,
+		}
+		if err = users.Insert(user); err != nil {
+			// Let the user try again with the same token
+			releaseRegistrationAccessToken(tokens, token, userID)
+			return "", router.NewHandlerError(err, "Failed to create a User object in the DB", http.StatusInternalServerError)
+		}
+		if handlerErr = sendEmailToken(user, model.EmailTokenVerifyEmail, emailVerificationTTL, emailTokens, sender, verificationURL,
+			emailVerificationSubject, emailVerificationBody); handlerErr != nil {
+			return user.ID, handlerErr
+		}
+		w.WriteHeader(http.StatusCreated)
+		return user.ID, nil
+	}
+	return auditedWithUserID(auditLog, "user.register", handler)
+}
+
+// VerifyEmail returns a handler that confirms the email address of the user the token in the 'token' query
+// parameter was sent to. The user is also logged in and redirected to the admin page.
+func VerifyEmail(sessionManager session.Manager, users db.Users, emailTokens db.EmailTokens) router.Handler {
+	return func(w http.ResponseWriter, r *http.Request) *router.HandlerError {
+		emailToken, handlerErr := claimEmailToken(r.FormValue("token"), model.EmailTokenVerifyEmail, emailTokens)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		if err := users.SetEmailVerified(emailToken.UserID); err != nil {
+			return router.NewHandlerError(err, "Failed to mark the email address as verified", http.StatusInternalServerError)
+		}
+		if err := sessionManager.Start(w, r, emailToken.UserID); err != nil {
+			return router.NewHandlerError(err, "Failed to start a session", http.StatusInternalServerError)
+		}
+		http.Redirect(w, r, "/#/admin", http.StatusSeeOther)
+		return nil
+	}
+}
+
+// LoginWithEmail returns a handler that logs in the user with the email and password specified in the request
+// body. After that the user is recognized by checkLogin just like the users who've logged in through Facebook.
+func LoginWithEmail(sessionManager session.Manager, users db.Users) router.Handler {
+	return func(w http.ResponseWriter, r *http.Request) *router.HandlerError {
+		var credentials emailCredentials
+		if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+			return router.NewHandlerError(err, "Failed to parse the email and password", http.StatusBadRequest)
+		}
+		user, err := users.GetEmail(credentials.Email)
+		if err == mgo.ErrNotFound {
+			bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(credentials.Password))
+			return router.NewHandlerError(err, "Invalid email or password", http.StatusUnauthorized)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to find the user from DB", http.StatusInternalServerError)
+		} else if len(user.PasswordHash) == 0 {
+			bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(credentials.Password))
+			return router.NewStringHandlerError("User has no password", "Invalid email or password", http.StatusUnauthorized)
+		}
+		if err = bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(credentials.Password)); err != nil {
+			return router.NewHandlerError(err, "Invalid email or password", http.StatusUnauthorized)
+		} else if !user.EmailVerified {
+			return router.NewSimpleHandlerError("Please confirm your email address before logging in", http.StatusForbidden)
+		}
+		if err = sessionManager.Start(w, r, user.ID); err != nil {
+			return router.NewHandlerError(err, "Failed to start a session", http.StatusInternalServerError)
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+}
+
+// RequestPasswordReset returns a handler that emails a password reset link to the user with the email address
+// specified in the request body. The link should lead to resetURL, which should let the user choose a new
+// password and send it along with the token to ResetPassword. The response is the same whether or not such a
+// user exists, so that it couldn't be used to find out who has registered.
+func RequestPasswordReset(users db.Users, emailTokens db.EmailTokens, sender mail.Sender, resetURL string) router.Handler {
+	return func(w http.ResponseWriter, r *http.Request) *router.HandlerError {
+		var credentials emailCredentials
+		if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+			return router.NewHandlerError(err, "Failed to parse the email", http.StatusBadRequest)
+		}
+		user, err := users.GetEmail(credentials.Email)
+		if err == mgo.ErrNotFound {
+			w.WriteHeader(http.StatusOK)
+			return nil
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to find the user from DB", http.StatusInternalServerError)
+		}
+		if handlerErr := sendEmailToken(user, model.EmailTokenResetPassword, passwordResetTTL, emailTokens, sender, resetURL,
+			passwordResetSubject, passwordResetBody); handlerErr != nil {
+			return handlerErr
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+}
+
+// ResetPassword returns a handler that sets a new password for the user the password reset token was sent to.
+// Because the user has proven that they own the email address, the address is considered verified. All of the
+// user's sessions are ended, so that whoever knew the previous password would be logged out.
+func ResetPassword(users db.Users, emailTokens db.EmailTokens, sessions db.Sessions, auditLog audit.Log) router.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) (bson.ObjectId, *router.HandlerError) {
+		var reset passwordReset
+		if err := json.NewDecoder(r.Body).Decode(&reset); err != nil {
+			return "", router.NewHandlerError(err, "Failed to parse the token and password", http.StatusBadRequest)
+		}
+		passwordHash, handlerErr := hashPassword(reset.Password)
+		if handlerErr != nil {
+			return "", handlerErr
+		}
+		emailToken, handlerErr := claimEmailToken(reset.Token, model.EmailTokenResetPassword, emailTokens)
+		if handlerErr != nil {
+			return "", handlerErr
+		}
+		if err := users.SetPasswordHash(emailToken.UserID, passwordHash); err != nil {
+			return emailToken.UserID, router.NewHandlerError(err, "Failed to store the new password", http.StatusInternalServerError)
+		}
+		if err := sessions.RemoveForUser(emailToken.UserID); err != nil {
+			return emailToken.UserID, router.NewHandlerError(err, "Failed to end the user's sessions", http.StatusInternalServerError)
+		}
+		if err := users.SetEmailVerified(emailToken.UserID); err != nil {
+			return emailToken.UserID, router.NewHandlerError(err, "Failed to mark the email address as verified", http.StatusInternalServerError)
+		}
+		w.WriteHeader(http.StatusOK)
+		return emailToken.UserID, nil
+	}
+	return auditedWithUserID(auditLog, "user.password_reset", handler)
+}
+
+func hashPassword(password string) ([]byte, *router.HandlerError) {
+	if len(password) < minPasswordLength {
+		return nil, router.NewStringHandlerError("Password too short",
+			fmt.Sprintf("Please use a password with at least %d characters", minPasswordLength), http.StatusBadRequest)
+	}
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, router.NewHandlerError(err, "Failed to hash the password", http.StatusInternalServerError)
+	}
+	return passwordHash, nil
+}
+
+func sendEmailToken(user *model.User, purpose model.EmailTokenPurpose, validFor time.Duration, emailTokens db.EmailTokens,
+	sender mail.Sender, baseURL, subject, bodyTemplate string) *router.HandlerError {
+	emailToken, err := model.NewEmailToken(user.ID, purpose, validFor)
+	if err != nil {
+		return router.NewHandlerError(err, "Failed to create a token", http.StatusInternalServerError)
+	}
+	if err = emailTokens.Insert(emailToken); err != nil {
+		return router.NewHandlerError(err, "Failed to store the token in the DB", http.StatusInternalServerError)
+	}
+	link := fmt.Sprintf("%s?token=%s", baseURL, emailToken.Token.String())
+	err = sender.Send(mail.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf(bodyTemplate, link),
+	})
+	if err != nil {
+		return router.NewHandlerError(err, "Failed to send the email", http.StatusBadGateway)
+	}
+	return nil
+}
+
+func claimEmailToken(tokenString string, purpose model.EmailTokenPurpose, emailTokens db.EmailTokens) (*model.EmailToken,
+	*router.HandlerError) {
+	token, err := model.TokenFromString(tokenString)
+	if err != nil {
+		return nil, router.NewHandlerError(err, "Failed to parse the token", http.StatusBadRequest)
+	}
+	emailToken, err := emailTokens.Claim(token, purpose)
+	if err == mgo.ErrNotFound {
+		return nil, router.NewHandlerError(err, "Invalid or expired token", http.StatusForbidden)
+	} else if err != nil {
+		return nil, router.NewHandlerError(err, "Failed to check the token", http.StatusInternalServerError)
+	}
+	return emailToken, nil
+}
@@ -0,0 +1,497 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/Lunchr/luncher-api/mail"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EmailLoginHandler", func() {
+	var (
+		sessionManager  *mocks.Manager
+		usersCollection *mocks.Users
+		sessions        *mocks.Sessions
+		emailTokens     *mocks.EmailTokens
+		accessTokens    *mocks.RegistrationAccessTokens
+		sender          *mocks.Sender
+		handler         router.Handler
+		userID          bson.ObjectId
+	)
+
+	BeforeEach(func() {
+		sessionManager = new(mocks.Manager)
+		usersCollection = new(mocks.Users)
+		sessions = new(mocks.Sessions)
+		emailTokens = new(mocks.EmailTokens)
+		accessTokens = new(mocks.RegistrationAccessTokens)
+		sender = new(mocks.Sender)
+		userID = bson.NewObjectId()
+		requestMethod = "POST"
+		requestQuery = url.Values{}
+	})
+
+	AfterEach(func() {
+		sessionManager.AssertExpectations(GinkgoT())
+		usersCollection.AssertExpectations(GinkgoT())
+		sessions.AssertExpectations(GinkgoT())
+		emailTokens.AssertExpectations(GinkgoT())
+		accessTokens.AssertExpectations(GinkgoT())
+		sender.AssertExpectations(GinkgoT())
+	})
+
+	Describe("RegisterWithEmail", func() {
+		var token model.Token
+
+		JustBeforeEach(func() {
+			handler = RegisterWithEmail(usersCollection, emailTokens, accessTokens, sender, "http://luncher.test/verify", auditLog)
+		})
+
+		BeforeEach(func() {
+			var err error
+			token, err = model.NewToken()
+			Expect(err).NotTo(HaveOccurred())
+			requestData = map[string]interface{}{
+				"email":    " Owner@Restaurant.test ",
+				"password": "a secret password",
+				"token":    token.String(),
+			}
+		})
+
+		Context("with a new email address", func() {
+			var (
+				insertedUser *model.User
+				emailToken   *model.EmailToken
+				message      mail.Message
+			)
+
+			BeforeEach(func() {
+				usersCollection.On("GetEmail", "owner@restaurant.test").Return(nil, mgo.ErrNotFound)
+				accessTokens.On("Claim", token, mock.AnythingOfType("bson.ObjectId")).Return(nil)
+				usersCollection.On("Insert", mock.AnythingOfType("[]*model.User")).Return(nil).Run(func(args mock.Arguments) {
+					insertedUser = args.Get(0).([]*model.User)[0]
+				})
+				emailTokens.On("Insert", mock.AnythingOfType("*model.EmailToken")).Return(nil).Run(func(args mock.Arguments) {
+					emailToken = args.Get(0).(*model.EmailToken)
+				})
+				sender.On("Send", mock.AnythingOfType("mail.Message")).Return(nil).Run(func(args mock.Arguments) {
+					message = args.Get(0).(mail.Message)
+				})
+			})
+
+			It("should create an unverified user with a hashed password", func() {
+				err := handler(responseRecorder, request)
+				Expect(err).To(BeNil())
+				Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+				Expect(insertedUser.Email).To(Equal("owner@restaurant.test"))
+				Expect(insertedUser.EmailVerified).To(BeFalse())
+				Expect(bcrypt.CompareHashAndPassword(insertedUser.PasswordHash, []byte("a secret password"))).To(Succeed())
+			})
+
+			It("should claim the registration access token for the user", func() {
+				handler(responseRecorder, request)
+				accessTokens.AssertCalled(GinkgoT(), "Claim", token, insertedUser.ID)
+			})
+
+			It("should email a verification link to the user", func() {
+				handler(responseRecorder, request)
+				Expect(emailToken.UserID).To(Equal(insertedUser.ID))
+				Expect(emailToken.Purpose).To(Equal(model.EmailTokenVerifyEmail))
+				Expect(message.To).To(Equal("owner@restaurant.test"))
+				Expect(message.Body).To(ContainSubstring("http://luncher.test/verify?token=" + emailToken.Token.String()))
+			})
+
+			It("should record the registration in the audit log", func() {
+				handler(responseRecorder, request)
+				Expect(auditEntries).To(HaveLen(1))
+				Expect(auditEntries[0].Action).To(Equal("user.register"))
+				Expect(auditEntries[0].ActorID).To(Equal(insertedUser.ID))
+				Expect(auditEntries[0].Outcome).To(Equal(model.AuditOutcomeSuccess))
+			})
+		})
+
+		Context("with the registration access token already used or expired", func() {
+			BeforeEach(func() {
+				accessTokens.On("Claim", token, mock.AnythingOfType("bson.ObjectId")).Return(mgo.ErrNotFound)
+			})
+
+			It("should fail with StatusForbidden without creating the user", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+				usersCollection.AssertNotCalled(GinkgoT(), "Insert", mock.Anything)
+			})
+		})
+
+		Context("with the user failing to be created", func() {
+			BeforeEach(func() {
+				usersCollection.On("GetEmail", "owner@restaurant.test").Return(nil, mgo.ErrNotFound)
+				accessTokens.On("Claim", token, mock.AnythingOfType("bson.ObjectId")).Return(nil)
+				usersCollection.On("Insert", mock.AnythingOfType("[]*model.User")).Return(errors.New("something went wrong"))
+				accessTokens.On("Release", token, mock.AnythingOfType("bson.ObjectId")).Return(errors.New("something else went wrong"))
+			})
+
+			It("should fail and release the token", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
+		Context("without a registration access token", func() {
+			BeforeEach(func() {
+				delete(requestData.(map[string]interface{}), "token")
+			})
+
+			It("should fail", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("with an already registered email address", func() {
+			var message mail.Message
+
+			BeforeEach(func() {
+				usersCollection.On("GetEmail", "owner@restaurant.test").Return(&model.User{}, nil)
+				accessTokens.On("Claim", token, mock.AnythingOfType("bson.ObjectId")).Return(nil)
+				accessTokens.On("Release", token, mock.AnythingOfType("bson.ObjectId")).Return(nil)
+				sender.On("Send", mock.AnythingOfType("mail.Message")).Return(nil).Run(func(args mock.Arguments) {
+					message = args.Get(0).(mail.Message)
+				})
+			})
+
+			It("should respond just like for a new address", func() {
+				err := handler(responseRecorder, request)
+				Expect(err).To(BeNil())
+				Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+			})
+
+			It("should notify the owner of the address instead of creating a user", func() {
+				handler(responseRecorder, request)
+				Expect(message.To).To(Equal("owner@restaurant.test"))
+				Expect(message.Body).To(ContainSubstring("you already have one"))
+				usersCollection.AssertNotCalled(GinkgoT(), "Insert", mock.Anything)
+				emailTokens.AssertNotCalled(GinkgoT(), "Insert", mock.Anything)
+			})
+
+			It("should release the registration access token", func() {
+				handler(responseRecorder, request)
+				accessTokens.AssertCalled(GinkgoT(), "Release", token, mock.AnythingOfType("bson.ObjectId"))
+			})
+		})
+
+		Context("with a short password", func() {
+			BeforeEach(func() {
+				requestData.(map[string]interface{})["password"] = "short"
+			})
+
+			It("should fail", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("with an invalid email address", func() {
+			BeforeEach(func() {
+				requestData.(map[string]interface{})["email"] = "owner"
+			})
+
+			It("should fail", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("VerifyEmail", func() {
+		var token model.Token
+
+		JustBeforeEach(func() {
+			handler = VerifyEmail(sessionManager, usersCollection, emailTokens)
+		})
+
+		BeforeEach(func() {
+			var err error
+			requestMethod = "GET"
+			token, err = model.NewToken()
+			Expect(err).NotTo(HaveOccurred())
+			requestQuery = url.Values{"token": {token.String()}}
+		})
+
+		Context("with a valid token", func() {
+			BeforeEach(func() {
+				emailTokens.On("Claim", token, model.EmailTokenVerifyEmail).Return(&model.EmailToken{
+					UserID: userID,
+				}, nil)
+				usersCollection.On("SetEmailVerified", userID).Return(nil)
+				sessionManager.On("Start", mock.Anything, mock.Anything, userID).Return(nil)
+			})
+
+			It("should verify the email address and log the user in", func() {
+				err := handler(responseRecorder, request)
+				Expect(err).To(BeNil())
+				Expect(responseRecorder.Code).To(Equal(http.StatusSeeOther))
+			})
+		})
+
+		Context("with an invalid or used token", func() {
+			BeforeEach(func() {
+				emailTokens.On("Claim", token, model.EmailTokenVerifyEmail).Return(nil, mgo.ErrNotFound)
+			})
+
+			It("should be forbidden", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("with a malformed token", func() {
+			BeforeEach(func() {
+				requestQuery = url.Values{"token": {"gibberish"}}
+			})
+
+			It("should fail", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("LoginWithEmail", func() {
+		var user *model.User
+
+		JustBeforeEach(func() {
+			handler = LoginWithEmail(sessionManager, usersCollection)
+		})
+
+		BeforeEach(func() {
+			passwordHash, err := bcrypt.GenerateFromPassword([]byte("a secret password"), bcrypt.MinCost)
+			Expect(err).NotTo(HaveOccurred())
+			user = &model.User{
+				ID:            userID,
+				Email:         "owner@restaurant.test",
+				PasswordHash:  passwordHash,
+				EmailVerified: true,
+			}
+			requestData = map[string]interface{}{
+				"email":    "owner@restaurant.test",
+				"password": "a secret password",
+			}
+		})
+
+		Context("with a registered user", func() {
+			BeforeEach(func() {
+				usersCollection.On("GetEmail", "owner@restaurant.test").Return(user, nil)
+			})
+
+			Context("with the correct password", func() {
+				BeforeEach(func() {
+					sessionManager.On("Start", mock.Anything, mock.Anything, userID).Return(nil)
+				})
+
+				It("should log the user in", func() {
+					err := handler(responseRecorder, request)
+					Expect(err).To(BeNil())
+				})
+			})
+
+			Context("with a wrong password", func() {
+				BeforeEach(func() {
+					requestData.(map[string]interface{})["password"] = "a wrong password"
+				})
+
+				It("should be unauthorized", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusUnauthorized))
+				})
+			})
+
+			Context("with the email address not verified", func() {
+				BeforeEach(func() {
+					user.EmailVerified = false
+				})
+
+				It("should be forbidden", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusForbidden))
+				})
+			})
+
+			Context("with the user not having a password", func() {
+				BeforeEach(func() {
+					user.PasswordHash = nil
+				})
+
+				It("should be unauthorized", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusUnauthorized))
+				})
+			})
+		})
+
+		Context("with an unknown email address", func() {
+			BeforeEach(func() {
+				usersCollection.On("GetEmail", "owner@restaurant.test").Return(nil, mgo.ErrNotFound)
+			})
+
+			It("should be unauthorized", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+
+	Describe("RequestPasswordReset", func() {
+		JustBeforeEach(func() {
+			handler = RequestPasswordReset(usersCollection, emailTokens, sender, "http://luncher.test/#/reset_password")
+		})
+
+		BeforeEach(func() {
+			requestData = map[string]interface{}{
+				"email": "owner@restaurant.test",
+			}
+		})
+
+		Context("with a registered user", func() {
+			var (
+				emailToken *model.EmailToken
+				message    mail.Message
+			)
+
+			BeforeEach(func() {
+				usersCollection.On("GetEmail", "owner@restaurant.test").Return(&model.User{
+					ID:    userID,
+					Email: "owner@restaurant.test",
+				}, nil)
+				emailTokens.On("Insert", mock.AnythingOfType("*model.EmailToken")).Return(nil).Run(func(args mock.Arguments) {
+					emailToken = args.Get(0).(*model.EmailToken)
+				})
+			})
+
+			Context("with the email sent", func() {
+				BeforeEach(func() {
+					sender.On("Send", mock.AnythingOfType("mail.Message")).Return(nil).Run(func(args mock.Arguments) {
+						message = args.Get(0).(mail.Message)
+					})
+				})
+
+				It("should email a reset link to the user", func() {
+					err := handler(responseRecorder, request)
+					Expect(err).To(BeNil())
+					Expect(emailToken.Purpose).To(Equal(model.EmailTokenResetPassword))
+					Expect(message.To).To(Equal("owner@restaurant.test"))
+					Expect(message.Body).To(ContainSubstring("http://luncher.test/#/reset_password?token=" + emailToken.Token.String()))
+				})
+			})
+
+			Context("with the email failing to send", func() {
+				BeforeEach(func() {
+					sender.On("Send", mock.AnythingOfType("mail.Message")).Return(errors.New("something went wrong"))
+				})
+
+				It("should fail", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusBadGateway))
+				})
+			})
+		})
+
+		Context("with an unknown email address", func() {
+			BeforeEach(func() {
+				usersCollection.On("GetEmail", "owner@restaurant.test").Return(nil, mgo.ErrNotFound)
+			})
+
+			It("should respond as if the email was sent", func() {
+				err := handler(responseRecorder, request)
+				Expect(err).To(BeNil())
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("ResetPassword", func() {
+		var token model.Token
+
+		JustBeforeEach(func() {
+			handler = ResetPassword(usersCollection, emailTokens, sessions, auditLog)
+		})
+
+		BeforeEach(func() {
+			var err error
+			token, err = model.NewToken()
+			Expect(err).NotTo(HaveOccurred())
+			requestData = map[string]interface{}{
+				"token":    token.String(),
+				"password": "a new secret password",
+			}
+		})
+
+		Context("with a valid token", func() {
+			var passwordHash []byte
+
+			BeforeEach(func() {
+				emailTokens.On("Claim", token, model.EmailTokenResetPassword).Return(&model.EmailToken{
+					UserID: userID,
+				}, nil)
+				usersCollection.On("SetPasswordHash", userID, mock.AnythingOfType("[]uint8")).Return(nil).Run(func(args mock.Arguments) {
+					passwordHash = args.Get(1).([]byte)
+				})
+				usersCollection.On("SetEmailVerified", userID).Return(nil)
+				sessions.On("RemoveForUser", userID).Return(nil)
+			})
+
+			It("should store the new password and end the user's sessions", func() {
+				err := handler(responseRecorder, request)
+				Expect(err).To(BeNil())
+				Expect(bcrypt.CompareHashAndPassword(passwordHash, []byte("a new secret password"))).To(Succeed())
+			})
+
+			It("should record the reset in the audit log as the user's action", func() {
+				handler(responseRecorder, request)
+				Expect(auditEntries).To(HaveLen(1))
+				Expect(auditEntries[0].Action).To(Equal("user.password_reset"))
+				Expect(auditEntries[0].ActorID).To(Equal(userID))
+			})
+		})
+
+		Context("with an invalid or used token", func() {
+			BeforeEach(func() {
+				emailTokens.On("Claim", token, model.EmailTokenResetPassword).Return(nil, mgo.ErrNotFound)
+			})
+
+			It("should be forbidden", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+			})
+
+			It("should record the failed attempt without a user", func() {
+				handler(responseRecorder, request)
+				Expect(auditEntries).To(HaveLen(1))
+				Expect(auditEntries[0].ActorID).To(BeEmpty())
+				Expect(auditEntries[0].Outcome).To(Equal(model.AuditOutcomeFailure))
+			})
+		})
+
+		Context("with a short password", func() {
+			BeforeEach(func() {
+				requestData.(map[string]interface{})["password"] = "short"
+			})
+
+			It("should fail without using up the token", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+})
@@ -4,7 +4,6 @@ import (
 	"net/http"
 
 	"gopkg.in/mgo.v2"
-	"gopkg.in/mgo.v2/bson"
 
 	"github.com/Lunchr/luncher-api/db"
 	"github.com/Lunchr/luncher-api/db/model"
@@ -44,9 +43,12 @@ func RedirectedFromFBForLogin(sessionManager session.Manager, fbAuth facebook.Au
 		} else if err != nil {
 			return router.NewHandlerError(err, "Failed to find the user from DB", http.StatusInternalServerError)
 		}
-		if handlerErr = storeAccessTokensInDB(user.ID, fbUserID, tok, session, users); err != nil {
+		if handlerErr = storeAccessTokensInDB(fbUserID, tok, users); handlerErr != nil {
 			return handlerErr
 		}
+		if err = sessionManager.Start(w, r, user.ID); err != nil {
+			return router.NewHandlerError(err, "Failed to start a session", http.StatusInternalServerError)
+		}
 		if handlerErr = storeTokensForRestaurantPages(fbUserID, tok, restaurants, users, fbAuth); err != nil {
 			return handlerErr
 		}
@@ -128,13 +130,10 @@ func getLongTermToken(session string, r *http.Request, auther facebook.Authentic
 	return tok, nil
 }
 
-func storeAccessTokensInDB(userID bson.ObjectId, fbUserID string, tok *oauth2.Token, sessionID string, usersCollection db.Users) *router.HandlerError {
+func storeAccessTokensInDB(fbUserID string, tok *oauth2.Token, usersCollection db.Users) *router.HandlerError {
 	if err := usersCollection.SetAccessToken(fbUserID, *tok); err != nil {
 		return router.NewHandlerError(err, "Failed to persist Facebook user access token in DB", http.StatusInternalServerError)
 	}
-	if err := usersCollection.SetSessionID(userID, sessionID); err != nil {
-		return router.NewHandlerError(err, "Failed to persist session ID in DB", http.StatusInternalServerError)
-	}
 	return nil
 }
 
@@ -4,10 +4,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/Lunchr/luncher-api/db/model"
 	. "github.com/Lunchr/luncher-api/handler"
 	"github.com/Lunchr/luncher-api/router"
 	"github.com/Lunchr/luncher-api/session"
 	"github.com/deiwin/facebook"
+	"gopkg.in/mgo.v2/bson"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -53,24 +55,34 @@ func ExpectLocationToBeMockedURL(responseRecorder *httptest.ResponseRecorder, ur
 }
 
 type mockSessionManager struct {
-	isSet bool
-	id    string
+	isSet  bool
+	userID bson.ObjectId
 }
 
-func (m mockSessionManager) Get(r *http.Request) (string, error) {
+func (m mockSessionManager) Resolve(r *http.Request) (*model.Session, error) {
 	if !m.isSet {
-		return "", session.ErrNotFound
+		return nil, session.ErrNotFound
 	}
-	if m.id == "" {
-		return "session", nil
-	}
-	return m.id, nil
+	return &model.Session{UserID: m.userID}, nil
 }
 
 func (m mockSessionManager) GetOrInit(w http.ResponseWriter, r *http.Request) string {
 	return "session"
 }
 
+func (m mockSessionManager) Start(w http.ResponseWriter, r *http.Request, userID bson.ObjectId) error {
+	return nil
+}
+
+func (m mockSessionManager) Impersonate(w http.ResponseWriter, r *http.Request, userID, impersonatorID bson.ObjectId) error {
+	return nil
+}
+
+func (m mockSessionManager) End(w http.ResponseWriter, r *http.Request) error {
+	Expect(m.isSet).To(BeTrue())
+	return nil
+}
+
 type mockAuthenticator struct {
 	api facebook.API
 	facebook.Authenticator
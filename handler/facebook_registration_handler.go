@@ -1,12 +1,17 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
 
 	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 
 	"github.com/Lunchr/luncher-api/db"
 	"github.com/Lunchr/luncher-api/db/model"
@@ -18,35 +23,45 @@ import (
 )
 
 // RedirectToFBForRegistration returns a handler that redirects the user to Facebook to log in
-// so they could be registered in our system
+// so they could be registered in our system. The registration access token is carried through
+// the OAuth state to RedirectedFromFBForRegistration, which claims it.
 func RedirectToFBForRegistration(sessionManager session.Manager, auther facebook.Authenticator,
 	tokens db.RegistrationAccessTokens) router.Handler {
 	return func(w http.ResponseWriter, r *http.Request) *router.HandlerError {
 		tokenString := r.FormValue("token")
-		if tokenString != "" {
-			token, err := model.TokenFromString(tokenString)
-			if err != nil {
-				return router.NewHandlerError(err, "Failed to parse the token", http.StatusBadRequest)
-			}
-			tokenExists, err := tokens.Exists(token)
-			if err != nil {
-				return router.NewHandlerError(err, "Failed to check if token exists in DB", http.StatusBadRequest)
-			} else if !tokenExists {
-				return router.NewSimpleHandlerError("Invalid access token", http.StatusForbidden)
-			}
+		if tokenString == "" {
+			return router.NewSimpleHandlerError("Expecting a registration access token", http.StatusBadRequest)
+		}
+		token, err := model.TokenFromString(tokenString)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to parse the token", http.StatusBadRequest)
+		}
+		registrationAccessToken, err := tokens.Get(token)
+		if err == mgo.ErrNotFound {
+			return router.NewSimpleHandlerError("Invalid access token", http.StatusForbidden)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to find the token from the DB", http.StatusInternalServerError)
+		} else if !registrationAccessToken.IsUsable(time.Now()) {
+			return router.NewSimpleHandlerError("The access token has already been used or has expired", http.StatusForbidden)
 		}
 		session := sessionManager.GetOrInit(w, r)
-		redirectURL := auther.AuthURL(session)
+		redirectURL := auther.AuthURL(registrationState(session, token))
 		return writeString(w, redirectURL)
 	}
 }
 
 // RedirectedFromFBForRegistration provides a handler that stores the data about the current user
-// required to continue the registration in the DB.
-func RedirectedFromFBForRegistration(sessionManager session.Manager, auther facebook.Authenticator, usersCollection db.Users) router.Handler {
+// required to continue the registration in the DB. The registration access token the user started
+// the registration with gets claimed for the new user.
+func RedirectedFromFBForRegistration(sessionManager session.Manager, auther facebook.Authenticator, usersCollection db.Users,
+	tokens db.RegistrationAccessTokens) router.Handler {
 	return func(w http.ResponseWriter, r *http.Request) *router.HandlerError {
 		session := sessionManager.GetOrInit(w, r)
-		tok, handlerErr := getLongTermToken(session, r, auther)
+		token, err := registrationTokenFromState(r.FormValue("state"))
+		if err != nil {
+			return router.NewHandlerError(err, "Expecting a registration access token in the 'state' value", http.StatusBadRequest)
+		}
+		tok, handlerErr := getLongTermToken(registrationState(session, token), r, auther)
 		if handlerErr != nil {
 			return handlerErr
 		}
@@ -60,8 +75,17 @@ func RedirectedFromFBForRegistration(sessionManager session.Manager, auther face
 		} else if err != mgo.ErrNotFound {
 			return router.NewHandlerError(err, "Failed to check the DB for users", http.StatusInternalServerError)
 		}
-		err = usersCollection.Insert(&model.User{FacebookUserID: fbUserID})
+		userID := bson.NewObjectId()
+		if handlerErr = claimRegistrationAccessToken(tokens, token, userID); handlerErr != nil {
+			return handlerErr
+		}
+		err = usersCollection.Insert(&model.User{
+			ID:             userID,
+			FacebookUserID: fbUserID,
+		})
 		if err != nil {
+			// Let the user try again with the same token
+			releaseRegistrationAccessToken(tokens, token, userID)
 			return router.NewHandlerError(err, "Failed to create a User object in the DB", http.StatusInternalServerError)
 		}
 		// We're not storing the session data in the DB so that the user will be asked to log in again when they get
@@ -71,6 +95,39 @@ func RedirectedFromFBForRegistration(sessionManager session.Manager, auther face
 	}
 }
 
+func claimRegistrationAccessToken(tokens db.RegistrationAccessTokens, token model.Token, userID bson.ObjectId) *router.HandlerError {
+	if err := tokens.Claim(token, userID); err == mgo.ErrNotFound {
+		return router.NewSimpleHandlerError("The access token has already been used or has expired", http.StatusForbidden)
+	} else if err != nil {
+		return router.NewHandlerError(err, "Failed to claim the access token", http.StatusInternalServerError)
+	}
+	return nil
+}
+
+// releaseRegistrationAccessToken lets the token be used again after the registration failed. The
+// registration has already failed at this point, so a failure to release the token is only logged.
+func releaseRegistrationAccessToken(tokens db.RegistrationAccessTokens, token model.Token, userID bson.ObjectId) {
+	if err := tokens.Release(token, userID); err != nil {
+		// Only a prefix of the token is logged, so that the logs couldn't be used to register
+		log.Printf("Failed to release the registration access token %s… claimed for user %s: %v", token.String()[:8],
+			userID.Hex(), err)
+	}
+}
+
+// registrationState binds the registration access token to the OAuth state, so that the token
+// couldn't be swapped out during the redirects
+func registrationState(session string, token model.Token) string {
+	return session + "." + token.String()
+}
+
+func registrationTokenFromState(state string) (model.Token, error) {
+	i := strings.LastIndex(state, ".")
+	if i == -1 {
+		return model.Token{}, errors.New("The state doesn't include a registration access token")
+	}
+	return model.TokenFromString(state[i+1:])
+}
+
 // ListPagesManagedByUser returns a handler that lists all pages managed by the currently logged in user
 func ListPagesManagedByUser(sessionManager session.Manager, auther facebook.Authenticator, usersCollection db.Users) router.Handler {
 	handler := func(w http.ResponseWriter, r *http.Request, user *model.User) *router.HandlerError {
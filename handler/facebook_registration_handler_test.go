@@ -2,8 +2,10 @@ package handler_test
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/Lunchr/luncher-api/db"
 	"github.com/Lunchr/luncher-api/db/model"
@@ -14,6 +16,9 @@ import (
 	fbModel "github.com/deiwin/facebook/model"
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/oauth2"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -40,7 +45,7 @@ var _ = Describe("Facebook registration handler", func() {
 
 		BeforeEach(func() {
 			accessTokens = new(mocks.RegistrationAccessTokens)
-			auther.On("AuthURL", "session").Return(testURL)
+			requestQuery = url.Values{}
 		})
 
 		JustBeforeEach(func() {
@@ -48,14 +53,9 @@ var _ = Describe("Facebook registration handler", func() {
 		})
 
 		Context("without an access token", func() {
-			It("responds with the redirect URL", func() {
+			It("fails with StatusBadRequest", func() {
 				err := handler(responseRecorder, request)
-				Expect(err).NotTo(HaveOccurred())
-				contentTypes := responseRecorder.HeaderMap["Content-Type"]
-				Expect(contentTypes).To(HaveLen(1))
-				Expect(contentTypes[0]).To(Equal("text/plain"))
-				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
-				Expect(responseRecorder.Body.String()).To(Equal(testURL))
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
 			})
 		})
 
@@ -72,14 +72,54 @@ var _ = Describe("Facebook registration handler", func() {
 			})
 		})
 
-		Context("with a made up or expired token", func() {
+		Context("with a made up token", func() {
+			BeforeEach(func() {
+				token, err := model.NewToken()
+				Expect(err).NotTo(HaveOccurred())
+				requestQuery = url.Values{
+					"token": {token.String()},
+				}
+				accessTokens.On("Get", token).Return(nil, mgo.ErrNotFound)
+			})
+
+			It("fails with StatusForbidden", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("with an already used token", func() {
 			BeforeEach(func() {
 				token, err := model.NewToken()
 				Expect(err).NotTo(HaveOccurred())
 				requestQuery = url.Values{
 					"token": {token.String()},
 				}
-				accessTokens.On("Exists", token).Return(false, nil)
+				accessTokens.On("Get", token).Return(&model.RegistrationAccessToken{
+					CreatedAt: time.Now(),
+					Token:     token,
+					ClaimedBy: bson.NewObjectId(),
+				}, nil)
+			})
+
+			It("fails with StatusForbidden", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("with an expired token", func() {
+			BeforeEach(func() {
+				token, err := model.NewToken()
+				Expect(err).NotTo(HaveOccurred())
+				requestQuery = url.Values{
+					"token": {token.String()},
+				}
+				accessTokens.On("Get", token).Return(&model.RegistrationAccessToken{
+					CreatedAt: time.Now(),
+					Token:     token,
+					ExpiresAt: time.Now().Add(-time.Minute),
+				}, nil)
 			})
 
 			It("fails with StatusForbidden", func() {
@@ -95,10 +135,14 @@ var _ = Describe("Facebook registration handler", func() {
 				requestQuery = url.Values{
 					"token": {token.String()},
 				}
-				accessTokens.On("Exists", token).Return(true, nil)
+				accessTokens.On("Get", token).Return(&model.RegistrationAccessToken{
+					CreatedAt: time.Now(),
+					Token:     token,
+				}, nil)
+				auther.On("AuthURL", "session."+token.String()).Return(testURL)
 			})
 
-			It("responds with the redirect URL", func() {
+			It("responds with the redirect URL that carries the token in the state", func() {
 				err := handler(responseRecorder, request)
 				Expect(err).NotTo(HaveOccurred())
 				contentTypes := responseRecorder.HeaderMap["Content-Type"]
@@ -110,6 +154,114 @@ var _ = Describe("Facebook registration handler", func() {
 		})
 	})
 
+	Describe("Redirected", func() {
+		var (
+			accessTokens *mocks.RegistrationAccessTokens
+			mockUsers    *mocks.Users
+			api          *mocks.API
+			token        model.Token
+			handler      router.Handler
+		)
+
+		BeforeEach(func() {
+			accessTokens = new(mocks.RegistrationAccessTokens)
+			mockUsers = new(mocks.Users)
+			api = new(mocks.API)
+			var err error
+			token, err = model.NewToken()
+			Expect(err).NotTo(HaveOccurred())
+			requestQuery = url.Values{
+				"state": {"session." + token.String()},
+			}
+			auther.On("Token", "session."+token.String(), mock.Anything).Return(&oauth2.Token{}, nil)
+			auther.On("APIConnection", mock.AnythingOfType("*oauth2.Token")).Return(api)
+			api.On("Me").Return(&fbModel.User{ID: "fbuserid"}, nil)
+		})
+
+		JustBeforeEach(func() {
+			handler = RedirectedFromFBForRegistration(sessionManager, auther, mockUsers, accessTokens)
+		})
+
+		Context("without a token in the state", func() {
+			BeforeEach(func() {
+				requestQuery = url.Values{
+					"state": {"session"},
+				}
+			})
+
+			It("fails with StatusBadRequest", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("with a new Facebook user", func() {
+			var userID bson.ObjectId
+
+			BeforeEach(func() {
+				mockUsers.On("GetFbID", "fbuserid").Return(nil, mgo.ErrNotFound)
+			})
+
+			Context("with the token claimed successfully", func() {
+				BeforeEach(func() {
+					accessTokens.On("Claim", token, mock.AnythingOfType("bson.ObjectId")).Return(func(t model.Token, id bson.ObjectId) error {
+						userID = id
+						return nil
+					})
+					mockUsers.On("Insert", mock.Anything).Return(nil)
+				})
+
+				It("creates the user the token was claimed for", func() {
+					err := handler(responseRecorder, request)
+					Expect(err).To(BeNil())
+					Expect(responseRecorder.Code).To(Equal(http.StatusSeeOther))
+					insertedUsers := mockUsers.Calls[1].Arguments.Get(0).([]*model.User)
+					Expect(insertedUsers).To(HaveLen(1))
+					Expect(insertedUsers[0].ID).To(Equal(userID))
+					Expect(insertedUsers[0].FacebookUserID).To(Equal("fbuserid"))
+				})
+			})
+
+			Context("with the token already used by someone else", func() {
+				BeforeEach(func() {
+					accessTokens.On("Claim", token, mock.AnythingOfType("bson.ObjectId")).Return(mgo.ErrNotFound)
+				})
+
+				It("fails with StatusForbidden without creating a user", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusForbidden))
+					mockUsers.AssertNotCalled(GinkgoT(), "Insert", mock.Anything)
+				})
+			})
+
+			Context("with the user insertion failing", func() {
+				BeforeEach(func() {
+					accessTokens.On("Claim", token, mock.AnythingOfType("bson.ObjectId")).Return(nil)
+					mockUsers.On("Insert", mock.Anything).Return(errors.New("something went wrong"))
+					accessTokens.On("Release", token, mock.AnythingOfType("bson.ObjectId")).Return(nil)
+				})
+
+				It("releases the token", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusInternalServerError))
+					accessTokens.AssertCalled(GinkgoT(), "Release", token, mock.AnythingOfType("bson.ObjectId"))
+				})
+			})
+		})
+
+		Context("with an already registered Facebook user", func() {
+			BeforeEach(func() {
+				mockUsers.On("GetFbID", "fbuserid").Return(&model.User{}, nil)
+			})
+
+			It("fails without claiming the token", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+				accessTokens.AssertNotCalled(GinkgoT(), "Claim", token, mock.Anything)
+			})
+		})
+	})
+
 	Describe("ListPagesManagedByUser", func() {
 		JustBeforeEach(func() {
 			handler = ListPagesManagedByUser(sessionManager, auther, usersCollection)
@@ -134,8 +286,8 @@ var _ = Describe("Facebook registration handler", func() {
 				mockSessionManager = new(mocks.Manager)
 				sessionManager = mockSessionManager
 
-				mockSessionManager.On("Get", mock.Anything).Return("session", nil)
-				mockUsers.On("GetSessionID", "session").Return(&model.User{
+				mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+				mockUsers.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(&model.User{
 					Session: model.UserSession{
 						FacebookPageTokens: []model.FacebookPageToken{model.FacebookPageToken{
 							PageID: "id3",
@@ -213,7 +365,7 @@ var _ = Describe("Facebook registration handler", func() {
 			)
 
 			BeforeEach(func() {
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 				usersCollection = mockUsers{}
 				api = new(mocks.API)
 				auther.On("APIConnection", mock.AnythingOfType("*oauth2.Token")).Return(api)
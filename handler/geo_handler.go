@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/geo"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/session"
+	"gopkg.in/mgo.v2"
+)
+
+// ReverseGeocode returns a handler that finds the address for the location specified with the lat and lng query
+// parameters. The location has to be in the same country as the region specified by the region query parameter.
+// Only the logged in users can look up addresses, because every lookup may cost a request to the geocoder.
+func ReverseGeocode(sessionManager session.Manager, users db.Users, regions db.Regions, geocoder geo.Coder) router.Handler {
+	return checkLogin(sessionManager, users, func(w http.ResponseWriter, r *http.Request, user *model.User) *router.HandlerError {
+		region, handlerErr := getRegionFromRequest(r, regions)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		location, handlerErr := getLocFromRequest(r)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		address, err := geocoder.ReverseCode(location)
+		if err == geo.ErrorNoResults {
+			return router.NewHandlerError(err, "Couldn't find an address for the location", http.StatusNotFound)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to look up the address", http.StatusBadGateway)
+		}
+		if address.CountryCode != geo.CountryCodeForCCTLD(region.CCTLD) {
+			return router.NewStringHandlerError("Location not in the region's country", "The location isn't in a supported region", http.StatusNotFound)
+		}
+		return writeJSON(w, address)
+	})
+}
+
+// SuggestAddresses returns a handler that lists addresses within the region specified by the region query
+// parameter that match the (possibly partial or misspelled) input specified by the q query parameter
+func SuggestAddresses(sessionManager session.Manager, users db.Users, regions db.Regions, geocoder geo.Coder) router.Handler {
+	return checkLogin(sessionManager, users, func(w http.ResponseWriter, r *http.Request, user *model.User) *router.HandlerError {
+		region, handlerErr := getRegionFromRequest(r, regions)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		input := strings.TrimSpace(r.FormValue("q"))
+		if input == "" {
+			return router.NewStringHandlerError("Input not specified", "Please specify the address using the 'q' attribute", http.StatusBadRequest)
+		}
+		addresses, err := geocoder.Suggest(input, region.CCTLD)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to look up the address", http.StatusBadGateway)
+		}
+		return writeJSON(w, addresses)
+	})
+}
+
+func getRegionFromRequest(r *http.Request, regions db.Regions) (*model.Region, *router.HandlerError) {
+	regionName := r.FormValue("region")
+	if regionName == "" {
+		return nil, router.NewStringHandlerError("Region not specified", "Please specify the region using the 'region' attribute", http.StatusBadRequest)
+	}
+	region, err := regions.GetName(regionName)
+	if err == mgo.ErrNotFound {
+		return nil, router.NewHandlerError(err, "Unable to find the specified region", http.StatusNotFound)
+	} else if err != nil {
+		return nil, router.NewHandlerError(err, "Failed to find the specified region", http.StatusInternalServerError)
+	}
+	return region, nil
+}
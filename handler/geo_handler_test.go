@@ -0,0 +1,246 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/geo"
+	. "github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/session"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GeoHandler", func() {
+	var (
+		regionsCollection *mocks.Regions
+		geocoder          *mocks.Coder
+	)
+
+	BeforeEach(func() {
+		requestMethod = "GET"
+		regionsCollection = new(mocks.Regions)
+		regionsCollection.On("GetName", "Tartu").Return(&model.Region{
+			Name:  "Tartu",
+			CCTLD: "ee",
+		}, nil)
+		regionsCollection.On("GetName", "Narnia").Return(nil, mgo.ErrNotFound)
+		regionsCollection.On("GetName", "Atlantis").Return(nil, errors.New("something went wrong"))
+		geocoder = new(mocks.Coder)
+	})
+
+	Describe("GET /geo/reverse", func() {
+		var (
+			sessionManager  session.Manager
+			usersCollection db.Users
+			handler         router.Handler
+			location        geo.Location
+		)
+
+		BeforeEach(func() {
+			location = geo.Location{Lat: 58.38, Lng: 26.72}
+			requestQuery = url.Values{
+				"lat":    {"58.38"},
+				"lng":    {"26.72"},
+				"region": {"Tartu"},
+			}
+		})
+
+		JustBeforeEach(func() {
+			handler = ReverseGeocode(sessionManager, usersCollection, regionsCollection, geocoder)
+		})
+
+		ExpectUserToBeLoggedIn(func() *router.HandlerError {
+			return handler(responseRecorder, request)
+		}, func(mgr session.Manager, users db.Users) {
+			sessionManager = mgr
+			usersCollection = users
+		})
+
+		Context("with session set and a matching user in DB", func() {
+			BeforeEach(func() {
+				mockSessionManager := new(mocks.Manager)
+				mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+				sessionManager = mockSessionManager
+				mockUsersCollection := new(mocks.Users)
+				mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(&model.User{}, nil)
+				usersCollection = mockUsersCollection
+			})
+
+			Context("with the address found in the region's country", func() {
+				BeforeEach(func() {
+					geocoder.On("ReverseCode", location).Return(geo.Address{
+						FormattedAddress: "Küüni 5, Tartu, Estonia",
+						Street:           "Küüni",
+						City:             "Tartu",
+						CountryCode:      "ee",
+						Location:         location,
+					}, nil)
+				})
+
+				It("should return the address", func() {
+					err := handler(responseRecorder, request)
+					Expect(err).To(BeNil())
+					var address geo.Address
+					json.Unmarshal(responseRecorder.Body.Bytes(), &address)
+					Expect(address.Street).To(Equal("Küüni"))
+					Expect(address.City).To(Equal("Tartu"))
+				})
+			})
+
+			Context("with the address found in another country", func() {
+				BeforeEach(func() {
+					geocoder.On("ReverseCode", location).Return(geo.Address{
+						CountryCode: "lv",
+					}, nil)
+				})
+
+				It("should fail", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusNotFound))
+				})
+			})
+
+			Context("with no address found", func() {
+				BeforeEach(func() {
+					geocoder.On("ReverseCode", location).Return(geo.Address{}, geo.ErrorNoResults)
+				})
+
+				It("should fail", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusNotFound))
+				})
+			})
+
+			Context("with an unsupported region", func() {
+				BeforeEach(func() {
+					requestQuery.Set("region", "Narnia")
+				})
+
+				It("should fail", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusNotFound))
+				})
+			})
+
+			Context("with the DB failing to find the region", func() {
+				BeforeEach(func() {
+					requestQuery.Set("region", "Atlantis")
+				})
+
+				It("should fail", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusInternalServerError))
+				})
+			})
+
+			Context("without a location", func() {
+				BeforeEach(func() {
+					requestQuery.Del("lat")
+				})
+
+				It("should fail", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusBadRequest))
+				})
+			})
+		})
+	})
+
+	Describe("GET /geo/suggest", func() {
+		var (
+			sessionManager  session.Manager
+			usersCollection db.Users
+			handler         router.Handler
+		)
+
+		BeforeEach(func() {
+			requestQuery = url.Values{
+				"q":      {"Kyyni 5"},
+				"region": {"Tartu"},
+			}
+		})
+
+		JustBeforeEach(func() {
+			handler = SuggestAddresses(sessionManager, usersCollection, regionsCollection, geocoder)
+		})
+
+		ExpectUserToBeLoggedIn(func() *router.HandlerError {
+			return handler(responseRecorder, request)
+		}, func(mgr session.Manager, users db.Users) {
+			sessionManager = mgr
+			usersCollection = users
+		})
+
+		Context("with session set and a matching user in DB", func() {
+			BeforeEach(func() {
+				mockSessionManager := new(mocks.Manager)
+				mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+				sessionManager = mockSessionManager
+				mockUsersCollection := new(mocks.Users)
+				mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(&model.User{}, nil)
+				usersCollection = mockUsersCollection
+			})
+
+			Context("with suggestions found", func() {
+				BeforeEach(func() {
+					geocoder.On("Suggest", "Kyyni 5", "ee").Return([]geo.Address{
+						{FormattedAddress: "Küüni 5, Tartu, Estonia"},
+						{FormattedAddress: "Küüni 5, Viljandi, Estonia"},
+					}, nil)
+				})
+
+				It("should return the suggestions", func() {
+					err := handler(responseRecorder, request)
+					Expect(err).To(BeNil())
+					var addresses []geo.Address
+					json.Unmarshal(responseRecorder.Body.Bytes(), &addresses)
+					Expect(addresses).To(HaveLen(2))
+					Expect(addresses[0].FormattedAddress).To(Equal("Küüni 5, Tartu, Estonia"))
+				})
+			})
+
+			Context("without an input", func() {
+				BeforeEach(func() {
+					requestQuery.Set("q", " ")
+				})
+
+				It("should fail", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusBadRequest))
+				})
+			})
+
+			Context("without a region", func() {
+				BeforeEach(func() {
+					requestQuery.Del("region")
+				})
+
+				It("should fail", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusBadRequest))
+				})
+			})
+
+			Context("with the geocoder failing", func() {
+				BeforeEach(func() {
+					geocoder.On("Suggest", "Kyyni 5", "ee").Return(nil, errors.New("something went wrong"))
+				})
+
+				It("should fail", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusBadGateway))
+				})
+			})
+		})
+	})
+})
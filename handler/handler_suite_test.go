@@ -8,6 +8,10 @@ import (
 	"net/url"
 	"strconv"
 
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/stretchr/testify/mock"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -26,11 +30,18 @@ var (
 	requestPath      string
 	requestData      interface{}
 	requestQuery     url.Values
+	auditLog         *mocks.Log
+	auditEntries     []*model.AuditEntry
 )
 
 var _ = BeforeEach(func(done Done) {
 	defer close(done)
 	responseRecorder = httptest.NewRecorder()
+	auditEntries = nil
+	auditLog = new(mocks.Log)
+	auditLog.On("Record", mock.AnythingOfType("*model.AuditEntry")).Return().Run(func(args mock.Arguments) {
+		auditEntries = append(auditEntries, args.Get(0).(*model.AuditEntry))
+	})
 })
 
 var _ = JustBeforeEach(func() {
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Lunchr/luncher-api/audit"
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/mail"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/session"
+)
+
+const (
+	inviteTTL     = 7 * 24 * time.Hour
+	inviteSubject = "You've been invited to manage %s on Luncher"
+	inviteBody    = `Hi!
+
+You've been invited to help manage %s on Luncher. To accept the invitation, log in or create an account and follow this link:
+%s
+
+The link is valid for a week. If you weren't expecting this invitation, you can safely ignore this email.`
+)
+
+type invitePOST struct {
+	Role  model.Role `json:"role"`
+	Email string     `json:"email"`
+}
+
+type inviteAcceptance struct {
+	Token model.Token `json:"token"`
+}
+
+// RestaurantInvites returns a handler that lists the restaurant's unexpired invites
+func RestaurantInvites(sessionManager session.Manager, users db.Users, memberships db.Memberships, apiKeys db.APIKeys,
+	restaurants db.Restaurants, invites db.Invites) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+		restaurantInvites, err := invites.GetForRestaurant(restaurant.ID)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to find the restaurant's invites", http.StatusInternalServerError)
+		}
+		return writeJSON(w, restaurantInvites)
+	}
+	return forRestaurant(sessionManager, users, memberships, apiKeys, restaurants, model.RoleOwner, handler)
+}
+
+// PostRestaurantInvite returns a handler that creates an invite for the restaurant. If an email address is
+// specified, the invite is sent to that address with a link to acceptURL, which should let the invitee log in
+// and then send the token to AcceptInvite. Otherwise the invite's token can be shared by the owner.
+func PostRestaurantInvite(sessionManager session.Manager, users db.Users, memberships db.Memberships, apiKeys db.APIKeys,
+	restaurants db.Restaurants, invites db.Invites, sender mail.Sender, acceptURL string,
+	auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+		var post invitePOST
+		if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+			return router.NewHandlerError(err, "Failed to parse the invite", http.StatusBadRequest)
+		} else if !post.Role.IsValid() {
+			return router.NewSimpleHandlerError("Please specify a valid role", http.StatusBadRequest)
+		}
+		email := db.NormalizeEmail(post.Email)
+		if email != "" && !strings.Contains(email, "@") {
+			return router.NewStringHandlerError("Invalid email", "Please specify a valid email address", http.StatusBadRequest)
+		}
+		invite, err := model.NewInvite(restaurant.ID, user.ID, post.Role, email, inviteTTL)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to create a token", http.StatusInternalServerError)
+		}
+		if invite, err = invites.Insert(invite); err != nil {
+			return router.NewHandlerError(err, "Failed to store the invite in the DB", http.StatusInternalServerError)
+		}
+		if invite.IsSingleUse() {
+			err = sender.Send(mail.Message{
+				To:      invite.Email,
+				Subject: fmt.Sprintf(inviteSubject, restaurant.Name),
+				Body:    fmt.Sprintf(inviteBody, restaurant.Name, fmt.Sprintf("%s?token=%s", acceptURL, invite.Token.String())),
+			})
+			if err != nil {
+				return router.NewHandlerError(err, "Failed to send the invite", http.StatusBadGateway)
+			}
+		}
+		return writeJSONWithCode(w, invite, http.StatusCreated)
+	}
+	return forRestaurant(sessionManager, users, memberships, apiKeys, restaurants, model.RoleOwner,
+		auditedForRestaurant(auditLog, "invite.create", handler))
+}
+
+// DeleteRestaurantInvite returns a handler that revokes the invite specified by the id param
+func DeleteRestaurantInvite(sessionManager session.Manager, users db.Users, memberships db.Memberships, apiKeys db.APIKeys,
+	restaurants db.Restaurants, invites db.Invites, auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User,
+		restaurant *model.Restaurant) *router.HandlerError {
+		idString := ps.ByName("id")
+		if !bson.IsObjectIdHex(idString) {
+			return router.NewSimpleHandlerError("Invalid invite ID", http.StatusBadRequest)
+		}
+		err := invites.Remove(bson.ObjectIdHex(idString), restaurant.ID)
+		if err == mgo.ErrNotFound {
+			return router.NewHandlerError(err, "Failed to find the specified invite", http.StatusNotFound)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to remove the invite from the DB", http.StatusInternalServerError)
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return forRestaurantWithParams(sessionManager, users, memberships, apiKeys, restaurants, model.RoleOwner,
+		auditedForRestaurantWithParams(auditLog, "invite.delete", handler))
+}
+
+// AcceptInvite returns a handler that gives the logged in user the role specified by the invite with the
+// token in the request body. The user's current role for the restaurant is kept if it includes the invited
+// role. Invites sent to an email address can only be accepted by the user with that address. Users who log in
+// through Facebook don't have an address, so the invited address is bound to their account instead, as
+// following the link in the invite proves that they can read mail sent to it. Responds with the restaurant
+// the user was invited to.
+func AcceptInvite(sessionManager session.Manager, users db.Users, memberships db.Memberships, restaurants db.Restaurants,
+	invites db.Invites, auditLog audit.Log) router.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User) (bson.ObjectId, *router.HandlerError) {
+		var acceptance inviteAcceptance
+		if err := json.NewDecoder(r.Body).Decode(&acceptance); err != nil {
+			return "", router.NewHandlerError(err, "Failed to parse the token", http.StatusBadRequest)
+		}
+		invite, err := invites.GetToken(acceptance.Token)
+		if err == mgo.ErrNotFound {
+			return "", router.NewHandlerError(err, "Invalid or expired invite", http.StatusForbidden)
+		} else if err != nil {
+			return "", router.NewHandlerError(err, "Failed to find the invite", http.StatusInternalServerError)
+		}
+		if invite.Email != "" && user.Email != "" && invite.Email != db.NormalizeEmail(user.Email) {
+			return invite.RestaurantID, router.NewStringHandlerError("Invite sent to another email address",
+				"This invite was sent to another email address", http.StatusForbidden)
+		}
+		restaurant, err := restaurants.GetID(invite.RestaurantID)
+		if err == mgo.ErrNotFound {
+			return invite.RestaurantID, router.NewHandlerError(err, "The restaurant no longer exists", http.StatusNotFound)
+		} else if err != nil {
+			return invite.RestaurantID, router.NewHandlerError(err, "Failed to find the restaurant", http.StatusInternalServerError)
+		}
+		if invite.Email != "" && user.Email == "" {
+			err = users.SetVerifiedEmail(user.ID, invite.Email)
+			if mgo.IsDup(err) {
+				return invite.RestaurantID, router.NewStringHandlerError("Invited email address belongs to another user",
+					"This invite was sent to an email address that belongs to another account. Please log in with that account.",
+					http.StatusConflict)
+			} else if err != nil {
+				return invite.RestaurantID, router.NewHandlerError(err, "Failed to store the user's email address", http.StatusInternalServerError)
+			}
+		}
+		currentRole, err := roleForRestaurant(user, restaurant, memberships)
+		if err != nil {
+			return invite.RestaurantID, router.NewHandlerError(err, "Failed to check the user's role for this restaurant", http.StatusInternalServerError)
+		}
+		if !currentRole.Includes(invite.Role) {
+			if err = memberships.Set(user.ID, restaurant.ID, invite.Role); err != nil {
+				return invite.RestaurantID, router.NewHandlerError(err, "Failed to store the membership in the DB", http.StatusInternalServerError)
+			}
+		}
+		// The invite is only used up once the user has the role, so that it could be accepted again if storing
+		// the membership fails. Only the user with the invited address can accept it, so if it's already been
+		// removed, it's been accepted by the same user in the meanwhile.
+		if invite.IsSingleUse() {
+			if err = invites.Remove(invite.ID, invite.RestaurantID); err != nil && err != mgo.ErrNotFound {
+				return invite.RestaurantID, router.NewHandlerError(err, "Failed to remove the invite from the DB", http.StatusInternalServerError)
+			}
+		}
+		return invite.RestaurantID, writeJSON(w, restaurant)
+	}
+	return checkLogin(sessionManager, users, auditedWithRestaurantID(auditLog, "invite.accept", handler))
+}
@@ -0,0 +1,429 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/Lunchr/luncher-api/mail"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InvitesHandler", func() {
+	var (
+		sessionManager  *mocks.Manager
+		usersCollection *mocks.Users
+		memberships     *mocks.Memberships
+		apiKeys         *mocks.APIKeys
+		restaurants     *mocks.Restaurants
+		invites         *mocks.Invites
+		user            *model.User
+		restaurant      *model.Restaurant
+		params          httprouter.Params
+	)
+
+	BeforeEach(func() {
+		sessionManager = new(mocks.Manager)
+		usersCollection = new(mocks.Users)
+		memberships = new(mocks.Memberships)
+		apiKeys = new(mocks.APIKeys)
+		restaurants = new(mocks.Restaurants)
+		invites = new(mocks.Invites)
+		restaurant = &model.Restaurant{
+			ID:   bson.NewObjectId(),
+			Name: "Asian Chef",
+		}
+		user = &model.User{
+			ID: bson.NewObjectId(),
+		}
+		sessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+		usersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+		restaurants.On("GetID", restaurant.ID).Return(restaurant, nil)
+		params = httprouter.Params{httprouter.Param{
+			Key:   "restaurantID",
+			Value: restaurant.ID.Hex(),
+		}}
+		requestQuery = url.Values{}
+	})
+
+	AfterEach(func() {
+		memberships.AssertExpectations(GinkgoT())
+		invites.AssertExpectations(GinkgoT())
+	})
+
+	Describe("GET /restaurants/:restaurantID/invites", func() {
+		var handler router.HandlerWithParams
+
+		BeforeEach(func() {
+			requestMethod = "GET"
+		})
+
+		JustBeforeEach(func() {
+			handler = RestaurantInvites(sessionManager, usersCollection, memberships, apiKeys, restaurants, invites)
+		})
+
+		Context("with the user being the restaurant's owner", func() {
+			BeforeEach(func() {
+				memberships.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+					Role: model.RoleOwner,
+				}, nil)
+				invites.On("GetForRestaurant", restaurant.ID).Return([]*model.Invite{
+					{ID: bson.NewObjectId(), Role: model.RoleEditor},
+				}, nil)
+			})
+
+			It("should list the invites", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+				var response []*model.Invite
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response).To(HaveLen(1))
+				Expect(response[0].Role).To(Equal(model.RoleEditor))
+			})
+		})
+
+		Context("with the user being an editor of the restaurant", func() {
+			BeforeEach(func() {
+				memberships.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+					Role: model.RoleEditor,
+				}, nil)
+			})
+
+			It("should fail with StatusForbidden", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+
+	Describe("POST /restaurants/:restaurantID/invites", func() {
+		var (
+			sender  *mocks.Sender
+			handler router.HandlerWithParams
+		)
+
+		BeforeEach(func() {
+			sender = new(mocks.Sender)
+			requestMethod = "POST"
+			memberships.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+				Role: model.RoleOwner,
+			}, nil)
+		})
+
+		JustBeforeEach(func() {
+			handler = PostRestaurantInvite(sessionManager, usersCollection, memberships, apiKeys, restaurants, invites,
+				sender, "http://luncher.test/#/invites/accept", auditLog)
+		})
+
+		AfterEach(func() {
+			sender.AssertExpectations(GinkgoT())
+		})
+
+		Context("with an email address", func() {
+			var insertedInvite *model.Invite
+
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"role":  "editor",
+					"email": " Cook@Restaurant.test",
+				}
+				invites.On("Insert", mock.AnythingOfType("*model.Invite")).Return(func(invite *model.Invite) *model.Invite {
+					return invite
+				}, nil).Run(func(args mock.Arguments) {
+					insertedInvite = args.Get(0).(*model.Invite)
+				})
+				sender.On("Send", mock.AnythingOfType("mail.Message")).Return(nil).Run(func(args mock.Arguments) {
+					message := args.Get(0).(mail.Message)
+					Expect(message.To).To(Equal("cook@restaurant.test"))
+					Expect(message.Subject).To(ContainSubstring("Asian Chef"))
+					Expect(message.Body).To(ContainSubstring("http://luncher.test/#/invites/accept?token=" + insertedInvite.Token.String()))
+				})
+			})
+
+			It("should create a single use invite and email it", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+				Expect(responseRecorder.Code).To(Equal(http.StatusCreated))
+				Expect(insertedInvite.RestaurantID).To(Equal(restaurant.ID))
+				Expect(insertedInvite.InvitedBy).To(Equal(user.ID))
+				Expect(insertedInvite.Role).To(Equal(model.RoleEditor))
+				Expect(insertedInvite.IsSingleUse()).To(BeTrue())
+				Expect(insertedInvite.ExpiresAt).To(BeTemporally(">", time.Now().Add(6*24*time.Hour)))
+			})
+		})
+
+		Context("without an email address", func() {
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"role": "viewer",
+				}
+				invites.On("Insert", mock.AnythingOfType("*model.Invite")).Return(func(invite *model.Invite) *model.Invite {
+					return invite
+				}, nil)
+			})
+
+			It("should respond with a shareable token", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+				var response map[string]interface{}
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response["token"]).To(HaveLen(36))
+				Expect(response["role"]).To(Equal("viewer"))
+			})
+		})
+
+		Context("with an invalid role", func() {
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"role": "admin",
+				}
+			})
+
+			It("should fail", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("DELETE /restaurants/:restaurantID/invites/:id", func() {
+		var (
+			handler  router.HandlerWithParams
+			inviteID bson.ObjectId
+		)
+
+		BeforeEach(func() {
+			requestMethod = "DELETE"
+			inviteID = bson.NewObjectId()
+			memberships.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+				Role: model.RoleOwner,
+			}, nil)
+			params = append(params, httprouter.Param{
+				Key:   "id",
+				Value: inviteID.Hex(),
+			})
+		})
+
+		JustBeforeEach(func() {
+			handler = DeleteRestaurantInvite(sessionManager, usersCollection, memberships, apiKeys, restaurants, invites, auditLog)
+		})
+
+		Context("with the invite existing", func() {
+			BeforeEach(func() {
+				invites.On("Remove", inviteID, restaurant.ID).Return(nil)
+			})
+
+			It("should revoke the invite", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+			})
+		})
+
+		Context("with the invite not existing", func() {
+			BeforeEach(func() {
+				invites.On("Remove", inviteID, restaurant.ID).Return(mgo.ErrNotFound)
+			})
+
+			It("should fail with StatusNotFound", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("POST /invites/accept", func() {
+		var (
+			handler router.Handler
+			invite  *model.Invite
+		)
+
+		BeforeEach(func() {
+			requestMethod = "POST"
+			invite = &model.Invite{
+				ID:           bson.NewObjectId(),
+				Token:        model.Token{0xef, 0x41, 0x20, 0xda, 0x3, 0x2, 0xbc, 0xee, 0x71, 0x2b, 0x1c, 0x25, 0x8d, 0x2f, 0xb6, 0xd4},
+				RestaurantID: restaurant.ID,
+				Role:         model.RoleEditor,
+			}
+			requestData = map[string]interface{}{
+				"token": "EF4120DA-0302-BCEE-712B-1C258D2FB6D4",
+			}
+		})
+
+		JustBeforeEach(func() {
+			handler = AcceptInvite(sessionManager, usersCollection, memberships, restaurants, invites, auditLog)
+		})
+
+		Context("with a shareable invite", func() {
+			BeforeEach(func() {
+				invites.On("GetToken", invite.Token).Return(invite, nil)
+			})
+
+			Context("with the user not yet a member", func() {
+				BeforeEach(func() {
+					memberships.On("Get", user.ID, restaurant.ID).Return(nil, mgo.ErrNotFound)
+					memberships.On("Set", user.ID, restaurant.ID, model.RoleEditor).Return(nil)
+				})
+
+				It("should give the user the invited role", func() {
+					err := handler(responseRecorder, request)
+					Expect(err).To(BeNil())
+					var response *model.Restaurant
+					json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+					Expect(response.ID).To(Equal(restaurant.ID))
+				})
+
+				It("should record the restaurant in the audit log", func() {
+					handler(responseRecorder, request)
+					Expect(auditEntries).To(HaveLen(1))
+					Expect(auditEntries[0].Action).To(Equal("invite.accept"))
+					Expect(auditEntries[0].RestaurantID).To(Equal(restaurant.ID))
+				})
+			})
+
+			Context("with the user already having a higher role", func() {
+				BeforeEach(func() {
+					memberships.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+						Role: model.RoleOwner,
+					}, nil)
+				})
+
+				It("should keep the current role", func() {
+					err := handler(responseRecorder, request)
+					Expect(err).To(BeNil())
+				})
+			})
+		})
+
+		Context("with a single use invite", func() {
+			BeforeEach(func() {
+				invite.Email = "cook@restaurant.test"
+				user.Email = "Cook@Restaurant.test"
+				invites.On("GetToken", invite.Token).Return(invite, nil)
+			})
+
+			Context("with the user having another email address", func() {
+				BeforeEach(func() {
+					user.Email = "someone.else@restaurant.test"
+				})
+
+				It("should fail with StatusForbidden without using up the invite", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusForbidden))
+					invites.AssertNotCalled(GinkgoT(), "Remove", mock.Anything, mock.Anything)
+				})
+			})
+
+			Context("with the invite not accepted yet", func() {
+				BeforeEach(func() {
+					invites.On("Remove", invite.ID, restaurant.ID).Return(nil)
+					memberships.On("Get", user.ID, restaurant.ID).Return(nil, mgo.ErrNotFound)
+					memberships.On("Set", user.ID, restaurant.ID, model.RoleEditor).Return(nil)
+				})
+
+				It("should use up the invite", func() {
+					err := handler(responseRecorder, request)
+					Expect(err).To(BeNil())
+					invites.AssertCalled(GinkgoT(), "Remove", invite.ID, restaurant.ID)
+				})
+			})
+
+			Context("with the invite already accepted by the user in the meanwhile", func() {
+				BeforeEach(func() {
+					invites.On("Remove", invite.ID, restaurant.ID).Return(mgo.ErrNotFound)
+					memberships.On("Get", user.ID, restaurant.ID).Return(nil, mgo.ErrNotFound)
+					memberships.On("Set", user.ID, restaurant.ID, model.RoleEditor).Return(nil)
+				})
+
+				It("should succeed", func() {
+					err := handler(responseRecorder, request)
+					Expect(err).To(BeNil())
+				})
+			})
+
+			Context("with the membership failing to be stored", func() {
+				BeforeEach(func() {
+					memberships.On("Get", user.ID, restaurant.ID).Return(nil, mgo.ErrNotFound)
+					memberships.On("Set", user.ID, restaurant.ID, model.RoleEditor).Return(errors.New("something went wrong"))
+				})
+
+				It("should fail without using up the invite", func() {
+					err := handler(responseRecorder, request)
+					Expect(err.Code).To(Equal(http.StatusInternalServerError))
+					invites.AssertNotCalled(GinkgoT(), "Remove", mock.Anything, mock.Anything)
+				})
+			})
+
+			Context("with a user who logs in through Facebook and has no email address", func() {
+				BeforeEach(func() {
+					user.Email = ""
+				})
+
+				Context("with the address not belonging to anyone else", func() {
+					BeforeEach(func() {
+						usersCollection.On("SetVerifiedEmail", user.ID, "cook@restaurant.test").Return(nil)
+						invites.On("Remove", invite.ID, restaurant.ID).Return(nil)
+						memberships.On("Get", user.ID, restaurant.ID).Return(nil, mgo.ErrNotFound)
+						memberships.On("Set", user.ID, restaurant.ID, model.RoleEditor).Return(nil)
+					})
+
+					It("should bind the invited address to the user and give them the role", func() {
+						err := handler(responseRecorder, request)
+						Expect(err).To(BeNil())
+						usersCollection.AssertCalled(GinkgoT(), "SetVerifiedEmail", user.ID, "cook@restaurant.test")
+						memberships.AssertCalled(GinkgoT(), "Set", user.ID, restaurant.ID, model.RoleEditor)
+					})
+				})
+
+				Context("with the address belonging to another user", func() {
+					BeforeEach(func() {
+						usersCollection.On("SetVerifiedEmail", user.ID, "cook@restaurant.test").Return(&mgo.LastError{Code: 11000})
+					})
+
+					It("should fail with StatusConflict without using up the invite", func() {
+						err := handler(responseRecorder, request)
+						Expect(err.Code).To(Equal(http.StatusConflict))
+						memberships.AssertNotCalled(GinkgoT(), "Set", mock.Anything, mock.Anything, mock.Anything)
+						invites.AssertNotCalled(GinkgoT(), "Remove", mock.Anything, mock.Anything)
+					})
+				})
+			})
+		})
+
+		Context("with an expired or revoked invite", func() {
+			BeforeEach(func() {
+				invites.On("GetToken", invite.Token).Return(nil, mgo.ErrNotFound)
+			})
+
+			It("should fail with StatusForbidden", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("with a malformed token", func() {
+			BeforeEach(func() {
+				requestData = map[string]interface{}{
+					"token": "not a token",
+				}
+			})
+
+			It("should fail with StatusBadRequest", func() {
+				err := handler(responseRecorder, request)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+})
@@ -0,0 +1,92 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/geo"
+
+type Coder struct {
+	mock.Mock
+}
+
+func (_m *Coder) Code(address string) ([]geo.Candidate, error) {
+	ret := _m.Called(address)
+
+	var r0 []geo.Candidate
+	if rf, ok := ret.Get(0).(func(string) []geo.Candidate); ok {
+		r0 = rf(address)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]geo.Candidate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(address)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Coder) CodeForRegion(address string, region string) ([]geo.Candidate, error) {
+	ret := _m.Called(address, region)
+
+	var r0 []geo.Candidate
+	if rf, ok := ret.Get(0).(func(string, string) []geo.Candidate); ok {
+		r0 = rf(address, region)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]geo.Candidate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(address, region)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Coder) ReverseCode(_a0 geo.Location) (geo.Address, error) {
+	ret := _m.Called(_a0)
+
+	var r0 geo.Address
+	if rf, ok := ret.Get(0).(func(geo.Location) geo.Address); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(geo.Address)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(geo.Location) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Coder) Suggest(input string, region string) ([]geo.Address, error) {
+	ret := _m.Called(input, region)
+
+	var r0 []geo.Address
+	if rf, ok := ret.Get(0).(func(string, string) []geo.Address); ok {
+		r0 = rf(input, region)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]geo.Address)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(input, region)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,44 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/userdata"
+import "gopkg.in/mgo.v2/bson"
+
+type Controller struct {
+	mock.Mock
+}
+
+func (_m *Controller) Export(userID bson.ObjectId) (*userdata.Archive, error) {
+	ret := _m.Called(userID)
+
+	var r0 *userdata.Archive
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) *userdata.Archive); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*userdata.Archive)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Controller) Delete(userID bson.ObjectId) error {
+	ret := _m.Called(userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
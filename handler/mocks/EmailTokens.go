@@ -0,0 +1,56 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/db/model"
+import "gopkg.in/mgo.v2/bson"
+
+type EmailTokens struct {
+	mock.Mock
+}
+
+func (_m *EmailTokens) Insert(_a0 *model.EmailToken) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.EmailToken) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *EmailTokens) Claim(_a0 model.Token, _a1 model.EmailTokenPurpose) (*model.EmailToken, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *model.EmailToken
+	if rf, ok := ret.Get(0).(func(model.Token, model.EmailTokenPurpose) *model.EmailToken); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.EmailToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(model.Token, model.EmailTokenPurpose) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *EmailTokens) RemoveForUser(userID bson.ObjectId) error {
+	ret := _m.Called(userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
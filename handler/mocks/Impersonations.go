@@ -0,0 +1,88 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/db/model"
+import "time"
+
+import "gopkg.in/mgo.v2/bson"
+
+type Impersonations struct {
+	mock.Mock
+}
+
+func (_m *Impersonations) Insert(_a0 *model.Impersonation) (*model.Impersonation, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *model.Impersonation
+	if rf, ok := ret.Get(0).(func(*model.Impersonation) *model.Impersonation); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Impersonation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*model.Impersonation) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Impersonations) End(adminID bson.ObjectId, userID bson.ObjectId, endedAt time.Time) error {
+	ret := _m.Called(adminID, userID, endedAt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, bson.ObjectId, time.Time) error); ok {
+		r0 = rf(adminID, userID, endedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Impersonations) GetRecent(limit int) ([]*model.Impersonation, error) {
+	ret := _m.Called(limit)
+
+	var r0 []*model.Impersonation
+	if rf, ok := ret.Get(0).(func(int) []*model.Impersonation); ok {
+		r0 = rf(limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Impersonation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Impersonations) GetForUser(userID bson.ObjectId) ([]*model.Impersonation, error) {
+	ret := _m.Called(userID)
+
+	var r0 []*model.Impersonation
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) []*model.Impersonation); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Impersonation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -3,6 +3,8 @@ package mocks
 import "github.com/stretchr/testify/mock"
 
 import "net/http"
+import "github.com/Lunchr/luncher-api/db/model"
+import "gopkg.in/mgo.v2/bson"
 
 type Manager struct {
 	mock.Mock
@@ -20,14 +22,28 @@ func (_m *Manager) GetOrInit(_a0 http.ResponseWriter, _a1 *http.Request) string
 
 	return r0
 }
-func (_m *Manager) Get(_a0 *http.Request) (string, error) {
+func (_m *Manager) Start(w http.ResponseWriter, r *http.Request, userID bson.ObjectId) error {
+	ret := _m.Called(w, r, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(http.ResponseWriter, *http.Request, bson.ObjectId) error); ok {
+		r0 = rf(w, r, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Manager) Resolve(_a0 *http.Request) (*model.Session, error) {
 	ret := _m.Called(_a0)
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func(*http.Request) string); ok {
+	var r0 *model.Session
+	if rf, ok := ret.Get(0).(func(*http.Request) *model.Session); ok {
 		r0 = rf(_a0)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Session)
+		}
 	}
 
 	var r1 error
@@ -39,3 +55,28 @@ func (_m *Manager) Get(_a0 *http.Request) (string, error) {
 
 	return r0, r1
 }
+func (_m *Manager) End(_a0 http.ResponseWriter, _a1 *http.Request) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(http.ResponseWriter, *http.Request) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *Manager) Impersonate(w http.ResponseWriter, r *http.Request, userID bson.ObjectId, impersonatorID bson.ObjectId) error {
+	ret := _m.Called(w, r, userID, impersonatorID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(http.ResponseWriter, *http.Request, bson.ObjectId, bson.ObjectId) error); ok {
+		r0 = rf(w, r, userID, impersonatorID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
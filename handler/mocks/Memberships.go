@@ -0,0 +1,110 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/db/model"
+import "gopkg.in/mgo.v2/bson"
+
+type Memberships struct {
+	mock.Mock
+}
+
+func (_m *Memberships) Get(_a0 bson.ObjectId, _a1 bson.ObjectId) (*model.Membership, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *model.Membership
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, bson.ObjectId) *model.Membership); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Membership)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId, bson.ObjectId) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Memberships) GetForUser(_a0 bson.ObjectId) ([]*model.Membership, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*model.Membership
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) []*model.Membership); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Membership)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Memberships) Set(_a0 bson.ObjectId, _a1 bson.ObjectId, _a2 model.Role) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, bson.ObjectId, model.Role) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Memberships) RemoveForRestaurant(_a0 bson.ObjectId) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Memberships) GetForRestaurant(restaurantID bson.ObjectId) ([]*model.Membership, error) {
+	ret := _m.Called(restaurantID)
+
+	var r0 []*model.Membership
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) []*model.Membership); ok {
+		r0 = rf(restaurantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Membership)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId) error); ok {
+		r1 = rf(restaurantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Memberships) RemoveForUser(userID bson.ObjectId) error {
+	ret := _m.Called(userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -85,3 +85,36 @@ func (_m *OfferGroupPosts) GetByDate(_a0 model.DateWithoutTime, _a1 bson.ObjectI
 
 	return r0, r1
 }
+func (_m *OfferGroupPosts) GetByRestaurantID(_a0 bson.ObjectId) ([]*model.OfferGroupPost, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*model.OfferGroupPost
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) []*model.OfferGroupPost); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.OfferGroupPost)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *OfferGroupPosts) RemoveByRestaurantID(_a0 bson.ObjectId) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
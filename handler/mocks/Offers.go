@@ -204,3 +204,27 @@ func (_m *Offers) RemoveID(_a0 bson.ObjectId) error {
 
 	return r0
 }
+func (_m *Offers) UpdateRestaurant(_a0 model.OfferRestaurant) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(model.OfferRestaurant) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Offers) RemoveForRestaurant(restaurantID bson.ObjectId) error {
+	ret := _m.Called(restaurantID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(restaurantID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -3,6 +3,7 @@ package mocks
 import "github.com/stretchr/testify/mock"
 
 import "github.com/Lunchr/luncher-api/db/model"
+import "github.com/Lunchr/luncher-api/facebook"
 import "github.com/Lunchr/luncher-api/router"
 
 type Post struct {
@@ -23,3 +24,40 @@ func (_m *Post) Update(_a0 model.DateWithoutTime, _a1 *model.User, _a2 *model.Re
 
 	return r0
 }
+func (_m *Post) Delete(_a0 *model.OfferGroupPost, _a1 *model.User, _a2 *model.Restaurant) *router.HandlerError {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *router.HandlerError
+	if rf, ok := ret.Get(0).(func(*model.OfferGroupPost, *model.User, *model.Restaurant) *router.HandlerError); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*router.HandlerError)
+		}
+	}
+
+	return r0
+}
+func (_m *Post) Preview(_a0 model.DateWithoutTime, _a1 *model.Restaurant) (*facebook.PostPreview, *router.HandlerError) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *facebook.PostPreview
+	if rf, ok := ret.Get(0).(func(model.DateWithoutTime, *model.Restaurant) *facebook.PostPreview); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*facebook.PostPreview)
+		}
+	}
+
+	var r1 *router.HandlerError
+	if rf, ok := ret.Get(1).(func(model.DateWithoutTime, *model.Restaurant) *router.HandlerError); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*router.HandlerError)
+		}
+	}
+
+	return r0, r1
+}
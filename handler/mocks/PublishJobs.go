@@ -0,0 +1,147 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/db/model"
+import "gopkg.in/mgo.v2/bson"
+import "time"
+
+type PublishJobs struct {
+	mock.Mock
+}
+
+func (_m *PublishJobs) Enqueue(restaurantID bson.ObjectId, date model.DateWithoutTime, userID bson.ObjectId, runAt time.Time, deadline time.Time) error {
+	ret := _m.Called(restaurantID, date, userID, runAt, deadline)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, model.DateWithoutTime, bson.ObjectId, time.Time, time.Time) error); ok {
+		r0 = rf(restaurantID, date, userID, runAt, deadline)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *PublishJobs) Claim(lease time.Duration) (*model.PublishJob, error) {
+	ret := _m.Called(lease)
+
+	var r0 *model.PublishJob
+	if rf, ok := ret.Get(0).(func(time.Duration) *model.PublishJob); ok {
+		r0 = rf(lease)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.PublishJob)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(time.Duration) error); ok {
+		r1 = rf(lease)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *PublishJobs) Complete(_a0 *model.PublishJob) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.PublishJob) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *PublishJobs) Retry(job *model.PublishJob, runAt time.Time, lastError string) error {
+	ret := _m.Called(job, runAt, lastError)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.PublishJob, time.Time, string) error); ok {
+		r0 = rf(job, runAt, lastError)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *PublishJobs) Fail(job *model.PublishJob, lastError string) error {
+	ret := _m.Called(job, lastError)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.PublishJob, string) error); ok {
+		r0 = rf(job, lastError)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *PublishJobs) GetForRestaurant(restaurantID bson.ObjectId, limit int) ([]*model.PublishJob, error) {
+	ret := _m.Called(restaurantID, limit)
+
+	var r0 []*model.PublishJob
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, int) []*model.PublishJob); ok {
+		r0 = rf(restaurantID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.PublishJob)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId, int) error); ok {
+		r1 = rf(restaurantID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *PublishJobs) GetByDate(date model.DateWithoutTime, restaurantID bson.ObjectId) (*model.PublishJob, error) {
+	ret := _m.Called(date, restaurantID)
+
+	var r0 *model.PublishJob
+	if rf, ok := ret.Get(0).(func(model.DateWithoutTime, bson.ObjectId) *model.PublishJob); ok {
+		r0 = rf(date, restaurantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.PublishJob)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(model.DateWithoutTime, bson.ObjectId) error); ok {
+		r1 = rf(date, restaurantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *PublishJobs) UnsetUser(userID bson.ObjectId) error {
+	ret := _m.Called(userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *PublishJobs) RemoveForRestaurant(restaurantID bson.ObjectId) error {
+	ret := _m.Called(restaurantID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(restaurantID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
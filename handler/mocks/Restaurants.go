@@ -86,6 +86,27 @@ func (_m *Restaurants) GetByFacebookPageIDs(_a0 []string) ([]*model.Restaurant,
 
 	return r0, r1
 }
+func (_m *Restaurants) GetByRegion(_a0 string) ([]*model.Restaurant, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*model.Restaurant
+	if rf, ok := ret.Get(0).(func(string) []*model.Restaurant); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Restaurant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
 func (_m *Restaurants) GetID(_a0 bson.ObjectId) (*model.Restaurant, error) {
 	ret := _m.Called(_a0)
 
@@ -138,3 +159,61 @@ func (_m *Restaurants) UpdateID(_a0 bson.ObjectId, _a1 *model.Restaurant) error
 
 	return r0
 }
+func (_m *Restaurants) UpdateDetails(_a0 bson.ObjectId, _a1 *model.Restaurant) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, *model.Restaurant) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Restaurants) SetDeactivated(_a0 bson.ObjectId, _a1 bool) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, bool) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Restaurants) RemoveID(_a0 bson.ObjectId) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *Restaurants) Search(query string, limit int) ([]*model.Restaurant, error) {
+	ret := _m.Called(query, limit)
+
+	var r0 []*model.Restaurant
+	if rf, ok := ret.Get(0).(func(string, int) []*model.Restaurant); ok {
+		r0 = rf(query, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Restaurant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(query, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
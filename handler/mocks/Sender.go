@@ -0,0 +1,22 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/mail"
+
+type Sender struct {
+	mock.Mock
+}
+
+func (_m *Sender) Send(_a0 mail.Message) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(mail.Message) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,68 @@
+package mocks
+
+import "github.com/Lunchr/luncher-api/db"
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/db/model"
+
+type Tags struct {
+	mock.Mock
+}
+
+func (_m *Tags) Insert(_a0 ...*model.Tag) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(...*model.Tag) error); ok {
+		r0 = rf(_a0...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Tags) GetName(_a0 string) (*model.Tag, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *model.Tag
+	if rf, ok := ret.Get(0).(func(string) *model.Tag); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Tag)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Tags) GetAll() db.TagIter {
+	ret := _m.Called()
+
+	var r0 db.TagIter
+	if rf, ok := ret.Get(0).(func() db.TagIter); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(db.TagIter)
+	}
+
+	return r0
+}
+func (_m *Tags) UpdateName(_a0 string, _a1 *model.Tag) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, *model.Tag) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -45,11 +45,11 @@ func (_m *Users) GetFbID(_a0 string) (*model.User, error) {
 
 	return r0, r1
 }
-func (_m *Users) GetSessionID(_a0 string) (*model.User, error) {
+func (_m *Users) GetID(_a0 bson.ObjectId) (*model.User, error) {
 	ret := _m.Called(_a0)
 
 	var r0 *model.User
-	if rf, ok := ret.Get(0).(func(string) *model.User); ok {
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) *model.User); ok {
 		r0 = rf(_a0)
 	} else {
 		if ret.Get(0) != nil {
@@ -58,7 +58,7 @@ func (_m *Users) GetSessionID(_a0 string) (*model.User, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
+	if rf, ok := ret.Get(1).(func(bson.ObjectId) error); ok {
 		r1 = rf(_a0)
 	} else {
 		r1 = ret.Error(1)
@@ -114,11 +114,56 @@ func (_m *Users) SetPageAccessTokens(_a0 string, _a1 []model.FacebookPageToken)
 
 	return r0
 }
-func (_m *Users) SetSessionID(_a0 bson.ObjectId, _a1 string) error {
+func (_m *Users) SetFacebookTokenHealth(_a0 bson.ObjectId, _a1 model.FacebookTokenHealth) error {
 	ret := _m.Called(_a0, _a1)
 
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, model.FacebookTokenHealth) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Users) RemoveRestaurant(restaurantID bson.ObjectId, facebookPageID string) error {
+	ret := _m.Called(restaurantID, facebookPageID)
+
 	var r0 error
 	if rf, ok := ret.Get(0).(func(bson.ObjectId, string) error); ok {
+		r0 = rf(restaurantID, facebookPageID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Users) GetEmail(_a0 string) (*model.User, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *model.User
+	if rf, ok := ret.Get(0).(func(string) *model.User); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Users) UpdateID(_a0 bson.ObjectId, _a1 *model.User) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, *model.User) error); ok {
 		r0 = rf(_a0, _a1)
 	} else {
 		r0 = ret.Error(0)
@@ -126,7 +171,65 @@ func (_m *Users) SetSessionID(_a0 bson.ObjectId, _a1 string) error {
 
 	return r0
 }
-func (_m *Users) UnsetSessionID(_a0 bson.ObjectId) error {
+func (_m *Users) SetPasswordHash(_a0 bson.ObjectId, _a1 []byte) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, []byte) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Users) SetEmailVerified(_a0 bson.ObjectId) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+func (_m *Users) Search(query string, limit int) ([]*model.User, error) {
+	ret := _m.Called(query, limit)
+
+	var r0 []*model.User
+	if rf, ok := ret.Get(0).(func(string, int) []*model.User); ok {
+		r0 = rf(query, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(query, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Users) SetAdmin(id bson.ObjectId, isAdmin bool) error {
+	ret := _m.Called(id, isAdmin)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, bool) error); ok {
+		r0 = rf(id, isAdmin)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Users) RemoveID(_a0 bson.ObjectId) error {
 	ret := _m.Called(_a0)
 
 	var r0 error
@@ -138,3 +241,49 @@ func (_m *Users) UnsetSessionID(_a0 bson.ObjectId) error {
 
 	return r0
 }
+
+func (_m *Users) UnsetRestaurantIDs(_a0 bson.ObjectId) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Users) SetVerifiedEmail(id bson.ObjectId, email string) error {
+	ret := _m.Called(id, email)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, string) error); ok {
+		r0 = rf(id, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Users) GetForFacebookPage(pageID string) ([]*model.User, error) {
+	ret := _m.Called(pageID)
+
+	var r0 []*model.User
+	if rf, ok := ret.Get(0).(func(string) []*model.User); ok {
+		r0 = rf(pageID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pageID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
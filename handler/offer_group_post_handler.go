@@ -2,11 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 
+	"github.com/Lunchr/luncher-api/audit"
 	"github.com/Lunchr/luncher-api/db"
 	"github.com/Lunchr/luncher-api/db/model"
 	"github.com/Lunchr/luncher-api/facebook"
@@ -16,7 +18,8 @@ import (
 )
 
 // OfferGroupPost handles GET requests to /restaurant/posts/:date. It returns all current day's offers for the region.
-func OfferGroupPost(c db.OfferGroupPosts, sessionManager session.Manager, users db.Users, restaurants db.Restaurants) router.HandlerWithParams {
+func OfferGroupPost(c db.OfferGroupPosts, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys, restaurants db.Restaurants) router.HandlerWithParams {
 	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant,
 		date model.DateWithoutTime) *router.HandlerError {
 		post, err := c.GetByDate(date, restaurant.ID)
@@ -27,16 +30,33 @@ func OfferGroupPost(c db.OfferGroupPosts, sessionManager session.Manager, users
 		}
 		return writeJSON(w, post)
 	}
-	return forDate(sessionManager, users, restaurants, handler)
+	return forRestaurantWithParams(sessionManager, users, memberships, apiKeys, restaurants, model.RoleViewer, forDate(handler))
+}
+
+// OfferGroupPostPreview handles GET requests to /restaurants/:restaurantID/posts/:date/preview. It returns the message,
+// the scheduled publish time and the image collage of the post that would be published on Facebook, without calling FB.
+func OfferGroupPostPreview(sessionManager session.Manager, users db.Users, memberships db.Memberships, apiKeys db.APIKeys,
+	restaurants db.Restaurants, facebookPost facebook.Post) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant,
+		date model.DateWithoutTime) *router.HandlerError {
+		preview, handlerErr := facebookPost.Preview(date, restaurant)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		return writeJSON(w, preview)
+	}
+	return forRestaurantWithParams(sessionManager, users, memberships, apiKeys, restaurants, model.RoleViewer, forDate(handler))
 }
 
 // PostOfferGroupPost handles POST requests to /restaurant/posts. It stores the info in the DB and updates the post in FB.
-func PostOfferGroupPost(c db.OfferGroupPosts, sessionManager session.Manager, users db.Users, restaurants db.Restaurants,
-	facebookPost facebook.Post) router.HandlerWithParams {
+func PostOfferGroupPost(c db.OfferGroupPosts, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys, restaurants db.Restaurants, facebookPost facebook.Post, auditLog audit.Log) router.HandlerWithParams {
 	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
 		post, handlerErr := parseOfferGroupPost(r, restaurant)
 		if handlerErr != nil {
 			return handlerErr
+		} else if handlerErr = checkMessageTemplate(post.MessageTemplate); handlerErr != nil {
+			return handlerErr
 		}
 		insertedPosts, err := c.Insert(post)
 		if err != nil {
@@ -48,12 +68,13 @@ func PostOfferGroupPost(c db.OfferGroupPosts, sessionManager session.Manager, us
 		}
 		return writeJSON(w, insertedPost)
 	}
-	return forRestaurant(sessionManager, users, restaurants, handler)
+	return forRestaurant(sessionManager, users, memberships, apiKeys, restaurants, model.RoleEditor,
+		auditedForRestaurant(auditLog, "post.create", handler))
 }
 
 // PutOfferGroupPost handles PUT requests to /restaurant/posts/:date. It stores the info in the DB and updates the post in FB.
-func PutOfferGroupPost(c db.OfferGroupPosts, sessionManager session.Manager, users db.Users, restaurants db.Restaurants,
-	facebookPost facebook.Post) router.HandlerWithParams {
+func PutOfferGroupPost(c db.OfferGroupPosts, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys, restaurants db.Restaurants, facebookPost facebook.Post, auditLog audit.Log) router.HandlerWithParams {
 	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant,
 		date model.DateWithoutTime) *router.HandlerError {
 		updatedMessageTemplate, handlerErr := parseOfferGroupPostUpdatedMessage(r)
@@ -64,6 +85,9 @@ func PutOfferGroupPost(c db.OfferGroupPosts, sessionManager session.Manager, use
 		if err != nil {
 			return router.NewSimpleHandlerError("Failed to get the post from DB", http.StatusBadRequest)
 		}
+		if handlerErr = checkMessageTemplate(updatedMessageTemplate); handlerErr != nil {
+			return handlerErr
+		}
 		post.MessageTemplate = updatedMessageTemplate
 		if err = c.UpdateByID(post.ID, post); err != nil {
 			return router.NewSimpleHandlerError("Failed to insert the post to DB", http.StatusBadRequest)
@@ -74,15 +98,15 @@ func PutOfferGroupPost(c db.OfferGroupPosts, sessionManager session.Manager, use
 		}
 		return writeJSON(w, post)
 	}
-	return forDate(sessionManager, users, restaurants, handler)
+	return forRestaurantWithParams(sessionManager, users, memberships, apiKeys, restaurants, model.RoleEditor,
+		auditedForRestaurantWithParams(auditLog, "post.update", forDate(handler)))
 }
 
 type HandlerWithRestaurantAndDate func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant,
 	date model.DateWithoutTime) *router.HandlerError
 
-func forDate(sessionManager session.Manager, users db.Users, restaurants db.Restaurants,
-	handler HandlerWithRestaurantAndDate) router.HandlerWithParams {
-	handlerWithRestaurant := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User,
+func forDate(handler HandlerWithRestaurantAndDate) HandlerWithParamsWithRestaurant {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User,
 		restaurant *model.Restaurant) *router.HandlerError {
 		date := model.DateWithoutTime(ps.ByName("date"))
 		if date == "" {
@@ -93,7 +117,6 @@ func forDate(sessionManager session.Manager, users db.Users, restaurants db.Rest
 		}
 		return handler(w, r, user, restaurant, date)
 	}
-	return forRestaurantWithParams(sessionManager, users, restaurants, handlerWithRestaurant)
 }
 
 func parseOfferGroupPost(r *http.Request, restaurant *model.Restaurant) (*model.OfferGroupPost, *router.HandlerError) {
@@ -121,6 +144,14 @@ func parseOfferGroupPost(r *http.Request, restaurant *model.Restaurant) (*model.
 	}, nil
 }
 
+// checkMessageTemplate responds with a 400 if the message template of a group post can't be used
+func checkMessageTemplate(messageTemplate string) *router.HandlerError {
+	if err := facebook.ValidateMessageTemplate(messageTemplate); err != nil {
+		return router.NewHandlerError(err, fmt.Sprintf("Invalid message template: %v", err), http.StatusBadRequest)
+	}
+	return nil
+}
+
 // Only the message template can be updated, therefore this method
 func parseOfferGroupPostUpdatedMessage(r *http.Request) (string, *router.HandlerError) {
 	var post struct {
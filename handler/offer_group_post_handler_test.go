@@ -3,9 +3,11 @@ package handler_test
 import (
 	"encoding/json"
 	"errors"
+	"net/http"
 
 	"github.com/Lunchr/luncher-api/db"
 	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/facebook"
 	. "github.com/Lunchr/luncher-api/handler"
 	"github.com/Lunchr/luncher-api/handler/mocks"
 	"github.com/Lunchr/luncher-api/router"
@@ -19,6 +21,16 @@ import (
 )
 
 var _ = Describe("OfferGroupPostHandlers", func() {
+	var (
+		membershipsCollection *mocks.Memberships
+		apiKeysCollection     *mocks.APIKeys
+	)
+
+	BeforeEach(func() {
+		membershipsCollection = new(mocks.Memberships)
+		apiKeysCollection = new(mocks.APIKeys)
+	})
+
 	Describe("GET /restaurants/:restaurantID/posts/:date", func() {
 		var (
 			sessionManager        session.Manager
@@ -29,7 +41,8 @@ var _ = Describe("OfferGroupPostHandlers", func() {
 		)
 
 		JustBeforeEach(func() {
-			handler = OfferGroupPost(postsCollection, sessionManager, usersCollection, restaurantsCollection)
+			handler = OfferGroupPost(postsCollection, sessionManager, usersCollection, membershipsCollection,
+				apiKeysCollection, restaurantsCollection)
 		})
 
 		ExpectUserToBeLoggedIn(func() *router.HandlerError {
@@ -73,8 +86,8 @@ var _ = Describe("OfferGroupPostHandlers", func() {
 					},
 				}
 
-				mockSessionManager.On("Get", mock.Anything).Return("session", nil)
-				mockUsersCollection.On("GetSessionID", "session").Return(user, nil)
+				mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+				mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
 				mockRestaurantsCollection.On("GetID", restaurantID).Return(restaurant, nil)
 
 				params = httprouter.Params{httprouter.Param{
@@ -148,6 +161,73 @@ var _ = Describe("OfferGroupPostHandlers", func() {
 		})
 	})
 
+	Describe("GET /restaurants/:restaurantID/posts/:date/preview", func() {
+		var (
+			sessionManager        *mocks.Manager
+			usersCollection       *mocks.Users
+			restaurantsCollection *mocks.Restaurants
+			facebookPost          *mocks.Post
+			restaurant            *model.Restaurant
+			date                  model.DateWithoutTime
+			params                httprouter.Params
+			handler               router.HandlerWithParams
+		)
+
+		BeforeEach(func() {
+			sessionManager = new(mocks.Manager)
+			usersCollection = new(mocks.Users)
+			restaurantsCollection = new(mocks.Restaurants)
+			facebookPost = new(mocks.Post)
+			restaurant = &model.Restaurant{ID: bson.NewObjectId()}
+			user := &model.User{
+				ID: bson.NewObjectId(),
+			}
+			membershipsCollection.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+				Role: model.RoleOwner,
+			}, nil)
+			sessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+			usersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+			restaurantsCollection.On("GetID", restaurant.ID).Return(restaurant, nil)
+			date = model.DateWithoutTime("2015-04-10")
+			params = httprouter.Params{httprouter.Param{
+				Key:   "date",
+				Value: string(date),
+			}, httprouter.Param{
+				Key:   "restaurantID",
+				Value: restaurant.ID.Hex(),
+			}}
+		})
+
+		JustBeforeEach(func() {
+			handler = OfferGroupPostPreview(sessionManager, usersCollection, membershipsCollection, apiKeysCollection,
+				restaurantsCollection, facebookPost)
+		})
+
+		AfterEach(func() {
+			facebookPost.AssertExpectations(GinkgoT())
+		})
+
+		It("should respond with the preview", func() {
+			facebookPost.On("Preview", date, restaurant).Return(&facebook.PostPreview{
+				Message: "a message",
+				Image:   "data:image/jpeg;base64,abc",
+			}, nil)
+			err := handler(responseRecorder, request, params)
+			Expect(err).To(BeNil())
+			var preview *facebook.PostPreview
+			json.Unmarshal(responseRecorder.Body.Bytes(), &preview)
+			Expect(preview.Message).To(Equal("a message"))
+			Expect(preview.Image).To(Equal("data:image/jpeg;base64,abc"))
+		})
+
+		It("should fail if the preview fails", func() {
+			handlerErr := router.NewSimpleHandlerError("There are no offers to post for that date", http.StatusNotFound)
+			facebookPost.On("Preview", date, restaurant).Return(nil, handlerErr)
+			err := handler(responseRecorder, request, params)
+			Expect(err).To(Equal(handlerErr))
+		})
+	})
+
 	Describe("POST /restaurants/:restaurantID/posts", func() {
 		var (
 			sessionManager        session.Manager
@@ -160,7 +240,8 @@ var _ = Describe("OfferGroupPostHandlers", func() {
 		)
 
 		JustBeforeEach(func() {
-			handler = PostOfferGroupPost(postsCollection, sessionManager, usersCollection, restaurantsCollection, facebookPost)
+			handler = PostOfferGroupPost(postsCollection, sessionManager, usersCollection, membershipsCollection,
+				apiKeysCollection, restaurantsCollection, facebookPost, auditLog)
 		})
 
 		ExpectUserToBeLoggedIn(func() *router.HandlerError {
@@ -209,8 +290,8 @@ var _ = Describe("OfferGroupPostHandlers", func() {
 					},
 				}
 
-				mockSessionManager.On("Get", mock.Anything).Return("session", nil)
-				mockUsersCollection.On("GetSessionID", "session").Return(user, nil).Once()
+				mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+				mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil).Once()
 				mockRestaurantsCollection.On("GetID", restaurantID).Return(restaurant, nil).Once()
 
 				requestMethod = "POST"
@@ -294,12 +375,15 @@ var _ = Describe("OfferGroupPostHandlers", func() {
 								FacebookPageID: fbPageID,
 							}
 							user := &model.User{
-								RestaurantIDs: []bson.ObjectId{restaurant.ID},
+								ID: bson.NewObjectId(),
 							}
+							membershipsCollection.On("Get", user.ID, restaurantID).Return(&model.Membership{
+								Role: model.RoleOwner,
+							}, nil)
 							mockRestaurantsCollection.GetID(restaurantID) // Best way I could think of getting rid of the previous mock
 							mockRestaurantsCollection.On("GetID", restaurantID).Return(restaurant, nil)
-							mockUsersCollection.GetSessionID("session")
-							mockUsersCollection.On("GetSessionID", "session").Return(user, nil)
+							mockUsersCollection.GetID("")
+							mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
 							facebookPost.On("Update", model.DateWithoutTime("2115-04-18"), user, restaurant).Return(nil)
 						})
 
@@ -334,6 +418,27 @@ var _ = Describe("OfferGroupPostHandlers", func() {
 					Expect(err).NotTo(BeNil())
 				})
 			})
+
+			Context("with the user only being a viewer of the restaurant", func() {
+				BeforeEach(func() {
+					user.ID = bson.NewObjectId()
+					user.Session.FacebookPageTokens = nil
+					membershipsCollection.On("Get", user.ID, restaurantID).Return(&model.Membership{
+						UserID:       user.ID,
+						RestaurantID: restaurantID,
+						Role:         model.RoleViewer,
+					}, nil)
+					requestData = map[string]interface{}{
+						"date": "2115-04-18",
+					}
+				})
+
+				It("should fail with StatusForbidden", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err).NotTo(BeNil())
+					Expect(err.Code).To(Equal(http.StatusForbidden))
+				})
+			})
 		})
 	})
 
@@ -348,8 +453,8 @@ var _ = Describe("OfferGroupPostHandlers", func() {
 		)
 
 		JustBeforeEach(func() {
-			handler = PutOfferGroupPost(postsCollection, sessionManager, usersCollection, restaurantsCollection,
-				facebookPost)
+			handler = PutOfferGroupPost(postsCollection, sessionManager, usersCollection, membershipsCollection,
+				apiKeysCollection, restaurantsCollection, facebookPost, auditLog)
 		})
 
 		ExpectUserToBeLoggedIn(func() *router.HandlerError {
@@ -399,8 +504,8 @@ var _ = Describe("OfferGroupPostHandlers", func() {
 					},
 				}
 
-				mockSessionManager.On("Get", mock.Anything).Return("session", nil)
-				mockUsersCollection.On("GetSessionID", "session").Return(user, nil)
+				mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+				mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
 				mockRestaurantsCollection.On("GetID", restaurantID).Return(restaurant, nil)
 
 				requestMethod = "PUT"
@@ -500,6 +605,20 @@ var _ = Describe("OfferGroupPostHandlers", func() {
 					})
 				})
 			})
+
+			Context("with an invalid message template", func() {
+				BeforeEach(func() {
+					requestData = map[string]interface{}{
+						"message_template": "{{range .Offers}}",
+					}
+				})
+
+				It("should fail with 400", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err.Code).To(Equal(http.StatusBadRequest))
+					mockPostsCollection.AssertNotCalled(GinkgoT(), "UpdateByID", mock.Anything, mock.Anything)
+				})
+			})
 		})
 	})
 })
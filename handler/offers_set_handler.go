@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Lunchr/luncher-api/audit"
 	"github.com/Lunchr/luncher-api/db"
 	"github.com/Lunchr/luncher-api/db/model"
 	"github.com/Lunchr/luncher-api/facebook"
@@ -19,8 +20,9 @@ type HandlerWithRestaurantAndOffer func(http.ResponseWriter, *http.Request, *mod
 
 // PostOffers handles POST requests to /offers. It stores the offer in the DB and
 // sends it to Facebook to be posted on the page's wall at the requested time.
-func PostOffers(offers db.Offers, users db.Users, restaurants db.Restaurants, sessionManager session.Manager,
-	imageStorage storage.Images, facebookPost facebook.Post, regions db.Regions) router.HandlerWithParams {
+func PostOffers(offers db.Offers, users db.Users, memberships db.Memberships, apiKeys db.APIKeys, restaurants db.Restaurants,
+	sessionManager session.Manager, imageStorage storage.Images, facebookPost facebook.Post, regions db.Regions,
+	auditLog audit.Log) router.HandlerWithParams {
 	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
 		offerPOST, err := parseOffer(r, restaurant)
 		if err != nil {
@@ -51,13 +53,15 @@ func PostOffers(offers db.Offers, users db.Users, restaurants db.Restaurants, se
 		}
 		return writeJSON(w, offerJSON)
 	}
-	return forRestaurant(sessionManager, users, restaurants, handler)
+	return forRestaurant(sessionManager, users, memberships, apiKeys, restaurants, model.RoleEditor,
+		auditedForRestaurant(auditLog, "offer.create", handler))
 }
 
 // PutOffers handles PUT requests to /offers. It updates the offer in the DB and
 // updates the related Facebook post.
-func PutOffers(offers db.Offers, users db.Users, restaurants db.Restaurants, sessionManager session.Manager,
-	imageStorage storage.Images, facebookPost facebook.Post, regions db.Regions) router.HandlerWithParams {
+func PutOffers(offers db.Offers, users db.Users, memberships db.Memberships, apiKeys db.APIKeys, restaurants db.Restaurants,
+	sessionManager session.Manager, imageStorage storage.Images, facebookPost facebook.Post, regions db.Regions,
+	auditLog audit.Log) router.HandlerWithParams {
 	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant, currentOffer *model.Offer) *router.HandlerError {
 		offerPOST, err := parseOffer(r, restaurant)
 		if err != nil {
@@ -100,13 +104,14 @@ func PutOffers(offers db.Offers, users db.Users, restaurants db.Restaurants, ses
 		return writeJSON(w, offerJSON)
 	}
 
-	return forRestaurantWithParams(sessionManager, users, restaurants, forOffer(offers, handler))
+	return forRestaurantWithParams(sessionManager, users, memberships, apiKeys, restaurants, model.RoleEditor,
+		auditedForRestaurantWithParams(auditLog, "offer.update", forOffer(offers, handler)))
 }
 
 // DeleteOffers handles DELETE requests to /offers. It deletes the offer from the DB and
 // deletes the related Facebook post.
-func DeleteOffers(offers db.Offers, users db.Users, sessionManager session.Manager, restaurants db.Restaurants,
-	facebookPost facebook.Post, regions db.Regions) router.HandlerWithParams {
+func DeleteOffers(offers db.Offers, users db.Users, memberships db.Memberships, apiKeys db.APIKeys, sessionManager session.Manager,
+	restaurants db.Restaurants, facebookPost facebook.Post, regions db.Regions, auditLog audit.Log) router.HandlerWithParams {
 	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant, currentOffer *model.Offer) *router.HandlerError {
 		if err := offers.RemoveID(currentOffer.ID); err != nil {
 			return router.NewHandlerError(err, "Failed to delete the offer from DB", http.StatusInternalServerError)
@@ -125,7 +130,8 @@ func DeleteOffers(offers db.Offers, users db.Users, sessionManager session.Manag
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
-	return forRestaurantWithParams(sessionManager, users, restaurants, forOffer(offers, handler))
+	return forRestaurantWithParams(sessionManager, users, memberships, apiKeys, restaurants, model.RoleEditor,
+		auditedForRestaurantWithParams(auditLog, "offer.delete", forOffer(offers, handler)))
 }
 
 func forOffer(offersCollection db.Offers, handler HandlerWithRestaurantAndOffer) HandlerWithParamsWithRestaurant {
@@ -149,14 +155,7 @@ func parseOffer(r *http.Request, restaurant *model.Restaurant) (*model.OfferPOST
 	if err != nil {
 		return nil, err
 	}
-	offer.Restaurant = model.OfferRestaurant{
-		ID:       restaurant.ID,
-		Name:     restaurant.Name,
-		Region:   restaurant.Region,
-		Address:  restaurant.Address,
-		Location: restaurant.Location,
-		Phone:    restaurant.Phone,
-	}
+	offer.Restaurant = model.MapRestaurantToOfferRestaurant(restaurant)
 	return &offer, nil
 }
 
@@ -174,16 +173,20 @@ func parseAndStoreImage(imageDataURL string, imageStorage storage.Images) (strin
 	if err != nil {
 		return "", err
 	}
+	if err = storeImage(imageDataURL, imageChecksum, imageStorage); err != nil {
+		return "", err
+	}
+	return imageChecksum, nil
+}
+
+func storeImage(imageDataURL, imageChecksum string, imageStorage storage.Images) error {
 	alreadyStored, err := imageStorage.HasChecksum(imageChecksum)
 	if err != nil {
-		return "", err
+		return err
 	} else if alreadyStored {
-		return imageChecksum, nil
-	}
-	if err = imageStorage.StoreDataURL(imageDataURL); err != nil {
-		return "", err
+		return nil
 	}
-	return imageChecksum, nil
+	return imageStorage.StoreDataURL(imageDataURL)
 }
 
 func getLocationForRestaurant(restaurant *model.Restaurant, regions db.Regions) (*time.Location, *router.HandlerError) {
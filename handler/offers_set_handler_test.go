@@ -26,13 +26,20 @@ import (
 var _ = Describe("OffersHandler", func() {
 
 	var (
-		offersCollection  db.Offers
-		imageStorage      *mocks.Images
-		regionsCollection *mocks.Regions
+		offersCollection      db.Offers
+		membershipsCollection *mocks.Memberships
+		apiKeysCollection     *mocks.APIKeys
+		imageStorage          *mocks.Images
+		regionsCollection     *mocks.Regions
 	)
 
 	BeforeEach(func() {
 		offersCollection = &mockOffers{}
+		membershipsCollection = new(mocks.Memberships)
+		membershipsCollection.On("Get", objectID, bson.ObjectId("12letrrestid")).Return(&model.Membership{
+			Role: model.RoleOwner,
+		}, nil)
+		apiKeysCollection = new(mocks.APIKeys)
 		imageStorage = new(mocks.Images)
 		imageStorage.On("ChecksumDataURL", "image data url").Return("image checksum", nil)
 		imageStorage.On("HasChecksum", "image checksum").Return(false, nil)
@@ -85,8 +92,8 @@ var _ = Describe("OffersHandler", func() {
 		})
 
 		JustBeforeEach(func() {
-			handler = PostOffers(offersCollection, usersCollection, restaurantsCollection, sessionManager,
-				imageStorage, facebookPost, regionsCollection)
+			handler = PostOffers(offersCollection, usersCollection, membershipsCollection, apiKeysCollection,
+				restaurantsCollection, sessionManager, imageStorage, facebookPost, regionsCollection, auditLog)
 		})
 
 		ExpectUserToBeLoggedIn(func() *router.HandlerError {
@@ -98,7 +105,7 @@ var _ = Describe("OffersHandler", func() {
 
 		Context("with session set and a matching user in DB", func() {
 			BeforeEach(func() {
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 				requestMethod = "POST"
 				requestData = map[string]interface{}{
 					"title":       "thetitle",
@@ -201,8 +208,8 @@ var _ = Describe("OffersHandler", func() {
 		})
 
 		JustBeforeEach(func() {
-			handler = PutOffers(offersCollection, usersCollection, restaurantsCollection, sessionManager,
-				imageStorage, facebookPost, regionsCollection)
+			handler = PutOffers(offersCollection, usersCollection, membershipsCollection, apiKeysCollection,
+				restaurantsCollection, sessionManager, imageStorage, facebookPost, regionsCollection, auditLog)
 		})
 
 		ExpectUserToBeLoggedIn(func() *router.HandlerError {
@@ -214,7 +221,7 @@ var _ = Describe("OffersHandler", func() {
 
 		Context("with no matching offer in the DB", func() {
 			BeforeEach(func() {
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 			})
 
 			It("should fail", func() {
@@ -232,7 +239,7 @@ var _ = Describe("OffersHandler", func() {
 					Key:   "restaurantID",
 					Value: restaurantID.Hex(),
 				}}
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 			})
 
 			It("should fail", func() {
@@ -243,7 +250,7 @@ var _ = Describe("OffersHandler", func() {
 
 		Context("with image not changed", func() {
 			BeforeEach(func() {
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 				requestMethod = "PUT"
 				requestData = map[string]interface{}{
 					"title":       "thetitle",
@@ -280,7 +287,7 @@ var _ = Describe("OffersHandler", func() {
 
 		Context("with session set, a matching user in DB and an offer in DB", func() {
 			BeforeEach(func() {
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 				requestMethod = "PUT"
 				requestData = map[string]interface{}{
 					"title":       "thetitle",
@@ -431,7 +438,8 @@ var _ = Describe("OffersHandler", func() {
 		})
 
 		JustBeforeEach(func() {
-			handler = DeleteOffers(offersCollection, usersCollection, sessionManager, restaurantsCollection, facebookPost, regionsCollection)
+			handler = DeleteOffers(offersCollection, usersCollection, membershipsCollection, apiKeysCollection,
+				sessionManager, restaurantsCollection, facebookPost, regionsCollection, auditLog)
 		})
 
 		ExpectUserToBeLoggedIn(func() *router.HandlerError {
@@ -443,7 +451,7 @@ var _ = Describe("OffersHandler", func() {
 
 		Context("with no matching offer in the DB", func() {
 			BeforeEach(func() {
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 			})
 
 			It("should fail", func() {
@@ -461,7 +469,7 @@ var _ = Describe("OffersHandler", func() {
 					Key:   "restaurantID",
 					Value: restaurantID.Hex(),
 				}}
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 			})
 
 			It("should fail", func() {
@@ -472,7 +480,7 @@ var _ = Describe("OffersHandler", func() {
 
 		Context("with session set, a matching user in DB and an offer in DB", func() {
 			BeforeEach(func() {
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 				requestMethod = "DELETE"
 				currentOffer := &model.Offer{
 					CommonOfferFields: model.CommonOfferFields{
@@ -502,13 +510,12 @@ type mockUsers struct {
 	db.Users
 }
 
-func (m mockUsers) GetSessionID(session string) (*model.User, error) {
-	if session != "correctSession" {
+func (m mockUsers) GetID(id bson.ObjectId) (*model.User, error) {
+	if id != objectID {
 		return nil, mgo.ErrNotFound
 	}
 	user := &model.User{
-		ID:            objectID,
-		RestaurantIDs: []bson.ObjectId{"12letrrestid"},
+		ID: objectID,
 		Session: model.UserSession{
 			FacebookUserToken: oauth2.Token{
 				AccessToken: "usertoken",
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/storage"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PublicRestaurant handles GET requests to /public/restaurants/:id. It returns the
+// publicly available information about the restaurant along with its upcoming offers.
+func PublicRestaurant(restaurants db.Restaurants, offers db.Offers, regions db.Regions,
+	imageStorage storage.Images) router.HandlerWithParams {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) *router.HandlerError {
+		restaurant, handlerErr := getRestaurantByID(ps.ByName("id"), restaurants)
+		if handlerErr != nil {
+			return handlerErr
+		} else if restaurant.Deactivated {
+			return router.NewSimpleHandlerError("Failed to find the specified restaurant", http.StatusNotFound)
+		}
+		timeLocation, handlerErr := getLocationForRestaurant(restaurant, regions)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		today, _ := getTodaysTimeRange(timeLocation)
+		upcomingOffers, err := offers.GetForRestaurant(restaurant.ID, today)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to find upcoming offers for this restaurant", http.StatusInternalServerError)
+		}
+		offerJSONs, handlerErr := mapOffersToJSON(upcomingOffers, imageStorage)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		return writeJSON(w, &model.PublicRestaurantWithOffers{
+			PublicRestaurant: *model.MapRestaurantToPublic(restaurant),
+			Offers:           offerJSONs,
+		})
+	}
+}
+
+// RegionRestaurants handles GET requests to /regions/:name/restaurants. It returns the
+// public information about all the restaurants in the region.
+func RegionRestaurants(restaurants db.Restaurants, regions db.Regions) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, region *model.Region) *router.HandlerError {
+		restaurantsInRegion, err := restaurants.GetByRegion(region.Name)
+		if err != nil {
+			return router.NewHandlerError(err, "An error occured while trying to fetch the restaurants in this region", http.StatusInternalServerError)
+		}
+		publicRestaurants := make([]*model.PublicRestaurant, len(restaurantsInRegion))
+		for i, restaurant := range restaurantsInRegion {
+			publicRestaurants[i] = model.MapRestaurantToPublic(restaurant)
+		}
+		return writeJSON(w, publicRestaurants)
+	}
+	return forRegion(regions, handler)
+}
@@ -0,0 +1,206 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PublicRestaurantsHandlers", func() {
+	var (
+		restaurantsCollection *mocks.Restaurants
+		regionsCollection     *mocks.Regions
+		restaurant            *model.Restaurant
+	)
+
+	BeforeEach(func() {
+		restaurantsCollection = new(mocks.Restaurants)
+		regionsCollection = new(mocks.Regions)
+		restaurant = &model.Restaurant{
+			ID:      bson.NewObjectId(),
+			Name:    "Asian Chef",
+			Region:  "Tartu",
+			Address: "an-address",
+			Location: model.Location{
+				Type:        "Point",
+				Coordinates: []float64{26.7, 58.4},
+			},
+			Phone:          "+372 5678 910",
+			Website:        "http://asian.chef",
+			Email:          "asian@chef.ee",
+			FacebookPageID: "a facebook page ID",
+
+			DefaultGroupPostMessageTemplate: "a template",
+		}
+		regionsCollection.On("GetName", "Tartu").Return(&model.Region{
+			Name:     "Tartu",
+			Location: "Europe/Tallinn",
+		}, nil)
+	})
+
+	Describe("GET /public/restaurants/:id", func() {
+		var (
+			offersCollection *mocks.Offers
+			imageStorage     *mocks.Images
+			params           httprouter.Params
+			handler          router.HandlerWithParams
+		)
+
+		BeforeEach(func() {
+			offersCollection = new(mocks.Offers)
+			imageStorage = new(mocks.Images)
+			imageStorage.On("PathsFor", "").Return(nil, nil)
+		})
+
+		JustBeforeEach(func() {
+			handler = PublicRestaurant(restaurantsCollection, offersCollection, regionsCollection, imageStorage)
+		})
+
+		Context("with an invalid restaurant ID", func() {
+			BeforeEach(func() {
+				params = httprouter.Params{httprouter.Param{
+					Key:   "id",
+					Value: "gibberish",
+				}}
+			})
+
+			It("fails with StatusBadRequest", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("with a valid restaurant ID", func() {
+			BeforeEach(func() {
+				params = httprouter.Params{httprouter.Param{
+					Key:   "id",
+					Value: restaurant.ID.Hex(),
+				}}
+			})
+
+			Context("with no such restaurant in the DB", func() {
+				BeforeEach(func() {
+					restaurantsCollection.On("GetID", restaurant.ID).Return(nil, mgo.ErrNotFound)
+				})
+
+				It("fails with StatusNotFound", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err.Code).To(Equal(http.StatusNotFound))
+				})
+			})
+
+			Context("with the restaurant being deactivated", func() {
+				BeforeEach(func() {
+					restaurant.Deactivated = true
+					restaurantsCollection.On("GetID", restaurant.ID).Return(restaurant, nil)
+				})
+
+				It("fails with StatusNotFound", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err.Code).To(Equal(http.StatusNotFound))
+				})
+			})
+
+			Context("with the restaurant and its offers in the DB", func() {
+				BeforeEach(func() {
+					restaurantsCollection.On("GetID", restaurant.ID).Return(restaurant, nil)
+					offersCollection.On("GetForRestaurant", restaurant.ID, mock.AnythingOfType("time.Time")).Return([]*model.Offer{
+						&model.Offer{
+							CommonOfferFields: model.CommonOfferFields{
+								Title: "an offer",
+							},
+						},
+					}, nil)
+				})
+
+				It("succeeds", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err).To(BeNil())
+				})
+
+				It("includes the public restaurant data and its offers", func() {
+					handler(responseRecorder, request, params)
+					var response *model.PublicRestaurantWithOffers
+					json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+					Expect(response.ID).To(Equal(restaurant.ID))
+					Expect(response.Name).To(Equal("Asian Chef"))
+					Expect(response.Website).To(Equal("http://asian.chef"))
+					Expect(response.Offers).To(HaveLen(1))
+					Expect(response.Offers[0].Title).To(Equal("an offer"))
+				})
+
+				It("omits the private fields", func() {
+					handler(responseRecorder, request, params)
+					var response map[string]interface{}
+					json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+					Expect(response).NotTo(HaveKey("facebook_page_id"))
+					Expect(response).NotTo(HaveKey("default_group_post_message_template"))
+					Expect(response).NotTo(HaveKey("email"))
+				})
+			})
+		})
+	})
+
+	Describe("GET /regions/:name/restaurants", func() {
+		var (
+			params  httprouter.Params
+			handler router.HandlerWithParams
+		)
+
+		BeforeEach(func() {
+			params = httprouter.Params{httprouter.Param{
+				Key:   "name",
+				Value: "Tartu",
+			}}
+		})
+
+		JustBeforeEach(func() {
+			handler = RegionRestaurants(restaurantsCollection, regionsCollection)
+		})
+
+		Context("with the DB request failing", func() {
+			BeforeEach(func() {
+				restaurantsCollection.On("GetByRegion", "Tartu").Return(nil, errors.New("something went wrong"))
+			})
+
+			It("fails with StatusInternalServerError", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
+		Context("with restaurants in the region", func() {
+			BeforeEach(func() {
+				restaurantsCollection.On("GetByRegion", "Tartu").Return([]*model.Restaurant{restaurant}, nil)
+			})
+
+			It("succeeds", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+			})
+
+			It("lists the restaurants without their private fields", func() {
+				handler(responseRecorder, request, params)
+				var response []map[string]interface{}
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response).To(HaveLen(1))
+				Expect(response[0]["name"]).To(Equal("Asian Chef"))
+				Expect(response[0]).NotTo(HaveKey("facebook_page_id"))
+				Expect(response[0]).NotTo(HaveKey("default_group_post_message_template"))
+			})
+		})
+	})
+})
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/session"
+)
+
+// publishJobsLimit limits the number of publishing jobs listed
+const publishJobsLimit = 31
+
+// RestaurantPublishJobs returns a handler that lists the jobs of publishing the restaurant's offers on
+// Facebook, latest date first. The jobs' statuses tell whether the posts are still being published or
+// whether publishing them has failed.
+func RestaurantPublishJobs(sessionManager session.Manager, users db.Users, memberships db.Memberships, apiKeys db.APIKeys,
+	restaurants db.Restaurants, publishJobs db.PublishJobs) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+		jobs, err := publishJobs.GetForRestaurant(restaurant.ID, publishJobsLimit)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to find the restaurant's publishing jobs", http.StatusInternalServerError)
+		}
+		if jobs == nil {
+			jobs = []*model.PublishJob{}
+		}
+		return writeJSON(w, jobs)
+	}
+	return forRestaurant(sessionManager, users, memberships, apiKeys, restaurants, model.RoleViewer, handler)
+}
@@ -0,0 +1,84 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PublishJobsHandler", func() {
+	var (
+		sessionManager  *mocks.Manager
+		usersCollection *mocks.Users
+		memberships     *mocks.Memberships
+		apiKeys         *mocks.APIKeys
+		restaurants     *mocks.Restaurants
+		publishJobs     *mocks.PublishJobs
+		restaurant      *model.Restaurant
+		params          httprouter.Params
+		handler         router.HandlerWithParams
+	)
+
+	BeforeEach(func() {
+		sessionManager = new(mocks.Manager)
+		usersCollection = new(mocks.Users)
+		memberships = new(mocks.Memberships)
+		apiKeys = new(mocks.APIKeys)
+		restaurants = new(mocks.Restaurants)
+		publishJobs = new(mocks.PublishJobs)
+		restaurant = &model.Restaurant{ID: bson.NewObjectId()}
+		user := &model.User{
+			ID: bson.NewObjectId(),
+		}
+		memberships.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+			Role: model.RoleOwner,
+		}, nil)
+		restaurants.On("GetID", restaurant.ID).Return(restaurant, nil)
+		sessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+		usersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+		params = httprouter.Params{httprouter.Param{
+			Key:   "restaurantID",
+			Value: restaurant.ID.Hex(),
+		}}
+		requestMethod = "GET"
+		requestQuery = url.Values{}
+	})
+
+	JustBeforeEach(func() {
+		handler = RestaurantPublishJobs(sessionManager, usersCollection, memberships, apiKeys, restaurants, publishJobs)
+	})
+
+	Describe("GET /restaurants/:restaurantID/publish_jobs", func() {
+		It("should list the jobs with their statuses", func() {
+			publishJobs.On("GetForRestaurant", restaurant.ID, mock.AnythingOfType("int")).Return([]*model.PublishJob{
+				{Date: "2016-03-05", Status: model.PublishJobPending},
+				{Date: "2016-03-04", Status: model.PublishJobFailed, LastError: "Failed to post the offers to Facebook"},
+			}, nil)
+			err := handler(responseRecorder, request, params)
+			Expect(err).To(BeNil())
+			var result []map[string]interface{}
+			json.Unmarshal(responseRecorder.Body.Bytes(), &result)
+			Expect(result).To(HaveLen(2))
+			Expect(result[0]["status"]).To(Equal("pending"))
+			Expect(result[1]["status"]).To(Equal("failed"))
+			Expect(result[1]["last_error"]).To(Equal("Failed to post the offers to Facebook"))
+		})
+
+		It("should respond with an empty list if there are no jobs", func() {
+			publishJobs.On("GetForRestaurant", restaurant.ID, mock.AnythingOfType("int")).Return(nil, nil)
+			err := handler(responseRecorder, request, params)
+			Expect(err).To(BeNil())
+			Expect(responseRecorder.Body.String()).To(MatchJSON("[]"))
+		})
+	})
+})
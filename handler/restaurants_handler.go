@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"net/url"
 	"strings"
@@ -10,8 +11,11 @@ import (
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 
+	"github.com/Lunchr/luncher-api/audit"
 	"github.com/Lunchr/luncher-api/db"
 	"github.com/Lunchr/luncher-api/db/model"
+	luncherFacebook "github.com/Lunchr/luncher-api/facebook"
+	"github.com/Lunchr/luncher-api/geo"
 	"github.com/Lunchr/luncher-api/router"
 	"github.com/Lunchr/luncher-api/session"
 	"github.com/Lunchr/luncher-api/storage"
@@ -19,10 +23,31 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
+const defaultGroupPostMessageTemplate = "Tänased päevapakkumised on:"
+
+// maxConfirmedLocationDistance is how far (in meters) a location the client has confirmed for an address may
+// be from the geocoder's candidates for the address
+const maxConfirmedLocationDistance = 5000
+
 // UserRestaurants returns a list of restaurants the user has access to
-func UserRestaurants(restaurants db.Restaurants, sessionManager session.Manager, users db.Users) router.Handler {
+func UserRestaurants(restaurants db.Restaurants, sessionManager session.Manager, users db.Users,
+	memberships db.Memberships) router.Handler {
 	handlerWithUser := func(w http.ResponseWriter, r *http.Request, user *model.User) *router.HandlerError {
-		restaurantsByIDs, err := restaurants.GetByIDs(user.RestaurantIDs)
+		userMemberships, err := memberships.GetForUser(user.ID)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to find the memberships of this user", http.StatusInternalServerError)
+		}
+		restaurantIDs := make([]bson.ObjectId, len(userMemberships))
+		for i, membership := range userMemberships {
+			restaurantIDs[i] = membership.RestaurantID
+		}
+		// The restaurants linked to the user directly until `lunchman migrate memberships` has been run
+		for _, restaurantID := range user.RestaurantIDs {
+			if !idsInclude(restaurantIDs, restaurantID) {
+				restaurantIDs = append(restaurantIDs, restaurantID)
+			}
+		}
+		restaurantsByIDs, err := restaurants.GetByIDs(restaurantIDs)
 		if err != nil {
 			return router.NewHandlerError(err, "Failed to find restaurants associated with this user", http.StatusInternalServerError)
 		}
@@ -35,64 +60,266 @@ func UserRestaurants(restaurants db.Restaurants, sessionManager session.Manager,
 		if err != nil {
 			return router.NewHandlerError(err, "Failed to find restaurants for FB pages associated with this user", http.StatusInternalServerError)
 		}
-		allRestaurants := append(restaurantsByIDs, restaurantsByFBPageIDs...)
-		return writeJSON(w, allRestaurants)
+		allRestaurants := restaurantsByIDs
+		for _, restaurant := range restaurantsByFBPageIDs {
+			if !idsInclude(restaurantIDs, restaurant.ID) {
+				allRestaurants = append(allRestaurants, restaurant)
+			}
+		}
+		response := make([]UserRestaurant, len(allRestaurants))
+		for i, restaurant := range allRestaurants {
+			response[i] = UserRestaurant{
+				Restaurant:          restaurant,
+				FacebookTokenHealth: getFacebookTokenHealth(user, restaurant),
+			}
+		}
+		return writeJSON(w, response)
 	}
 	return checkLogin(sessionManager, users, handlerWithUser)
 }
 
+// UserRestaurant defines the response format for the UserRestaurants() handler
+type UserRestaurant struct {
+	*model.Restaurant
+	FacebookTokenHealth *FacebookTokenHealth `json:"facebook_token_health,omitempty"`
+}
+
+// FacebookTokenHealth tells the client whether the restaurant's offers can be posted to its
+// Facebook page on behalf of the user or whether the user should log in through Facebook again
+type FacebookTokenHealth struct {
+	// Connected is false if the user has no access token for the restaurant's page
+	Connected   bool       `json:"connected"`
+	NeedsReauth bool       `json:"needs_reauth"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+}
+
+func getFacebookTokenHealth(user *model.User, restaurant *model.Restaurant) *FacebookTokenHealth {
+	if restaurant.FacebookPageID == "" {
+		return nil
+	}
+	if !hasPageAccessTokenForRestaurant(user, restaurant) {
+		return &FacebookTokenHealth{}
+	}
+	health := user.Session.FacebookTokenHealth
+	response := &FacebookTokenHealth{
+		Connected:   true,
+		NeedsReauth: health.PageNeedsReauth(restaurant.FacebookPageID),
+	}
+	if !health.ExpiresAt.IsZero() {
+		response.ExpiresAt = &health.ExpiresAt
+	}
+	if !health.CheckedAt.IsZero() {
+		response.CheckedAt = &health.CheckedAt
+	}
+	return response
+}
+
 // Restaurant returns a router.Handler that returns the restaurant information for the specified restaurant
-func Restaurant(c db.Restaurants, sessionManager session.Manager, users db.Users) router.HandlerWithParams {
+func Restaurant(c db.Restaurants, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys) router.HandlerWithParams {
 	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
 		return writeJSON(w, restaurant)
 	}
-	return forRestaurant(sessionManager, users, c, handler)
+	return forRestaurant(sessionManager, users, memberships, apiKeys, c, model.RoleViewer, handler)
 }
 
-// PostRestaurants returns an handler for creating a restaurant
-func PostRestaurants(c db.Restaurants, sessionManager session.Manager, users db.Users, fbAuth facebook.Authenticator) router.Handler {
-	handler := func(w http.ResponseWriter, r *http.Request, user *model.User) *router.HandlerError {
-		restaurant, err := parseRestaurant(r)
+// PutRestaurant handles PUT requests to /restaurants/:restaurantID. It updates the restaurant's
+// contact details and the default message template, re-geocodes the restaurant if its address has
+// changed and updates the copies of the restaurant's information in all of its offers.
+func PutRestaurant(c db.Restaurants, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys, offers db.Offers, regions db.Regions, geocoder geo.Coder, auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+		update, err := parseRestaurantUpdate(r)
 		if err != nil {
 			return router.NewHandlerError(err, "Failed to parse the restaurant", http.StatusBadRequest)
-		} else if restaurant.FacebookPageID == "" {
-			return router.NewHandlerError(err, "Registering without an assciated FB page is currently disabled", http.StatusBadRequest)
+		} else if update.Name == "" {
+			return router.NewSimpleHandlerError("The restaurant's name must be specified", http.StatusBadRequest)
+		} else if update.Address == "" {
+			return router.NewSimpleHandlerError("The restaurant's address must be specified", http.StatusBadRequest)
+		} else if handlerErr := checkMessageTemplate(update.DefaultGroupPostMessageTemplate); handlerErr != nil {
+			return handlerErr
 		}
+		if update.Address != restaurant.Address || update.ConfirmedLocation != nil {
+			location, handlerErr := locateRestaurant(w, update.Address, restaurant.Region, update.ConfirmedLocation,
+				regions, geocoder)
+			if handlerErr != nil {
+				return handlerErr
+			} else if location == nil {
+				return nil
+			}
+			restaurant.Location = model.NewPoint(*location)
+		}
+		restaurant.Name = update.Name
+		restaurant.Address = update.Address
+		restaurant.Phone = update.Phone
+		restaurant.Website = update.Website
+		restaurant.Email = update.Email
+		restaurant.DefaultGroupPostMessageTemplate = update.DefaultGroupPostMessageTemplate
+		if err = c.UpdateDetails(restaurant.ID, restaurant); err != nil {
+			return router.NewHandlerError(err, "Failed to update the restaurant in the DB", http.StatusInternalServerError)
+		}
+		if err = offers.UpdateRestaurant(model.MapRestaurantToOfferRestaurant(restaurant)); err != nil {
+			return router.NewHandlerError(err, "Failed to update the restaurant's offers in the DB", http.StatusInternalServerError)
+		}
+		return writeJSON(w, restaurant)
+	}
+	return forRestaurant(sessionManager, users, memberships, apiKeys, c, model.RoleOwner,
+		auditedForRestaurant(auditLog, "restaurant.update", handler))
+}
+
+// DeactivateRestaurant handles POST requests to /restaurants/:restaurantID/deactivate. It hides the
+// restaurant and all of its offers from the public endpoints.
+func DeactivateRestaurant(c db.Restaurants, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys, offers db.Offers, auditLog audit.Log) router.HandlerWithParams {
+	return setRestaurantDeactivated(c, sessionManager, users, memberships, apiKeys, offers, auditLog, true)
+}
+
+// ReactivateRestaurant handles POST requests to /restaurants/:restaurantID/reactivate. It reverses the
+// effects of DeactivateRestaurant.
+func ReactivateRestaurant(c db.Restaurants, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys, offers db.Offers, auditLog audit.Log) router.HandlerWithParams {
+	return setRestaurantDeactivated(c, sessionManager, users, memberships, apiKeys, offers, auditLog, false)
+}
+
+func setRestaurantDeactivated(c db.Restaurants, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys, offers db.Offers, auditLog audit.Log, deactivated bool) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+		if err := c.SetDeactivated(restaurant.ID, deactivated); err != nil {
+			return router.NewHandlerError(err, "Failed to update the restaurant in the DB", http.StatusInternalServerError)
+		}
+		restaurant.Deactivated = deactivated
+		if err := offers.UpdateRestaurant(model.MapRestaurantToOfferRestaurant(restaurant)); err != nil {
+			return router.NewHandlerError(err, "Failed to update the restaurant's offers in the DB", http.StatusInternalServerError)
+		}
+		return writeJSON(w, restaurant)
+	}
+	action := "restaurant.reactivate"
+	if deactivated {
+		action = "restaurant.deactivate"
+	}
+	return forRestaurant(sessionManager, users, memberships, apiKeys, c, model.RoleOwner,
+		auditedForRestaurant(auditLog, action, handler))
+}
+
+// DeleteRestaurant handles DELETE requests to /restaurants/:restaurantID. It removes the restaurant along
+// with its offers, group posts, invites, publish jobs and API keys and all references to the restaurant from
+// the users. If the request includes a 'delete_facebook_posts' query parameter set to 'true', the group posts
+// are also deleted from Facebook.
+//
+// The restaurant is deactivated first, so that diners wouldn't see it half-deleted. Its users keep access to
+// it until it's gone, so if any of the steps fail, the request can be retried, as all of the steps are
+// idempotent.
+func DeleteRestaurant(c db.Restaurants, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys, offers db.Offers, groupPosts db.OfferGroupPosts, invites db.Invites, publishJobs db.PublishJobs,
+	facebookPost luncherFacebook.Post, auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
+		if err := c.SetDeactivated(restaurant.ID, true); err != nil {
+			return router.NewHandlerError(err, "Failed to deactivate the restaurant", http.StatusInternalServerError)
+		}
+		// Before the posts are deleted from Facebook, so that the jobs couldn't publish them again
+		if err := publishJobs.RemoveForRestaurant(restaurant.ID); err != nil {
+			return router.NewHandlerError(err, "Failed to delete the restaurant's publish jobs from the DB", http.StatusInternalServerError)
+		}
+		if r.FormValue("delete_facebook_posts") == "true" {
+			posts, err := groupPosts.GetByRestaurantID(restaurant.ID)
+			if err != nil {
+				return router.NewHandlerError(err, "Failed to find the restaurant's group posts", http.StatusInternalServerError)
+			}
+			for _, post := range posts {
+				if handlerErr := facebookPost.Delete(post, user, restaurant); handlerErr != nil {
+					return handlerErr
+				}
+			}
+		}
+		if err := offers.RemoveForRestaurant(restaurant.ID); err != nil {
+			return router.NewHandlerError(err, "Failed to delete the restaurant's offers from the DB", http.StatusInternalServerError)
+		}
+		if err := groupPosts.RemoveByRestaurantID(restaurant.ID); err != nil {
+			return router.NewHandlerError(err, "Failed to delete the restaurant's group posts from the DB", http.StatusInternalServerError)
+		}
+		if err := invites.RemoveForRestaurant(restaurant.ID); err != nil {
+			return router.NewHandlerError(err, "Failed to delete the restaurant's invites from the DB", http.StatusInternalServerError)
+		}
+		if err := apiKeys.RemoveForRestaurant(restaurant.ID); err != nil {
+			return router.NewHandlerError(err, "Failed to remove the restaurant's API keys from the DB", http.StatusInternalServerError)
+		}
+		if err := c.RemoveID(restaurant.ID); err != nil {
+			return router.NewHandlerError(err, "Failed to delete the restaurant from the DB", http.StatusInternalServerError)
+		}
+		// The references to a removed restaurant are ignored, so failing to remove them doesn't leave
+		// anything usable behind
+		if err := users.RemoveRestaurant(restaurant.ID, restaurant.FacebookPageID); err != nil {
+			return router.NewHandlerError(err, "Failed to remove the restaurant from the users in the DB", http.StatusInternalServerError)
+		}
+		if err := memberships.RemoveForRestaurant(restaurant.ID); err != nil {
+			return router.NewHandlerError(err, "Failed to remove the restaurant's memberships from the DB", http.StatusInternalServerError)
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return forRestaurant(sessionManager, users, memberships, apiKeys, c, model.RoleOwner,
+		auditedForRestaurant(auditLog, "restaurant.delete", handler))
+}
+
+// PostRestaurants returns an handler for creating a restaurant. The restaurant's address gets geocoded
+// unless the client has specified a confirmed location for the restaurant. If the address can't be
+// geocoded unambiguously, the client will be asked to confirm the location with a StatusConflict response.
+func PostRestaurants(c db.Restaurants, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	fbAuth facebook.Authenticator, regions db.Regions, geocoder geo.Coder, auditLog audit.Log) router.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User) (bson.ObjectId, *router.HandlerError) {
+		restaurantPOST, err := parseRestaurant(r)
+		if err != nil {
+			return "", router.NewHandlerError(err, "Failed to parse the restaurant", http.StatusBadRequest)
+		} else if restaurantPOST.FacebookPageID == "" && user.FacebookUserID != "" {
+			// Only the users who've registered with an email address can manage restaurants without a FB page
+			return "", router.NewSimpleHandlerError("Registering without an associated FB page is currently disabled", http.StatusBadRequest)
+		} else if handlerErr := checkMessageTemplate(restaurantPOST.DefaultGroupPostMessageTemplate); handlerErr != nil {
+			return "", handlerErr
+		}
+		restaurant := &restaurantPOST.Restaurant
+		location, handlerErr := locateRestaurant(w, restaurant.Address, restaurant.Region, restaurantPOST.ConfirmedLocation,
+			regions, geocoder)
+		if handlerErr != nil {
+			return "", handlerErr
+		} else if location == nil {
+			return "", nil
+		}
+		restaurant.Location = model.NewPoint(*location)
 		insertedRestaurants, err := c.Insert(restaurant)
 		if err != nil {
-			return router.NewHandlerError(err, "Failed to store the restaurant in the DB", http.StatusInternalServerError)
+			return "", router.NewHandlerError(err, "Failed to store the restaurant in the DB", http.StatusInternalServerError)
 		}
 		var insertedRestaurant = insertedRestaurants[0]
 		// We want to leave the FB page related restaurant role management wholly to FB, so we handle them totally
 		// separately
 		if insertedRestaurant.FacebookPageID == "" {
-			user.RestaurantIDs = append(user.RestaurantIDs, insertedRestaurant.ID)
-			err = users.Update(user.FacebookUserID, user)
+			err = memberships.Set(user.ID, insertedRestaurant.ID, model.RoleOwner)
 			if err != nil {
 				// TODO: revert the restaurant insertion we just did? Look into mgo's txn package
-				return router.NewHandlerError(err, "Failed to attach the restaurant to the user in the DB", http.StatusInternalServerError)
+				return insertedRestaurant.ID, router.NewHandlerError(err, "Failed to make the user the restaurant's owner in the DB", http.StatusInternalServerError)
 			}
 		} else {
 			pageAccessToken, handlerErr := getPageAccessToken(&user.Session.FacebookUserToken, insertedRestaurant.FacebookPageID, fbAuth)
 			if handlerErr != nil {
-				return handlerErr
+				return insertedRestaurant.ID, handlerErr
 			}
 			user.Session.FacebookPageTokens = append(user.Session.FacebookPageTokens, pageAccessToken)
-			err = users.Update(user.FacebookUserID, user)
+			err = users.UpdateID(user.ID, user)
 			if err != nil {
-				return router.NewHandlerError(err, "Failed to store the access token for this restaurant's page in the DB", http.StatusInternalServerError)
+				return insertedRestaurant.ID, router.NewHandlerError(err, "Failed to store the access token for this restaurant's page in the DB", http.StatusInternalServerError)
 			}
 		}
-		return writeJSON(w, insertedRestaurant)
+		return insertedRestaurant.ID, writeJSON(w, insertedRestaurant)
 	}
-	return checkLogin(sessionManager, users, handler)
+	return checkLogin(sessionManager, users, auditedWithRestaurantID(auditLog, "restaurant.create", handler))
 }
 
 // RestaurantOffers returns all upcoming offers for the restaurant linked to the currently
 // logged in user unless the request includes a 'title' query parameter, in which the offer
 // with the specified title will be fetched instead.
-func RestaurantOffers(restaurants db.Restaurants, sessionManager session.Manager, users db.Users, offers db.Offers,
-	imageStorage storage.Images, regions db.Regions) router.HandlerWithParams {
+func RestaurantOffers(restaurants db.Restaurants, sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys, offers db.Offers, imageStorage storage.Images, regions db.Regions) router.HandlerWithParams {
 	getTodaysOffersForRestaurant := func(w http.ResponseWriter, restaurant *model.Restaurant) *router.HandlerError {
 		region, err := regions.GetName(restaurant.Region)
 		if err != nil {
@@ -139,13 +366,13 @@ func RestaurantOffers(restaurants db.Restaurants, sessionManager session.Manager
 		}
 		return getTodaysOffersForRestaurant(w, restaurant)
 	}
-	return forRestaurant(sessionManager, users, restaurants, handler)
+	return forRestaurant(sessionManager, users, memberships, apiKeys, restaurants, model.RoleViewer, handler)
 }
 
 // RestaurantOfferSuggestions handles GET requests to /restaurants/:id/offer_suggestions and expects a 'title' query
 // parameter. It returns a list of previously used offer titles matching the one provided.
 func RestaurantOfferSuggestions(restaurants db.Restaurants, sessionManager session.Manager, users db.Users,
-	offers db.Offers) router.HandlerWithParams {
+	memberships db.Memberships, apiKeys db.APIKeys, offers db.Offers) router.HandlerWithParams {
 	handler := func(w http.ResponseWriter, r *http.Request, user *model.User, restaurant *model.Restaurant) *router.HandlerError {
 		escapedPartialTitle := r.FormValue("title")
 		partialTitle, err := url.QueryUnescape(escapedPartialTitle)
@@ -161,40 +388,70 @@ func RestaurantOfferSuggestions(restaurants db.Restaurants, sessionManager sessi
 		}
 		return writeJSON(w, matchingTitles)
 	}
-	return forRestaurant(sessionManager, users, restaurants, handler)
+	return forRestaurant(sessionManager, users, memberships, apiKeys, restaurants, model.RoleEditor, handler)
 }
 
 type HandlerWithRestaurant func(w http.ResponseWriter, r *http.Request, user *model.User,
 	restaurant *model.Restaurant) *router.HandlerError
 
-func forRestaurant(sessionManager session.Manager, users db.Users, restaurants db.Restaurants, handler HandlerWithRestaurant) router.HandlerWithParams {
-	handlerWithUser := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User) *router.HandlerError {
-		restaurant, handlerErr := getRestaurantByParams(ps, user, restaurants)
-		if handlerErr != nil {
-			return handlerErr
-		}
+// forRestaurant makes sure that the user is logged in and has at least the specified role for the restaurant
+// specified by the restaurantID param, before calling the handler. Instead of logging in, the request can
+// include an API key of the restaurant that allows the role in the Authorization header, in which case the
+// handler is called with the user who created the key.
+func forRestaurant(sessionManager session.Manager, users db.Users, memberships db.Memberships, apiKeys db.APIKeys,
+	restaurants db.Restaurants, role model.Role, handler HandlerWithRestaurant) router.HandlerWithParams {
+	handlerWithParams := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User,
+		restaurant *model.Restaurant) *router.HandlerError {
 		return handler(w, r, user, restaurant)
 	}
-	return checkLoginWithParams(sessionManager, users, handlerWithUser)
+	return forRestaurantWithParams(sessionManager, users, memberships, apiKeys, restaurants, role, handlerWithParams)
 }
 
 type HandlerWithParamsWithRestaurant func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User,
 	restaurant *model.Restaurant) *router.HandlerError
 
-func forRestaurantWithParams(sessionManager session.Manager, users db.Users, restaurants db.Restaurants,
-	handler HandlerWithParamsWithRestaurant) router.HandlerWithParams {
-	handlerWithUser := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User) *router.HandlerError {
-		restaurant, handlerErr := getRestaurantByParams(ps, user, restaurants)
+func forRestaurantWithParams(sessionManager session.Manager, users db.Users, memberships db.Memberships,
+	apiKeys db.APIKeys, restaurants db.Restaurants, role model.Role, handler HandlerWithParamsWithRestaurant) router.HandlerWithParams {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) *router.HandlerError {
+		user, userSession, apiKey, handlerErr := getUserForSessionOrAPIKey(sessionManager, users, apiKeys, r)
 		if handlerErr != nil {
 			return handlerErr
 		}
+		restaurant, handlerErr := getRestaurantByParams(ps, user, memberships, restaurants, role)
+		if handlerErr != nil {
+			return handlerErr
+		}
+		if apiKey != nil {
+			if apiKey.RestaurantID != restaurant.ID {
+				return router.NewSimpleHandlerError("The API key is not valid for this restaurant", http.StatusForbidden)
+			} else if !apiKey.Role.Includes(role) {
+				return router.NewSimpleHandlerError("The API key's role doesn't allow this", http.StatusForbidden)
+			}
+		} else {
+			r = withSession(r, userSession)
+		}
 		return handler(w, r, ps, user, restaurant)
 	}
-	return checkLoginWithParams(sessionManager, users, handlerWithUser)
 }
 
-func getRestaurantByParams(ps httprouter.Params, user *model.User, restaurants db.Restaurants) (*model.Restaurant, *router.HandlerError) {
-	restaurantIDString := ps.ByName("restaurantID")
+func getRestaurantByParams(ps httprouter.Params, user *model.User, memberships db.Memberships, restaurants db.Restaurants,
+	requiredRole model.Role) (*model.Restaurant, *router.HandlerError) {
+	restaurant, handlerErr := getRestaurantByID(ps.ByName("restaurantID"), restaurants)
+	if handlerErr != nil {
+		return nil, handlerErr
+	}
+	role, err := roleForRestaurant(user, restaurant, memberships)
+	if err != nil {
+		return nil, router.NewHandlerError(err, "Failed to check the user's role for this restaurant", http.StatusInternalServerError)
+	} else if role == "" {
+		return nil, router.NewSimpleHandlerError("Not authorized to access this restaurant", http.StatusForbidden)
+	} else if !role.Includes(requiredRole) {
+		return nil, router.NewSimpleHandlerError("Your role for this restaurant doesn't allow this", http.StatusForbidden)
+	}
+	return restaurant, nil
+}
+
+func getRestaurantByID(restaurantIDString string, restaurants db.Restaurants) (*model.Restaurant, *router.HandlerError) {
 	if restaurantIDString == "" {
 		return nil, router.NewSimpleHandlerError("Expected a restaurant ID to be specified", http.StatusBadRequest)
 	} else if !bson.IsObjectIdHex(restaurantIDString) {
@@ -207,16 +464,28 @@ func getRestaurantByParams(ps httprouter.Params, user *model.User, restaurants d
 	} else if err != nil {
 		return nil, router.NewHandlerError(err, "Something went wrong while trying to find the specified restaurant", http.StatusInternalServerError)
 	}
-	if !authorizedToManageRestaurant(user, restaurant) {
-		return nil, router.NewSimpleHandlerError("Not authorized to access this restaurant", http.StatusForbidden)
-	}
 	return restaurant, nil
 }
 
-func authorizedToManageRestaurant(user *model.User, restaurant *model.Restaurant) bool {
-	hasDirectAccess := idsInclude(user.RestaurantIDs, restaurant.ID)
-	authorizedThroughFB := hasPageAccessTokenForRestaurant(user, restaurant)
-	return hasDirectAccess || authorizedThroughFB
+// roleForRestaurant returns the user's role for the restaurant or an empty role if the user has no access to
+// the restaurant. The admins of the restaurant's FB page are always owners, because we want to leave the FB
+// page related role management wholly to FB. All other roles come from the memberships.
+func roleForRestaurant(user *model.User, restaurant *model.Restaurant, memberships db.Memberships) (model.Role, error) {
+	if hasPageAccessTokenForRestaurant(user, restaurant) {
+		return model.RoleOwner, nil
+	}
+	// The users linked to the restaurant directly keep owning it until `lunchman migrate memberships`
+	// has turned the links into memberships
+	if idsInclude(user.RestaurantIDs, restaurant.ID) {
+		return model.RoleOwner, nil
+	}
+	membership, err := memberships.Get(user.ID, restaurant.ID)
+	if err == mgo.ErrNotFound {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return membership.Role, nil
 }
 
 func idsInclude(ids []bson.ObjectId, id bson.ObjectId) bool {
@@ -237,16 +506,94 @@ func hasPageAccessTokenForRestaurant(user *model.User, restaurant *model.Restaur
 	return false
 }
 
-func parseRestaurant(r *http.Request) (*model.Restaurant, error) {
-	var restaurant model.Restaurant
+func parseRestaurantUpdate(r *http.Request) (*model.RestaurantPOST, error) {
+	var restaurant model.RestaurantPOST
+	err := json.NewDecoder(r.Body).Decode(&restaurant)
+	if err != nil {
+		return nil, err
+	}
+	restaurant.Name = strings.TrimSpace(restaurant.Name)
+	restaurant.Address = strings.TrimSpace(restaurant.Address)
+	if restaurant.DefaultGroupPostMessageTemplate == "" {
+		restaurant.DefaultGroupPostMessageTemplate = defaultGroupPostMessageTemplate
+	}
+	return &restaurant, nil
+}
+
+// locateRestaurant geocodes the address using the region's ccTLD. If the geocoder fails to find an unambiguous
+// match for the address, the client is asked to confirm the location and a nil location is returned. A confirmed
+// location is used instead of the geocoded one, as long as it's near one of the candidates for the address, or
+// if the geocoder fails.
+func locateRestaurant(w http.ResponseWriter, address, regionName string, confirmedLocation *geo.Location, regions db.Regions,
+	geocoder geo.Coder) (*geo.Location, *router.HandlerError) {
+	if confirmedLocation != nil && !confirmedLocation.IsValid() {
+		return nil, router.NewSimpleHandlerError("The confirmed location is not a valid coordinate", http.StatusBadRequest)
+	}
+	region, err := regions.GetName(regionName)
+	if err != nil {
+		return nil, router.NewHandlerError(err, "Failed to find the restaurant's region", http.StatusInternalServerError)
+	}
+	candidates, err := geocoder.CodeForRegion(address, region.CCTLD)
+	if confirmedLocation != nil {
+		if err != nil {
+			// The confirmation exists for correcting the geocoder, so it's trusted when there are no candidates
+			// to check it against, e.g. because the geocoder is unavailable
+			if err != geo.ErrorNoResults {
+				log.Printf("Accepting the confirmed location of %q without checking it, as geocoding failed: %v", address, err)
+			}
+			return confirmedLocation, nil
+		} else if !isNearAnyCandidate(*confirmedLocation, candidates) {
+			return nil, router.NewSimpleHandlerError("The confirmed location is too far from the address", http.StatusBadRequest)
+		}
+		return confirmedLocation, nil
+	}
+	if err == geo.ErrorNoResults {
+		return nil, requestLocationConfirmation(w, "no_results", address, []geo.Candidate{})
+	} else if err != nil {
+		return nil, router.NewHandlerError(err, "Failed to find the location of the specified address", http.StatusBadGateway)
+	}
+	if location, ok := geo.Unambiguous(candidates); ok {
+		return &location, nil
+	} else if len(candidates) > 1 {
+		return nil, requestLocationConfirmation(w, "multiple_results", address, candidates)
+	}
+	return nil, requestLocationConfirmation(w, "inexact_match", address, candidates)
+}
+
+// isNearAnyCandidate checks that the location is within maxConfirmedLocationDistance of one of the candidates.
+// If the geocoder couldn't find the address at all, the client is trusted to know the location.
+func isNearAnyCandidate(location geo.Location, candidates []geo.Candidate) bool {
+	if len(candidates) == 0 {
+		return true
+	}
+	for _, candidate := range candidates {
+		if location.DistanceTo(candidate.Location) <= maxConfirmedLocationDistance {
+			return true
+		}
+	}
+	return false
+}
+
+func requestLocationConfirmation(w http.ResponseWriter, reason, address string, candidates []geo.Candidate) *router.HandlerError {
+	return writeJSONWithCode(w, &model.LocationConfirmationRequest{
+		Reason:     reason,
+		Address:    address,
+		Candidates: candidates,
+	}, http.StatusConflict)
+}
+
+func parseRestaurant(r *http.Request) (*model.RestaurantPOST, error) {
+	var restaurant model.RestaurantPOST
 	err := json.NewDecoder(r.Body).Decode(&restaurant)
 	if err != nil {
 		return nil, err
 	}
 	// Add default values for configurable fields
 	if restaurant.DefaultGroupPostMessageTemplate == "" {
-		restaurant.DefaultGroupPostMessageTemplate = "Tänased päevapakkumised on:"
+		restaurant.DefaultGroupPostMessageTemplate = defaultGroupPostMessageTemplate
 	}
+	// The collage settings are validated and stored through their own endpoint
+	restaurant.CollageSettings = nil
 	// XXX please look away, this is a hack
 	if strings.Contains(strings.ToLower(restaurant.Address), "tartu") {
 		restaurant.Region = "Tartu"
@@ -14,6 +14,7 @@ import (
 
 	"github.com/Lunchr/luncher-api/db"
 	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/geo"
 	. "github.com/Lunchr/luncher-api/handler"
 	"github.com/Lunchr/luncher-api/handler/mocks"
 	"github.com/Lunchr/luncher-api/router"
@@ -26,6 +27,16 @@ import (
 )
 
 var _ = Describe("RestaurantsHandlers", func() {
+	var (
+		membershipsCollection *mocks.Memberships
+		apiKeysCollection     *mocks.APIKeys
+	)
+
+	BeforeEach(func() {
+		membershipsCollection = new(mocks.Memberships)
+		apiKeysCollection = new(mocks.APIKeys)
+	})
+
 	Describe("GET /user/restaurants", func() {
 		var (
 			sessionManager session.Manager
@@ -36,7 +47,7 @@ var _ = Describe("RestaurantsHandlers", func() {
 		)
 
 		JustBeforeEach(func() {
-			handler = UserRestaurants(restaurants, sessionManager, users)
+			handler = UserRestaurants(restaurants, sessionManager, users, membershipsCollection)
 		})
 
 		ExpectUserToBeLoggedIn(func() *router.HandlerError {
@@ -50,6 +61,8 @@ var _ = Describe("RestaurantsHandlers", func() {
 			var (
 				mockSessionManager *mocks.Manager
 				mockUsers          *mocks.Users
+				user               *model.User
+				allRestaurants     []*model.Restaurant
 			)
 
 			BeforeEach(func() {
@@ -59,7 +72,7 @@ var _ = Describe("RestaurantsHandlers", func() {
 				users = mockUsers
 				restaurants = new(mocks.Restaurants)
 
-				allRestaurants := []*model.Restaurant{&model.Restaurant{
+				allRestaurants = []*model.Restaurant{&model.Restaurant{
 					ID: bson.NewObjectId(),
 				}, &model.Restaurant{
 					FacebookPageID: "fbpageid1",
@@ -71,8 +84,8 @@ var _ = Describe("RestaurantsHandlers", func() {
 				fbUserToken := oauth2.Token{
 					AccessToken: "usertoken",
 				}
-				user := &model.User{
-					RestaurantIDs: []bson.ObjectId{allRestaurants[0].ID, allRestaurants[2].ID},
+				user = &model.User{
+					ID: bson.NewObjectId(),
 					Session: model.UserSession{
 						FacebookUserToken: fbUserToken,
 						FacebookPageTokens: []model.FacebookPageToken{model.FacebookPageToken{
@@ -80,13 +93,21 @@ var _ = Describe("RestaurantsHandlers", func() {
 						}, model.FacebookPageToken{
 							PageID: "fbpageid2",
 						}},
+						FacebookTokenHealth: model.FacebookTokenHealth{
+							CheckedAt:      time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC),
+							InvalidPageIDs: []string{"fbpageid2"},
+						},
 					},
 				}
 
-				mockSessionManager.On("Get", request).Return("session", nil)
-				mockUsers.On("GetSessionID", "session").Return(user, nil)
-				restaurants.On("GetByIDs", user.RestaurantIDs).Return([]*model.Restaurant{allRestaurants[0], allRestaurants[2]}, nil)
+				mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+				mockUsers.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+				restaurants.On("GetByIDs", []bson.ObjectId{allRestaurants[0].ID, allRestaurants[2].ID}).Return([]*model.Restaurant{allRestaurants[0], allRestaurants[2]}, nil)
 				restaurants.On("GetByFacebookPageIDs", []string{"fbpageid1", "fbpageid2"}).Return([]*model.Restaurant{allRestaurants[1], allRestaurants[3]}, nil)
+				membershipsCollection.On("GetForUser", user.ID).Return([]*model.Membership{
+					{UserID: user.ID, RestaurantID: allRestaurants[0].ID, Role: model.RoleOwner},
+					{UserID: user.ID, RestaurantID: allRestaurants[2].ID, Role: model.RoleEditor},
+				}, nil)
 			})
 
 			It("succeeds", func() {
@@ -107,6 +128,37 @@ var _ = Describe("RestaurantsHandlers", func() {
 				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
 				Expect(response).To(HaveLen(4))
 			})
+
+			It("includes the Facebook token health for the restaurants with a page", func() {
+				handler(responseRecorder, request)
+				var response []*UserRestaurant
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response[0].FacebookTokenHealth).To(BeNil())
+				Expect(response[2].FacebookTokenHealth.Connected).To(BeTrue())
+				Expect(response[2].FacebookTokenHealth.NeedsReauth).To(BeFalse())
+				Expect(*response[2].FacebookTokenHealth.CheckedAt).To(BeTemporally("==", time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)))
+				Expect(response[2].FacebookTokenHealth.ExpiresAt).To(BeNil())
+				Expect(response[3].FacebookTokenHealth.Connected).To(BeTrue())
+				Expect(response[3].FacebookTokenHealth.NeedsReauth).To(BeTrue())
+			})
+
+			Context("with restaurants linked to the user directly, before the memberships have been migrated", func() {
+				BeforeEach(func() {
+					linkedRestaurant := &model.Restaurant{
+						ID: bson.NewObjectId(),
+					}
+					user.RestaurantIDs = []bson.ObjectId{allRestaurants[0].ID, linkedRestaurant.ID}
+					restaurants.On("GetByIDs", []bson.ObjectId{allRestaurants[0].ID, allRestaurants[2].ID, linkedRestaurant.ID}).
+						Return([]*model.Restaurant{allRestaurants[0], allRestaurants[2], linkedRestaurant}, nil)
+				})
+
+				It("includes the linked restaurants once", func() {
+					handler(responseRecorder, request)
+					var response []*model.Restaurant
+					json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+					Expect(response).To(HaveLen(5))
+				})
+			})
 		})
 	})
 
@@ -117,10 +169,13 @@ var _ = Describe("RestaurantsHandlers", func() {
 			usersCollection       db.Users
 			handler               router.Handler
 			fbAuth                *mocks.Authenticator
+			regionsCollection     *mocks.Regions
+			geocoder              *mocks.Coder
 		)
 
 		JustBeforeEach(func() {
-			handler = PostRestaurants(restaurantsCollection, sessionManager, usersCollection, fbAuth)
+			handler = PostRestaurants(restaurantsCollection, sessionManager, usersCollection, membershipsCollection, fbAuth,
+				regionsCollection, geocoder, auditLog)
 		})
 
 		ExpectUserToBeLoggedIn(func() *router.HandlerError {
@@ -147,6 +202,12 @@ var _ = Describe("RestaurantsHandlers", func() {
 				mockUsersCollection = new(mocks.Users)
 				usersCollection = mockUsersCollection
 				fbAuth = new(mocks.Authenticator)
+				regionsCollection = new(mocks.Regions)
+				regionsCollection.On("GetName", "Tallinn").Return(&model.Region{
+					Name:  "Tallinn",
+					CCTLD: "ee",
+				}, nil)
+				geocoder = new(mocks.Coder)
 
 				user = &model.User{
 					Session: model.UserSession{
@@ -157,8 +218,8 @@ var _ = Describe("RestaurantsHandlers", func() {
 				}
 				id = bson.NewObjectId()
 
-				mockSessionManager.On("Get", mock.Anything).Return("session", nil)
-				mockUsersCollection.On("GetSessionID", "session").Return(user, nil)
+				mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+				mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
 
 				requestMethod = "POST"
 				requestData = map[string]interface{}{
@@ -168,10 +229,6 @@ var _ = Describe("RestaurantsHandlers", func() {
 					"phone":            "+372 1234567890",
 					"website":          "https://some.address.com/some/path",
 					"email":            "an.email@address.com",
-					"location": map[string]interface{}{
-						"type":        "Point",
-						"coordinates": []float64{12.34, 56.78},
-					},
 				}
 			})
 
@@ -179,16 +236,164 @@ var _ = Describe("RestaurantsHandlers", func() {
 				mockSessionManager.AssertExpectations(GinkgoT())
 				mockRestaurantsCollection.AssertExpectations(GinkgoT())
 				mockUsersCollection.AssertExpectations(GinkgoT())
+				geocoder.AssertExpectations(GinkgoT())
+			})
+
+			Context("with the geocoder not finding an exact match", func() {
+				BeforeEach(func() {
+					geocoder.On("CodeForRegion", "Street 10, City, Country", "ee").Return([]geo.Candidate{
+						{Location: geo.Location{Lat: 56.78, Lng: 12.34}, Quality: geo.MatchPartial},
+					}, nil)
+				})
+
+				It("should not insert the restaurant", func() {
+					err := handler(responseRecorder, request)
+					Expect(err).To(BeNil())
+					Expect(responseRecorder.Code).To(Equal(http.StatusConflict))
+					mockRestaurantsCollection.AssertNotCalled(GinkgoT(), "Insert", mock.Anything)
+				})
+
+				It("should ask the client to confirm the candidate", func() {
+					handler(responseRecorder, request)
+					var response *model.LocationConfirmationRequest
+					json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+					Expect(response.Reason).To(Equal("inexact_match"))
+					Expect(response.Address).To(Equal("Street 10, City, Country"))
+					Expect(response.Candidates).To(Equal([]geo.Candidate{
+						{Location: geo.Location{Lat: 56.78, Lng: 12.34}, Quality: geo.MatchPartial},
+					}))
+				})
+			})
+
+			Context("with the geocoder finding multiple matches", func() {
+				BeforeEach(func() {
+					geocoder.On("CodeForRegion", "Street 10, City, Country", "ee").Return([]geo.Candidate{
+						{FormattedAddress: "Street 10, City", Location: geo.Location{Lat: 56.78, Lng: 12.34}, Quality: geo.MatchExact},
+						{FormattedAddress: "Street 10, Town", Location: geo.Location{Lat: 57.78, Lng: 13.34}, Quality: geo.MatchExact},
+					}, nil)
+				})
+
+				It("should ask the client to pick one of the candidates", func() {
+					err := handler(responseRecorder, request)
+					Expect(err).To(BeNil())
+					Expect(responseRecorder.Code).To(Equal(http.StatusConflict))
+					var response *model.LocationConfirmationRequest
+					json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+					Expect(response.Reason).To(Equal("multiple_results"))
+					Expect(response.Candidates).To(HaveLen(2))
+					Expect(response.Candidates[1].FormattedAddress).To(Equal("Street 10, Town"))
+				})
+			})
+
+			Context("with the geocoder not finding the address", func() {
+				BeforeEach(func() {
+					geocoder.On("CodeForRegion", "Street 10, City, Country", "ee").Return(nil, geo.ErrorNoResults)
+				})
+
+				It("should ask the client to specify the location", func() {
+					handler(responseRecorder, request)
+					Expect(responseRecorder.Code).To(Equal(http.StatusConflict))
+					var response *model.LocationConfirmationRequest
+					json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+					Expect(response.Reason).To(Equal("no_results"))
+					Expect(response.Candidates).To(BeEmpty())
+				})
+			})
+
+			Context("with a confirmed location specified", func() {
+				var insertedRestaurant *model.Restaurant
+
+				var confirmLocation = func(lat, lng float64) {
+					requestData.(map[string]interface{})["confirmed_location"] = map[string]interface{}{
+						"lat": lat,
+						"lng": lng,
+					}
+				}
+
+				BeforeEach(func() {
+					confirmLocation(56.79, 12.35)
+					geocoder.On("CodeForRegion", "Street 10, City, Country", "ee").Return([]geo.Candidate{
+						{Location: geo.Location{Lat: 56.78, Lng: 12.34}, Quality: geo.MatchPartial},
+					}, nil)
+				})
+
+				Context("near one of the candidates", func() {
+					BeforeEach(func() {
+						mockRestaurantsCollection.On("Insert", mock.AnythingOfType("[]*model.Restaurant")).Return([]*model.Restaurant{
+							&model.Restaurant{
+								ID: id,
+							},
+						}, nil).Run(func(args mock.Arguments) {
+							insertedRestaurant = args.Get(0).([]*model.Restaurant)[0]
+						})
+						membershipsCollection.On("Set", user.ID, id, model.RoleOwner).Return(nil)
+					})
+
+					It("should use the confirmed location instead of the geocoded one", func() {
+						handler(responseRecorder, request)
+						Expect(insertedRestaurant.Location.Coordinates).To(Equal([]float64{12.35, 56.79}))
+					})
+				})
+
+				Context("with the geocoder unavailable", func() {
+					BeforeEach(func() {
+						confirmLocation(58.38, 26.72)
+						geocoder.ExpectedCalls = nil
+						geocoder.On("CodeForRegion", "Street 10, City, Country", "ee").Return(nil, errors.New("service unavailable"))
+						mockRestaurantsCollection.On("Insert", mock.AnythingOfType("[]*model.Restaurant")).Return([]*model.Restaurant{
+							&model.Restaurant{
+								ID: id,
+							},
+						}, nil).Run(func(args mock.Arguments) {
+							insertedRestaurant = args.Get(0).([]*model.Restaurant)[0]
+						})
+						membershipsCollection.On("Set", user.ID, id, model.RoleOwner).Return(nil)
+					})
+
+					It("should use the confirmed location without checking it", func() {
+						err := handler(responseRecorder, request)
+						Expect(err).To(BeNil())
+						Expect(insertedRestaurant.Location.Coordinates).To(Equal([]float64{26.72, 58.38}))
+					})
+				})
+
+				Context("far from the candidates", func() {
+					BeforeEach(func() {
+						confirmLocation(58.38, 26.72)
+					})
+
+					It("should fail with StatusBadRequest without inserting the restaurant", func() {
+						err := handler(responseRecorder, request)
+						Expect(err.Code).To(Equal(http.StatusBadRequest))
+						mockRestaurantsCollection.AssertNotCalled(GinkgoT(), "Insert", mock.Anything)
+					})
+				})
+
+				Context("with impossible coordinates", func() {
+					BeforeEach(func() {
+						confirmLocation(156.79, 12.35)
+						geocoder.ExpectedCalls = nil
+					})
+
+					It("should fail with StatusBadRequest without inserting the restaurant", func() {
+						err := handler(responseRecorder, request)
+						Expect(err.Code).To(Equal(http.StatusBadRequest))
+						mockRestaurantsCollection.AssertNotCalled(GinkgoT(), "Insert", mock.Anything)
+					})
+				})
 			})
 
 			Context("with DB inserts succeeding", func() {
 				BeforeEach(func() {
+					geocoder.On("CodeForRegion", "Street 10, City, Country", "ee").Return([]geo.Candidate{
+						{Location: geo.Location{Lat: 56.78, Lng: 12.34}, Quality: geo.MatchExact},
+					}, nil)
 					mockRestaurantsCollection.On("Insert", mock.AnythingOfType("[]*model.Restaurant")).Return([]*model.Restaurant{
 						&model.Restaurant{
 							ID: id,
 						},
 					}, nil)
-					mockUsersCollection.On("Update", mock.AnythingOfType("string"), mock.AnythingOfType("*model.User")).Return(nil)
+					membershipsCollection.On("Set", user.ID, id, model.RoleOwner).Return(nil)
 				})
 
 				It("should succeed", func() {
@@ -209,11 +414,21 @@ var _ = Describe("RestaurantsHandlers", func() {
 					json.Unmarshal(responseRecorder.Body.Bytes(), &restaurant)
 					Expect(restaurant.ID).To(Equal(id))
 				})
+
+				It("should record the new restaurant in the audit log", func() {
+					handler(responseRecorder, request)
+					Expect(auditEntries).To(HaveLen(1))
+					Expect(auditEntries[0].Action).To(Equal("restaurant.create"))
+					Expect(auditEntries[0].RestaurantID).To(Equal(id))
+				})
 			})
 
 			Context("the inserted restaurant", func() {
 				var insertedRestaurant *model.Restaurant
 				BeforeEach(func() {
+					geocoder.On("CodeForRegion", "Street 10, City, Country", "ee").Return([]geo.Candidate{
+						{Location: geo.Location{Lat: 56.78, Lng: 12.34}, Quality: geo.MatchExact},
+					}, nil)
 					mockRestaurantsCollection.On("Insert", mock.AnythingOfType("[]*model.Restaurant")).Return([]*model.Restaurant{
 						&model.Restaurant{
 							ID: id,
@@ -221,7 +436,7 @@ var _ = Describe("RestaurantsHandlers", func() {
 					}, nil).Run(func(args mock.Arguments) {
 						insertedRestaurant = args.Get(0).([]*model.Restaurant)[0]
 					})
-					mockUsersCollection.On("Update", mock.AnythingOfType("string"), mock.AnythingOfType("*model.User")).Return(nil)
+					membershipsCollection.On("Set", user.ID, id, model.RoleOwner).Return(nil)
 				})
 
 				It("should correctly parse and insert the restaurant", func() {
@@ -240,8 +455,52 @@ var _ = Describe("RestaurantsHandlers", func() {
 				})
 			})
 
+			Context("without a FB page", func() {
+				BeforeEach(func() {
+					delete(requestData.(map[string]interface{}), "facebook_page_id")
+				})
+
+				Context("with the user logged in through Facebook", func() {
+					BeforeEach(func() {
+						user.FacebookUserID = "fbuserid"
+					})
+
+					It("should fail", func() {
+						err := handler(responseRecorder, request)
+						Expect(err.Code).To(Equal(http.StatusBadRequest))
+					})
+				})
+
+				Context("with the user logged in with an email address", func() {
+					BeforeEach(func() {
+						user.Email = "owner@restaurant.test"
+						geocoder.On("CodeForRegion", "Street 10, City, Country", "ee").Return([]geo.Candidate{
+							{Location: geo.Location{Lat: 56.78, Lng: 12.34}, Quality: geo.MatchExact},
+						}, nil)
+						mockRestaurantsCollection.On("Insert", mock.AnythingOfType("[]*model.Restaurant")).Return([]*model.Restaurant{
+							&model.Restaurant{
+								ID: id,
+							},
+						}, nil)
+						membershipsCollection.On("Set", user.ID, id, model.RoleOwner).Return(nil)
+					})
+
+					It("should make the user the restaurant's owner", func() {
+						err := handler(responseRecorder, request)
+						Expect(err).To(BeNil())
+						membershipsCollection.AssertExpectations(GinkgoT())
+					})
+				})
+			})
+
 			Describe("the updated user", func() {
 				var updatedUser *model.User
+
+				BeforeEach(func() {
+					geocoder.On("CodeForRegion", "Street 10, City, Country", "ee").Return([]geo.Candidate{
+						{Location: geo.Location{Lat: 56.78, Lng: 12.34}, Quality: geo.MatchExact},
+					}, nil)
+				})
 				Context("with restaurant not being attached to a FB page", func() {
 					BeforeEach(func() {
 						mockRestaurantsCollection.On("Insert", mock.AnythingOfType("[]*model.Restaurant")).Return([]*model.Restaurant{
@@ -249,14 +508,13 @@ var _ = Describe("RestaurantsHandlers", func() {
 								ID: id,
 							},
 						}, nil)
-						mockUsersCollection.On("Update", mock.AnythingOfType("string"), mock.AnythingOfType("*model.User")).Return(nil).Run(func(args mock.Arguments) {
-							updatedUser = args.Get(1).(*model.User)
-						})
+						membershipsCollection.On("Set", user.ID, id, model.RoleOwner).Return(nil)
 					})
 
-					It("should update the user to include a reference to the restaurant", func() {
+					It("should make the user the restaurant's owner instead", func() {
 						handler(responseRecorder, request)
-						Expect(updatedUser.RestaurantIDs[0]).To(Equal(id))
+						membershipsCollection.AssertExpectations(GinkgoT())
+						mockUsersCollection.AssertNotCalled(GinkgoT(), "UpdateID", mock.Anything, mock.Anything)
 					})
 				})
 
@@ -273,7 +531,7 @@ var _ = Describe("RestaurantsHandlers", func() {
 								FacebookPageID: facebookPageID,
 							},
 						}, nil)
-						mockUsersCollection.On("Update", mock.AnythingOfType("string"), mock.AnythingOfType("*model.User")).Return(nil).Run(func(args mock.Arguments) {
+						mockUsersCollection.On("UpdateID", mock.AnythingOfType("bson.ObjectId"), mock.AnythingOfType("*model.User")).Return(nil).Run(func(args mock.Arguments) {
 							updatedUser = args.Get(1).(*model.User)
 						})
 						fbAuth.On("PageAccessToken", &user.Session.FacebookUserToken, facebookPageID).Return(pageAccessToken, nil)
@@ -301,7 +559,8 @@ var _ = Describe("RestaurantsHandlers", func() {
 		)
 
 		JustBeforeEach(func() {
-			handler = Restaurant(restaurantsCollection, sessionManager, usersCollection)
+			handler = Restaurant(restaurantsCollection, sessionManager, usersCollection, membershipsCollection,
+				apiKeysCollection)
 		})
 
 		ExpectUserToBeLoggedIn(func() *router.HandlerError {
@@ -335,12 +594,12 @@ var _ = Describe("RestaurantsHandlers", func() {
 					FacebookPageID: facebookPageID,
 				}
 
-				mockSessionManager.On("Get", mock.Anything).Return("session", nil)
+				mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
 			})
 
 			Context("with an invalid restaurant ID", func() {
 				BeforeEach(func() {
-					mockUsersCollection.On("GetSessionID", "session").Return(&model.User{}, nil)
+					mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(&model.User{}, nil)
 					params = httprouter.Params{httprouter.Param{
 						Key:   "restaurantID",
 						Value: "gibberish",
@@ -366,7 +625,8 @@ var _ = Describe("RestaurantsHandlers", func() {
 				Context("but not authorized", func() {
 					BeforeEach(func() {
 						user := &model.User{}
-						mockUsersCollection.On("GetSessionID", "session").Return(user, nil)
+						mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+						membershipsCollection.On("Get", user.ID, restaurant.ID).Return(nil, mgo.ErrNotFound)
 					})
 
 					It("fails", func() {
@@ -376,17 +636,51 @@ var _ = Describe("RestaurantsHandlers", func() {
 					})
 				})
 
+				Context("having a viewer role for the restaurant", func() {
+					BeforeEach(func() {
+						user := &model.User{
+							ID: bson.NewObjectId(),
+						}
+						mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+						membershipsCollection.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+							UserID:       user.ID,
+							RestaurantID: restaurant.ID,
+							Role:         model.RoleViewer,
+						}, nil)
+					})
+
+					It("succeeds", func() {
+						err := handler(responseRecorder, request, params)
+						Expect(err).To(BeNil())
+					})
+				})
+
+				Context("with the role lookup failing", func() {
+					BeforeEach(func() {
+						user := &model.User{
+							ID: bson.NewObjectId(),
+						}
+						mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+						membershipsCollection.On("Get", user.ID, restaurant.ID).Return(nil, errors.New("something went wrong"))
+					})
+
+					It("fails", func() {
+						err := handler(responseRecorder, request, params)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Code).To(Equal(http.StatusInternalServerError))
+					})
+				})
+
 				Context("having a FB page access token for the restaurant's page", func() {
 					BeforeEach(func() {
 						user := &model.User{
-							RestaurantIDs: []bson.ObjectId{},
 							Session: model.UserSession{
 								FacebookPageTokens: []model.FacebookPageToken{model.FacebookPageToken{
 									PageID: facebookPageID,
 								}},
 							},
 						}
-						mockUsersCollection.On("GetSessionID", "session").Return(user, nil)
+						mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
 					})
 
 					It("succeeds", func() {
@@ -395,12 +689,33 @@ var _ = Describe("RestaurantsHandlers", func() {
 					})
 				})
 
-				Context("having direct access", func() {
+				Context("only being linked to the restaurant directly, before the memberships have been migrated", func() {
 					BeforeEach(func() {
 						user := &model.User{
+							ID:            bson.NewObjectId(),
 							RestaurantIDs: []bson.ObjectId{restaurant.ID},
 						}
-						mockUsersCollection.On("GetSessionID", "session").Return(user, nil)
+						mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+					})
+
+					It("succeeds as an owner", func() {
+						err := handler(responseRecorder, request, params)
+						Expect(err).To(BeNil())
+						membershipsCollection.AssertNotCalled(GinkgoT(), "Get", mock.Anything, mock.Anything)
+					})
+				})
+
+				Context("having an owner role for the restaurant", func() {
+					BeforeEach(func() {
+						user := &model.User{
+							ID: bson.NewObjectId(),
+						}
+						mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+						membershipsCollection.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+							UserID:       user.ID,
+							RestaurantID: restaurant.ID,
+							Role:         model.RoleOwner,
+						}, nil)
 					})
 
 					It("should succeed", func() {
@@ -428,6 +743,494 @@ var _ = Describe("RestaurantsHandlers", func() {
 		})
 	})
 
+	Describe("PUT /restaurants/:id", func() {
+		var (
+			sessionManager        session.Manager
+			restaurantsCollection db.Restaurants
+			usersCollection       db.Users
+			offersCollection      *mocks.Offers
+			regionsCollection     *mocks.Regions
+			geocoder              *mocks.Coder
+			params                httprouter.Params
+			handler               router.HandlerWithParams
+		)
+
+		BeforeEach(func() {
+			offersCollection = new(mocks.Offers)
+			regionsCollection = new(mocks.Regions)
+			geocoder = new(mocks.Coder)
+		})
+
+		JustBeforeEach(func() {
+			handler = PutRestaurant(restaurantsCollection, sessionManager, usersCollection, membershipsCollection,
+				apiKeysCollection, offersCollection, regionsCollection, geocoder, auditLog)
+		})
+
+		ExpectUserToBeLoggedIn(func() *router.HandlerError {
+			return handler(responseRecorder, request, nil)
+		}, func(mgr session.Manager, users db.Users) {
+			sessionManager = mgr
+			usersCollection = users
+		})
+
+		Context("with user logged in and authorized to manage the restaurant", func() {
+			var (
+				mockSessionManager        *mocks.Manager
+				mockRestaurantsCollection *mocks.Restaurants
+				mockUsersCollection       *mocks.Users
+				restaurant                *model.Restaurant
+			)
+
+			BeforeEach(func() {
+				mockSessionManager = new(mocks.Manager)
+				sessionManager = mockSessionManager
+				mockRestaurantsCollection = new(mocks.Restaurants)
+				restaurantsCollection = mockRestaurantsCollection
+				mockUsersCollection = new(mocks.Users)
+				usersCollection = mockUsersCollection
+
+				restaurant = &model.Restaurant{
+					ID:      bson.NewObjectId(),
+					Name:    "restname",
+					Address: "Küüni 5, Tartu",
+					Region:  "Tartu",
+					Location: model.Location{
+						Type:        "Point",
+						Coordinates: []float64{26.72, 58.37},
+					},
+					FacebookPageID:                  "fbpageid",
+					DefaultGroupPostMessageTemplate: "Old template",
+				}
+				user := &model.User{
+					ID: bson.NewObjectId(),
+				}
+				membershipsCollection.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+					Role: model.RoleOwner,
+				}, nil)
+				mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+				mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+				mockRestaurantsCollection.On("GetID", restaurant.ID).Return(restaurant, nil)
+				params = httprouter.Params{httprouter.Param{
+					Key:   "restaurantID",
+					Value: restaurant.ID.Hex(),
+				}}
+
+				requestMethod = "PUT"
+				requestData = map[string]interface{}{
+					"name":                                "New Name",
+					"address":                             "Küüni 5, Tartu",
+					"phone":                               "+372 1234567890",
+					"website":                             "https://some.address.com",
+					"email":                               "an.email@address.com",
+					"default_group_post_message_template": "New template",
+				}
+			})
+
+			AfterEach(func() {
+				mockRestaurantsCollection.AssertExpectations(GinkgoT())
+				offersCollection.AssertExpectations(GinkgoT())
+				geocoder.AssertExpectations(GinkgoT())
+			})
+
+			Context("with name missing", func() {
+				BeforeEach(func() {
+					requestData = map[string]interface{}{
+						"address": "Küüni 5, Tartu",
+					}
+				})
+
+				It("should fail with StatusBadRequest", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err).NotTo(BeNil())
+					Expect(err.Code).To(Equal(http.StatusBadRequest))
+				})
+			})
+
+			Context("with an invalid default message template", func() {
+				BeforeEach(func() {
+					requestData = map[string]interface{}{
+						"name":                                "New Name",
+						"address":                             "Küüni 5, Tartu",
+						"default_group_post_message_template": "{{.Offers",
+					}
+				})
+
+				It("should fail with StatusBadRequest", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err).NotTo(BeNil())
+					Expect(err.Code).To(Equal(http.StatusBadRequest))
+				})
+			})
+
+			Context("with the address unchanged", func() {
+				var (
+					updatedRestaurant      *model.Restaurant
+					updatedOfferRestaurant model.OfferRestaurant
+				)
+
+				BeforeEach(func() {
+					mockRestaurantsCollection.On("UpdateDetails", restaurant.ID, mock.AnythingOfType("*model.Restaurant")).Return(nil).Run(func(args mock.Arguments) {
+						updatedRestaurant = args.Get(1).(*model.Restaurant)
+					})
+					offersCollection.On("UpdateRestaurant", mock.AnythingOfType("model.OfferRestaurant")).Return(nil).Run(func(args mock.Arguments) {
+						updatedOfferRestaurant = args.Get(0).(model.OfferRestaurant)
+					})
+				})
+
+				It("should succeed", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err).To(BeNil())
+				})
+
+				It("should update the editable fields and keep the rest", func() {
+					handler(responseRecorder, request, params)
+					Expect(updatedRestaurant.Name).To(Equal("New Name"))
+					Expect(updatedRestaurant.Phone).To(Equal("+372 1234567890"))
+					Expect(updatedRestaurant.Website).To(Equal("https://some.address.com"))
+					Expect(updatedRestaurant.Email).To(Equal("an.email@address.com"))
+					Expect(updatedRestaurant.DefaultGroupPostMessageTemplate).To(Equal("New template"))
+					Expect(updatedRestaurant.FacebookPageID).To(Equal("fbpageid"))
+					Expect(updatedRestaurant.Region).To(Equal("Tartu"))
+					Expect(updatedRestaurant.Location.Coordinates).To(Equal([]float64{26.72, 58.37}))
+				})
+
+				Context("with the contact details cleared", func() {
+					BeforeEach(func() {
+						delete(requestData.(map[string]interface{}), "phone")
+						requestData.(map[string]interface{})["website"] = ""
+					})
+
+					It("should pass on the empty details, so they'd get removed", func() {
+						handler(responseRecorder, request, params)
+						Expect(updatedRestaurant.Phone).To(BeEmpty())
+						Expect(updatedRestaurant.Website).To(BeEmpty())
+						Expect(updatedRestaurant.Email).To(Equal("an.email@address.com"))
+					})
+				})
+
+				It("should update the restaurant in its offers", func() {
+					handler(responseRecorder, request, params)
+					Expect(updatedOfferRestaurant.ID).To(Equal(restaurant.ID))
+					Expect(updatedOfferRestaurant.Name).To(Equal("New Name"))
+					Expect(updatedOfferRestaurant.Phone).To(Equal("+372 1234567890"))
+				})
+
+				It("should respond with the updated restaurant", func() {
+					handler(responseRecorder, request, params)
+					var response *model.Restaurant
+					json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+					Expect(response.ID).To(Equal(restaurant.ID))
+					Expect(response.Name).To(Equal("New Name"))
+				})
+			})
+
+			Context("with the address changed", func() {
+				BeforeEach(func() {
+					requestData.(map[string]interface{})["address"] = "Võru 24, Tartu"
+					regionsCollection.On("GetName", "Tartu").Return(&model.Region{
+						Name:  "Tartu",
+						CCTLD: "ee",
+					}, nil)
+				})
+
+				Context("with the geocoder finding an exact match", func() {
+					var updatedOfferRestaurant model.OfferRestaurant
+
+					BeforeEach(func() {
+						geocoder.On("CodeForRegion", "Võru 24, Tartu", "ee").Return([]geo.Candidate{
+							{Location: geo.Location{Lat: 58.36, Lng: 26.73}, Quality: geo.MatchExact},
+						}, nil)
+						mockRestaurantsCollection.On("UpdateDetails", restaurant.ID, mock.AnythingOfType("*model.Restaurant")).Return(nil)
+						offersCollection.On("UpdateRestaurant", mock.AnythingOfType("model.OfferRestaurant")).Return(nil).Run(func(args mock.Arguments) {
+							updatedOfferRestaurant = args.Get(0).(model.OfferRestaurant)
+						})
+					})
+
+					It("should update the location of the restaurant in its offers", func() {
+						err := handler(responseRecorder, request, params)
+						Expect(err).To(BeNil())
+						Expect(updatedOfferRestaurant.Address).To(Equal("Võru 24, Tartu"))
+						Expect(updatedOfferRestaurant.Location.Coordinates).To(Equal([]float64{26.73, 58.36}))
+					})
+				})
+
+				Context("with the geocoder finding a partial match", func() {
+					BeforeEach(func() {
+						geocoder.On("CodeForRegion", "Võru 24, Tartu", "ee").Return([]geo.Candidate{
+							{Location: geo.Location{Lat: 58.36, Lng: 26.73}, Quality: geo.MatchPartial},
+						}, nil)
+					})
+
+					It("should ask the client to confirm the location", func() {
+						err := handler(responseRecorder, request, params)
+						Expect(err).To(BeNil())
+						Expect(responseRecorder.Code).To(Equal(http.StatusConflict))
+					})
+				})
+			})
+		})
+	})
+
+	Describe("POST /restaurants/:id/deactivate", func() {
+		var (
+			mockSessionManager        *mocks.Manager
+			mockRestaurantsCollection *mocks.Restaurants
+			mockUsersCollection       *mocks.Users
+			offersCollection          *mocks.Offers
+			user                      *model.User
+			role                      model.Role
+			restaurant                *model.Restaurant
+			params                    httprouter.Params
+			handler                   router.HandlerWithParams
+		)
+
+		BeforeEach(func() {
+			mockSessionManager = new(mocks.Manager)
+			mockRestaurantsCollection = new(mocks.Restaurants)
+			mockUsersCollection = new(mocks.Users)
+			offersCollection = new(mocks.Offers)
+			role = model.RoleOwner
+
+			restaurant = &model.Restaurant{
+				ID:   bson.NewObjectId(),
+				Name: "restname",
+			}
+			user = &model.User{
+				ID: bson.NewObjectId(),
+			}
+			mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+			mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+			mockRestaurantsCollection.On("GetID", restaurant.ID).Return(restaurant, nil)
+			params = httprouter.Params{httprouter.Param{
+				Key:   "restaurantID",
+				Value: restaurant.ID.Hex(),
+			}}
+			requestMethod = "POST"
+		})
+
+		JustBeforeEach(func() {
+			membershipsCollection.On("Get", user.ID, restaurant.ID).Return(&model.Membership{
+				UserID:       user.ID,
+				RestaurantID: restaurant.ID,
+				Role:         role,
+			}, nil)
+			handler = DeactivateRestaurant(mockRestaurantsCollection, mockSessionManager, mockUsersCollection,
+				membershipsCollection, apiKeysCollection, offersCollection, auditLog)
+		})
+
+		AfterEach(func() {
+			mockRestaurantsCollection.AssertExpectations(GinkgoT())
+			offersCollection.AssertExpectations(GinkgoT())
+		})
+
+		Context("with DB updates succeeding", func() {
+			var updatedOfferRestaurant model.OfferRestaurant
+
+			BeforeEach(func() {
+				mockRestaurantsCollection.On("SetDeactivated", restaurant.ID, true).Return(nil)
+				offersCollection.On("UpdateRestaurant", mock.AnythingOfType("model.OfferRestaurant")).Return(nil).Run(func(args mock.Arguments) {
+					updatedOfferRestaurant = args.Get(0).(model.OfferRestaurant)
+				})
+			})
+
+			It("should deactivate the restaurant and its offers", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+				Expect(updatedOfferRestaurant.ID).To(Equal(restaurant.ID))
+				Expect(updatedOfferRestaurant.Deactivated).To(BeTrue())
+			})
+
+			It("should respond with the deactivated restaurant", func() {
+				handler(responseRecorder, request, params)
+				var response *model.Restaurant
+				json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+				Expect(response.Deactivated).To(BeTrue())
+			})
+		})
+
+		Context("with the user only being an editor of the restaurant", func() {
+			BeforeEach(func() {
+				role = model.RoleEditor
+			})
+
+			It("should fail with StatusForbidden", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).NotTo(BeNil())
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("with the DB update failing", func() {
+			BeforeEach(func() {
+				mockRestaurantsCollection.On("SetDeactivated", restaurant.ID, true).Return(errors.New("something went wrong"))
+			})
+
+			It("should fail with StatusInternalServerError", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).NotTo(BeNil())
+				Expect(err.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+
+	Describe("DELETE /restaurants/:id", func() {
+		var (
+			mockSessionManager        *mocks.Manager
+			mockRestaurantsCollection *mocks.Restaurants
+			mockUsersCollection       *mocks.Users
+			offersCollection          *mocks.Offers
+			groupPostsCollection      *mocks.OfferGroupPosts
+			invitesCollection         *mocks.Invites
+			publishJobsCollection     *mocks.PublishJobs
+			facebookPost              *mocks.Post
+			user                      *model.User
+			restaurant                *model.Restaurant
+			params                    httprouter.Params
+			handler                   router.HandlerWithParams
+		)
+
+		BeforeEach(func() {
+			mockSessionManager = new(mocks.Manager)
+			mockRestaurantsCollection = new(mocks.Restaurants)
+			mockUsersCollection = new(mocks.Users)
+			offersCollection = new(mocks.Offers)
+			groupPostsCollection = new(mocks.OfferGroupPosts)
+			invitesCollection = new(mocks.Invites)
+			publishJobsCollection = new(mocks.PublishJobs)
+			facebookPost = new(mocks.Post)
+
+			restaurant = &model.Restaurant{
+				ID:             bson.NewObjectId(),
+				Name:           "restname",
+				FacebookPageID: "fbpageid",
+			}
+			user = &model.User{
+				Session: model.UserSession{
+					FacebookPageTokens: []model.FacebookPageToken{model.FacebookPageToken{
+						PageID: "fbpageid",
+					}},
+				},
+			}
+			mockSessionManager.On("Resolve", mock.Anything).Return(&model.Session{}, nil)
+			mockUsersCollection.On("GetID", mock.AnythingOfType("bson.ObjectId")).Return(user, nil)
+			mockRestaurantsCollection.On("GetID", restaurant.ID).Return(restaurant, nil)
+			params = httprouter.Params{httprouter.Param{
+				Key:   "restaurantID",
+				Value: restaurant.ID.Hex(),
+			}}
+			requestMethod = "DELETE"
+			requestQuery = url.Values{}
+		})
+
+		JustBeforeEach(func() {
+			handler = DeleteRestaurant(mockRestaurantsCollection, mockSessionManager, mockUsersCollection,
+				membershipsCollection, apiKeysCollection, offersCollection, groupPostsCollection, invitesCollection,
+				publishJobsCollection, facebookPost, auditLog)
+		})
+
+		AfterEach(func() {
+			mockRestaurantsCollection.AssertExpectations(GinkgoT())
+			mockUsersCollection.AssertExpectations(GinkgoT())
+			offersCollection.AssertExpectations(GinkgoT())
+			groupPostsCollection.AssertExpectations(GinkgoT())
+			invitesCollection.AssertExpectations(GinkgoT())
+			publishJobsCollection.AssertExpectations(GinkgoT())
+			facebookPost.AssertExpectations(GinkgoT())
+			membershipsCollection.AssertExpectations(GinkgoT())
+		})
+
+		var expectDeactivation = func() {
+			mockRestaurantsCollection.On("SetDeactivated", restaurant.ID, true).Return(nil)
+			publishJobsCollection.On("RemoveForRestaurant", restaurant.ID).Return(nil)
+		}
+
+		var expectRemovalFromDB = func() {
+			offersCollection.On("RemoveForRestaurant", restaurant.ID).Return(nil)
+			groupPostsCollection.On("RemoveByRestaurantID", restaurant.ID).Return(nil)
+			invitesCollection.On("RemoveForRestaurant", restaurant.ID).Return(nil)
+			mockUsersCollection.On("RemoveRestaurant", restaurant.ID, "fbpageid").Return(nil)
+			membershipsCollection.On("RemoveForRestaurant", restaurant.ID).Return(nil)
+			apiKeysCollection.On("RemoveForRestaurant", restaurant.ID).Return(nil)
+			mockRestaurantsCollection.On("RemoveID", restaurant.ID).Return(nil)
+		}
+
+		Context("without asking for the Facebook posts to be deleted", func() {
+			BeforeEach(func() {
+				expectDeactivation()
+				expectRemovalFromDB()
+			})
+
+			It("should remove the restaurant and everything related to it", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+				Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("with removing the offers failing", func() {
+			BeforeEach(func() {
+				expectDeactivation()
+				offersCollection.On("RemoveForRestaurant", restaurant.ID).Return(errors.New("something went wrong"))
+			})
+
+			It("should fail while the users still have access to the restaurant, so they could retry", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusInternalServerError))
+				mockRestaurantsCollection.AssertNotCalled(GinkgoT(), "RemoveID", mock.Anything)
+				membershipsCollection.AssertNotCalled(GinkgoT(), "RemoveForRestaurant", mock.Anything)
+				mockUsersCollection.AssertNotCalled(GinkgoT(), "RemoveRestaurant", mock.Anything, mock.Anything)
+				apiKeysCollection.AssertNotCalled(GinkgoT(), "RemoveForRestaurant", mock.Anything)
+			})
+		})
+
+		Context("with asking for the Facebook posts to be deleted", func() {
+			var posts []*model.OfferGroupPost
+
+			BeforeEach(func() {
+				requestQuery = url.Values{
+					"delete_facebook_posts": {"true"},
+				}
+				posts = []*model.OfferGroupPost{&model.OfferGroupPost{
+					ID:       bson.NewObjectId(),
+					FBPostID: "post1",
+				}, &model.OfferGroupPost{
+					ID:       bson.NewObjectId(),
+					FBPostID: "post2",
+				}}
+				groupPostsCollection.On("GetByRestaurantID", restaurant.ID).Return(posts, nil)
+			})
+
+			Context("with Facebook post deletion succeeding", func() {
+				BeforeEach(func() {
+					expectDeactivation()
+					facebookPost.On("Delete", posts[0], user, restaurant).Return(nil)
+					facebookPost.On("Delete", posts[1], user, restaurant).Return(nil)
+					expectRemovalFromDB()
+				})
+
+				It("should delete the posts from Facebook and remove the restaurant", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err).To(BeNil())
+				})
+			})
+
+			Context("with Facebook post deletion failing", func() {
+				BeforeEach(func() {
+					expectDeactivation()
+					facebookPost.On("Delete", posts[0], user, restaurant).Return(router.NewSimpleHandlerError("FB failed", http.StatusBadGateway))
+				})
+
+				It("should fail without removing the restaurant or its offers", func() {
+					err := handler(responseRecorder, request, params)
+					Expect(err).NotTo(BeNil())
+					Expect(err.Code).To(Equal(http.StatusBadGateway))
+					offersCollection.AssertNotCalled(GinkgoT(), "RemoveForRestaurant", mock.Anything)
+					mockRestaurantsCollection.AssertNotCalled(GinkgoT(), "RemoveID", mock.Anything)
+				})
+			})
+		})
+	})
+
 	Describe("GET /restaurants/:id/offers", func() {
 		var (
 			sessionManager            session.Manager
@@ -452,7 +1255,7 @@ var _ = Describe("RestaurantsHandlers", func() {
 
 		JustBeforeEach(func() {
 			handler = RestaurantOffers(mockRestaurantsCollection, sessionManager, mockUsersCollection,
-				offersCollection, imageStorage, regionsCollection)
+				membershipsCollection, apiKeysCollection, offersCollection, imageStorage, regionsCollection)
 		})
 
 		ExpectUserToBeLoggedIn(func() *router.HandlerError {
@@ -466,8 +1269,11 @@ var _ = Describe("RestaurantsHandlers", func() {
 			var restaurantID = bson.ObjectId("12letrrestid")
 
 			BeforeEach(func() {
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 				mockUsersCollection = mockUsers{}
+				membershipsCollection.On("Get", objectID, bson.ObjectId("12letrrestid")).Return(&model.Membership{
+					Role: model.RoleOwner,
+				}, nil)
 				mockRestaurantsCollection = &mockRestaurants{}
 				regionsCollection = new(mocks.Regions)
 				regionsCollection.On("GetName", "Tartu").Return(&model.Region{
@@ -596,7 +1402,7 @@ var _ = Describe("RestaurantsHandlers", func() {
 
 		JustBeforeEach(func() {
 			handler = RestaurantOfferSuggestions(mockRestaurantsCollection, sessionManager, mockUsersCollection,
-				offersCollection)
+				membershipsCollection, apiKeysCollection, offersCollection)
 		})
 
 		ExpectUserToBeLoggedIn(func() *router.HandlerError {
@@ -608,8 +1414,11 @@ var _ = Describe("RestaurantsHandlers", func() {
 
 		Context("with user logged in", func() {
 			BeforeEach(func() {
-				sessionManager = &mockSessionManager{isSet: true, id: "correctSession"}
+				sessionManager = &mockSessionManager{isSet: true, userID: objectID}
 				mockUsersCollection = mockUsers{}
+				membershipsCollection.On("Get", objectID, bson.ObjectId("12letrrestid")).Return(&model.Membership{
+					Role: model.RoleOwner,
+				}, nil)
 				offersCollection = new(mocks.Offers)
 				mockRestaurantsCollection = &mockRestaurants{}
 				params = httprouter.Params{httprouter.Param{
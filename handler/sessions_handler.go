@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Lunchr/luncher-api/audit"
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/session"
+)
+
+// UserSession defines the response format for the UserSessions() handler
+type UserSession struct {
+	*model.Session
+	Current bool `json:"current"`
+}
+
+// UserSessions returns a handler that lists the logged in user's unexpired sessions, most recently used first.
+// The session the request was made with is marked as current.
+func UserSessions(sessionManager session.Manager, users db.Users, sessions db.Sessions) router.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User) *router.HandlerError {
+		currentSession := resolvedSession(r)
+		userSessions, err := sessions.GetForUser(user.ID)
+		if err != nil {
+			return router.NewHandlerError(err, "Failed to find the user's sessions", http.StatusInternalServerError)
+		}
+		response := make([]UserSession, len(userSessions))
+		for i, userSession := range userSessions {
+			response[i] = UserSession{
+				Session: userSession,
+				Current: userSession.ID == currentSession.ID,
+			}
+		}
+		return writeJSON(w, response)
+	}
+	return checkLogin(sessionManager, users, handler)
+}
+
+// DeleteUserSession returns a handler that ends the logged in user's session specified by the id param
+func DeleteUserSession(sessionManager session.Manager, users db.Users, sessions db.Sessions,
+	auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User) *router.HandlerError {
+		idString := ps.ByName("id")
+		if !bson.IsObjectIdHex(idString) {
+			return router.NewSimpleHandlerError("Invalid session ID", http.StatusBadRequest)
+		}
+		err := sessions.Remove(bson.ObjectIdHex(idString), user.ID)
+		if err == mgo.ErrNotFound {
+			return router.NewHandlerError(err, "Failed to find the specified session", http.StatusNotFound)
+		} else if err != nil {
+			return router.NewHandlerError(err, "Failed to remove the session from the DB", http.StatusInternalServerError)
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return checkLoginWithParams(sessionManager, users, auditedWithParams(auditLog, "session.delete", handler))
+}
@@ -0,0 +1,142 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SessionsHandler", func() {
+	var (
+		sessionManager  *mocks.Manager
+		usersCollection *mocks.Users
+		sessions        *mocks.Sessions
+		user            *model.User
+		currentSession  *model.Session
+	)
+
+	BeforeEach(func() {
+		sessionManager = new(mocks.Manager)
+		usersCollection = new(mocks.Users)
+		sessions = new(mocks.Sessions)
+		user = &model.User{
+			ID: bson.NewObjectId(),
+		}
+		currentSession = &model.Session{
+			ID:     bson.NewObjectId(),
+			Token:  "a secret token",
+			UserID: user.ID,
+		}
+		sessionManager.On("Resolve", mock.Anything).Return(currentSession, nil)
+		usersCollection.On("GetID", user.ID).Return(user, nil)
+		requestQuery = url.Values{}
+	})
+
+	AfterEach(func() {
+		sessions.AssertExpectations(GinkgoT())
+	})
+
+	Describe("GET /user/sessions", func() {
+		var handler router.Handler
+
+		BeforeEach(func() {
+			requestMethod = "GET"
+		})
+
+		JustBeforeEach(func() {
+			handler = UserSessions(sessionManager, usersCollection, sessions)
+		})
+
+		Context("with the user logged in on multiple devices", func() {
+			BeforeEach(func() {
+				sessions.On("GetForUser", user.ID).Return([]*model.Session{
+					currentSession,
+					{ID: bson.NewObjectId(), Token: "another secret token", UserID: user.ID, UserAgent: "a phone"},
+				}, nil)
+			})
+
+			It("should list the sessions and mark the current one", func() {
+				err := handler(responseRecorder, request)
+				Expect(err).To(BeNil())
+				var result []map[string]interface{}
+				json.Unmarshal(responseRecorder.Body.Bytes(), &result)
+				Expect(result).To(HaveLen(2))
+				Expect(result[0]["current"]).To(BeTrue())
+				Expect(result[1]["current"]).To(BeFalse())
+				Expect(result[1]["user_agent"]).To(Equal("a phone"))
+			})
+
+			It("should not expose the session tokens", func() {
+				err := handler(responseRecorder, request)
+				Expect(err).To(BeNil())
+				Expect(responseRecorder.Body.String()).NotTo(ContainSubstring("secret token"))
+			})
+		})
+	})
+
+	Describe("DELETE /user/sessions/:id", func() {
+		var (
+			handler   router.HandlerWithParams
+			sessionID bson.ObjectId
+			params    httprouter.Params
+		)
+
+		BeforeEach(func() {
+			requestMethod = "DELETE"
+			sessionID = bson.NewObjectId()
+			params = httprouter.Params{httprouter.Param{
+				Key:   "id",
+				Value: sessionID.Hex(),
+			}}
+		})
+
+		JustBeforeEach(func() {
+			handler = DeleteUserSession(sessionManager, usersCollection, sessions, auditLog)
+		})
+
+		Context("with the session existing", func() {
+			BeforeEach(func() {
+				sessions.On("Remove", sessionID, user.ID).Return(nil)
+			})
+
+			It("should end the session", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err).To(BeNil())
+			})
+		})
+
+		Context("with the session not existing or belonging to someone else", func() {
+			BeforeEach(func() {
+				sessions.On("Remove", sessionID, user.ID).Return(mgo.ErrNotFound)
+			})
+
+			It("should fail with StatusNotFound", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("with an invalid ID", func() {
+			BeforeEach(func() {
+				params[0].Value = "invalid"
+			})
+
+			It("should fail with StatusBadRequest", func() {
+				err := handler(responseRecorder, request, params)
+				Expect(err.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+})
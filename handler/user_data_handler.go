@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Lunchr/luncher-api/audit"
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/session"
+	"github.com/Lunchr/luncher-api/userdata"
+)
+
+// UserDataExport returns a handler that responds with an archive of everything associated with the logged
+// in user, as a JSON file attachment
+func UserDataExport(sessionManager session.Manager, users db.Users, userData userdata.Controller,
+	auditLog audit.Log) router.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request, user *model.User) *router.HandlerError {
+		return exportUserData(w, userData, user.ID)
+	}
+	return checkLogin(sessionManager, users, audited(auditLog, "user.export", handler))
+}
+
+// DeleteUser returns a handler that deletes the logged in user's account and ends the session. Users who are
+// the only owners of restaurants have to hand the restaurants over or delete them first.
+func DeleteUser(sessionManager session.Manager, users db.Users, userData userdata.Controller,
+	auditLog audit.Log) router.HandlerWithParams {
+	handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params, user *model.User) *router.HandlerError {
+		if currentSession := resolvedSession(r); currentSession.ImpersonatorID != "" {
+			return router.NewSimpleHandlerError("Impersonated users can only be deleted through the administration API",
+				http.StatusForbidden)
+		}
+		if handlerErr := deleteUserData(userData, user.ID); handlerErr != nil {
+			return handlerErr
+		}
+		if err := sessionManager.End(w, r); err != nil {
+			return router.NewHandlerError(err, "Failed to end the session", http.StatusInternalServerError)
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return checkLoginWithParams(sessionManager, users, auditedWithParams(auditLog, "user.delete", handler))
+}
+
+func exportUserData(w http.ResponseWriter, userData userdata.Controller, userID bson.ObjectId) *router.HandlerError {
+	archive, err := userData.Export(userID)
+	if err == mgo.ErrNotFound {
+		return router.NewHandlerError(err, "Failed to find the specified user", http.StatusNotFound)
+	} else if err != nil {
+		return router.NewHandlerError(err, "Failed to export the user's data", http.StatusInternalServerError)
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"luncher-user-%s.json\"", userID.Hex()))
+	return writeJSON(w, archive)
+}
+
+func deleteUserData(userData userdata.Controller, userID bson.ObjectId) *router.HandlerError {
+	err := userData.Delete(userID)
+	if soleOwnerErr, ok := err.(*userdata.SoleOwnerError); ok {
+		return router.NewHandlerError(err, soleOwnerErr.Error(), http.StatusConflict)
+	} else if err == mgo.ErrNotFound {
+		return router.NewHandlerError(err, "Failed to find the specified user", http.StatusNotFound)
+	} else if err != nil {
+		return router.NewHandlerError(err, "Failed to delete the user's data", http.StatusInternalServerError)
+	}
+	return nil
+}
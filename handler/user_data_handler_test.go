@@ -0,0 +1,129 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/handler/mocks"
+	"github.com/Lunchr/luncher-api/router"
+	"github.com/Lunchr/luncher-api/userdata"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UserDataHandler", func() {
+	var (
+		sessionManager  *mocks.Manager
+		usersCollection *mocks.Users
+		userData        *mocks.Controller
+		user            *model.User
+		currentSession  *model.Session
+	)
+
+	BeforeEach(func() {
+		sessionManager = new(mocks.Manager)
+		usersCollection = new(mocks.Users)
+		userData = new(mocks.Controller)
+		user = &model.User{
+			ID:    bson.NewObjectId(),
+			Email: "user@example.com",
+		}
+		currentSession = &model.Session{
+			ID:     bson.NewObjectId(),
+			UserID: user.ID,
+		}
+		sessionManager.On("Resolve", mock.Anything).Return(currentSession, nil)
+		usersCollection.On("GetID", user.ID).Return(user, nil)
+		requestQuery = url.Values{}
+	})
+
+	AfterEach(func() {
+		userData.AssertExpectations(GinkgoT())
+	})
+
+	Describe("GET /user/export", func() {
+		var handler router.Handler
+
+		BeforeEach(func() {
+			requestMethod = "GET"
+			userData.On("Export", user.ID).Return(&userdata.Archive{
+				User: userdata.User{ID: user.ID, Email: user.Email},
+			}, nil)
+		})
+
+		JustBeforeEach(func() {
+			handler = UserDataExport(sessionManager, usersCollection, userData, auditLog)
+		})
+
+		It("should respond with the user's data as an attachment", func() {
+			err := handler(responseRecorder, request)
+			Expect(err).To(BeNil())
+			Expect(responseRecorder.Header().Get("Content-Disposition")).To(ContainSubstring("attachment"))
+			var result map[string]interface{}
+			json.Unmarshal(responseRecorder.Body.Bytes(), &result)
+			Expect(result["user"]).To(HaveKeyWithValue("email", "user@example.com"))
+		})
+
+		It("should record the export in the audit log", func() {
+			handler(responseRecorder, request)
+			Expect(auditEntries).To(HaveLen(1))
+			Expect(auditEntries[0].Action).To(Equal("user.export"))
+		})
+	})
+
+	Describe("DELETE /user", func() {
+		var handler router.HandlerWithParams
+
+		BeforeEach(func() {
+			requestMethod = "DELETE"
+		})
+
+		JustBeforeEach(func() {
+			handler = DeleteUser(sessionManager, usersCollection, userData, auditLog)
+		})
+
+		It("should delete the user and end the session", func() {
+			userData.On("Delete", user.ID).Return(nil)
+			sessionManager.On("End", responseRecorder, mock.AnythingOfType("*http.Request")).Return(nil)
+			err := handler(responseRecorder, request, nil)
+			Expect(err).To(BeNil())
+			sessionManager.AssertExpectations(GinkgoT())
+		})
+
+		Context("with the user being the only owner of a restaurant", func() {
+			var restaurantID bson.ObjectId
+
+			BeforeEach(func() {
+				restaurantID = bson.NewObjectId()
+				userData.On("Delete", user.ID).Return(&userdata.SoleOwnerError{
+					RestaurantIDs: []bson.ObjectId{restaurantID},
+				})
+			})
+
+			It("should fail with StatusConflict and name the restaurant", func() {
+				err := handler(responseRecorder, request, nil)
+				Expect(err.Code).To(Equal(http.StatusConflict))
+				Expect(err.Message).To(ContainSubstring(restaurantID.Hex()))
+				sessionManager.AssertNotCalled(GinkgoT(), "End", mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("with an impersonating administrator", func() {
+			BeforeEach(func() {
+				currentSession.ImpersonatorID = bson.NewObjectId()
+			})
+
+			It("should be forbidden", func() {
+				err := handler(responseRecorder, request, nil)
+				Expect(err.Code).To(Equal(http.StatusForbidden))
+				userData.AssertNotCalled(GinkgoT(), "Delete", mock.Anything)
+			})
+		})
+	})
+})
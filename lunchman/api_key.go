@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/deiwin/interact"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type APIKey struct {
+	Actor                 interact.Actor
+	Collection            db.APIKeys
+	UsersCollection       db.Users
+	RestaurantsCollection db.Restaurants
+}
+
+var checkIsAPIKeyRole = func(i string) error {
+	if !model.IsAllowedAPIKeyRole(model.Role(i)) {
+		return errors.New("Must be either viewer or editor")
+	}
+	return nil
+}
+
+func (a APIKey) Add() {
+	restaurantID := promptOrExit(a.Actor, "Please enter the ID of the restaurant the key is for", checkNotEmpty, checkIsObjectID)
+	restaurant, err := a.RestaurantsCollection.GetID(bson.ObjectIdHex(restaurantID))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	userID := promptOrExit(a.Actor, "Please enter the ID of the user the key acts on behalf of", checkNotEmpty, checkIsObjectID)
+	user, err := a.UsersCollection.GetID(bson.ObjectIdHex(userID))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	name := promptOrExit(a.Actor, "Please enter a name for the key", checkNotEmpty)
+	role := promptOptionalOrExit(a.Actor, "Please enter the key's role (viewer or editor)", string(model.RoleViewer), checkIsAPIKeyRole)
+
+	apiKey, key, err := model.NewAPIKey(restaurant.ID, user.ID, name, model.Role(role))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	confirmDBInsertion(a.Actor, apiKey)
+	if _, err = a.Collection.Insert(apiKey); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("API key %s successfully created and added! It won't be shown again.\n", key)
+}
+
+func (a APIKey) List(restaurantID string) {
+	if err := checkIsObjectID(restaurantID); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	apiKeys, err := a.Collection.GetForRestaurant(bson.ObjectIdHex(restaurantID))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("Listing the API keys' IDs, prefixes, names and roles:")
+	for _, apiKey := range apiKeys {
+		fmt.Printf("%s - %s… - %s (%s)\n", apiKey.ID.Hex(), apiKey.Prefix, apiKey.Name, apiKey.Role)
+	}
+}
+
+func (a APIKey) Revoke(restaurantID, id string) {
+	for _, i := range []string{restaurantID, id} {
+		if err := checkIsObjectID(i); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if err := a.Collection.Remove(bson.ObjectIdHex(id), bson.ObjectIdHex(restaurantID)); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("API key successfully revoked!")
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/Lunchr/luncher-api/db"
 	"github.com/Lunchr/luncher-api/geo"
+	"github.com/Lunchr/luncher-api/userdata"
 	"github.com/deiwin/interact"
 	"gopkg.in/alecthomas/kingpin.v1"
 	"gopkg.in/mgo.v2/bson"
@@ -19,15 +20,20 @@ var (
 	add                  = lunchman.Command("add", "Add a new value to the DB")
 	addRegion            = add.Command("region", "Add a region")
 	addRestaurant        = add.Command("restaurant", "Add a restarant")
+	addRestaurantRefresh = addRestaurant.Flag("refresh-geocode", "Ignore the cached geocoding results for the address").Bool()
 	addUser              = add.Command("user", "Add a user")
 	addTag               = add.Command("tag", "Add a tag")
 	addRegistrationToken = add.Command("token", "Create and add a new registration access token")
+	addAPIKey            = add.Command("apikey", "Create and add a new API key for a restaurant")
 
 	list            = lunchman.Command("list", "List the current values in DB")
 	listRegions     = list.Command("regions", "List all regions")
 	listRestaurants = list.Command("restaurants", "List all restaurants")
 	listUsers       = list.Command("users", "List all users")
 	listTags        = list.Command("tags", "List all tags")
+	listTokens      = list.Command("tokens", "List all registration access tokens")
+	listAPIKeys     = list.Command("apikeys", "List a restaurant's API keys")
+	listAPIKeysID   = listAPIKeys.Arg("restaurantid", "The restaurant's ID").Required().String()
 
 	show             = lunchman.Command("show", "Show a specific DB item")
 	showRegion       = show.Command("region", "Show a region")
@@ -38,16 +44,48 @@ var (
 	showUserID       = showUser.Arg("facebookid", "The user's Facebook ID").Required().String()
 	showTag          = show.Command("tag", "Show a tag")
 	showTagName      = showTag.Arg("name", "The tag's name").Required().String()
+	showToken        = show.Command("token", "Show a registration access token")
+	showTokenValue   = showToken.Arg("token", "The token").Required().String()
 
-	edit             = lunchman.Command("edit", "Edit a specific DB item")
-	editRegion       = edit.Command("region", "Edit a region")
-	editRegionName   = editRegion.Arg("name", "The region's name").Required().String()
-	editRestaurant   = edit.Command("restaurant", "Edit a restaurant")
-	editRestaurantID = editRestaurant.Arg("id", "The restaurant's ID").Required().String()
-	editUser         = edit.Command("user", "Edit a user")
-	editUserID       = editUser.Arg("facebookid", "The user's Facebook ID").Required().String()
-	editTag          = edit.Command("tag", "Edit a tag")
-	editTagName      = editTag.Arg("name", "The tag's name").Required().String()
+	edit                  = lunchman.Command("edit", "Edit a specific DB item")
+	editRegion            = edit.Command("region", "Edit a region")
+	editRegionName        = editRegion.Arg("name", "The region's name").Required().String()
+	editRestaurant        = edit.Command("restaurant", "Edit a restaurant")
+	editRestaurantID      = editRestaurant.Arg("id", "The restaurant's ID").Required().String()
+	editRestaurantRefresh = editRestaurant.Flag("refresh-geocode", "Ignore the cached geocoding results for the address").Bool()
+	editUser              = edit.Command("user", "Edit a user")
+	editUserID            = editUser.Arg("facebookid", "The user's Facebook ID").Required().String()
+	editTag               = edit.Command("tag", "Edit a tag")
+	editTagName           = editTag.Arg("name", "The tag's name").Required().String()
+
+	revoke                   = lunchman.Command("revoke", "Revoke a specific DB item")
+	revokeAPIKey             = revoke.Command("apikey", "Revoke a restaurant's API key")
+	revokeAPIKeyRestaurantID = revokeAPIKey.Arg("restaurantid", "The restaurant's ID").Required().String()
+	revokeAPIKeyID           = revokeAPIKey.Arg("id", "The API key's ID").Required().String()
+	revokeToken              = revoke.Command("token", "Revoke an unused registration access token")
+	revokeTokenValue         = revokeToken.Arg("token", "The token").Required().String()
+	revokeAdmin              = revoke.Command("admin", "Revoke a user's access to the administration API")
+	revokeAdminUserID        = revokeAdmin.Arg("userid", "The user's ID").Required().String()
+
+	grant            = lunchman.Command("grant", "Grant a specific privilege")
+	grantAdmin       = grant.Command("admin", "Grant a user access to the administration API")
+	grantAdminUserID = grantAdmin.Arg("userid", "The user's ID").Required().String()
+
+	extend           = lunchman.Command("extend", "Extend the validity of a specific DB item")
+	extendToken      = extend.Command("token", "Restart the TTL of an unused registration access token")
+	extendTokenValue = extendToken.Arg("token", "The token").Required().String()
+
+	export           = lunchman.Command("export", "Export data from the DB")
+	exportUser       = export.Command("user", "Export everything associated with a user as JSON")
+	exportUserID     = exportUser.Arg("userid", "The user's ID").Required().String()
+	exportUserOutput = exportUser.Flag("output", "Write the export to the file instead of the standard output").Short('o').String()
+
+	del          = lunchman.Command("delete", "Delete a specific DB item")
+	deleteUser   = del.Command("user", "Delete a user's account along with their memberships, sessions and tokens")
+	deleteUserID = deleteUser.Arg("userid", "The user's ID").Required().String()
+
+	migrate            = lunchman.Command("migrate", "Migrate the data in the DB")
+	migrateMemberships = migrate.Command("memberships", "Make the users owners of the restaurants they're linked to directly or through FB pages")
 
 	checkNotEmpty = func(i string) error {
 		if i == "" {
@@ -85,7 +123,7 @@ func main() {
 		region := initRegion(actor, dbClient)
 		region.Add()
 	case addRestaurant.FullCommand():
-		restaurant := initRestaurant(actor, dbClient)
+		restaurant := initRestaurant(actor, dbClient, *addRestaurantRefresh)
 		restaurant.Add()
 	case addUser.FullCommand():
 		user := initUser(actor, dbClient)
@@ -94,14 +132,17 @@ func main() {
 		tag := initTag(actor, dbClient)
 		tag.Add()
 	case addRegistrationToken.FullCommand():
-		token := initRegistrationToken(dbClient)
+		token := initRegistrationToken(actor, dbClient)
 		token.CreateAndAdd()
+	case addAPIKey.FullCommand():
+		apiKey := initAPIKey(actor, dbClient)
+		apiKey.Add()
 
 	case listRegions.FullCommand():
 		region := initRegion(actor, dbClient)
 		region.List()
 	case listRestaurants.FullCommand():
-		restaurant := initRestaurant(actor, dbClient)
+		restaurant := initRestaurant(actor, dbClient, false)
 		restaurant.List()
 	case listUsers.FullCommand():
 		user := initUser(actor, dbClient)
@@ -109,12 +150,18 @@ func main() {
 	case listTags.FullCommand():
 		tag := initTag(actor, dbClient)
 		tag.List()
+	case listTokens.FullCommand():
+		token := initRegistrationToken(actor, dbClient)
+		token.List()
+	case listAPIKeys.FullCommand():
+		apiKey := initAPIKey(actor, dbClient)
+		apiKey.List(*listAPIKeysID)
 
 	case showRegion.FullCommand():
 		region := initRegion(actor, dbClient)
 		region.Show(*showRegionName)
 	case showRestaurant.FullCommand():
-		restaurant := initRestaurant(actor, dbClient)
+		restaurant := initRestaurant(actor, dbClient, false)
 		restaurant.Show(*showRestaurantID)
 	case showUser.FullCommand():
 		user := initUser(actor, dbClient)
@@ -122,12 +169,15 @@ func main() {
 	case showTag.FullCommand():
 		tag := initTag(actor, dbClient)
 		tag.Show(*showTagName)
+	case showToken.FullCommand():
+		token := initRegistrationToken(actor, dbClient)
+		token.Show(*showTokenValue)
 
 	case editRegion.FullCommand():
 		region := initRegion(actor, dbClient)
 		region.Edit(*editRegionName)
 	case editRestaurant.FullCommand():
-		restaurant := initRestaurant(actor, dbClient)
+		restaurant := initRestaurant(actor, dbClient, *editRestaurantRefresh)
 		restaurant.Edit(*editRestaurantID)
 	case editUser.FullCommand():
 		user := initUser(actor, dbClient)
@@ -135,6 +185,36 @@ func main() {
 	case editTag.FullCommand():
 		tag := initTag(actor, dbClient)
 		tag.Edit(*editTagName)
+
+	case revokeAPIKey.FullCommand():
+		apiKey := initAPIKey(actor, dbClient)
+		apiKey.Revoke(*revokeAPIKeyRestaurantID, *revokeAPIKeyID)
+	case revokeToken.FullCommand():
+		token := initRegistrationToken(actor, dbClient)
+		token.Revoke(*revokeTokenValue)
+	case revokeAdmin.FullCommand():
+		user := initUser(actor, dbClient)
+		user.SetAdmin(*revokeAdminUserID, false)
+
+	case grantAdmin.FullCommand():
+		user := initUser(actor, dbClient)
+		user.SetAdmin(*grantAdminUserID, true)
+
+	case extendToken.FullCommand():
+		token := initRegistrationToken(actor, dbClient)
+		token.Extend(*extendTokenValue)
+
+	case exportUser.FullCommand():
+		userData := initUserData(actor, dbClient)
+		userData.Export(*exportUserID, *exportUserOutput)
+
+	case deleteUser.FullCommand():
+		userData := initUserData(actor, dbClient)
+		userData.Delete(*deleteUserID)
+
+	case migrateMemberships.FullCommand():
+		migration := initMigration(dbClient)
+		migration.Memberships()
 	}
 }
 
@@ -143,18 +223,47 @@ func initRegion(actor interact.Actor, dbClient *db.Client) Region {
 	return Region{actor, regionsCollection}
 }
 
-func initRestaurant(actor interact.Actor, dbClient *db.Client) Restaurant {
+func initRestaurant(actor interact.Actor, dbClient *db.Client, refreshGeocodes bool) Restaurant {
 	restaurantsCollection := db.NewRestaurants(dbClient)
 	regionsCollection := db.NewRegions(dbClient)
+	offersCollection, err := db.NewOffers(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	geocodesCollection, err := db.NewGeocodes(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	geoConf := geo.NewConfig()
-	geocoder := geo.NewCoder(geoConf)
-	return Restaurant{actor, restaurantsCollection, regionsCollection, geocoder}
+	uncachedGeocoder, err := geo.NewCoder(geoConf)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	cachingGeocoder := geo.NewCachingCoder(uncachedGeocoder, geoConf.Provider, geocodesCollection, geo.DefaultCacheTTL,
+		geo.DefaultNegativeCacheTTL)
+	var geocoder geo.Coder = cachingGeocoder
+	if refreshGeocodes {
+		geocoder = cachingGeocoder.Bypassing()
+	}
+	return Restaurant{actor, restaurantsCollection, regionsCollection, offersCollection, geocoder}
 }
 
 func initUser(actor interact.Actor, dbClient *db.Client) User {
-	usersCollection := db.NewUsers(dbClient)
+	usersCollection, err := db.NewUsers(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	restaurantsCollection := db.NewRestaurants(dbClient)
-	return User{actor, usersCollection, restaurantsCollection}
+	membershipsCollection, err := db.NewMemberships(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return User{actor, usersCollection, restaurantsCollection, membershipsCollection}
 }
 
 func initTag(actor interact.Actor, dbClient *db.Client) Tag {
@@ -162,13 +271,111 @@ func initTag(actor interact.Actor, dbClient *db.Client) Tag {
 	return Tag{actor, tagsCollection}
 }
 
-func initRegistrationToken(dbClient *db.Client) RegistrationToken {
+func initRegistrationToken(actor interact.Actor, dbClient *db.Client) RegistrationToken {
 	collection, err := db.NewRegistrationAccessTokens(dbClient)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
-	return RegistrationToken{collection}
+	usersCollection, err := db.NewUsers(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return RegistrationToken{actor, collection, usersCollection}
+}
+
+func initAPIKey(actor interact.Actor, dbClient *db.Client) APIKey {
+	apiKeysCollection, err := db.NewAPIKeys(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	usersCollection, err := db.NewUsers(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	restaurantsCollection := db.NewRestaurants(dbClient)
+	return APIKey{actor, apiKeysCollection, usersCollection, restaurantsCollection}
+}
+
+func initUserData(actor interact.Actor, dbClient *db.Client) UserData {
+	usersCollection, err := db.NewUsers(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	membershipsCollection, err := db.NewMemberships(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	offersCollection, err := db.NewOffers(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	sessionsCollection, err := db.NewSessions(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	emailTokensCollection, err := db.NewEmailTokens(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	registrationTokensCollection, err := db.NewRegistrationAccessTokens(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	invitesCollection, err := db.NewInvites(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	publishJobsCollection, err := db.NewPublishJobs(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	apiKeysCollection, err := db.NewAPIKeys(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	impersonationsCollection, err := db.NewImpersonations(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	auditEntriesCollection, err := db.NewAuditEntries(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	controller := userdata.NewController(usersCollection, membershipsCollection, db.NewRestaurants(dbClient),
+		offersCollection, db.NewOfferGroupPosts(dbClient), sessionsCollection, emailTokensCollection,
+		registrationTokensCollection, invitesCollection, apiKeysCollection, publishJobsCollection,
+		impersonationsCollection, auditEntriesCollection)
+	return UserData{actor, controller}
+}
+
+func initMigration(dbClient *db.Client) Migration {
+	usersCollection, err := db.NewUsers(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	membershipsCollection, err := db.NewMemberships(dbClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	restaurantsCollection := db.NewRestaurants(dbClient)
+	return Migration{usersCollection, restaurantsCollection, membershipsCollection}
 }
 
 func confirmDBInsertion(actor interact.Actor, o interface{}) {
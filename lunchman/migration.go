@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type Migration struct {
+	UsersCollection       db.Users
+	RestaurantsCollection db.Restaurants
+	MembershipsCollection db.Memberships
+}
+
+// Memberships makes the users owners of all the restaurants they are linked to either directly or through
+// the access tokens of the restaurants' FB pages. The direct links are removed afterwards, so that the
+// roles could be managed through the memberships alone.
+func (m Migration) Memberships() {
+	users := m.getAllUsers()
+	count := 0
+	for _, user := range users {
+		restaurantIDs, err := m.linkedRestaurantIDs(user)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		for _, restaurantID := range restaurantIDs {
+			if err = m.MembershipsCollection.Set(user.ID, restaurantID, model.RoleOwner); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			count++
+		}
+		if len(user.RestaurantIDs) > 0 {
+			if err = m.UsersCollection.UnsetRestaurantIDs(user.ID); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+	}
+	fmt.Printf("Created or updated %d memberships for %d users\n", count, len(users))
+}
+
+func (m Migration) linkedRestaurantIDs(user *model.User) ([]bson.ObjectId, error) {
+	restaurantIDs := user.RestaurantIDs
+	if len(user.Session.FacebookPageTokens) == 0 {
+		return restaurantIDs, nil
+	}
+	pageIDs := make([]string, len(user.Session.FacebookPageTokens))
+	for i, pageToken := range user.Session.FacebookPageTokens {
+		pageIDs[i] = pageToken.PageID
+	}
+	restaurants, err := m.RestaurantsCollection.GetByFacebookPageIDs(pageIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, restaurant := range restaurants {
+		restaurantIDs = append(restaurantIDs, restaurant.ID)
+	}
+	return restaurantIDs, nil
+}
+
+func (m Migration) getAllUsers() []*model.User {
+	iter := m.UsersCollection.GetAll()
+	var users []*model.User
+	for {
+		var user model.User
+		if !iter.Next(&user) {
+			break
+		}
+		users = append(users, &user)
+	}
+	if err := iter.Close(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return users
+}
@@ -3,13 +3,33 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/Lunchr/luncher-api/db"
 	"github.com/Lunchr/luncher-api/db/model"
+	"github.com/deiwin/interact"
+	"gopkg.in/mgo.v2"
 )
 
+const timeFormat = "2006-01-02 15:04"
+
 type RegistrationToken struct {
-	Collection db.RegistrationAccessTokens
+	Actor           interact.Actor
+	Collection      db.RegistrationAccessTokens
+	UsersCollection db.Users
+}
+
+var maxTokenValidityDays = int(model.RegistrationAccessTokenTTL / (24 * time.Hour))
+
+var checkIsEmptyOrValidityDays = func(i string) error {
+	if i == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(i); err != nil || n <= 0 || n > maxTokenValidityDays {
+		return fmt.Errorf("Must be a number between 1 and %d", maxTokenValidityDays)
+	}
+	return nil
 }
 
 func (r RegistrationToken) CreateAndAdd() {
@@ -18,9 +38,97 @@ func (r RegistrationToken) CreateAndAdd() {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	token.Notes = promptOptionalOrExit(r.Actor, "Please enter notes about who the token is for", "")
+	token.ExpiresAt = r.promptExpiresAt(token.CreatedAt)
 	if _, err = r.Collection.Insert(token); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 	fmt.Printf("Token %s successfully created and added!\n", token.Token.String())
 }
+
+func (r RegistrationToken) List() {
+	iter := r.Collection.GetAll()
+	var token model.RegistrationAccessToken
+	fmt.Println("Listing the tokens with their creation times, expiry times, users and notes:")
+	for iter.Next(&token) {
+		fmt.Printf("%s - %s - %s - %s - %s\n", token.Token.String(), token.CreatedAt.Format(timeFormat),
+			token.Expiry().Format(timeFormat), r.usedBy(&token), token.Notes)
+		token = model.RegistrationAccessToken{}
+	}
+	if err := iter.Close(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func (r RegistrationToken) Show(tokenString string) {
+	token := r.getToken(tokenString)
+	fmt.Println(pretty(token))
+	fmt.Printf("Expires at: %s\n", token.Expiry().Format(timeFormat))
+	fmt.Printf("Used by: %s\n", r.usedBy(token))
+}
+
+func (r RegistrationToken) Revoke(tokenString string) {
+	token := r.getToken(tokenString)
+	if err := r.Collection.Remove(token.Token); err == mgo.ErrNotFound {
+		fmt.Println("The token has already been used")
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("Token successfully revoked!")
+}
+
+func (r RegistrationToken) Extend(tokenString string) {
+	token := r.getToken(tokenString)
+	expiresAt := r.promptExpiresAt(time.Now())
+	if err := r.Collection.Extend(token.Token, expiresAt); err == mgo.ErrNotFound {
+		fmt.Println("The token has already been used")
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("Token successfully extended!")
+}
+
+func (r RegistrationToken) getToken(tokenString string) *model.RegistrationAccessToken {
+	t, err := model.TokenFromString(tokenString)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	token, err := r.Collection.Get(t)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return token
+}
+
+// promptExpiresAt returns a zero time if the token should be valid for the full TTL
+func (r RegistrationToken) promptExpiresAt(from time.Time) time.Time {
+	days := promptOptionalOrExit(r.Actor, fmt.Sprintf("Please enter the number of days the token is valid for (at most %d)",
+		maxTokenValidityDays), "", checkIsEmptyOrValidityDays)
+	if days == "" {
+		return time.Time{}
+	}
+	n, _ := strconv.Atoi(days)
+	return from.Add(time.Duration(n) * 24 * time.Hour)
+}
+
+func (r RegistrationToken) usedBy(token *model.RegistrationAccessToken) string {
+	if token.ClaimedBy == "" {
+		return "unused"
+	}
+	user, err := r.UsersCollection.GetID(token.ClaimedBy)
+	if err == mgo.ErrNotFound {
+		return fmt.Sprintf("a removed user %s", token.ClaimedBy.Hex())
+	} else if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return fmt.Sprintf("user %s (Facebook ID %s) at %s", user.ID.Hex(), user.FacebookUserID, token.ClaimedAt.Format(timeFormat))
+}
@@ -17,6 +17,7 @@ type Restaurant struct {
 	Actor             interact.Actor
 	Collection        db.Restaurants
 	RegionsCollection db.Regions
+	OffersCollection  db.Offers
 	Geocoder          geo.Coder
 }
 
@@ -89,6 +90,12 @@ func (r Restaurant) updateRestaurant(id bson.ObjectId, name, address, region str
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	restaurant.ID = id
+	err = r.OffersCollection.UpdateRestaurant(model.MapRestaurantToOfferRestaurant(restaurant))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
 
 func (r Restaurant) insertRestaurantAndGetID(name, address, region string, location geo.Location, phone, fbPageID string) bson.ObjectId {
@@ -120,20 +127,14 @@ func (r Restaurant) findLocationOrExit(address, regionName string) geo.Location
 		fmt.Println(err)
 		os.Exit(1)
 	}
-	location, err := r.Geocoder.CodeForRegion(address, region.CCTLD)
-	if err == nil {
+	candidates, err := r.Geocoder.CodeForRegion(address, region.CCTLD)
+	if err != nil {
+		fmt.Println(err)
+	} else if location, ok := geo.Unambiguous(candidates); ok {
+		return location
+	} else if location, ok := r.pickCandidateOrExit(candidates); ok {
 		return location
 	}
-	if err == geo.ErrorPartialMatch {
-		message := fmt.Sprintf("Geocoder returned a partial match of (%.6f, %.6f). Do you want to continue using the partial match?", location.Lat, location.Lng)
-		if confirmed, err := r.Actor.Confirm(message, interact.ConfirmDefaultToNo); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		} else if confirmed {
-			return location
-		}
-	}
-	fmt.Println(err)
 	shouldPromptForCoords, err := r.Actor.Confirm("Do you want to enter the coordinates manually?", interact.ConfirmDefaultToYes)
 	if err != nil {
 		fmt.Println(err)
@@ -153,6 +154,34 @@ func (r Restaurant) findLocationOrExit(address, regionName string) geo.Location
 	}
 }
 
+func (r Restaurant) pickCandidateOrExit(candidates []geo.Candidate) (geo.Location, bool) {
+	fmt.Println("Geocoder didn't find an exact match for the address. The candidates are:")
+	for i, candidate := range candidates {
+		fmt.Printf("%d - %s (%.6f, %.6f), %s match\n", i+1, candidate.FormattedAddress, candidate.Location.Lat,
+			candidate.Location.Lng, candidate.Quality)
+	}
+	choice := promptOptionalOrExit(r.Actor, "Please enter the number of the correct candidate or 0 if none of them are correct",
+		"0", checkNotEmpty, getCandidateNumberCheck(len(candidates)))
+	i, _ := strconv.Atoi(choice)
+	if i == 0 {
+		return geo.Location{}, false
+	}
+	return candidates[i-1].Location, true
+}
+
+func getCandidateNumberCheck(candidateCount int) interact.InputCheck {
+	return func(i string) error {
+		n, err := strconv.Atoi(i)
+		if err != nil {
+			return err
+		}
+		if n < 0 || n > candidateCount {
+			return fmt.Errorf("Please enter a number between 0 and %d!", candidateCount)
+		}
+		return nil
+	}
+}
+
 func (r Restaurant) getRestaurantUniquenessCheck() interact.InputCheck {
 	return func(i string) error {
 		if exists, err := r.Collection.Exists(i); err != nil {
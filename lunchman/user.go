@@ -14,6 +14,7 @@ type User struct {
 	Actor                 interact.Actor
 	Collection            db.Users
 	RestaurantsCollection db.Restaurants
+	MembershipsCollection db.Memberships
 }
 
 func (u User) Add() {
@@ -37,11 +38,11 @@ func (u User) Edit(fbUserID string) {
 
 	checkExists := u.getRestaurantExistanceCheck()
 
-	restaurantIDString := promptOptionalOrExit(u.Actor, "Please enter the restaurant's ID this user will administrate", user.RestaurantIDs[0].Hex(), checkNotEmpty, checkIsObjectID, checkExists)
+	restaurantIDString := promptOptionalOrExit(u.Actor, "Please enter the restaurant's ID this user will administrate", u.currentRestaurantID(user), checkNotEmpty, checkIsObjectID, checkExists)
 	restaurantID := bson.ObjectIdHex(restaurantIDString)
 	newFBUserID := promptOptionalOrExit(u.Actor, "Please enter the restaurant administrator's Facebook user ID", user.FacebookUserID, checkNotEmpty)
 
-	u.updateUser(fbUserID, restaurantID, newFBUserID)
+	u.updateUser(user.ID, fbUserID, restaurantID, newFBUserID)
 
 	fmt.Println("User successfully updated!")
 }
@@ -68,29 +69,76 @@ func (u User) Show(fbUserID string) {
 	fmt.Println(pretty(user))
 }
 
-func (u User) updateUser(fbUserID string, restaurantID bson.ObjectId, newFBUserID string) {
-	user := createUser(restaurantID, newFBUserID)
+// SetAdmin grants or revokes the user's access to the platform administration API
+func (u User) SetAdmin(userID string, isAdmin bool) {
+	if err := checkIsObjectID(userID); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	user, err := u.Collection.GetID(bson.ObjectIdHex(userID))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err = u.Collection.SetAdmin(user.ID, isAdmin); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if isAdmin {
+		fmt.Println("The user is now an administrator!")
+	} else {
+		fmt.Println("The user is no longer an administrator!")
+	}
+}
+
+// currentRestaurantID returns the ID of a restaurant the user already administrates, if any
+func (u User) currentRestaurantID(user *model.User) string {
+	memberships, err := u.MembershipsCollection.GetForUser(user.ID)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	for _, membership := range memberships {
+		if membership.Role == model.RoleOwner {
+			return membership.RestaurantID.Hex()
+		}
+	}
+	return ""
+}
+
+func (u User) updateUser(userID bson.ObjectId, fbUserID string, restaurantID bson.ObjectId, newFBUserID string) {
+	user := createUser(newFBUserID)
+	user.ID = userID
 	confirmDBInsertion(u.Actor, user)
 	err := u.Collection.Update(fbUserID, user)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	u.makeOwner(userID, restaurantID)
 }
 
 func (u User) insertUser(restaurantID bson.ObjectId, fbUserID string) {
-	user := createUser(restaurantID, fbUserID)
+	user := createUser(fbUserID)
 	confirmDBInsertion(u.Actor, user)
 	err := u.Collection.Insert(user)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	u.makeOwner(user.ID, restaurantID)
+}
+
+func (u User) makeOwner(userID, restaurantID bson.ObjectId) {
+	if err := u.MembershipsCollection.Set(userID, restaurantID, model.RoleOwner); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
 
-func createUser(restaurantID bson.ObjectId, fbUserID string) *model.User {
+func createUser(fbUserID string) *model.User {
 	return &model.User{
-		RestaurantIDs:  []bson.ObjectId{restaurantID},
+		ID:             bson.NewObjectId(),
 		FacebookUserID: fbUserID,
 	}
 }
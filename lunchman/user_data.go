@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/Lunchr/luncher-api/userdata"
+	"github.com/deiwin/interact"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// UserData fulfils the data subject requests of the users
+type UserData struct {
+	Actor      interact.Actor
+	Controller userdata.Controller
+}
+
+// Export prints everything associated with the user as JSON or writes it to the output file, if specified
+func (u UserData) Export(userID, output string) {
+	if err := checkIsObjectID(userID); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	archive, err := u.Controller.Export(bson.ObjectIdHex(userID))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if output == "" {
+		fmt.Println(pretty(archive))
+		return
+	}
+	if err = ioutil.WriteFile(output, []byte(pretty(archive)), 0600); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("The user's data was successfully exported to %s!\n", output)
+}
+
+// Delete removes the user's account after asking for a confirmation
+func (u UserData) Delete(userID string) {
+	if err := checkIsObjectID(userID); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	confirmationMessage := fmt.Sprintf("Going to delete the user %s along with their memberships, sessions, tokens, "+
+		"API keys and invites. This can't be undone. Are you sure you want to continue?", userID)
+	confirmed, err := u.Actor.Confirm(confirmationMessage, interact.ConfirmDefaultToNo)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	} else if !confirmed {
+		fmt.Println("Aborted")
+		os.Exit(1)
+	}
+	if err = u.Controller.Delete(bson.ObjectIdHex(userID)); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("User successfully deleted!")
+}
@@ -0,0 +1,42 @@
+package mail
+
+import "github.com/deiwin/gonfigure"
+
+const (
+	// SenderSMTP sends the emails through an SMTP server
+	SenderSMTP = "smtp"
+	// SenderLog only logs the emails, which is useful for local development
+	SenderLog = "log"
+)
+
+var (
+	senderProperty       = gonfigure.NewEnvProperty("MAIL_SENDER", SenderLog)
+	smtpAddressProperty  = gonfigure.NewEnvProperty("SMTP_ADDRESS", "localhost:1025")
+	smtpUsernameProperty = gonfigure.NewEnvProperty("SMTP_USERNAME", "")
+	smtpPasswordProperty = gonfigure.NewEnvProperty("SMTP_PASSWORD", "")
+	fromProperty         = gonfigure.NewEnvProperty("MAIL_FROM", "Luncher <noreply@localhost>")
+)
+
+type Config struct {
+	// Sender is either SenderSMTP or SenderLog
+	Sender string
+	// SMTPAddress is the host:port of the SMTP server. The default points to a
+	// local stand-in, e.g. MailHog.
+	SMTPAddress string
+	// SMTPUsername and SMTPPassword are used for PLAIN authentication if the
+	// username is set
+	SMTPUsername string
+	SMTPPassword string
+	// From is the sender address of all the emails
+	From string
+}
+
+func NewConfig() *Config {
+	return &Config{
+		Sender:       senderProperty.Value(),
+		SMTPAddress:  smtpAddressProperty.Value(),
+		SMTPUsername: smtpUsernameProperty.Value(),
+		SMTPPassword: smtpPasswordProperty.Value(),
+		From:         fromProperty.Value(),
+	}
+}
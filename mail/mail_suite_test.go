@@ -0,0 +1,13 @@
+package mail_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestMail(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mail Suite")
+}
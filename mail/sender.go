@@ -0,0 +1,100 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"sync"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender is an object that knows how to deliver emails
+type Sender interface {
+	Send(Message) error
+}
+
+// NewSender creates a Sender for the type specified in the configuration
+func NewSender(conf *Config) (Sender, error) {
+	from, err := mail.ParseAddress(conf.From)
+	if err != nil {
+		return nil, err
+	}
+	switch conf.Sender {
+	case SenderSMTP:
+		return smtpSender{conf, from}, nil
+	case SenderLog, "":
+		return NewWriterSender(logWriter{}, from), nil
+	default:
+		return nil, fmt.Errorf("Unknown mail sender: %s", conf.Sender)
+	}
+}
+
+// NewWriterSender creates a Sender that writes the emails to w instead of
+// delivering them
+func NewWriterSender(w io.Writer, from *mail.Address) Sender {
+	return &writerSender{
+		w:    w,
+		from: from,
+	}
+}
+
+type smtpSender struct {
+	conf *Config
+	from *mail.Address
+}
+
+func (s smtpSender) Send(m Message) error {
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if s.conf.SMTPUsername != "" {
+		host, _, err := net.SplitHostPort(s.conf.SMTPAddress)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.conf.SMTPUsername, s.conf.SMTPPassword, host)
+	}
+	return smtp.SendMail(s.conf.SMTPAddress, auth, s.from.Address, []string{to.Address}, format(s.from, to, m))
+}
+
+type writerSender struct {
+	sync.Mutex
+	w    io.Writer
+	from *mail.Address
+}
+
+func (s *writerSender) Send(m Message) error {
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	_, err = s.w.Write(format(s.from, to, m))
+	return err
+}
+
+func format(from, to *mail.Address, m Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(m.Body)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
@@ -0,0 +1,77 @@
+package mail_test
+
+import (
+	"bytes"
+	"mime"
+	netmail "net/mail"
+
+	. "github.com/Lunchr/luncher-api/mail"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sender", func() {
+	Describe("NewSender", func() {
+		It("should fail for an unknown sender", func() {
+			_, err := NewSender(&Config{
+				Sender: "gibberish",
+				From:   "noreply@luncher.test",
+			})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail for an invalid from address", func() {
+			_, err := NewSender(&Config{
+				Sender: SenderLog,
+				From:   "gibberish",
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("WriterSender", func() {
+		var (
+			buffer *bytes.Buffer
+			sender Sender
+		)
+
+		BeforeEach(func() {
+			buffer = new(bytes.Buffer)
+			sender = NewWriterSender(buffer, &netmail.Address{Name: "Luncher", Address: "noreply@luncher.test"})
+		})
+
+		It("should write a readable email", func() {
+			err := sender.Send(Message{
+				To:      "owner@restaurant.test",
+				Subject: "Tere tulemast Luncherisse",
+				Body:    "Hello!",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			message, err := netmail.ReadMessage(buffer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(message.Header.Get("From")).To(Equal(`"Luncher" <noreply@luncher.test>`))
+			Expect(message.Header.Get("To")).To(Equal("<owner@restaurant.test>"))
+			Expect(message.Header.Get("Subject")).To(Equal("Tere tulemast Luncherisse"))
+		})
+
+		It("should encode non-ASCII subjects", func() {
+			sender.Send(Message{
+				To:      "owner@restaurant.test",
+				Subject: "Parooli lähtestamine",
+			})
+			message, err := netmail.ReadMessage(buffer)
+			Expect(err).NotTo(HaveOccurred())
+			subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(subject).To(Equal("Parooli lähtestamine"))
+		})
+
+		It("should fail for an invalid recipient", func() {
+			err := sender.Send(Message{
+				To: "gibberish",
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -5,12 +5,16 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/Lunchr/luncher-api/audit"
 	"github.com/Lunchr/luncher-api/db"
 	luncherFacebook "github.com/Lunchr/luncher-api/facebook"
+	"github.com/Lunchr/luncher-api/geo"
 	"github.com/Lunchr/luncher-api/handler"
+	"github.com/Lunchr/luncher-api/mail"
 	"github.com/Lunchr/luncher-api/router"
 	"github.com/Lunchr/luncher-api/session"
 	"github.com/Lunchr/luncher-api/storage"
+	"github.com/Lunchr/luncher-api/userdata"
 	"github.com/deiwin/facebook"
 	"github.com/deiwin/picasso"
 )
@@ -24,7 +28,10 @@ func main() {
 	}
 	defer dbClient.Disconnect()
 
-	usersCollection := db.NewUsers(dbClient)
+	usersCollection, err := db.NewUsers(dbClient)
+	if err != nil {
+		panic(err)
+	}
 	offersCollection, err := db.NewOffers(dbClient)
 	if err != nil {
 		panic(err)
@@ -37,8 +44,48 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	geocodesCollection, err := db.NewGeocodes(dbClient)
+	if err != nil {
+		panic(err)
+	}
+	emailTokensCollection, err := db.NewEmailTokens(dbClient)
+	if err != nil {
+		panic(err)
+	}
+	membershipsCollection, err := db.NewMemberships(dbClient)
+	if err != nil {
+		panic(err)
+	}
+	invitesCollection, err := db.NewInvites(dbClient)
+	if err != nil {
+		panic(err)
+	}
+	sessionsCollection, err := db.NewSessions(dbClient)
+	if err != nil {
+		panic(err)
+	}
+	apiKeysCollection, err := db.NewAPIKeys(dbClient)
+	if err != nil {
+		panic(err)
+	}
+	impersonationsCollection, err := db.NewImpersonations(dbClient)
+	if err != nil {
+		panic(err)
+	}
+	auditEntriesCollection, err := db.NewAuditEntries(dbClient)
+	if err != nil {
+		panic(err)
+	}
+	publishJobsCollection, err := db.NewPublishJobs(dbClient)
+	if err != nil {
+		panic(err)
+	}
 
-	sessionManager := session.NewManager()
+	sessionConfig, err := session.NewConfig()
+	if err != nil {
+		panic(err)
+	}
+	sessionManager := session.NewManager(sessionsCollection, sessionConfig)
 	mainConfig, err := NewConfig()
 	if err != nil {
 		panic(err)
@@ -54,12 +101,37 @@ func main() {
 	facebookRegistrationAuthenticator := facebook.NewAuthenticator(facebookRegistrationConfig)
 
 	imageStorage := storage.NewImages()
+	geoConfig := geo.NewConfig()
+	uncachedGeocoder, err := geo.NewCoder(geoConfig)
+	if err != nil {
+		panic(err)
+	}
+	geocoder := geo.NewCachingCoder(uncachedGeocoder, geoConfig.Provider, geocodesCollection, geo.DefaultCacheTTL,
+		geo.DefaultNegativeCacheTTL)
 	collageLayout := picasso.TopHeavyLayout()
+	mailSender, err := mail.NewSender(mail.NewConfig())
+	if err != nil {
+		panic(err)
+	}
 
+	auditLog := audit.NewLog(auditEntriesCollection)
+	userData := userdata.NewController(usersCollection, membershipsCollection, restaurantsCollection, offersCollection,
+		offerGroupPostsCollection, sessionsCollection, emailTokensCollection, registrationTokensCollection,
+		invitesCollection, apiKeysCollection, publishJobsCollection, impersonationsCollection, auditEntriesCollection)
 	facebookPost := luncherFacebook.NewPost(offerGroupPostsCollection, offersCollection, regionsCollection,
-		facebookLoginAuthenticator, imageStorage, collageLayout)
+		facebookLoginAuthenticator, imageStorage, collageLayout, auditLog)
+	facebookPublishQueue := luncherFacebook.NewPublishQueue(publishJobsCollection, facebookPost, usersCollection,
+		restaurantsCollection)
+	go facebookPublishQueue.Run(luncherFacebook.PublishWorkers)
+	facebookConfig, err := luncherFacebook.NewConfig()
+	if err != nil {
+		panic(err)
+	}
+	facebookTokenDebugger := luncherFacebook.NewTokenDebugger(facebookConfig)
+	facebookTokenMonitor := luncherFacebook.NewTokenMonitor(usersCollection, facebookTokenDebugger)
+	go facebookTokenMonitor.Run(luncherFacebook.TokenCheckInterval)
 
-	r := router.NewWithPrefix("/api/v1/")
+	r := router.NewWithPrefix("/api/v1/", session.NewCSRFGuard(sessionConfig))
 	r.GET(
 		"/regions",
 		handler.Regions(regionsCollection),
@@ -68,24 +140,36 @@ func main() {
 		"/regions/:name/offers",
 		handler.RegionOffers(offersCollection, regionsCollection, imageStorage),
 	)
+	r.GETWithParams(
+		"/regions/:name/restaurants",
+		handler.RegionRestaurants(restaurantsCollection, regionsCollection),
+	)
 	r.GET(
 		"/offers",
 		handler.ProximalOffers(offersCollection, imageStorage),
 	)
 	r.POSTWithParams(
 		"/restaurants/:restaurantID/offers",
-		handler.PostOffers(offersCollection, usersCollection, restaurantsCollection, sessionManager, imageStorage,
-			facebookPost, regionsCollection),
+		handler.PostOffers(offersCollection, usersCollection, membershipsCollection, apiKeysCollection,
+			restaurantsCollection, sessionManager, imageStorage, facebookPublishQueue, regionsCollection, auditLog),
 	)
 	r.PUT(
 		"/restaurants/:restaurantID/offers/:id",
-		handler.PutOffers(offersCollection, usersCollection, restaurantsCollection, sessionManager, imageStorage,
-			facebookPost, regionsCollection),
+		handler.PutOffers(offersCollection, usersCollection, membershipsCollection, apiKeysCollection,
+			restaurantsCollection, sessionManager, imageStorage, facebookPublishQueue, regionsCollection, auditLog),
 	)
 	r.DELETE(
 		"/restaurants/:restaurantID/offers/:id",
-		handler.DeleteOffers(offersCollection, usersCollection, sessionManager, restaurantsCollection, facebookPost,
-			regionsCollection),
+		handler.DeleteOffers(offersCollection, usersCollection, membershipsCollection, apiKeysCollection, sessionManager,
+			restaurantsCollection, facebookPublishQueue, regionsCollection, auditLog),
+	)
+	r.GET(
+		"/geo/reverse",
+		handler.ReverseGeocode(sessionManager, usersCollection, regionsCollection, geocoder),
+	)
+	r.GET(
+		"/geo/suggest",
+		handler.SuggestAddresses(sessionManager, usersCollection, regionsCollection, geocoder),
 	)
 	r.GET(
 		"/tags",
@@ -93,38 +177,138 @@ func main() {
 	)
 	r.GET(
 		"/user/restaurants",
-		handler.UserRestaurants(restaurantsCollection, sessionManager, usersCollection),
+		handler.UserRestaurants(restaurantsCollection, sessionManager, usersCollection, membershipsCollection),
+	)
+	r.GET(
+		"/user/export",
+		handler.UserDataExport(sessionManager, usersCollection, userData, auditLog),
+	)
+	r.DELETE(
+		"/user",
+		handler.DeleteUser(sessionManager, usersCollection, userData, auditLog),
+	)
+	r.GET(
+		"/user/sessions",
+		handler.UserSessions(sessionManager, usersCollection, sessionsCollection),
+	)
+	r.DELETE(
+		"/user/sessions/:id",
+		handler.DeleteUserSession(sessionManager, usersCollection, sessionsCollection, auditLog),
 	)
 	r.GETWithParams(
 		"/restaurants/:restaurantID",
-		handler.Restaurant(restaurantsCollection, sessionManager, usersCollection),
+		handler.Restaurant(restaurantsCollection, sessionManager, usersCollection, membershipsCollection,
+			apiKeysCollection),
+	)
+	r.PUT(
+		"/restaurants/:restaurantID",
+		handler.PutRestaurant(restaurantsCollection, sessionManager, usersCollection, membershipsCollection,
+			apiKeysCollection, offersCollection, regionsCollection, geocoder, auditLog),
+	)
+	r.POSTWithParams(
+		"/restaurants/:restaurantID/deactivate",
+		handler.DeactivateRestaurant(restaurantsCollection, sessionManager, usersCollection, membershipsCollection,
+			apiKeysCollection, offersCollection, auditLog),
+	)
+	r.POSTWithParams(
+		"/restaurants/:restaurantID/reactivate",
+		handler.ReactivateRestaurant(restaurantsCollection, sessionManager, usersCollection, membershipsCollection,
+			apiKeysCollection, offersCollection, auditLog),
+	)
+	r.DELETE(
+		"/restaurants/:restaurantID",
+		handler.DeleteRestaurant(restaurantsCollection, sessionManager, usersCollection, membershipsCollection,
+			apiKeysCollection, offersCollection, offerGroupPostsCollection, invitesCollection, publishJobsCollection,
+			facebookPost, auditLog),
+	)
+	r.GETWithParams(
+		"/restaurants/:restaurantID/invites",
+		handler.RestaurantInvites(sessionManager, usersCollection, membershipsCollection, apiKeysCollection,
+			restaurantsCollection, invitesCollection),
+	)
+	r.POSTWithParams(
+		"/restaurants/:restaurantID/invites",
+		handler.PostRestaurantInvite(sessionManager, usersCollection, membershipsCollection, apiKeysCollection,
+			restaurantsCollection, invitesCollection, mailSender, mainConfig.Domain+"/#/invites/accept", auditLog),
+	)
+	r.DELETE(
+		"/restaurants/:restaurantID/invites/:id",
+		handler.DeleteRestaurantInvite(sessionManager, usersCollection, membershipsCollection, apiKeysCollection,
+			restaurantsCollection, invitesCollection, auditLog),
+	)
+	r.PUT(
+		"/restaurants/:restaurantID/collage_settings",
+		handler.PutRestaurantCollageSettings(restaurantsCollection, sessionManager, usersCollection, membershipsCollection,
+			apiKeysCollection, offersCollection, regionsCollection, imageStorage, facebookPublishQueue, auditLog),
+	)
+	r.GETWithParams(
+		"/restaurants/:restaurantID/api_keys",
+		handler.RestaurantAPIKeys(sessionManager, usersCollection, membershipsCollection, apiKeysCollection,
+			restaurantsCollection),
+	)
+	r.POSTWithParams(
+		"/restaurants/:restaurantID/api_keys",
+		handler.PostRestaurantAPIKey(sessionManager, usersCollection, membershipsCollection, apiKeysCollection,
+			restaurantsCollection, auditLog),
+	)
+	r.DELETE(
+		"/restaurants/:restaurantID/api_keys/:id",
+		handler.DeleteRestaurantAPIKey(sessionManager, usersCollection, membershipsCollection, apiKeysCollection,
+			restaurantsCollection, auditLog),
+	)
+	r.GETWithParams(
+		"/restaurants/:restaurantID/audit_log",
+		handler.RestaurantAuditLog(sessionManager, usersCollection, membershipsCollection, apiKeysCollection,
+			restaurantsCollection, auditEntriesCollection),
+	)
+	r.POST(
+		"/invites/accept",
+		handler.AcceptInvite(sessionManager, usersCollection, membershipsCollection, restaurantsCollection,
+			invitesCollection, auditLog),
+	)
+	r.GETWithParams(
+		"/public/restaurants/:id",
+		handler.PublicRestaurant(restaurantsCollection, offersCollection, regionsCollection, imageStorage),
 	)
 	r.POST(
 		"/restaurants",
-		handler.PostRestaurants(restaurantsCollection, sessionManager, usersCollection, facebookLoginAuthenticator),
+		handler.PostRestaurants(restaurantsCollection, sessionManager, usersCollection, membershipsCollection,
+			facebookLoginAuthenticator, regionsCollection, geocoder, auditLog),
 	)
 	r.GETWithParams(
 		"/restaurants/:restaurantID/offers",
-		handler.RestaurantOffers(restaurantsCollection, sessionManager, usersCollection, offersCollection,
-			imageStorage, regionsCollection),
+		handler.RestaurantOffers(restaurantsCollection, sessionManager, usersCollection, membershipsCollection,
+			apiKeysCollection, offersCollection, imageStorage, regionsCollection),
 	)
 	r.POSTWithParams(
 		"/restaurants/:restaurantID/offer_suggestions",
-		handler.RestaurantOfferSuggestions(restaurantsCollection, sessionManager, usersCollection, offersCollection),
+		handler.RestaurantOfferSuggestions(restaurantsCollection, sessionManager, usersCollection, membershipsCollection,
+			apiKeysCollection, offersCollection),
 	)
 	r.GETWithParams(
 		"/restaurants/:restaurantID/posts/:date",
-		handler.OfferGroupPost(offerGroupPostsCollection, sessionManager, usersCollection, restaurantsCollection),
+		handler.OfferGroupPost(offerGroupPostsCollection, sessionManager, usersCollection, membershipsCollection,
+			apiKeysCollection, restaurantsCollection),
+	)
+	r.GETWithParams(
+		"/restaurants/:restaurantID/publish_jobs",
+		handler.RestaurantPublishJobs(sessionManager, usersCollection, membershipsCollection, apiKeysCollection,
+			restaurantsCollection, publishJobsCollection),
+	)
+	r.GETWithParams(
+		"/restaurants/:restaurantID/posts/:date/preview",
+		handler.OfferGroupPostPreview(sessionManager, usersCollection, membershipsCollection, apiKeysCollection,
+			restaurantsCollection, facebookPublishQueue),
 	)
 	r.POSTWithParams(
 		"/restaurants/:restaurantID/posts",
-		handler.PostOfferGroupPost(offerGroupPostsCollection, sessionManager, usersCollection, restaurantsCollection,
-			facebookPost),
+		handler.PostOfferGroupPost(offerGroupPostsCollection, sessionManager, usersCollection, membershipsCollection,
+			apiKeysCollection, restaurantsCollection, facebookPublishQueue, auditLog),
 	)
 	r.PUT(
 		"/restaurants/:restaurantID/posts/:date",
-		handler.PutOfferGroupPost(offerGroupPostsCollection, sessionManager, usersCollection, restaurantsCollection,
-			facebookPost),
+		handler.PutOfferGroupPost(offerGroupPostsCollection, sessionManager, usersCollection, membershipsCollection,
+			apiKeysCollection, restaurantsCollection, facebookPublishQueue, auditLog),
 	)
 	r.GET(
 		"/logout",
@@ -138,6 +322,28 @@ func main() {
 		handler.RedirectedFromFBForLogin(sessionManager, facebookLoginAuthenticator, usersCollection,
 			restaurantsCollection),
 	)
+	r.POST(
+		"/login/email",
+		handler.LoginWithEmail(sessionManager, usersCollection),
+	)
+	r.GET(
+		"/login/email/verify",
+		handler.VerifyEmail(sessionManager, usersCollection, emailTokensCollection),
+	)
+	r.POST(
+		"/login/email/forgot",
+		handler.RequestPasswordReset(usersCollection, emailTokensCollection, mailSender,
+			mainConfig.Domain+"/#/reset_password"),
+	)
+	r.POST(
+		"/login/email/reset",
+		handler.ResetPassword(usersCollection, emailTokensCollection, sessionsCollection, auditLog),
+	)
+	r.POST(
+		"/register/email",
+		handler.RegisterWithEmail(usersCollection, emailTokensCollection, registrationTokensCollection, mailSender,
+			mainConfig.Domain+"/api/v1/login/email/verify", auditLog),
+	)
 	r.GET(
 		"/register/facebook",
 		handler.RedirectToFBForRegistration(sessionManager, facebookRegistrationAuthenticator,
@@ -145,7 +351,8 @@ func main() {
 	)
 	r.GET(
 		"/register/facebook/redirected",
-		handler.RedirectedFromFBForRegistration(sessionManager, facebookRegistrationAuthenticator, usersCollection),
+		handler.RedirectedFromFBForRegistration(sessionManager, facebookRegistrationAuthenticator, usersCollection,
+			registrationTokensCollection),
 	)
 	r.GET(
 		"/register/facebook/pages",
@@ -155,6 +362,55 @@ func main() {
 		"/register/facebook/pages/:id",
 		handler.Page(sessionManager, facebookLoginAuthenticator, usersCollection),
 	)
+	r.GET(
+		"/admin/users",
+		handler.AdminUsers(sessionManager, usersCollection),
+	)
+	r.GETWithParams(
+		"/admin/users/:id/export",
+		handler.AdminUserDataExport(sessionManager, usersCollection, userData, auditLog),
+	)
+	r.DELETE(
+		"/admin/users/:id",
+		handler.DeleteAdminUser(sessionManager, usersCollection, userData, auditLog),
+	)
+	r.POSTWithParams(
+		"/admin/users/:id/impersonate",
+		handler.PostAdminImpersonation(sessionManager, usersCollection, impersonationsCollection, auditLog),
+	)
+	r.DELETE(
+		"/admin/impersonation",
+		handler.DeleteAdminImpersonation(sessionManager, usersCollection, impersonationsCollection, auditLog),
+	)
+	r.GET(
+		"/admin/impersonations",
+		handler.AdminImpersonations(sessionManager, usersCollection, impersonationsCollection),
+	)
+	r.GET(
+		"/admin/restaurants",
+		handler.AdminRestaurants(sessionManager, usersCollection, restaurantsCollection),
+	)
+	r.POSTWithParams(
+		"/admin/restaurants/:restaurantID/members",
+		handler.PostAdminRestaurantMember(sessionManager, usersCollection, restaurantsCollection,
+			membershipsCollection, auditLog),
+	)
+	r.POST(
+		"/admin/tags",
+		handler.PostAdminTag(sessionManager, usersCollection, tagsCollection, auditLog),
+	)
+	r.PUT(
+		"/admin/tags/:name",
+		handler.PutAdminTag(sessionManager, usersCollection, tagsCollection, auditLog),
+	)
+	r.POST(
+		"/admin/regions",
+		handler.PostAdminRegion(sessionManager, usersCollection, regionsCollection, auditLog),
+	)
+	r.PUT(
+		"/admin/regions/:name",
+		handler.PutAdminRegion(sessionManager, usersCollection, regionsCollection, auditLog),
+	)
 
 	http.Handle("/api/v1/", r)
 	portString := fmt.Sprintf(":%d", mainConfig.Port)
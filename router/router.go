@@ -51,59 +51,90 @@ func NewSimpleHandlerError(message string, code int) *HandlerError {
 }
 
 func (r Router) GET(path string, handler Handler) {
-	r.Handler("GET", r.prefix+path, handleErrors(handler))
+	r.Handler("GET", r.prefix+path, r.handleErrors(handler))
 }
 
 func (r Router) GETWithParams(path string, handler HandlerWithParams) {
-	r.Router.GET(r.prefix+path, handleErrorsWithParams(handler))
+	r.Router.GET(r.prefix+path, r.handleErrorsWithParams(handler))
 }
 
 func (r Router) POST(path string, handler Handler) {
-	r.Handler("POST", r.prefix+path, handleErrors(handler))
+	r.Handler("POST", r.prefix+path, r.handleErrors(handler))
 }
 
 func (r Router) POSTWithParams(path string, handler HandlerWithParams) {
-	r.Router.POST(r.prefix+path, handleErrorsWithParams(handler))
+	r.Router.POST(r.prefix+path, r.handleErrorsWithParams(handler))
 }
 
 func (r Router) PUT(path string, handler HandlerWithParams) {
-	r.Router.PUT(r.prefix+path, handleErrorsWithParams(handler))
+	r.Router.PUT(r.prefix+path, r.handleErrorsWithParams(handler))
 }
 
 func (r Router) DELETE(path string, handler HandlerWithParams) {
-	r.Router.DELETE(r.prefix+path, handleErrorsWithParams(handler))
+	r.Router.DELETE(r.prefix+path, r.handleErrorsWithParams(handler))
+}
+
+// CSRFProtector hands out CSRF tokens and checks them
+type CSRFProtector interface {
+	// Ensure makes sure the client has a CSRF token
+	Ensure(http.ResponseWriter, *http.Request)
+	// Verify returns an error unless the request carries a valid CSRF token
+	Verify(*http.Request) error
 }
 
 // Router is a wrapper around julienschmidt/httprouter that implements error
-// handling specific to this application.
+// handling and CSRF protection specific to this application.
 type Router struct {
 	*httprouter.Router
 	prefix string
+	csrf   CSRFProtector
 }
 
 // NewWithPrefix creates a Router that adds an option to use a common prefix for
-// all the paths.
-func NewWithPrefix(prefix string) *Router {
+// all the paths. All POST, PUT and DELETE requests are rejected unless csrf
+// verifies them.
+func NewWithPrefix(prefix string, csrf CSRFProtector) *Router {
 	return &Router{
 		Router: httprouter.New(),
 		prefix: strings.TrimSuffix(prefix, "/"),
+		csrf:   csrf,
 	}
 }
 
-func handleErrors(h Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if e := h(w, r); e != nil {
+func (r Router) handleErrors(h Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if e := r.checkCSRF(w, req); e != nil {
+			handleError(w, e)
+		} else if e := h(w, req); e != nil {
 			handleError(w, e)
 		}
 	})
 }
 
-func handleErrorsWithParams(h HandlerWithParams) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		if e := h(w, r, ps); e != nil {
+func (r Router) handleErrorsWithParams(h HandlerWithParams) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		if e := r.checkCSRF(w, req); e != nil {
 			handleError(w, e)
+		} else if e := h(w, req, ps); e != nil {
+			handleError(w, e)
+		}
+	}
+}
+
+func (r Router) checkCSRF(w http.ResponseWriter, req *http.Request) *HandlerError {
+	r.csrf.Ensure(w, req)
+	// Browsers won't add an Authorization header to cross-site requests, so the
+	// requests authenticated with one (e.g. with API keys) can't be forged
+	if req.Header.Get("Authorization") != "" {
+		return nil
+	}
+	switch req.Method {
+	case "POST", "PUT", "DELETE":
+		if err := r.csrf.Verify(req); err != nil {
+			return NewHandlerError(err, "Missing or invalid CSRF token", http.StatusForbidden)
 		}
 	}
+	return nil
 }
 
 func handleError(w http.ResponseWriter, e *HandlerError) {
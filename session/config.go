@@ -0,0 +1,70 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/deiwin/gonfigure"
+)
+
+var (
+	secretProperty         = gonfigure.NewRequiredEnvProperty("SESSION_SECRET")
+	cookieSecureProperty   = gonfigure.NewEnvProperty("SESSION_COOKIE_SECURE", "true")
+	cookieHTTPOnlyProperty = gonfigure.NewEnvProperty("SESSION_COOKIE_HTTP_ONLY", "true")
+	cookieSameSiteProperty = gonfigure.NewEnvProperty("SESSION_COOKIE_SAME_SITE", "lax")
+)
+
+type Config struct {
+	// Secret is used to sign the session and CSRF cookies. Changing it logs
+	// everybody out.
+	Secret []byte
+	// CookieSecure limits the cookies to HTTPS connections. Should only be
+	// turned off for local development.
+	CookieSecure bool
+	// CookieHTTPOnly hides the session cookie from scripts. The CSRF cookie is
+	// always readable by scripts, because that's how the client gets the token.
+	CookieHTTPOnly bool
+	// CookieSameSite is one of http.SameSiteLaxMode, http.SameSiteStrictMode or
+	// http.SameSiteNoneMode
+	CookieSameSite http.SameSite
+}
+
+func NewConfig() (*Config, error) {
+	secret := secretProperty.Value()
+	if secret == "" {
+		return nil, errors.New("session: SESSION_SECRET has to be set")
+	}
+	secure, err := strconv.ParseBool(cookieSecureProperty.Value())
+	if err != nil {
+		return nil, err
+	}
+	httpOnly, err := strconv.ParseBool(cookieHTTPOnlyProperty.Value())
+	if err != nil {
+		return nil, err
+	}
+	sameSite, err := parseSameSite(cookieSameSiteProperty.Value())
+	if err != nil {
+		return nil, err
+	}
+	return &Config{
+		Secret:         []byte(secret),
+		CookieSecure:   secure,
+		CookieHTTPOnly: httpOnly,
+		CookieSameSite: sameSite,
+	}, nil
+}
+
+func parseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("session: invalid SameSite value %q", value)
+}
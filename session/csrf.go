@@ -0,0 +1,75 @@
+package session
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+)
+
+const (
+	csrfCookieName = "luncher_csrf"
+	// CSRFHeaderName is the header the client has to copy the value of the CSRF
+	// cookie into for any request that changes state
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+var ErrInvalidCSRFToken = errors.New("session: missing or invalid CSRF token")
+
+// CSRFGuard implements the double-submit cookie pattern: the client gets a
+// signed random token in a cookie readable by scripts, and has to send it back
+// in a header. Other sites can make the browser send the cookie, but can't
+// read it to set the header.
+type CSRFGuard interface {
+	// Ensure writes a new CSRF token into the response as a cookie, unless the
+	// request already has a valid one
+	Ensure(http.ResponseWriter, *http.Request)
+	// Verify returns ErrInvalidCSRFToken unless the request's CSRF header
+	// matches its valid CSRF cookie
+	Verify(*http.Request) error
+}
+
+type csrfGuard struct {
+	config *Config
+}
+
+// NewCSRFGuard returns an implementation of the CSRFGuard interface
+func NewCSRFGuard(config *Config) CSRFGuard {
+	return csrfGuard{config}
+}
+
+func (g csrfGuard) Ensure(w http.ResponseWriter, r *http.Request) {
+	if _, ok := g.getToken(r); ok {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    sign(createNewSession(), g.config.Secret),
+		Path:     "/",
+		Secure:   g.config.CookieSecure,
+		SameSite: g.config.CookieSameSite,
+	})
+}
+
+func (g csrfGuard) Verify(r *http.Request) error {
+	cookieValue, ok := g.getToken(r)
+	if !ok {
+		return ErrInvalidCSRFToken
+	}
+	headerValue := r.Header.Get(CSRFHeaderName)
+	if subtle.ConstantTimeCompare([]byte(headerValue), []byte(cookieValue)) != 1 {
+		return ErrInvalidCSRFToken
+	}
+	return nil
+}
+
+// getToken returns the signed value of the request's CSRF cookie, if it's valid
+func (g csrfGuard) getToken(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(csrfCookieName)
+	if err != nil {
+		return "", false
+	}
+	if _, ok := verify(cookie.Value, g.config.Secret); !ok {
+		return "", false
+	}
+	return cookie.Value, true
+}
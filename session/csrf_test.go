@@ -0,0 +1,105 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/Lunchr/luncher-api/session"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const csrfCookieName = "luncher_csrf"
+
+var _ = Describe("CSRFGuard", func() {
+	var guard CSRFGuard
+
+	BeforeEach(func() {
+		guard = NewCSRFGuard(config)
+	})
+
+	Describe("Ensure", func() {
+		It("should write a token readable by scripts into a cookie", func() {
+			guard.Ensure(responseRecorder, request)
+			cookie := singleCookie(responseRecorder)
+			Expect(cookie.Name).To(Equal(csrfCookieName))
+			Expect(cookie.Value).NotTo(BeEmpty())
+			Expect(cookie.HttpOnly).To(BeFalse())
+			Expect(cookie.Secure).To(BeTrue())
+		})
+
+		Context("with a valid token in the request", func() {
+			BeforeEach(func() {
+				recorder := httptest.NewRecorder()
+				guard.Ensure(recorder, request)
+				request.AddCookie(singleCookie(recorder))
+			})
+
+			It("should keep the token", func() {
+				guard.Ensure(responseRecorder, request)
+				Expect(responseRecorder.Result().Cookies()).To(BeEmpty())
+			})
+		})
+
+		Context("with a forged token in the request", func() {
+			BeforeEach(func() {
+				request.AddCookie(&http.Cookie{
+					Name:  csrfCookieName,
+					Value: "forged.token",
+				})
+			})
+
+			It("should replace the token", func() {
+				guard.Ensure(responseRecorder, request)
+				Expect(singleCookie(responseRecorder).Value).NotTo(Equal("forged.token"))
+			})
+		})
+	})
+
+	Describe("Verify", func() {
+		It("should fail without a token", func() {
+			Expect(guard.Verify(request)).To(Equal(ErrInvalidCSRFToken))
+		})
+
+		Context("with a valid token in the cookie", func() {
+			var token string
+
+			BeforeEach(func() {
+				recorder := httptest.NewRecorder()
+				guard.Ensure(recorder, request)
+				cookie := singleCookie(recorder)
+				token = cookie.Value
+				request.AddCookie(cookie)
+			})
+
+			It("should fail without the header", func() {
+				Expect(guard.Verify(request)).To(Equal(ErrInvalidCSRFToken))
+			})
+
+			It("should fail with a different token in the header", func() {
+				request.Header.Set(CSRFHeaderName, "another.token")
+				Expect(guard.Verify(request)).To(Equal(ErrInvalidCSRFToken))
+			})
+
+			It("should succeed with the same token in the header", func() {
+				request.Header.Set(CSRFHeaderName, token)
+				Expect(guard.Verify(request)).To(Succeed())
+			})
+		})
+
+		Context("with a forged token in both the cookie and the header", func() {
+			BeforeEach(func() {
+				request.AddCookie(&http.Cookie{
+					Name:  csrfCookieName,
+					Value: "forged.token",
+				})
+				request.Header.Set(CSRFHeaderName, "forged.token")
+			})
+
+			It("should fail", func() {
+				Expect(guard.Verify(request)).To(Equal(ErrInvalidCSRFToken))
+			})
+		})
+	})
+})
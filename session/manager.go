@@ -6,9 +6,26 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
 )
 
-const sessionCookieName = "luncher_session"
+const (
+	sessionCookieName = "luncher_session"
+	// SessionTTL is how long a session stays valid after it was last used
+	SessionTTL = 30 * 24 * time.Hour
+	// ImpersonationTTL is how long an administrator's session impersonating a user
+	// stays valid after it was last used
+	ImpersonationTTL = time.Hour
+	// touchInterval limits how often the last seen time of a session is updated
+	// in the DB, so that not every single request would require a write
+	touchInterval = time.Minute
+)
 
 var ErrNotFound = errors.New("session manager: no session found")
 
@@ -17,43 +34,150 @@ type Manager interface {
 	// GetOrInit returns the current session ID stored in the request
 	// as a cookie or creates a new id and writes it into the response as a cookie
 	GetOrInit(http.ResponseWriter, *http.Request) string
-	// Get returns the current session only if it exists. Returns an error otherwise
-	Get(*http.Request) (string, error)
+	// Start creates a new session for the user in the DB and writes its token
+	// into the response as a cookie
+	Start(w http.ResponseWriter, r *http.Request, userID bson.ObjectId) error
+	// Impersonate replaces the current session with a new session for the user
+	// on behalf of the impersonator
+	Impersonate(w http.ResponseWriter, r *http.Request, userID, impersonatorID bson.ObjectId) error
+	// Resolve returns the unexpired session referenced by the request's cookie
+	// and extends its expiry. Returns ErrNotFound if there is no such session.
+	Resolve(*http.Request) (*model.Session, error)
+	// End removes the session referenced by the request's cookie from the DB
+	// and clears the cookie
+	End(http.ResponseWriter, *http.Request) error
 }
 
-type manager struct{}
+type manager struct {
+	sessions db.Sessions
+	config   *Config
+}
 
 // NewManager returns an implementation of the Manager interface
-func NewManager() Manager {
-	return manager{}
+func NewManager(sessions db.Sessions, config *Config) Manager {
+	return manager{sessions, config}
+}
+
+func (m manager) GetOrInit(w http.ResponseWriter, r *http.Request) string {
+	if token, err := m.getToken(r); err == nil {
+		return token
+	}
+
+	token := createNewSession()
+	m.setCookie(w, token)
+	return token
+}
+
+func (m manager) Start(w http.ResponseWriter, r *http.Request, userID bson.ObjectId) error {
+	return m.start(w, r, userID, "")
+}
+
+func (m manager) Impersonate(w http.ResponseWriter, r *http.Request, userID, impersonatorID bson.ObjectId) error {
+	token, err := m.getToken(r)
+	if err == nil {
+		if err = m.sessions.RemoveToken(token); err != nil {
+			return err
+		}
+	} else if err != ErrNotFound {
+		return err
+	}
+	return m.start(w, r, userID, impersonatorID)
+}
+
+func (m manager) start(w http.ResponseWriter, r *http.Request, userID, impersonatorID bson.ObjectId) error {
+	now := time.Now()
+	session := &model.Session{
+		Token:          createNewSession(),
+		UserID:         userID,
+		CreatedAt:      now,
+		LastSeenAt:     now,
+		UserAgent:      r.UserAgent(),
+		ExpiresAt:      now.Add(ttl(impersonatorID)),
+		ImpersonatorID: impersonatorID,
+	}
+	if err := m.sessions.Insert(session); err != nil {
+		return err
+	}
+	m.setCookie(w, session.Token)
+	return nil
+}
+
+func (m manager) Resolve(r *http.Request) (*model.Session, error) {
+	token, err := m.getToken(r)
+	if err != nil {
+		return nil, err
+	}
+	session, err := m.sessions.GetToken(token)
+	if err == mgo.ErrNotFound {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) > touchInterval {
+		session.LastSeenAt = now
+		session.ExpiresAt = now.Add(ttl(session.ImpersonatorID))
+		if err = m.sessions.Touch(session.ID, session.LastSeenAt, session.ExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
 }
 
-func (m manager) Get(r *http.Request) (string, error) {
-	if cookie, err := r.Cookie(sessionCookieName); err == http.ErrNoCookie {
+func (m manager) End(w http.ResponseWriter, r *http.Request) error {
+	token, err := m.getToken(r)
+	if err == ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err = m.sessions.RemoveToken(token); err != nil {
+		return err
+	}
+	cookie := m.newCookie("")
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+	return nil
+}
+
+func ttl(impersonatorID bson.ObjectId) time.Duration {
+	if impersonatorID != "" {
+		return ImpersonationTTL
+	}
+	return SessionTTL
+}
+
+// getToken returns the session token from the request's cookie. Cookies with
+// an invalid signature are treated as if they weren't there.
+func (m manager) getToken(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err == http.ErrNoCookie {
 		return "", ErrNotFound
 	} else if err != nil {
 		return "", err
-	} else if cookie.Value == "" {
+	}
+	token, ok := verify(cookie.Value, m.config.Secret)
+	if !ok || token == "" {
 		return "", ErrNotFound
-	} else {
-		return cookie.Value, nil
 	}
+	return token, nil
 }
 
-func (m manager) GetOrInit(w http.ResponseWriter, r *http.Request) string {
-	if cookie, err := r.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
-		return cookie.Value
-	}
+func (m manager) setCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, m.newCookie(sign(token, m.config.Secret)))
+}
 
-	session := createNewSession()
-	cookie := &http.Cookie{
-		Name:  sessionCookieName,
-		Value: session,
-		Path:  "/",
-		// no MaxAge because we want this to be a session cookie
+func (m manager) newCookie(value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    value,
+		Path:     "/",
+		Secure:   m.config.CookieSecure,
+		HttpOnly: m.config.CookieHTTPOnly,
+		SameSite: m.config.CookieSameSite,
+		// no MaxAge because we want this to be a session cookie, the server
+		// side session expires on its own
 	}
-	http.SetCookie(w, cookie)
-	return session
 }
 
 // createNewSession creates a new random session ID string
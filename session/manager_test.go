@@ -4,7 +4,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
 	. "github.com/Lunchr/luncher-api/session"
 
 	. "github.com/onsi/ginkgo"
@@ -17,12 +23,28 @@ const (
 )
 
 var _ = Describe("Manager", func() {
-	var manager Manager
+	var (
+		manager  Manager
+		sessions *fakeSessions
+		userID   bson.ObjectId
+	)
 
 	BeforeEach(func() {
-		manager = NewManager()
+		sessions = &fakeSessions{}
+		manager = NewManager(sessions, config)
+		userID = bson.NewObjectId()
 	})
 
+	// startSession starts a session through the manager and adds the resulting
+	// cookie to the request
+	startSession := func() *model.Session {
+		recorder := httptest.NewRecorder()
+		err := manager.Start(recorder, request, userID)
+		Expect(err).NotTo(HaveOccurred())
+		request.AddCookie(singleCookie(recorder))
+		return sessions.sessions[len(sessions.sessions)-1]
+	}
+
 	Describe("GetOrInit", func() {
 		It("shoud return a non-empty string", func() {
 			cookieValue := manager.GetOrInit(responseRecorder, request)
@@ -33,49 +55,270 @@ var _ = Describe("Manager", func() {
 		})
 
 		Context("with session cookie in request", func() {
+			var requestCookieValue string
+
+			BeforeEach(func() {
+				recorder := httptest.NewRecorder()
+				requestCookieValue = manager.GetOrInit(recorder, request)
+				request.AddCookie(singleCookie(recorder))
+			})
+
+			It("should return the same cookie", func() {
+				cookieValue := manager.GetOrInit(responseRecorder, request)
+				Expect(cookieValue).To(Equal(requestCookieValue))
+			})
+		})
+
+		Context("with an unsigned session cookie in request", func() {
 			var requestCookieValue = "k_bV590l1T7mkhmwQgAIDA=="
 
 			BeforeEach(func() {
-				cookie := &http.Cookie{
+				request.AddCookie(&http.Cookie{
 					Name:  sessionCookieName,
 					Value: requestCookieValue,
-				}
-				request.AddCookie(cookie)
+				})
 			})
 
-			It("should return the same cookie", func() {
+			It("should replace the cookie", func() {
 				cookieValue := manager.GetOrInit(responseRecorder, request)
-				Expect(cookieValue).To(Equal(requestCookieValue))
+				Expect(cookieValue).NotTo(Equal(requestCookieValue))
+				verifySingleSessionCookie(responseRecorder, func(cookieValue string) {
+					Expect(cookieValue).NotTo(HavePrefix(requestCookieValue))
+				})
 			})
 		})
 	})
 
-	Describe("Get", func() {
-		It("should return an error", func() {
-			_, err := manager.Get(request)
-			Expect(err).NotTo(BeNil())
+	Describe("Start", func() {
+		BeforeEach(func() {
+			request.Header.Set("User-Agent", "a browser")
+		})
+
+		It("should store a session for the user", func() {
+			err := manager.Start(responseRecorder, request, userID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sessions.sessions).To(HaveLen(1))
+			session := sessions.sessions[0]
+			Expect(session.UserID).To(Equal(userID))
+			Expect(session.UserAgent).To(Equal("a browser"))
+			Expect(session.ExpiresAt).To(BeTemporally("~", time.Now().Add(SessionTTL), time.Minute))
+		})
+
+		It("should write the session's signed token into a cookie", func() {
+			err := manager.Start(responseRecorder, request, userID)
+			Expect(err).NotTo(HaveOccurred())
+			verifySingleSessionCookie(responseRecorder, func(cookieValue string) {
+				Expect(cookieValue).To(HavePrefix(sessions.sessions[0].Token + "."))
+			})
+		})
+
+		It("should set the configured cookie attributes", func() {
+			err := manager.Start(responseRecorder, request, userID)
+			Expect(err).NotTo(HaveOccurred())
+			cookie := singleCookie(responseRecorder)
+			Expect(cookie.Secure).To(BeTrue())
+			Expect(cookie.HttpOnly).To(BeTrue())
+			Expect(cookie.SameSite).To(Equal(http.SameSiteLaxMode))
 		})
 
 		Context("with session cookie in request", func() {
-			var requestCookieValue = "k_bV590l1T7mkhmwQgAIDA=="
+			var previousSession *model.Session
 
 			BeforeEach(func() {
-				cookie := &http.Cookie{
+				previousSession = startSession()
+			})
+
+			It("should use a new token", func() {
+				err := manager.Start(responseRecorder, request, userID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sessions.sessions[1].Token).NotTo(Equal(previousSession.Token))
+			})
+		})
+	})
+
+	Describe("Impersonate", func() {
+		var (
+			previousSession *model.Session
+			impersonatedID  bson.ObjectId
+		)
+
+		BeforeEach(func() {
+			previousSession = startSession()
+			impersonatedID = bson.NewObjectId()
+		})
+
+		It("should replace the impersonator's session", func() {
+			err := manager.Impersonate(responseRecorder, request, impersonatedID, userID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sessions.sessions).To(HaveLen(1))
+			Expect(sessions.sessions[0].Token).NotTo(Equal(previousSession.Token))
+			verifySingleSessionCookie(responseRecorder, func(cookieValue string) {
+				Expect(cookieValue).To(HavePrefix(sessions.sessions[0].Token + "."))
+			})
+		})
+
+		It("should store a short-lived session for the user on behalf of the impersonator", func() {
+			err := manager.Impersonate(responseRecorder, request, impersonatedID, userID)
+			Expect(err).NotTo(HaveOccurred())
+			session := sessions.sessions[0]
+			Expect(session.UserID).To(Equal(impersonatedID))
+			Expect(session.ImpersonatorID).To(Equal(userID))
+			Expect(session.ExpiresAt).To(BeTemporally("~", time.Now().Add(ImpersonationTTL), time.Minute))
+		})
+	})
+
+	Describe("Resolve", func() {
+		It("should return ErrNotFound", func() {
+			_, err := manager.Resolve(request)
+			Expect(err).To(Equal(ErrNotFound))
+		})
+
+		Context("with an unknown session cookie in request", func() {
+			BeforeEach(func() {
+				recorder := httptest.NewRecorder()
+				manager.GetOrInit(recorder, request)
+				request.AddCookie(singleCookie(recorder))
+			})
+
+			It("should return ErrNotFound", func() {
+				_, err := manager.Resolve(request)
+				Expect(err).To(Equal(ErrNotFound))
+			})
+		})
+
+		Context("with a stored session", func() {
+			var session *model.Session
+
+			BeforeEach(func() {
+				session = startSession()
+			})
+
+			It("should return the session", func() {
+				resolved, err := manager.Resolve(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resolved.UserID).To(Equal(userID))
+			})
+
+			It("should not extend the expiry of a session that was just used", func() {
+				_, err := manager.Resolve(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sessions.touched).To(BeFalse())
+			})
+
+			Context("that hasn't been used for a while", func() {
+				BeforeEach(func() {
+					session.LastSeenAt = time.Now().Add(-time.Hour)
+				})
+
+				It("should extend the session's expiry", func() {
+					resolved, err := manager.Resolve(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(sessions.touched).To(BeTrue())
+					Expect(resolved.LastSeenAt).To(BeTemporally("~", time.Now(), time.Minute))
+					Expect(resolved.ExpiresAt).To(BeTemporally("~", time.Now().Add(SessionTTL), time.Minute))
+				})
+			})
+		})
+
+		Context("with a stored session's token in an unsigned cookie", func() {
+			BeforeEach(func() {
+				session := startSession()
+				request.Header.Del("Cookie")
+				request.AddCookie(&http.Cookie{
 					Name:  sessionCookieName,
-					Value: requestCookieValue,
-				}
-				request.AddCookie(cookie)
+					Value: session.Token,
+				})
 			})
 
-			It("should return the same cookie", func() {
-				cookieValue, err := manager.Get(request)
-				Expect(err).To(BeNil())
-				Expect(cookieValue).To(Equal(requestCookieValue))
+			It("should return ErrNotFound", func() {
+				_, err := manager.Resolve(request)
+				Expect(err).To(Equal(ErrNotFound))
+			})
+		})
+
+		Context("with a stored session's token signed with another secret", func() {
+			BeforeEach(func() {
+				otherManager := NewManager(sessions, &Config{Secret: []byte("another secret")})
+				recorder := httptest.NewRecorder()
+				err := otherManager.Start(recorder, request, userID)
+				Expect(err).NotTo(HaveOccurred())
+				request.AddCookie(singleCookie(recorder))
+			})
+
+			It("should return ErrNotFound", func() {
+				_, err := manager.Resolve(request)
+				Expect(err).To(Equal(ErrNotFound))
+			})
+		})
+	})
+
+	Describe("End", func() {
+		Context("with a stored session", func() {
+			BeforeEach(func() {
+				startSession()
+			})
+
+			It("should remove the session", func() {
+				err := manager.End(responseRecorder, request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sessions.sessions).To(BeEmpty())
+			})
+
+			It("should clear the cookie", func() {
+				err := manager.End(responseRecorder, request)
+				Expect(err).NotTo(HaveOccurred())
+				cookie := singleCookie(responseRecorder)
+				Expect(cookie.Value).To(BeEmpty())
+				Expect(cookie.MaxAge).To(BeNumerically("<", 0))
 			})
 		})
 	})
 })
 
+// fakeSessions is an in-memory implementation of the parts of db.Sessions the
+// manager uses
+type fakeSessions struct {
+	db.Sessions
+	sessions []*model.Session
+	touched  bool
+}
+
+func (f *fakeSessions) Insert(session *model.Session) error {
+	session.ID = bson.NewObjectId()
+	f.sessions = append(f.sessions, session)
+	return nil
+}
+
+func (f *fakeSessions) GetToken(token string) (*model.Session, error) {
+	for _, session := range f.sessions {
+		if session.Token == token {
+			return session, nil
+		}
+	}
+	return nil, mgo.ErrNotFound
+}
+
+func (f *fakeSessions) Touch(id bson.ObjectId, lastSeenAt, expiresAt time.Time) error {
+	f.touched = true
+	return nil
+}
+
+func (f *fakeSessions) RemoveToken(token string) error {
+	for i, session := range f.sessions {
+		if session.Token == token {
+			f.sessions = append(f.sessions[:i], f.sessions[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func singleCookie(responseRecorder *httptest.ResponseRecorder) *http.Cookie {
+	cookies := responseRecorder.Result().Cookies()
+	Expect(cookies).To(HaveLen(1))
+	return cookies[0]
+}
+
 func verifySingleSessionCookie(responseRecorder *httptest.ResponseRecorder, verify func(string)) {
 	cookies := responseRecorder.HeaderMap[cookieHeader]
 	Expect(cookies).To(HaveLen(1))
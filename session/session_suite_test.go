@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/Lunchr/luncher-api/session"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -18,6 +20,12 @@ func TestSession(t *testing.T) {
 var (
 	responseRecorder *httptest.ResponseRecorder
 	request          *http.Request
+	config           = &session.Config{
+		Secret:         []byte("a secret"),
+		CookieSecure:   true,
+		CookieHTTPOnly: true,
+		CookieSameSite: http.SameSiteLaxMode,
+	}
 )
 
 var _ = BeforeEach(func(done Done) {
@@ -0,0 +1,33 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// sign appends an HMAC of the value to it, so that values that weren't
+// created by us could be rejected without a DB lookup
+func sign(value string, secret []byte) string {
+	return value + "." + signature(value, secret)
+}
+
+// verify returns the original value if the signed value has a valid signature
+func verify(signedValue string, secret []byte) (string, bool) {
+	i := strings.LastIndex(signedValue, ".")
+	if i < 0 {
+		return "", false
+	}
+	value, sig := signedValue[:i], signedValue[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(signature(value, secret))) {
+		return "", false
+	}
+	return value, true
+}
+
+func signature(value string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,285 @@
+// Package userdata helps to fulfil the data subject requests of the users by
+// exporting and deleting all the data associated with a user.
+package userdata
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Lunchr/luncher-api/db"
+	"github.com/Lunchr/luncher-api/db/model"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type (
+	// Controller exports and deletes the data associated with users
+	Controller interface {
+		// Export collects everything associated with the user into an archive.
+		// Returns mgo.ErrNotFound if there's no such user.
+		Export(userID bson.ObjectId) (*Archive, error)
+		// Delete removes the user along with their memberships, sessions, tokens, the
+		// registration access tokens they've claimed, the API keys and invites they've
+		// created and the invites addressed to them. The restaurants the user manages
+		// are unlinked from the user and the user is removed from the publishing jobs
+		// they've queued, but a user who is the only owner of a restaurant, either
+		// through a membership or the access token of the restaurant's Facebook page,
+		// can't be deleted before handing the restaurant over or deleting it, in
+		// which case a *SoleOwnerError is returned. The audit log and the history of
+		// impersonations are kept, because they belong to the restaurants and the
+		// administrators, and only refer to the user by ID.
+		// Returns mgo.ErrNotFound if there's no such user.
+		Delete(userID bson.ObjectId) error
+	}
+
+	// Archive holds everything associated with a user
+	Archive struct {
+		ExportedAt     time.Time               `json:"exported_at"`
+		User           User                    `json:"user"`
+		Memberships    []*model.Membership     `json:"memberships"`
+		Restaurants    []*model.Restaurant     `json:"restaurants"`
+		Offers         []*model.Offer          `json:"offers"`
+		GroupPosts     []*model.OfferGroupPost `json:"group_posts"`
+		Sessions       []*model.Session        `json:"sessions"`
+		APIKeys        []*model.APIKey         `json:"api_keys"`
+		Impersonations []*model.Impersonation  `json:"impersonations"`
+		AuditEntries   []*model.AuditEntry     `json:"audit_entries"`
+	}
+
+	// User is the user record as included in the archive. The credentials and the
+	// Facebook tokens are left out.
+	User struct {
+		ID             bson.ObjectId   `json:"_id"`
+		Email          string          `json:"email,omitempty"`
+		EmailVerified  bool            `json:"email_verified"`
+		FacebookUserID string          `json:"facebook_user_id,omitempty"`
+		IsAdmin        bool            `json:"is_admin"`
+		RestaurantIDs  []bson.ObjectId `json:"restaurant_ids,omitempty"`
+	}
+
+	// SoleOwnerError is returned when deleting a user would leave restaurants
+	// without an owner
+	SoleOwnerError struct {
+		RestaurantIDs []bson.ObjectId
+	}
+
+	controller struct {
+		users              db.Users
+		memberships        db.Memberships
+		restaurants        db.Restaurants
+		offers             db.Offers
+		groupPosts         db.OfferGroupPosts
+		sessions           db.Sessions
+		emailTokens        db.EmailTokens
+		registrationTokens db.RegistrationAccessTokens
+		invites            db.Invites
+		apiKeys            db.APIKeys
+		publishJobs        db.PublishJobs
+		impersonations     db.Impersonations
+		auditEntries       db.AuditEntries
+	}
+)
+
+func NewController(users db.Users, memberships db.Memberships, restaurants db.Restaurants, offers db.Offers,
+	groupPosts db.OfferGroupPosts, sessions db.Sessions, emailTokens db.EmailTokens,
+	registrationTokens db.RegistrationAccessTokens, invites db.Invites, apiKeys db.APIKeys, publishJobs db.PublishJobs,
+	impersonations db.Impersonations, auditEntries db.AuditEntries) Controller {
+	return &controller{
+		users:              users,
+		memberships:        memberships,
+		restaurants:        restaurants,
+		offers:             offers,
+		groupPosts:         groupPosts,
+		sessions:           sessions,
+		emailTokens:        emailTokens,
+		registrationTokens: registrationTokens,
+		invites:            invites,
+		apiKeys:            apiKeys,
+		publishJobs:        publishJobs,
+		impersonations:     impersonations,
+		auditEntries:       auditEntries,
+	}
+}
+
+func (e *SoleOwnerError) Error() string {
+	ids := make([]string, len(e.RestaurantIDs))
+	for i, id := range e.RestaurantIDs {
+		ids[i] = id.Hex()
+	}
+	return fmt.Sprintf("The user is the only owner of restaurants: %s", strings.Join(ids, ", "))
+}
+
+func (c controller) Export(userID bson.ObjectId) (*Archive, error) {
+	user, err := c.users.GetID(userID)
+	if err != nil {
+		return nil, err
+	}
+	archive := &Archive{
+		ExportedAt: time.Now(),
+		User: User{
+			ID:             user.ID,
+			Email:          user.Email,
+			EmailVerified:  user.EmailVerified,
+			FacebookUserID: user.FacebookUserID,
+			IsAdmin:        user.IsAdmin,
+			RestaurantIDs:  user.RestaurantIDs,
+		},
+	}
+	if archive.Memberships, err = c.memberships.GetForUser(userID); err != nil {
+		return nil, err
+	}
+	restaurantIDs := append([]bson.ObjectId{}, user.RestaurantIDs...)
+	for _, membership := range archive.Memberships {
+		restaurantIDs = append(restaurantIDs, membership.RestaurantID)
+	}
+	if archive.Restaurants, err = c.restaurants.GetByIDs(restaurantIDs); err != nil {
+		return nil, err
+	}
+	for _, restaurant := range archive.Restaurants {
+		// A zero start time includes all of the restaurant's offers, including the past ones
+		offers, err := c.offers.GetForRestaurant(restaurant.ID, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		archive.Offers = append(archive.Offers, offers...)
+		groupPosts, err := c.groupPosts.GetByRestaurantID(restaurant.ID)
+		if err != nil {
+			return nil, err
+		}
+		archive.GroupPosts = append(archive.GroupPosts, groupPosts...)
+	}
+	if archive.Sessions, err = c.sessions.GetForUser(userID); err != nil {
+		return nil, err
+	}
+	if archive.APIKeys, err = c.apiKeys.GetCreatedBy(userID); err != nil {
+		return nil, err
+	}
+	if archive.Impersonations, err = c.impersonations.GetForUser(userID); err != nil {
+		return nil, err
+	}
+	if archive.AuditEntries, err = c.auditEntries.GetForActor(userID); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (c controller) Delete(userID bson.ObjectId) error {
+	user, err := c.users.GetID(userID)
+	if err != nil {
+		return err
+	}
+	if err = c.checkNotSoleOwner(user); err != nil {
+		return err
+	}
+	if err = c.sessions.RemoveForUser(user.ID); err != nil {
+		return err
+	}
+	if err = c.emailTokens.RemoveForUser(user.ID); err != nil {
+		return err
+	}
+	if err = c.registrationTokens.RemoveClaimedBy(user.ID); err != nil {
+		return err
+	}
+	if err = c.apiKeys.RemoveCreatedBy(user.ID); err != nil {
+		return err
+	}
+	if err = c.invites.RemoveInvitedBy(user.ID); err != nil {
+		return err
+	}
+	if user.Email != "" {
+		if err = c.invites.RemoveForEmail(user.Email); err != nil {
+			return err
+		}
+	}
+	if err = c.publishJobs.UnsetUser(user.ID); err != nil {
+		return err
+	}
+	if err = c.memberships.RemoveForUser(user.ID); err != nil {
+		return err
+	}
+	// Removing the user also removes the user's links and page access tokens for the
+	// restaurants that are managed through Facebook pages
+	return c.users.RemoveID(user.ID)
+}
+
+// checkNotSoleOwner returns a *SoleOwnerError if removing the user would leave any
+// restaurants without an owner
+func (c controller) checkNotSoleOwner(user *model.User) error {
+	memberships, err := c.memberships.GetForUser(user.ID)
+	if err != nil {
+		return err
+	}
+	var ownedRestaurantIDs []bson.ObjectId
+	for _, membership := range memberships {
+		if membership.Role == model.RoleOwner {
+			ownedRestaurantIDs = append(ownedRestaurantIDs, membership.RestaurantID)
+		}
+	}
+	// The user owns the restaurants of the Facebook pages they have access tokens for as well
+	pageIDs := make([]string, len(user.Session.FacebookPageTokens))
+	for i, pageToken := range user.Session.FacebookPageTokens {
+		pageIDs[i] = pageToken.PageID
+	}
+	pageIDsByRestaurant := make(map[bson.ObjectId]string)
+	if len(pageIDs) > 0 {
+		pageRestaurants, err := c.restaurants.GetByFacebookPageIDs(pageIDs)
+		if err != nil {
+			return err
+		}
+		for _, restaurant := range pageRestaurants {
+			pageIDsByRestaurant[restaurant.ID] = restaurant.FacebookPageID
+			if !idsInclude(ownedRestaurantIDs, restaurant.ID) {
+				ownedRestaurantIDs = append(ownedRestaurantIDs, restaurant.ID)
+			}
+		}
+	}
+	var soleOwnerOf []bson.ObjectId
+	for _, restaurantID := range ownedRestaurantIDs {
+		hasOtherOwners, err := c.hasOtherOwners(restaurantID, pageIDsByRestaurant[restaurantID], user.ID)
+		if err != nil {
+			return err
+		} else if !hasOtherOwners {
+			soleOwnerOf = append(soleOwnerOf, restaurantID)
+		}
+	}
+	if len(soleOwnerOf) > 0 {
+		return &SoleOwnerError{soleOwnerOf}
+	}
+	return nil
+}
+
+// hasOtherOwners checks whether anyone besides the user owns the restaurant, either through a membership
+// or the access token of the restaurant's Facebook page
+func (c controller) hasOtherOwners(restaurantID bson.ObjectId, pageID string, userID bson.ObjectId) (bool, error) {
+	memberships, err := c.memberships.GetForRestaurant(restaurantID)
+	if err != nil {
+		return false, err
+	}
+	for _, membership := range memberships {
+		if membership.UserID != userID && membership.Role == model.RoleOwner {
+			return true, nil
+		}
+	}
+	if pageID == "" {
+		return false, nil
+	}
+	pageAdmins, err := c.users.GetForFacebookPage(pageID)
+	if err != nil {
+		return false, err
+	}
+	for _, pageAdmin := range pageAdmins {
+		if pageAdmin.ID != userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func idsInclude(ids []bson.ObjectId, id bson.ObjectId) bool {
+	for i := range ids {
+		if ids[i] == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package userdata_test
+
+import (
+	"time"
+
+	"github.com/Lunchr/luncher-api/db/model"
+	. "github.com/Lunchr/luncher-api/userdata"
+	"github.com/Lunchr/luncher-api/userdata/mocks"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Controller", func() {
+	var (
+		users              *mocks.Users
+		memberships        *mocks.Memberships
+		restaurants        *mocks.Restaurants
+		offers             *mocks.Offers
+		groupPosts         *mocks.OfferGroupPosts
+		sessions           *mocks.Sessions
+		emailTokens        *mocks.EmailTokens
+		registrationTokens *mocks.RegistrationAccessTokens
+		invites            *mocks.Invites
+		apiKeys            *mocks.APIKeys
+		publishJobs        *mocks.PublishJobs
+		impersonations     *mocks.Impersonations
+		auditEntries       *mocks.AuditEntries
+		controller         Controller
+		user               *model.User
+		restaurantID       bson.ObjectId
+	)
+
+	BeforeEach(func() {
+		users = new(mocks.Users)
+		memberships = new(mocks.Memberships)
+		restaurants = new(mocks.Restaurants)
+		offers = new(mocks.Offers)
+		groupPosts = new(mocks.OfferGroupPosts)
+		sessions = new(mocks.Sessions)
+		emailTokens = new(mocks.EmailTokens)
+		registrationTokens = new(mocks.RegistrationAccessTokens)
+		invites = new(mocks.Invites)
+		apiKeys = new(mocks.APIKeys)
+		publishJobs = new(mocks.PublishJobs)
+		impersonations = new(mocks.Impersonations)
+		auditEntries = new(mocks.AuditEntries)
+		controller = NewController(users, memberships, restaurants, offers, groupPosts, sessions, emailTokens,
+			registrationTokens, invites, apiKeys, publishJobs, impersonations, auditEntries)
+		restaurantID = bson.NewObjectId()
+		user = &model.User{
+			ID:           bson.NewObjectId(),
+			Email:        "user@example.com",
+			PasswordHash: []byte("a hash"),
+		}
+		users.On("GetID", user.ID).Return(user, nil)
+	})
+
+	Describe("Export", func() {
+		BeforeEach(func() {
+			memberships.On("GetForUser", user.ID).Return([]*model.Membership{
+				{UserID: user.ID, RestaurantID: restaurantID, Role: model.RoleEditor},
+			}, nil)
+			restaurants.On("GetByIDs", []bson.ObjectId{restaurantID}).Return([]*model.Restaurant{
+				{ID: restaurantID, Name: "Asian Chef"},
+			}, nil)
+			offers.On("GetForRestaurant", restaurantID, time.Time{}).Return([]*model.Offer{
+				{CommonOfferFields: model.CommonOfferFields{Title: "Kana"}},
+			}, nil)
+			groupPosts.On("GetByRestaurantID", restaurantID).Return([]*model.OfferGroupPost{
+				{RestaurantID: restaurantID},
+			}, nil)
+			sessions.On("GetForUser", user.ID).Return([]*model.Session{{UserID: user.ID}}, nil)
+			apiKeys.On("GetCreatedBy", user.ID).Return([]*model.APIKey{{CreatedBy: user.ID}}, nil)
+			impersonations.On("GetForUser", user.ID).Return([]*model.Impersonation{}, nil)
+			auditEntries.On("GetForActor", user.ID).Return([]*model.AuditEntry{{ActorID: user.ID}}, nil)
+		})
+
+		It("should collect everything associated with the user", func() {
+			archive, err := controller.Export(user.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(archive.User.ID).To(Equal(user.ID))
+			Expect(archive.User.Email).To(Equal("user@example.com"))
+			Expect(archive.Memberships).To(HaveLen(1))
+			Expect(archive.Restaurants).To(HaveLen(1))
+			Expect(archive.Offers).To(HaveLen(1))
+			Expect(archive.GroupPosts).To(HaveLen(1))
+			Expect(archive.Sessions).To(HaveLen(1))
+			Expect(archive.APIKeys).To(HaveLen(1))
+			Expect(archive.AuditEntries).To(HaveLen(1))
+		})
+
+		It("should fail for an unknown user", func() {
+			unknownID := bson.NewObjectId()
+			users.On("GetID", unknownID).Return(nil, mgo.ErrNotFound)
+			_, err := controller.Export(unknownID)
+			Expect(err).To(Equal(mgo.ErrNotFound))
+		})
+	})
+
+	Describe("Delete", func() {
+		var otherUserID bson.ObjectId
+
+		BeforeEach(func() {
+			otherUserID = bson.NewObjectId()
+			memberships.On("GetForUser", user.ID).Return([]*model.Membership{
+				{UserID: user.ID, RestaurantID: restaurantID, Role: model.RoleOwner},
+			}, nil)
+		})
+
+		AfterEach(func() {
+			users.AssertExpectations(GinkgoT())
+			sessions.AssertExpectations(GinkgoT())
+			emailTokens.AssertExpectations(GinkgoT())
+			apiKeys.AssertExpectations(GinkgoT())
+			invites.AssertExpectations(GinkgoT())
+			memberships.AssertExpectations(GinkgoT())
+			registrationTokens.AssertExpectations(GinkgoT())
+			publishJobs.AssertExpectations(GinkgoT())
+		})
+
+		var expectUserToBeRemoved = func() {
+			sessions.On("RemoveForUser", user.ID).Return(nil)
+			emailTokens.On("RemoveForUser", user.ID).Return(nil)
+			registrationTokens.On("RemoveClaimedBy", user.ID).Return(nil)
+			apiKeys.On("RemoveCreatedBy", user.ID).Return(nil)
+			invites.On("RemoveInvitedBy", user.ID).Return(nil)
+			invites.On("RemoveForEmail", "user@example.com").Return(nil)
+			publishJobs.On("UnsetUser", user.ID).Return(nil)
+			memberships.On("RemoveForUser", user.ID).Return(nil)
+			users.On("RemoveID", user.ID).Return(nil)
+		}
+
+		Context("with another owner for the restaurant", func() {
+			BeforeEach(func() {
+				memberships.On("GetForRestaurant", restaurantID).Return([]*model.Membership{
+					{UserID: user.ID, RestaurantID: restaurantID, Role: model.RoleOwner},
+					{UserID: otherUserID, RestaurantID: restaurantID, Role: model.RoleOwner},
+				}, nil)
+			})
+
+			It("should remove the user and scrub their tokens, invites and publishing jobs", func() {
+				expectUserToBeRemoved()
+				err := controller.Delete(user.ID)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("with the user being the only owner of the restaurant", func() {
+			BeforeEach(func() {
+				memberships.On("GetForRestaurant", restaurantID).Return([]*model.Membership{
+					{UserID: user.ID, RestaurantID: restaurantID, Role: model.RoleOwner},
+					{UserID: otherUserID, RestaurantID: restaurantID, Role: model.RoleEditor},
+				}, nil)
+			})
+
+			It("should refuse to remove the user", func() {
+				err := controller.Delete(user.ID)
+				Expect(err).To(BeAssignableToTypeOf(&SoleOwnerError{}))
+				Expect(err.(*SoleOwnerError).RestaurantIDs).To(ConsistOf(restaurantID))
+				users.AssertNotCalled(GinkgoT(), "RemoveID", mock.Anything)
+			})
+		})
+
+		Context("with the user managing a restaurant through its Facebook page", func() {
+			var pageRestaurantID bson.ObjectId
+
+			BeforeEach(func() {
+				pageRestaurantID = bson.NewObjectId()
+				user.Session.FacebookPageTokens = []model.FacebookPageToken{{PageID: "pageid", Token: "pagetoken"}}
+				memberships.On("GetForRestaurant", restaurantID).Return([]*model.Membership{
+					{UserID: otherUserID, RestaurantID: restaurantID, Role: model.RoleOwner},
+				}, nil)
+				restaurants.On("GetByFacebookPageIDs", []string{"pageid"}).Return([]*model.Restaurant{
+					{ID: pageRestaurantID, FacebookPageID: "pageid"},
+				}, nil)
+				memberships.On("GetForRestaurant", pageRestaurantID).Return([]*model.Membership{}, nil)
+			})
+
+			Context("with nobody else having access to the page", func() {
+				BeforeEach(func() {
+					users.On("GetForFacebookPage", "pageid").Return([]*model.User{user}, nil)
+				})
+
+				It("should refuse to remove the user", func() {
+					err := controller.Delete(user.ID)
+					Expect(err).To(BeAssignableToTypeOf(&SoleOwnerError{}))
+					Expect(err.(*SoleOwnerError).RestaurantIDs).To(ConsistOf(pageRestaurantID))
+					users.AssertNotCalled(GinkgoT(), "RemoveID", mock.Anything)
+				})
+			})
+
+			Context("with another user having access to the page", func() {
+				BeforeEach(func() {
+					users.On("GetForFacebookPage", "pageid").Return([]*model.User{user, {ID: otherUserID}}, nil)
+				})
+
+				It("should remove the user", func() {
+					expectUserToBeRemoved()
+					err := controller.Delete(user.ID)
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+		})
+	})
+})
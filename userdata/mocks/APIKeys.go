@@ -0,0 +1,144 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/db/model"
+import "gopkg.in/mgo.v2/bson"
+import "time"
+
+type APIKeys struct {
+	mock.Mock
+}
+
+func (_m *APIKeys) Insert(_a0 *model.APIKey) (*model.APIKey, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *model.APIKey
+	if rf, ok := ret.Get(0).(func(*model.APIKey) *model.APIKey); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*model.APIKey) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *APIKeys) GetKey(key string) (*model.APIKey, error) {
+	ret := _m.Called(key)
+
+	var r0 *model.APIKey
+	if rf, ok := ret.Get(0).(func(string) *model.APIKey); ok {
+		r0 = rf(key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *APIKeys) GetForRestaurant(restaurantID bson.ObjectId) ([]*model.APIKey, error) {
+	ret := _m.Called(restaurantID)
+
+	var r0 []*model.APIKey
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) []*model.APIKey); ok {
+		r0 = rf(restaurantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId) error); ok {
+		r1 = rf(restaurantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *APIKeys) SetLastUsed(id bson.ObjectId, lastUsedAt time.Time) error {
+	ret := _m.Called(id, lastUsedAt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, time.Time) error); ok {
+		r0 = rf(id, lastUsedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *APIKeys) Remove(id bson.ObjectId, restaurantID bson.ObjectId) error {
+	ret := _m.Called(id, restaurantID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, bson.ObjectId) error); ok {
+		r0 = rf(id, restaurantID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *APIKeys) RemoveForRestaurant(restaurantID bson.ObjectId) error {
+	ret := _m.Called(restaurantID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(restaurantID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *APIKeys) GetCreatedBy(userID bson.ObjectId) ([]*model.APIKey, error) {
+	ret := _m.Called(userID)
+
+	var r0 []*model.APIKey
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) []*model.APIKey); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *APIKeys) RemoveCreatedBy(userID bson.ObjectId) error {
+	ret := _m.Called(userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
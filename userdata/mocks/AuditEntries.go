@@ -0,0 +1,66 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/db/model"
+import "gopkg.in/mgo.v2/bson"
+import "time"
+
+type AuditEntries struct {
+	mock.Mock
+}
+
+func (_m *AuditEntries) Insert(_a0 *model.AuditEntry) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.AuditEntry) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *AuditEntries) GetForRestaurant(_a0 bson.ObjectId, _a1 time.Time, _a2 time.Time, _a3 int) ([]*model.AuditEntry, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 []*model.AuditEntry
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, time.Time, time.Time, int) []*model.AuditEntry); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.AuditEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId, time.Time, time.Time, int) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *AuditEntries) GetForActor(_a0 bson.ObjectId) ([]*model.AuditEntry, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*model.AuditEntry
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) []*model.AuditEntry); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.AuditEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
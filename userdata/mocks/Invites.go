@@ -0,0 +1,122 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+import "github.com/Lunchr/luncher-api/db/model"
+import "gopkg.in/mgo.v2/bson"
+
+type Invites struct {
+	mock.Mock
+}
+
+func (_m *Invites) Insert(_a0 *model.Invite) (*model.Invite, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *model.Invite
+	if rf, ok := ret.Get(0).(func(*model.Invite) *model.Invite); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Invite)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*model.Invite) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Invites) GetToken(_a0 model.Token) (*model.Invite, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *model.Invite
+	if rf, ok := ret.Get(0).(func(model.Token) *model.Invite); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Invite)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(model.Token) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Invites) GetForRestaurant(_a0 bson.ObjectId) ([]*model.Invite, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*model.Invite
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) []*model.Invite); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Invite)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *Invites) Remove(_a0 bson.ObjectId, _a1 bson.ObjectId) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, bson.ObjectId) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Invites) RemoveInvitedBy(userID bson.ObjectId) error {
+	ret := _m.Called(userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Invites) RemoveForEmail(email string) error {
+	ret := _m.Called(email)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *Invites) RemoveForRestaurant(restaurantID bson.ObjectId) error {
+	ret := _m.Called(restaurantID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(restaurantID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
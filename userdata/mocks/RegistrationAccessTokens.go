@@ -0,0 +1,128 @@
+package mocks
+
+import "github.com/Lunchr/luncher-api/db"
+import "github.com/stretchr/testify/mock"
+
+import "time"
+import "github.com/Lunchr/luncher-api/db/model"
+
+import "gopkg.in/mgo.v2/bson"
+
+type RegistrationAccessTokens struct {
+	mock.Mock
+}
+
+func (_m *RegistrationAccessTokens) Insert(_a0 *model.RegistrationAccessToken) (*model.RegistrationAccessToken, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *model.RegistrationAccessToken
+	if rf, ok := ret.Get(0).(func(*model.RegistrationAccessToken) *model.RegistrationAccessToken); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.RegistrationAccessToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*model.RegistrationAccessToken) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *RegistrationAccessTokens) Get(_a0 model.Token) (*model.RegistrationAccessToken, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *model.RegistrationAccessToken
+	if rf, ok := ret.Get(0).(func(model.Token) *model.RegistrationAccessToken); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.RegistrationAccessToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(model.Token) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *RegistrationAccessTokens) Claim(token model.Token, userID bson.ObjectId) error {
+	ret := _m.Called(token, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(model.Token, bson.ObjectId) error); ok {
+		r0 = rf(token, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *RegistrationAccessTokens) Release(token model.Token, userID bson.ObjectId) error {
+	ret := _m.Called(token, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(model.Token, bson.ObjectId) error); ok {
+		r0 = rf(token, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *RegistrationAccessTokens) GetAll() db.RegistrationAccessTokenIter {
+	ret := _m.Called()
+
+	var r0 db.RegistrationAccessTokenIter
+	if rf, ok := ret.Get(0).(func() db.RegistrationAccessTokenIter); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(db.RegistrationAccessTokenIter)
+	}
+
+	return r0
+}
+func (_m *RegistrationAccessTokens) Remove(_a0 model.Token) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(model.Token) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *RegistrationAccessTokens) Extend(token model.Token, expiresAt time.Time) error {
+	ret := _m.Called(token, expiresAt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(model.Token, time.Time) error); ok {
+		r0 = rf(token, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *RegistrationAccessTokens) RemoveClaimedBy(userID bson.ObjectId) error {
+	ret := _m.Called(userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) error); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
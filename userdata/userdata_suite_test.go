@@ -0,0 +1,13 @@
+package userdata_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestUserdata(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Userdata Suite")
+}